@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/auth"
 	"venta-de-pasajes/internal/baggage"
 )
 
@@ -12,19 +13,58 @@ func main() {
 	// Configurar la carga de configuración
 	cfg := config.NewConfig()
 
+	// Inicializar la verificación de los tokens emitidos por search-service
+	tokenManager, err := auth.NewTokenManager(cfg.Auth)
+	if err != nil {
+		log.Fatal("Error al inicializar los tokens de acceso: ", err)
+	}
+	// El rol vigente de cada usuario se lee de las cuentas que registra search-service
+	authRepo, err := auth.NewRepository(cfg)
+	if err != nil {
+		log.Fatal("Error al inicializar el repositorio de cuentas de usuario: ", err)
+	}
+	authenticator := auth.NewAuthenticator(tokenManager, authRepo)
+
 	// Inicializar el repositorio de equipaje
 	baggageRepo, err := baggage.NewRepository(cfg)
 	if err != nil {
 		log.Fatal("Error al crear el repositorio de equipaje: ", err)
 	}
 
+	// Crear los índices de las colecciones de equipaje
+	if err := baggageRepo.EnsureIndexes(); err != nil {
+		log.Fatal("Error al crear los índices de equipaje: ", err)
+	}
+
 	// Inicializar el manejador de equipaje
 	baggageHandler := baggage.NewBaggageHandler(baggageRepo)
 
 	// Configurar rutas de equipaje
-	http.HandleFunc("/baggage/reserve", baggageHandler.AddBaggageToReservationBaggageHandler)
+	http.HandleFunc("/baggage/reserve", authenticator.Require(baggageHandler.AddBaggageToReservationBaggageHandler))
 	http.HandleFunc("/baggage/types", baggageHandler.GetBaggageTypesByNameBaggageHandler)
-	http.HandleFunc("/baggage/add", baggageHandler.CreateReservationBaggageHandler)
+	http.HandleFunc("/baggage/types/create", authenticator.RequirePermission(auth.PermBaggageCatalog, baggageHandler.CreateBaggageTypeBaggageHandler))
+	http.HandleFunc("/baggage/types/update-price", authenticator.RequirePermission(auth.PermBaggageCatalog, baggageHandler.UpdateBaggageTypePriceBaggageHandler))
+	http.HandleFunc("/baggage/types/deactivate", authenticator.RequirePermission(auth.PermBaggageCatalog, baggageHandler.DeactivateBaggageTypeBaggageHandler))
+	http.HandleFunc("/baggage/add", authenticator.Require(baggageHandler.CreateReservationBaggageHandler))
+	http.HandleFunc("/baggage/quote", authenticator.Require(baggageHandler.CalculateBaggagePriceBaggageHandler))
+	http.HandleFunc("/baggage/reservation", authenticator.Require(baggageHandler.GetReservationBaggageHandler))
+	http.HandleFunc("/baggage/items/update", authenticator.Require(baggageHandler.UpdateBaggageItemBaggageHandler))
+	http.HandleFunc("/baggage/items/remove", authenticator.Require(baggageHandler.RemoveBaggageItemBaggageHandler))
+	http.HandleFunc("/baggage/items/review", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.ReviewBaggageItemBaggageHandler))
+	http.HandleFunc("/baggage/categories", baggageHandler.GetCategoriesBaggageHandler)
+	http.HandleFunc("/baggage/categories/save", authenticator.RequirePermission(auth.PermBaggageCatalog, baggageHandler.SaveCategoryBaggageHandler))
+	http.HandleFunc("/baggage/categories/usage", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.GetCategoryUsageBaggageHandler))
+	http.HandleFunc("/baggage/holds/configure", authenticator.RequirePermission(auth.PermBaggageHolds, baggageHandler.ConfigureHoldBaggageHandler))
+	http.HandleFunc("/baggage/holds/report", authenticator.RequirePermission(auth.PermBaggageHolds, baggageHandler.GetHoldLoadReportBaggageHandler))
+	http.HandleFunc("/baggage/tags", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.GetPiecesBaggageHandler))
+	http.HandleFunc("/baggage/tags/generate", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.TagBaggageBaggageHandler))
+	http.HandleFunc("/baggage/tags/barcode", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.GetTagBarcodeBaggageHandler))
+	http.HandleFunc("/baggage/tags/label", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.GetTagLabelBaggageHandler))
+	http.HandleFunc("/baggage/tracking/scan", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.ScanPieceBaggageHandler))
+	http.HandleFunc("/baggage/tracking/history", authenticator.RequirePermission(auth.PermBaggageHandling, baggageHandler.GetTrackingHistoryBaggageHandler))
+	http.HandleFunc("/baggage/tracking/status", authenticator.Require(baggageHandler.GetTrackingStatusBaggageHandler))
+	http.HandleFunc("/baggage/allowances", baggageHandler.GetAllowancesBaggageHandler)
+	http.HandleFunc("/baggage/allowances/create", authenticator.RequirePermission(auth.PermBaggageCatalog, baggageHandler.CreateAllowanceBaggageHandler))
 
 	// Configurar el servidor HTTP para que escuche en un puerto específico
 	serverAddr := ":8081" // Puerto al que HAProxy redirigirá las solicitudes
@@ -1,10 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"time"
+	_ "time/tzdata" // Zonas horarias del catálogo de ubicaciones aunque la imagen no las incluya
 
 	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/agency"
+	"venta-de-pasajes/internal/auth"
+	"venta-de-pasajes/internal/boarding"
+	"venta-de-pasajes/internal/crew"
+	"venta-de-pasajes/internal/fleet"
+	"venta-de-pasajes/internal/group"
+	"venta-de-pasajes/internal/location"
+	"venta-de-pasajes/internal/operator"
 	"venta-de-pasajes/internal/search"
 	"venta-de-pasajes/internal/search/repository"
 )
@@ -13,18 +25,261 @@ func main() {
 	// Configurar la carga de configuración
 	cfg := config.NewConfig()
 
+	// Inicializar la autenticación; los tokens se firman con el secreto compartido por todas las réplicas
+	tokenManager, err := auth.NewTokenManager(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Error al inicializar los tokens de acceso: %v", err)
+	}
+	authRepo, err := auth.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de cuentas de usuario: %v", err)
+	}
+	if err := authRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error al crear los índices de cuentas de usuario: %v", err)
+	}
+	authenticator := auth.NewAuthenticator(tokenManager, authRepo)
+
 	// Inicializar el repositorio de búsqueda para MongoDB
 	searchRepo, err := repository.NewMongoDBRepository(cfg)
 	if err != nil {
 		log.Fatalf("Error al inicializar el repositorio de MongoDB: %v", err)
 	}
 
+	// Inicializar el catálogo de ubicaciones
+	locationRepo, err := location.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de ubicaciones: %v", err)
+	}
+	locationCatalog := location.NewCatalog(locationRepo)
+	// Sin ubicaciones el servicio inicia igual, pero no resuelve ciudades hasta que se registren
+	if err := locationCatalog.Reload(context.Background()); errors.Is(err, location.ErrEmptyCatalog) {
+		log.Println(err)
+	} else if err != nil {
+		log.Fatalf("Error al cargar el catálogo de ubicaciones: %v", err)
+	}
+
+	// Inicializar el repositorio de operadores
+	operatorRepo, err := operator.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de operadores: %v", err)
+	}
+	if err := operatorRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error al crear los índices de operadores: %v", err)
+	}
+
+	// Inicializar el repositorio de la flota
+	fleetRepo, err := fleet.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de la flota: %v", err)
+	}
+	if err := fleetRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error al crear los índices de la flota: %v", err)
+	}
+
+	// Inicializar el registro de check-in y embarque, que también consultan la sobreventa y las cancelaciones de las agencias
+	boardingRepo, err := boarding.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de check-in y embarque: %v", err)
+	}
+	if err := boardingRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error al crear los índices de check-in y embarque: %v", err)
+	}
+
+	// La sobreventa se resuelve desde la administración de salidas y desde el check-in con el mismo BoardingDenier
+	boardingDenier := search.NewBoardingDenier(searchRepo, operatorRepo, boardingRepo)
+
 	// Inicializar el manejador de búsqueda
-	searchHandler := search.NewSearchHandler(searchRepo)
+	searchHandler := search.NewSearchHandler(searchRepo, locationCatalog, operatorRepo, fleetRepo, boardingRepo, boardingDenier)
+
+	// Inicializar el repositorio de la tripulación
+	crewRepo, err := crew.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de la tripulación: %v", err)
+	}
+	if err := crewRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error al crear los índices de la tripulación: %v", err)
+	}
+	crewAssigner := crew.NewAssigner(crewRepo, searchRepo, crew.NewDutyRules(cfg.Crew))
+
+	// Inicializar la lista de espera de las salidas agotadas
+	waitlist := search.NewWaitlist(searchRepo, fleetRepo, cfg.Waitlist.ClaimWindow)
+
+	// Inicializar las reservas de grupo
+	groupRepo, err := group.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de reservas de grupo: %v", err)
+	}
+	if err := groupRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error al crear los índices de las reservas de grupo: %v", err)
+	}
+	groupManager := group.NewManager(groupRepo, searchRepo, operatorRepo, boardingRepo, fleetRepo, cfg.Groups)
+	groupManager.RegisterSeatReleaseHook(waitlist)
 
-	// Configurar rutas de búsqueda
+	// Inicializar las agencias de viaje
+	agencyRepo, err := agency.NewRepository(cfg)
+	if err != nil {
+		log.Fatalf("Error al inicializar el repositorio de agencias: %v", err)
+	}
+	if err := agencyRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error al crear los índices de agencias: %v", err)
+	}
+	agencyManager := agency.NewManager(agencyRepo, searchRepo, operatorRepo, boardingRepo, fleetRepo, cfg.Agencies)
+	agencyManager.RegisterSeatReleaseHook(waitlist)
+
+	// Al cancelar una ruta primero se reubican las reservas y sus grupos y se reembolsa a las agencias, luego se notifica a los usuarios y se libera a la tripulación
+	searchHandler.RegisterCancellationHook(search.NewRebookingHook(searchRepo))
+	searchHandler.RegisterCancellationHook(groupManager)
+	searchHandler.RegisterCancellationHook(agencyManager)
+	searchHandler.RegisterCancellationHook(search.LogNotificationHook{})
+	searchHandler.RegisterCancellationHook(crewAssigner)
+	searchHandler.RegisterCancellationHook(waitlist)
+
+	// Al cancelar una reserva o ampliar una ruta los asientos se ofrecen a la lista de espera
+	searchHandler.RegisterSeatReleaseHook(waitlist)
+	searchHandler.RegisterCapacityIncreaseHook(waitlist)
+
+	// Al denegar el embarque en una salida sobrevendida se reembolsa a las agencias y los grupos siguen a su reserva
+	boardingDenier.RegisterHook(agencyManager)
+	boardingDenier.RegisterHook(groupManager)
+
+	// Al reprogramar una ruta se desplazan los turnos de la tripulación
+	searchHandler.RegisterRescheduleHook(crewAssigner)
+
+	// Configurar rutas de cuentas de usuario
+	accounts, err := auth.NewAccounts(authRepo, tokenManager, cfg.Auth.BcryptCost)
+	if err != nil {
+		log.Fatalf("Error al inicializar las cuentas de usuario: %v", err)
+	}
+	authHandler := auth.NewAuthHandler(authRepo, accounts, operatorRepo)
+	http.HandleFunc("/auth/register", authHandler.RegisterHandler)
+	http.HandleFunc("/auth/login", authHandler.LoginHandler)
+	http.HandleFunc("/auth/me", authenticator.Require(authHandler.MeHandler))
+	http.HandleFunc("/admin/users", authenticator.RequirePermission(auth.PermUsersManage, authHandler.GetUsersHandler))
+	http.HandleFunc("/admin/users/role", authenticator.RequirePermission(auth.PermUsersManage, authHandler.SetRoleHandler))
+
+	// Configurar rutas de búsqueda; las reservas siempre quedan a nombre del usuario autenticado
 	http.HandleFunc("/search", searchHandler.SearchRoutesHandler)
-	http.HandleFunc("/reserve", searchHandler.ReserveRouteHandler)
+	http.HandleFunc("/reserve", authenticator.Require(searchHandler.ReserveRouteHandler))
+	http.HandleFunc("/routes/seatmap", searchHandler.SeatMapHandler)
+	http.HandleFunc("/reservations/cancel", authenticator.Require(searchHandler.CancelReservationHandler))
+	http.HandleFunc("/reservations/change", authenticator.Require(searchHandler.ChangeReservationHandler))
+	http.HandleFunc("/reservations/volunteer", authenticator.Require(searchHandler.BoardingVolunteerHandler))
+
+	// Configurar rutas de la lista de espera
+	waitlistHandler := search.NewWaitlistHandler(searchRepo, waitlist)
+	http.HandleFunc("/waitlist", authenticator.Require(waitlistHandler.GetWaitlistHandler))
+	http.HandleFunc("/waitlist/join", authenticator.Require(waitlistHandler.JoinWaitlistHandler))
+	http.HandleFunc("/waitlist/claim", authenticator.Require(waitlistHandler.ClaimWaitlistHandler))
+	http.HandleFunc("/waitlist/leave", authenticator.Require(waitlistHandler.LeaveWaitlistHandler))
+
+	// Vencer periódicamente las ofertas no confirmadas y ofrecer sus asientos al siguiente de la lista
+	go waitlist.Run(context.Background(), cfg.Waitlist.SweepInterval)
+
+	// Configurar rutas de tarjetas de embarque
+	boardingSigner, err := boarding.NewSigner(cfg.Boarding)
+	if err != nil {
+		log.Fatalf("Error al inicializar la firma de las tarjetas de embarque: %v", err)
+	}
+	boardingIssuer := boarding.NewIssuer(searchRepo, boardingSigner, cfg.Boarding)
+	// El check-in resuelve la sobreventa de la salida antes de registrar a cada pasajero
+	boardingDesk := boarding.NewDesk(boardingRepo, searchRepo, boardingIssuer, boardingDenier, cfg.Boarding)
+	boardingHandler := boarding.NewBoardingHandler(boardingRepo, boardingDesk, boardingIssuer, boardingSigner, cfg.Boarding)
+	http.HandleFunc("/boarding-passes", authenticator.Require(boardingHandler.GetPassesHandler))
+	http.HandleFunc("/boarding-passes/qr", authenticator.Require(boardingHandler.GetQRCodeHandler))
+	http.HandleFunc("/boarding-passes/verify", boardingHandler.VerifyPassHandler)
+	http.HandleFunc("/boarding-passes/public-key", boardingHandler.PublicKeyHandler)
+
+	// Configurar rutas de check-in y embarque
+	http.HandleFunc("/check-in", authenticator.Require(boardingHandler.CheckInHandler))
+	http.HandleFunc("/admin/check-in", authenticator.RequirePermission(auth.PermBoardingManage, boardingHandler.CounterCheckInHandler))
+	http.HandleFunc("/admin/boarding/scan", authenticator.RequirePermission(auth.PermBoardingManage, boardingHandler.ScanHandler))
+	http.HandleFunc("/admin/boarding/manifest", authenticator.RequirePermission(auth.PermBoardingManage, boardingHandler.ManifestHandler))
+	http.HandleFunc("/admin/routes/no-show", authenticator.RequirePermission(auth.PermBoardingManage, boardingHandler.NoShowHandler))
+
+	// Configurar rutas de reservas de grupo
+	groupHandler := group.NewGroupHandler(groupRepo, groupManager)
+	http.HandleFunc("/groups", authenticator.Require(groupHandler.GetGroupsHandler))
+	http.HandleFunc("/groups/create", authenticator.Require(groupHandler.CreateGroupHandler))
+	http.HandleFunc("/groups/passengers", authenticator.Require(groupHandler.SetPassengersHandler))
+	http.HandleFunc("/groups/passengers/cancel", authenticator.Require(groupHandler.CancelPassengersHandler))
+	http.HandleFunc("/groups/cancel", authenticator.Require(groupHandler.CancelGroupHandler))
+	http.HandleFunc("/admin/groups/payment", authenticator.RequirePermission(auth.PermGroupPayments, groupHandler.AddPaymentHandler))
+
+	// Cancelar periódicamente los grupos que no pagaron el adelanto o el saldo a tiempo
+	go groupManager.Run(context.Background(), cfg.Groups.SweepInterval)
+
+	// Configurar rutas de la API de agencias, autenticadas con su clave de API, y de su administración
+	agencyHandler := agency.NewAgencyHandler(agencyRepo, agencyManager)
+	http.HandleFunc("/agency/account", agencyManager.RequireAPIKey(agencyHandler.AccountHandler))
+	http.HandleFunc("/agency/reserve", agencyManager.RequireAPIKey(agencyHandler.ReserveHandler))
+	http.HandleFunc("/agency/reservations", agencyManager.RequireAPIKey(agencyHandler.GetReservationHandler))
+	http.HandleFunc("/agency/reservations/cancel", agencyManager.RequireAPIKey(agencyHandler.CancelReservationHandler))
+	http.HandleFunc("/agency/statement", agencyManager.RequireAPIKey(agencyHandler.StatementHandler))
+	http.HandleFunc("/admin/agencies", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.GetAgenciesHandler))
+	http.HandleFunc("/admin/agencies/create", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.CreateAgencyHandler))
+	http.HandleFunc("/admin/agencies/update", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.UpdateAgencyHandler))
+	http.HandleFunc("/admin/agencies/active", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.SetAgencyActiveHandler))
+	http.HandleFunc("/admin/agencies/keys/issue", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.IssueKeyHandler))
+	http.HandleFunc("/admin/agencies/keys/revoke", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.RevokeKeyHandler))
+	http.HandleFunc("/admin/agencies/top-up", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.TopUpHandler))
+	http.HandleFunc("/admin/agencies/statement", authenticator.RequirePermission(auth.PermAgenciesManage, agencyHandler.StatementHandler))
+
+	// Configurar rutas de administración de rutas
+	http.HandleFunc("/admin/routes", authenticator.RequirePermission(auth.PermRoutesManage, searchHandler.GetRouteHandler))
+	http.HandleFunc("/admin/routes/create", authenticator.RequirePermission(auth.PermRoutesManage, searchHandler.CreateRouteHandler))
+	http.HandleFunc("/admin/routes/update", authenticator.RequirePermission(auth.PermRoutesManage, searchHandler.UpdateRouteHandler))
+	http.HandleFunc("/admin/routes/reschedule", authenticator.RequirePermission(auth.PermRoutesManage, searchHandler.RescheduleRouteHandler))
+	http.HandleFunc("/admin/routes/cancel", authenticator.RequirePermission(auth.PermRoutesManage, searchHandler.CancelRouteHandler))
+	http.HandleFunc("/admin/routes/assign-vehicle", authenticator.RequirePermission(auth.PermRoutesManage, searchHandler.AssignVehicleHandler))
+	http.HandleFunc("/admin/routes/denied-boarding", authenticator.RequirePermission(auth.PermOversaleResolve, searchHandler.DeniedBoardingHandler))
+
+	// Configurar rutas de la flota
+	fleetHandler := fleet.NewFleetHandler(fleetRepo, operatorRepo)
+	http.HandleFunc("/admin/vehicles", authenticator.RequirePermission(auth.PermFleetManage, fleetHandler.GetVehiclesHandler))
+	http.HandleFunc("/admin/vehicles/create", authenticator.RequirePermission(auth.PermFleetManage, fleetHandler.CreateVehicleHandler))
+	http.HandleFunc("/admin/vehicles/update", authenticator.RequirePermission(auth.PermFleetManage, fleetHandler.UpdateVehicleHandler))
+
+	// Configurar rutas de la tripulación
+	crewHandler := crew.NewCrewHandler(crewRepo, crewAssigner, operatorRepo)
+	http.HandleFunc("/admin/crew", authenticator.RequirePermission(auth.PermCrewManage, crewHandler.GetMembersHandler))
+	http.HandleFunc("/admin/crew/create", authenticator.RequirePermission(auth.PermCrewManage, crewHandler.CreateMemberHandler))
+	http.HandleFunc("/admin/crew/active", authenticator.RequirePermission(auth.PermCrewManage, crewHandler.SetMemberActiveHandler))
+	http.HandleFunc("/admin/crew/assign", authenticator.RequirePermission(auth.PermCrewManage, crewHandler.AssignHandler))
+	http.HandleFunc("/admin/crew/unassign", authenticator.RequirePermission(auth.PermCrewManage, crewHandler.UnassignHandler))
+	http.HandleFunc("/admin/crew/route", authenticator.RequirePermission(auth.PermCrewManage, crewHandler.RouteCrewHandler))
+	http.HandleFunc("/admin/crew/roster", authenticator.RequirePermission(auth.PermCrewManage, crewHandler.RosterHandler))
+
+	// Configurar rutas de operadores
+	operatorHandler := operator.NewOperatorHandler(operatorRepo)
+	http.HandleFunc("/operators", operatorHandler.GetOperatorsHandler)
+	http.HandleFunc("/admin/operators/create", authenticator.RequirePermission(auth.PermOperatorsManage, operatorHandler.CreateOperatorHandler))
+	http.HandleFunc("/admin/operators/update", authenticator.RequirePermission(auth.PermOperatorProfile, operatorHandler.UpdateOperatorHandler))
+	http.HandleFunc("/admin/operators/active", authenticator.RequirePermission(auth.PermOperatorsManage, operatorHandler.SetOperatorActiveHandler))
+	http.HandleFunc("/admin/operators/report", authenticator.RequirePermission(auth.PermReports, searchHandler.OperatorReportHandler))
+
+	// Configurar rutas del catálogo de ubicaciones
+	locationHandler := location.NewLocationHandler(locationRepo, locationCatalog)
+	http.HandleFunc("/locations", locationHandler.GetLocationsHandler)
+	http.HandleFunc("/locations/resolve", locationHandler.ResolveLocationHandler)
+	http.HandleFunc("/locations/suggest", locationHandler.SuggestLocationsHandler)
+	http.HandleFunc("/admin/locations/save", authenticator.RequirePermission(auth.PermLocationsManage, locationHandler.SaveLocationHandler))
+
+	// Actualizar periódicamente la popularidad de las ciudades para ordenar las sugerencias
+	go refreshPopularity(context.Background(), searchRepo, locationCatalog, cfg.Locations.PopularityInterval)
+
+	// Configurar rutas de horarios recurrentes y feriados
+	scheduleGenerator := search.NewScheduleGenerator(searchRepo, cfg.Schedule.Horizon)
+	scheduleHandler := search.NewScheduleHandler(searchRepo, scheduleGenerator, locationCatalog, operatorRepo, fleetRepo)
+	http.HandleFunc("/admin/schedules", authenticator.RequirePermission(auth.PermSchedulesManage, scheduleHandler.GetSchedulesHandler))
+	http.HandleFunc("/admin/schedules/create", authenticator.RequirePermission(auth.PermSchedulesManage, scheduleHandler.CreateScheduleHandler))
+	http.HandleFunc("/admin/schedules/exceptions", authenticator.RequirePermission(auth.PermSchedulesManage, scheduleHandler.SaveScheduleExceptionHandler))
+	http.HandleFunc("/admin/schedules/active", authenticator.RequirePermission(auth.PermSchedulesManage, scheduleHandler.SetScheduleActiveHandler))
+	http.HandleFunc("/admin/schedules/generate", authenticator.RequirePermission(auth.PermSchedulesRun, scheduleHandler.GenerateSchedulesHandler))
+	http.HandleFunc("/admin/holidays", authenticator.RequirePermission(auth.PermSchedulesManage, scheduleHandler.GetHolidaysHandler))
+	http.HandleFunc("/admin/holidays/save", authenticator.RequirePermission(auth.PermHolidaysManage, scheduleHandler.SaveHolidayHandler))
+
+	// Generar periódicamente las salidas del horizonte configurado
+	go scheduleGenerator.Run(context.Background(), cfg.Schedule.Interval)
 
 	// Realizar la migración de la base de datos
 	// err = searchHandler.MigrateDBHandler()
@@ -46,3 +301,24 @@ func main() {
 		log.Fatalf("Error al iniciar el servidor HTTP: %v", err)
 	}
 }
+
+// refreshPopularity actualiza la popularidad de las ciudades del catálogo hasta que el contexto se cancele
+func refreshPopularity(ctx context.Context, repo search.SearchRepository, catalog *location.Catalog, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		popularity, err := repo.GetCityPopularity(ctx)
+		if err != nil {
+			log.Printf("Error al calcular la popularidad de las ciudades: %v", err)
+		} else {
+			catalog.SetPopularity(popularity)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -13,6 +15,26 @@ type MongoDBConfig struct {
 	RoutesCollection              string
 	BaggageReservationsCollection string
 	BaggageTypesCollection        string
+	BaggageAllowancesCollection   string
+	BaggagePiecesCollection       string
+	BaggageEventsCollection       string
+	BaggageCategoriesCollection   string
+	CategoryUsageCollection       string
+	BaggageHoldsCollection        string
+	CountersCollection            string
+	ScheduleTemplatesCollection   string
+	HolidaysCollection            string
+	LocationsCollection           string
+	OperatorsCollection           string
+	VehiclesCollection            string
+	CrewMembersCollection         string
+	CrewAssignmentsCollection     string
+	WaitlistCollection            string
+	GroupBookingsCollection       string
+	BoardingRecordsCollection     string
+	UsersCollection               string
+	AgenciesCollection            string
+	AgencyTransactionsCollection  string
 	ServerPort                    string
 	MongoTimeout                  time.Duration
 }
@@ -26,10 +48,89 @@ type MySQLConfig struct {
 	DatabaseName string
 }
 
+// BaggageConfig almacena la configuración del servicio de equipaje
+type BaggageConfig struct {
+	DefaultHoldWeight float64 // Capacidad de peso de la bodega por salida (kg)
+	DefaultHoldVolume float64 // Capacidad de volumen de la bodega por salida (m³)
+}
+
+// ScheduleConfig almacena la configuración de la generación de salidas a partir de horarios
+type ScheduleConfig struct {
+	Horizon  time.Duration // Días hacia adelante para los que se generan salidas
+	Interval time.Duration // Frecuencia de la generación automática
+}
+
+// LocationConfig almacena la configuración del catálogo de ubicaciones
+type LocationConfig struct {
+	PopularityInterval time.Duration // Frecuencia de actualización de la popularidad para el autocompletado
+}
+
+// CrewConfig almacena los límites de jornada de los conductores
+type CrewConfig struct {
+	MaxContinuousDriving time.Duration // Conducción continua máxima
+	MaxDailyDriving      time.Duration // Conducción máxima en 24 horas
+	MinBreak             time.Duration // Pausa mínima que interrumpe la conducción continua
+	MinRest              time.Duration // Descanso mínimo entre jornadas
+}
+
+// WaitlistConfig almacena la configuración de la lista de espera
+type WaitlistConfig struct {
+	ClaimWindow   time.Duration // Tiempo para confirmar los asientos ofrecidos
+	SweepInterval time.Duration // Frecuencia de revisión de las ofertas vencidas
+}
+
+// GroupConfig almacena las condiciones de las reservas de grupo
+type GroupConfig struct {
+	MinSize          int           // Asientos mínimos para una reserva de grupo
+	DefaultDiscount  float64       // Descuento sobre la tarifa cuando no se negocia una tarifa de grupo (%)
+	DepositPercent   float64       // Adelanto sobre el total de la reserva (%)
+	DepositWindow    time.Duration // Plazo para pagar el adelanto desde la reserva
+	BalanceLeadTime  time.Duration // Anticipación a la salida con la que vence el pago del saldo
+	NameListLeadTime time.Duration // Anticipación a la salida con la que vence la lista de pasajeros
+	SweepInterval    time.Duration // Frecuencia de revisión de los pagos vencidos
+}
+
+// BoardingConfig almacena la configuración de las tarjetas de embarque
+type BoardingConfig struct {
+	SigningKey    string        // Semilla Ed25519 en base64 con la que se firman los códigos QR
+	KeyID         string        // Identificador de la clave, para rotarla sin invalidar las tarjetas ya emitidas
+	RetiredKeys   string        // Claves públicas anteriores que se siguen aceptando, "kid:clave en base64" separadas por comas
+	ValidAfter    time.Duration // Vigencia de la tarjeta después de la salida
+	PassTypeID    string        // Identificador del tipo de pase para las billeteras digitales
+	Organization  string        // Nombre que muestran las billeteras digitales
+	OnlineOpens   time.Duration // Anticipación a la salida con la que abre el check-in en línea
+	OnlineCloses  time.Duration // Anticipación a la salida con la que cierra el check-in en línea
+	CounterCloses time.Duration // Anticipación a la salida con la que cierra el check-in en mostrador
+	BoardingOpens time.Duration // Anticipación a la salida con la que empieza el embarque
+}
+
+// AuthConfig almacena la configuración de las cuentas de usuario y de los tokens de acceso
+type AuthConfig struct {
+	JWTSecret  string        // Secreto compartido por los servicios para firmar y verificar los tokens
+	TokenTTL   time.Duration // Vigencia de los tokens de acceso
+	Issuer     string        // Emisor de los tokens
+	BcryptCost int           // Costo del hash de las contraseñas
+}
+
+// AgencyConfig almacena las condiciones por defecto de las agencias de viaje
+type AgencyConfig struct {
+	DefaultCommission  float64 // Comisión sobre el total de la venta cuando la agencia no tiene una regla aplicable (%)
+	DefaultCreditLimit float64 // Crédito con el que se crea una agencia si no se indica otro
+}
+
 // Config almacena la configuración global del programa
 type Config struct {
 	MongoDB    MongoDBConfig
 	MySQL      MySQLConfig
+	Baggage    BaggageConfig
+	Schedule   ScheduleConfig
+	Locations  LocationConfig
+	Crew       CrewConfig
+	Waitlist   WaitlistConfig
+	Groups     GroupConfig
+	Boarding   BoardingConfig
+	Auth       AuthConfig
+	Agencies   AgencyConfig
 	ServerPort string
 	UsingMongo bool
 }
@@ -45,6 +146,26 @@ func NewConfig() *Config {
 			RoutesCollection:              getEnv("ROUTES_COLLECTION", "routes"),
 			BaggageReservationsCollection: getEnv("BAGGAGES_COLLECTION", "baggageReservations"),
 			BaggageTypesCollection:        getEnv("BAGGAGE_TYPES_COLLECTION", "baggageTypes"),
+			BaggageAllowancesCollection:   getEnv("BAGGAGE_ALLOWANCES_COLLECTION", "baggageAllowances"),
+			BaggagePiecesCollection:       getEnv("BAGGAGE_PIECES_COLLECTION", "baggagePieces"),
+			BaggageEventsCollection:       getEnv("BAGGAGE_EVENTS_COLLECTION", "baggageEvents"),
+			BaggageCategoriesCollection:   getEnv("BAGGAGE_CATEGORIES_COLLECTION", "baggageCategories"),
+			CategoryUsageCollection:       getEnv("CATEGORY_USAGE_COLLECTION", "baggageCategoryUsage"),
+			BaggageHoldsCollection:        getEnv("BAGGAGE_HOLDS_COLLECTION", "baggageHolds"),
+			CountersCollection:            getEnv("COUNTERS_COLLECTION", "counters"),
+			ScheduleTemplatesCollection:   getEnv("SCHEDULE_TEMPLATES_COLLECTION", "scheduleTemplates"),
+			HolidaysCollection:            getEnv("HOLIDAYS_COLLECTION", "holidays"),
+			LocationsCollection:           getEnv("LOCATIONS_COLLECTION", "locations"),
+			OperatorsCollection:           getEnv("OPERATORS_COLLECTION", "operators"),
+			VehiclesCollection:            getEnv("VEHICLES_COLLECTION", "vehicles"),
+			CrewMembersCollection:         getEnv("CREW_MEMBERS_COLLECTION", "crewMembers"),
+			CrewAssignmentsCollection:     getEnv("CREW_ASSIGNMENTS_COLLECTION", "crewAssignments"),
+			WaitlistCollection:            getEnv("WAITLIST_COLLECTION", "waitlist"),
+			GroupBookingsCollection:       getEnv("GROUP_BOOKINGS_COLLECTION", "groupBookings"),
+			BoardingRecordsCollection:     getEnv("BOARDING_RECORDS_COLLECTION", "boardingRecords"),
+			UsersCollection:               getEnv("USERS_COLLECTION", "users"),
+			AgenciesCollection:            getEnv("AGENCIES_COLLECTION", "agencies"),
+			AgencyTransactionsCollection:  getEnv("AGENCY_TRANSACTIONS_COLLECTION", "agencyTransactions"),
 		},
 		MySQL: MySQLConfig{
 			Username:     getEnv("MYSQL_USERNAME", "root"),
@@ -53,6 +174,58 @@ func NewConfig() *Config {
 			Port:         getEnv("MYSQL_PORT", "3306"),
 			DatabaseName: getEnv("MYSQL_DATABASE", "venta_de_pasajes"),
 		},
+		Baggage: BaggageConfig{
+			DefaultHoldWeight: getEnvFloat("DEFAULT_HOLD_WEIGHT", 1500),
+			DefaultHoldVolume: getEnvFloat("DEFAULT_HOLD_VOLUME", 10),
+		},
+		Schedule: ScheduleConfig{
+			Horizon:  getEnvDuration("SCHEDULE_HORIZON", 30*24*time.Hour),
+			Interval: getEnvDuration("SCHEDULE_INTERVAL", 6*time.Hour),
+		},
+		Locations: LocationConfig{
+			PopularityInterval: getEnvDuration("LOCATION_POPULARITY_INTERVAL", time.Hour),
+		},
+		Crew: CrewConfig{
+			MaxContinuousDriving: getEnvDuration("CREW_MAX_CONTINUOUS_DRIVING", 5*time.Hour),
+			MaxDailyDriving:      getEnvDuration("CREW_MAX_DAILY_DRIVING", 10*time.Hour),
+			MinBreak:             getEnvDuration("CREW_MIN_BREAK", 30*time.Minute),
+			MinRest:              getEnvDuration("CREW_MIN_REST", 8*time.Hour),
+		},
+		Waitlist: WaitlistConfig{
+			ClaimWindow:   getEnvDuration("WAITLIST_CLAIM_WINDOW", 30*time.Minute),
+			SweepInterval: getEnvDuration("WAITLIST_SWEEP_INTERVAL", time.Minute),
+		},
+		Groups: GroupConfig{
+			MinSize:          getEnvInt("GROUP_MIN_SIZE", 10),
+			DefaultDiscount:  getEnvFloat("GROUP_DEFAULT_DISCOUNT", 10),
+			DepositPercent:   getEnvFloat("GROUP_DEPOSIT_PERCENT", 30),
+			DepositWindow:    getEnvDuration("GROUP_DEPOSIT_WINDOW", 72*time.Hour),
+			BalanceLeadTime:  getEnvDuration("GROUP_BALANCE_LEAD_TIME", 7*24*time.Hour),
+			NameListLeadTime: getEnvDuration("GROUP_NAME_LIST_LEAD_TIME", 48*time.Hour),
+			SweepInterval:    getEnvDuration("GROUP_SWEEP_INTERVAL", 15*time.Minute),
+		},
+		Boarding: BoardingConfig{
+			SigningKey:    getEnv("BOARDING_SIGNING_KEY", ""),
+			KeyID:         getEnv("BOARDING_KEY_ID", "embarque-1"),
+			RetiredKeys:   getEnv("BOARDING_RETIRED_KEYS", ""),
+			ValidAfter:    getEnvDuration("BOARDING_PASS_VALID_AFTER", 2*time.Hour),
+			PassTypeID:    getEnv("BOARDING_PASS_TYPE_ID", "pass.pe.ventadepasajes.embarque"),
+			Organization:  getEnv("BOARDING_ORGANIZATION", "Venta de Pasajes"),
+			OnlineOpens:   getEnvDuration("CHECKIN_ONLINE_OPENS", 24*time.Hour),
+			OnlineCloses:  getEnvDuration("CHECKIN_ONLINE_CLOSES", time.Hour),
+			CounterCloses: getEnvDuration("CHECKIN_COUNTER_CLOSES", 15*time.Minute),
+			BoardingOpens: getEnvDuration("BOARDING_OPENS", time.Hour),
+		},
+		Auth: AuthConfig{
+			JWTSecret:  getEnv("JWT_SECRET", ""),
+			TokenTTL:   getEnvDuration("JWT_TTL", 24*time.Hour),
+			Issuer:     getEnv("JWT_ISSUER", "venta-de-pasajes"),
+			BcryptCost: getEnvInt("BCRYPT_COST", 12),
+		},
+		Agencies: AgencyConfig{
+			DefaultCommission:  getEnvFloat("AGENCY_DEFAULT_COMMISSION", 8),
+			DefaultCreditLimit: getEnvFloat("AGENCY_DEFAULT_CREDIT_LIMIT", 0),
+		},
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		UsingMongo: true,
 	}
@@ -66,12 +239,36 @@ func getEnv(key, fallbackValue string) string {
 	return fallbackValue
 }
 
-// getEnvDuration es una función de utilidad para obtener valores de variables de entorno como duración
+// getEnvDuration es una función de utilidad para obtener valores de variables de entorno como duración.
+// Todas las duraciones de la configuración son plazos o frecuencias, por lo que un valor no positivo se
+// descarta y se usa el valor por defecto.
 func getEnvDuration(key string, fallbackValue time.Duration) time.Duration {
 	if value, ok := os.LookupEnv(key); ok {
-		if duration, err := time.ParseDuration(value); err == nil {
+		duration, err := time.ParseDuration(value)
+		if err == nil && duration > 0 {
 			return duration
 		}
+		log.Printf("Valor inválido para %s (%q), se usa %s", key, value, fallbackValue)
+	}
+	return fallbackValue
+}
+
+// getEnvFloat es una función de utilidad para obtener valores de variables de entorno como número decimal
+func getEnvFloat(key string, fallbackValue float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	}
+	return fallbackValue
+}
+
+// getEnvInt es una función de utilidad para obtener valores de variables de entorno como número entero
+func getEnvInt(key string, fallbackValue int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
 	}
 	return fallbackValue
 }
@@ -0,0 +1,351 @@
+package agency
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"venta-de-pasajes/internal/operator"
+	"venta-de-pasajes/internal/search"
+)
+
+const (
+	// dateLayout es el formato de las fechas del periodo del estado de cuenta
+	dateLayout = "2006-01-02"
+)
+
+// statementLocation es la zona horaria en la que se interpretan las fechas del estado de cuenta
+var statementLocation = time.FixedZone("PET", -5*60*60)
+
+// AgencyHandler maneja las solicitudes de administración de agencias y las de la API de agencias
+type AgencyHandler struct {
+	repo    *Repository
+	manager *Manager
+}
+
+// NewAgencyHandler crea una nueva instancia de AgencyHandler
+func NewAgencyHandler(repo *Repository, manager *Manager) *AgencyHandler {
+	return &AgencyHandler{
+		repo:    repo,
+		manager: manager,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *AgencyHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAgencyNotFound), errors.Is(err, ErrAPIKeyNotFound), errors.Is(err, search.ErrRouteNotFound),
+		errors.Is(err, search.ErrReservationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAgencyExists), errors.Is(err, ErrAgencyInactive), errors.Is(err, ErrAlreadyRefunded),
+		errors.Is(err, search.ErrRouteCancelled), errors.Is(err, search.ErrReservationNotActive),
+		errors.Is(err, search.ErrCancellationClosed):
+		return http.StatusConflict
+	case errors.Is(err, ErrInsufficientCredit):
+		return http.StatusPaymentRequired
+	case errors.Is(err, ErrInvalidAgency), errors.Is(err, ErrInvalidSale), errors.Is(err, operator.ErrInvalidRUC),
+		errors.Is(err, search.ErrInvalidSeats), errors.Is(err, search.ErrInvalidSegment), errors.Is(err, search.ErrNotEnoughSeats):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// agencyRequest representa los datos de una agencia al registrarla o modificarla
+type agencyRequest struct {
+	Agency
+	CreditLimit *float64 `json:"credit_limit"` // Sin valor, al registrar se usa el crédito por defecto
+}
+
+// GetAgenciesHandler maneja las solicitudes para listar las agencias, o consultar una sola con el parámetro id
+func (h *AgencyHandler) GetAgenciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if agencyID := r.URL.Query().Get("id"); agencyID != "" {
+		agency, err := h.repo.GetAgency(r.Context(), agencyID)
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(&Account{Agency: agency, Available: agency.Available()})
+		return
+	}
+
+	agencies, err := h.repo.GetAgencies(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(agencies)
+}
+
+// CreateAgencyHandler maneja las solicitudes para registrar una agencia con sus comisiones y su límite de crédito
+func (h *AgencyHandler) CreateAgencyHandler(w http.ResponseWriter, r *http.Request) {
+	var request agencyRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	agency := request.Agency
+	if err := h.manager.Create(r.Context(), &agency, request.CreditLimit); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(agency)
+}
+
+// UpdateAgencyHandler maneja las solicitudes para modificar los datos, las comisiones o el límite de crédito de
+// una agencia. El saldo solo cambia con ventas, reembolsos y recargas.
+func (h *AgencyHandler) UpdateAgencyHandler(w http.ResponseWriter, r *http.Request) {
+	var request agencyRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if request.ID == "" || request.CreditLimit == nil {
+		http.Error(w, "los campos id y credit_limit son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	agency := request.Agency
+	agency.CreditLimit = *request.CreditLimit
+	updated, err := h.manager.Update(r.Context(), &agency)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// SetAgencyActiveHandler maneja las solicitudes para activar o desactivar una agencia
+func (h *AgencyHandler) SetAgencyActiveHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID     string `json:"id"`
+		Active *bool  `json:"active"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" || requestBody.Active == nil {
+		http.Error(w, "los campos id y active son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	agency, err := h.repo.SetAgencyActive(r.Context(), requestBody.ID, *requestBody.Active)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(agency)
+}
+
+// IssueKeyHandler maneja las solicitudes para emitir una clave de API de una agencia. La clave en claro solo
+// se muestra en esta respuesta.
+func (h *AgencyHandler) IssueKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		AgencyID string `json:"agency_id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.AgencyID == "" {
+		http.Error(w, "el campo agency_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	issued, err := h.manager.IssueKey(r.Context(), requestBody.AgencyID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(issued)
+}
+
+// RevokeKeyHandler maneja las solicitudes para revocar una clave de API de una agencia
+func (h *AgencyHandler) RevokeKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		AgencyID string `json:"agency_id"`
+		KeyID    string `json:"key_id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.AgencyID == "" || requestBody.KeyID == "" {
+		http.Error(w, "los campos agency_id y key_id son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	agency, err := h.repo.RevokeAPIKey(r.Context(), requestBody.AgencyID, requestBody.KeyID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(agency)
+}
+
+// TopUpHandler maneja las solicitudes para abonar una recarga o un pago al saldo de una agencia
+func (h *AgencyHandler) TopUpHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		AgencyID  string  `json:"agency_id"`
+		Amount    float64 `json:"amount"`
+		Reference string  `json:"reference"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.AgencyID == "" {
+		http.Error(w, "el campo agency_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.manager.TopUp(r.Context(), requestBody.AgencyID, requestBody.Amount, requestBody.Reference)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// StatementHandler maneja las solicitudes del estado de cuenta de una agencia. Con clave de API se obtiene el de
+// la agencia autenticada; en administración se indica con agency_id. Los parámetros from y to (AAAA-MM-DD) son
+// opcionales; por defecto se listan los últimos 30 días.
+func (h *AgencyHandler) StatementHandler(w http.ResponseWriter, r *http.Request) {
+	agencyID := r.URL.Query().Get("agency_id")
+	if agency, ok := FromContext(r.Context()); ok {
+		agencyID = agency.ID
+	}
+	if agencyID == "" {
+		http.Error(w, "el parámetro agency_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.ParseInLocation(dateLayout, value, statementLocation)
+		if err != nil {
+			http.Error(w, "el parámetro from debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.ParseInLocation(dateLayout, value, statementLocation)
+		if err != nil {
+			http.Error(w, "el parámetro to debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+			return
+		}
+		// Incluir el día completo
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		http.Error(w, "el parámetro to debe ser posterior a from", http.StatusBadRequest)
+		return
+	}
+
+	statement, err := h.manager.Statement(r.Context(), agencyID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statement)
+}
+
+// AccountHandler maneja las solicitudes de una agencia para consultar su saldo, su crédito disponible y sus comisiones
+func (h *AgencyHandler) AccountHandler(w http.ResponseWriter, r *http.Request) {
+	agency, _ := FromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&Account{Agency: agency, Available: agency.Available()})
+}
+
+// ReserveHandler maneja las solicitudes de una agencia para reservar asientos. El total menos la comisión se
+// descuenta de su saldo en el momento; sin saldo ni crédito suficientes responde 402 y no reserva.
+func (h *AgencyHandler) ReserveHandler(w http.ResponseWriter, r *http.Request) {
+	var request search.ReservationRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	agency, _ := FromContext(r.Context())
+	sale, err := h.manager.Reserve(r.Context(), agency, request)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sale)
+}
+
+// GetReservationHandler maneja las solicitudes de una agencia para consultar una de sus reservas (id)
+func (h *AgencyHandler) GetReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservationID := r.URL.Query().Get("id")
+	if reservationID == "" {
+		http.Error(w, "el parámetro id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	agency, _ := FromContext(r.Context())
+	reservation, err := h.manager.GetReservation(r.Context(), agency.ID, reservationID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// CancelReservationHandler maneja las solicitudes de una agencia para cancelar una de sus reservas dentro del
+// plazo del operador; la parte de lo cobrado que fija su política vuelve al saldo
+func (h *AgencyHandler) CancelReservationHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	agency, _ := FromContext(r.Context())
+	sale, err := h.manager.Cancel(r.Context(), agency.ID, requestBody.ID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sale)
+}
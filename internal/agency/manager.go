@@ -0,0 +1,469 @@
+package agency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/search"
+)
+
+// ErrInvalidSale indica que los datos de la venta o del movimiento de saldo no son válidos
+var ErrInvalidSale = errors.New("venta inválida")
+
+// Reservations es el acceso a las reservas de asientos que venden las agencias
+type Reservations interface {
+	GetRouteByID(ctx context.Context, routeID string) (*search.Route, error)
+	GetReservationByID(ctx context.Context, reservationID string) (*search.Reservation, error)
+	ReserveRoute(ctx context.Context, request search.ReservationRequest) (*search.Reservation, error)
+	CancelReservation(ctx context.Context, reservationID, userID string) (*search.Reservation, error)
+}
+
+// Boarding consulta el check-in y el embarque de las reservas
+type Boarding interface {
+	HasReservationRecords(ctx context.Context, reservationID string) (bool, error)
+}
+
+// Manager gestiona las agencias de viaje: sus claves de API, la venta de reservas con su comisión y el cargo
+// atómico en su saldo, los reembolsos de las reservas canceladas, las recargas y el estado de cuenta
+type Manager struct {
+	repo         *Repository
+	reservations Reservations
+	operators    search.OperatorDirectory
+	boarding     Boarding
+	vehicles     search.VehicleDirectory
+	config       config.AgencyConfig
+	released     []search.SeatReleaseHook
+}
+
+// NewManager crea una nueva instancia de Manager
+func NewManager(repo *Repository, reservations Reservations, operators search.OperatorDirectory, boarding Boarding, vehicles search.VehicleDirectory, cfg config.AgencyConfig) *Manager {
+	return &Manager{
+		repo:         repo,
+		reservations: reservations,
+		operators:    operators,
+		boarding:     boarding,
+		vehicles:     vehicles,
+		config:       cfg,
+	}
+}
+
+// RegisterSeatReleaseHook registra un hook que se ejecuta al liberar los asientos de una reserva de agencia
+func (m *Manager) RegisterSeatReleaseHook(hook search.SeatReleaseHook) {
+	m.released = append(m.released, hook)
+}
+
+// Create registra una agencia. Sin límite de crédito se usa el configurado por defecto.
+func (m *Manager) Create(ctx context.Context, agency *Agency, creditLimit *float64) error {
+	agency.CreditLimit = m.config.DefaultCreditLimit
+	if creditLimit != nil {
+		agency.CreditLimit = *creditLimit
+	}
+	if err := m.validate(ctx, agency); err != nil {
+		return err
+	}
+	return m.repo.CreateAgency(ctx, agency)
+}
+
+// Update reemplaza los datos, las comisiones y el límite de crédito de una agencia
+func (m *Manager) Update(ctx context.Context, agency *Agency) (*Agency, error) {
+	if err := m.validate(ctx, agency); err != nil {
+		return nil, err
+	}
+	return m.repo.UpdateAgency(ctx, agency)
+}
+
+// validate verifica los datos de la agencia y que los operadores de sus comisiones existan y estén activos
+func (m *Manager) validate(ctx context.Context, agency *Agency) error {
+	if err := validateAgency(agency); err != nil {
+		return err
+	}
+	for _, rule := range agency.Commissions {
+		if rule.OperatorID == "" {
+			continue
+		}
+		if err := m.operators.ValidateOperator(ctx, rule.OperatorID); err != nil {
+			return fmt.Errorf("%w: comisión del operador %s: %v", ErrInvalidAgency, rule.OperatorID, err)
+		}
+	}
+	return nil
+}
+
+// IssueKey emite una nueva clave de API para la agencia. La clave en claro solo se devuelve ahora.
+func (m *Manager) IssueKey(ctx context.Context, agencyID string) (*IssuedKey, error) {
+	issued, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.repo.AddAPIKey(ctx, agencyID, issued.APIKey); err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// CommissionPercent devuelve la comisión de la agencia sobre las ventas del operador: la regla del operador,
+// o la regla general de la agencia, o la comisión configurada por defecto
+func (m *Manager) CommissionPercent(agency *Agency, operatorID string) float64 {
+	percent := m.config.DefaultCommission
+	for _, rule := range agency.Commissions {
+		if rule.OperatorID == operatorID && operatorID != "" {
+			return rule.Percent
+		}
+		if rule.OperatorID == "" {
+			percent = rule.Percent
+		}
+	}
+	return percent
+}
+
+// Reserve cobra la venta a la agencia y reserva los asientos a su nombre. El total menos la comisión se
+// descuenta primero de su saldo, de forma atómica y solo si el saldo y el crédito alcanzan; si luego no se
+// pueden reservar los asientos, el cargo se devuelve.
+func (m *Manager) Reserve(ctx context.Context, agency *Agency, request search.ReservationRequest) (*Sale, error) {
+	if request.Seats == 0 {
+		request.Seats = len(request.SeatNumbers)
+	}
+	if request.RouteID == "" || request.Seats <= 0 {
+		return nil, fmt.Errorf("%w: el campo route_id y una cantidad de asientos positiva son obligatorios", ErrInvalidSale)
+	}
+
+	route, err := m.reservations.GetRouteByID(ctx, request.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == search.RouteCancelled {
+		return nil, search.ErrRouteCancelled
+	}
+	if !route.Departure.After(time.Now()) {
+		return nil, fmt.Errorf("%w: la salida ya partió", ErrInvalidSale)
+	}
+	segment, err := route.SegmentBetween(request.From, request.To)
+	if err != nil {
+		return nil, err
+	}
+	// Los asientos elegidos deben existir en el bus de la salida y no repetirse, antes de cobrar la venta
+	if err := search.ValidateSeatNumbers(ctx, m.vehicles, route, request.Seats, request.SeatNumbers); err != nil {
+		return nil, err
+	}
+
+	// Las agencias venden solo asientos disponibles, sin sobreventa, y la reserva queda a nombre de la agencia.
+	// La tarifa se fija a la cobrada para que la reserva no cambie de precio entre el cargo y la reserva.
+	request.UserID = agency.ID
+	request.AgencyID = agency.ID
+	request.Overbooking = 0
+	request.Fare = segment.Price
+	request.GroupID = ""
+
+	transaction := newSaleTransaction(agency.ID, route, segment.Price, request.Seats, m.CommissionPercent(agency, route.OperatorID))
+
+	charged, err := m.repo.Debit(ctx, agency.ID, -transaction.Amount)
+	if err != nil {
+		return nil, err
+	}
+	transaction.BalanceAfter = charged.Balance
+
+	reservation, err := m.reservations.ReserveRoute(ctx, request)
+	if err != nil {
+		m.returnCharge(ctx, transaction)
+		return nil, err
+	}
+	transaction.ReservationID = reservation.ID
+
+	if err := m.repo.CreateTransaction(ctx, transaction); err != nil {
+		// Sin el movimiento la venta no quedaría en el estado de cuenta: deshacer la reserva y el cargo. Si la
+		// reserva no se puede cancelar, la agencia conserva los asientos que pagó.
+		if _, cancelErr := m.reservations.CancelReservation(ctx, reservation.ID, agency.ID); cancelErr != nil {
+			log.Printf("Error al cancelar la reserva %s de la agencia %s sin movimiento registrado (cargo %.2f): %v",
+				reservation.ID, agency.ID, -transaction.Amount, cancelErr)
+			return nil, err
+		}
+		search.RunSeatReleaseHooks(ctx, m.released, reservation)
+		m.returnCharge(ctx, transaction)
+		return nil, err
+	}
+
+	return &Sale{Reservation: reservation, Transaction: transaction}, nil
+}
+
+// newSaleTransaction crea el cargo de la venta de seats asientos a la tarifa fare: el total menos la comisión de
+// la agencia, como monto negativo
+func newSaleTransaction(agencyID string, route *search.Route, fare float64, seats int, percent float64) *Transaction {
+	transaction := &Transaction{
+		AgencyID:          agencyID,
+		Type:              TransactionSale,
+		RouteID:           route.ID,
+		OperatorID:        route.OperatorID,
+		Gross:             search.RoundAmount(fare * float64(seats)),
+		CommissionPercent: percent,
+	}
+	transaction.Commission = search.RoundAmount(transaction.Gross * percent / 100)
+	transaction.Amount = -search.RoundAmount(transaction.Gross - transaction.Commission)
+	return transaction
+}
+
+// returnCharge devuelve a la agencia el cargo de una venta que no se concretó
+func (m *Manager) returnCharge(ctx context.Context, transaction *Transaction) {
+	if _, err := m.repo.AdjustBalance(ctx, transaction.AgencyID, -transaction.Amount); err != nil {
+		log.Printf("Error al devolver a la agencia %s el cargo de %.2f de una venta no concretada: %v",
+			transaction.AgencyID, -transaction.Amount, err)
+	}
+}
+
+// GetReservation obtiene una reserva de la agencia; las de otros usuarios se tratan como inexistentes
+func (m *Manager) GetReservation(ctx context.Context, agencyID, reservationID string) (*search.Reservation, error) {
+	reservation, err := m.reservations.GetReservationByID(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil || reservation.AgencyID != agencyID {
+		return nil, search.ErrReservationNotFound
+	}
+	return reservation, nil
+}
+
+// Cancel cancela una reserva de la agencia, ofrece sus asientos mediante los hooks registrados y devuelve a su
+// saldo la parte de lo cobrado que fija la política de cancelación del operador. Como las de los clientes, no
+// se cancela después de la salida ni dentro del plazo del operador, y tampoco si algún pasajero ya hizo el
+// check-in. Cancelar de nuevo una reserva ya cancelada cuyo reembolso no llegó a registrarse lo completa.
+func (m *Manager) Cancel(ctx context.Context, agencyID, reservationID string) (*Sale, error) {
+	current, err := m.GetReservation(ctx, agencyID, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == search.ReservationCancelled {
+		return m.completeRefund(ctx, current)
+	}
+	route, err := m.reservations.GetRouteByID(ctx, current.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	percent, err := search.ReservationCancellationTerms(ctx, m.operators, m.boarding, route, current.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := m.reservations.CancelReservation(ctx, reservationID, agencyID)
+	if err != nil {
+		return nil, err
+	}
+
+	search.RunSeatReleaseHooks(ctx, m.released, reservation)
+
+	transaction, err := m.refund(ctx, reservation, percent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sale{Reservation: reservation, Transaction: transaction}, nil
+}
+
+// completeRefund reembolsa una reserva de agencia ya cancelada cuyo reembolso falló o quedó interrumpido. Si ya
+// se reembolsó, devuelve ese reembolso; el índice único de movimientos evita abonarlo dos veces.
+func (m *Manager) completeRefund(ctx context.Context, reservation *search.Reservation) (*Sale, error) {
+	transaction, err := m.repo.GetReservationTransaction(ctx, reservation.ID, TransactionRefund)
+	if err != nil {
+		return nil, err
+	}
+	if transaction != nil {
+		return &Sale{Reservation: reservation, Transaction: transaction}, nil
+	}
+
+	route, err := m.reservations.GetRouteByID(ctx, reservation.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	percent, err := search.RefundPercent(ctx, m.operators, route)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err = m.refund(ctx, reservation, percent)
+	if err != nil {
+		return nil, err
+	}
+	return &Sale{Reservation: reservation, Transaction: transaction}, nil
+}
+
+// refund devuelve a la agencia el porcentaje indicado de lo cobrado por la venta de una reserva cancelada; la
+// comisión se revierte en la misma proporción. Una reserva reubicada no tiene venta propia: se reembolsa según
+// la venta de la reserva original.
+func (m *Manager) refund(ctx context.Context, reservation *search.Reservation, percent float64) (*Transaction, error) {
+	sale, err := m.saleOf(ctx, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := newRefundTransaction(sale, reservation, percent)
+
+	credited, err := m.repo.AdjustBalance(ctx, sale.AgencyID, transaction.Amount)
+	if err != nil {
+		return nil, err
+	}
+	transaction.BalanceAfter = credited.Balance
+
+	if err := m.repo.CreateTransaction(ctx, transaction); err != nil {
+		// El reembolso ya registrado, o no registrado, no debe quedar abonado en el saldo
+		if _, adjustErr := m.repo.AdjustBalance(ctx, sale.AgencyID, -transaction.Amount); adjustErr != nil {
+			log.Printf("Error al deshacer el reembolso de la reserva %s a la agencia %s: %v", reservation.ID, sale.AgencyID, adjustErr)
+		}
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// newRefundTransaction crea el abono del porcentaje indicado de la venta sale por la reserva cancelada; el total
+// y la comisión se reembolsan en la misma proporción
+func newRefundTransaction(sale *Transaction, reservation *search.Reservation, percent float64) *Transaction {
+	transaction := &Transaction{
+		AgencyID:          sale.AgencyID,
+		Type:              TransactionRefund,
+		ReservationID:     reservation.ID,
+		RouteID:           reservation.RouteID,
+		OperatorID:        sale.OperatorID,
+		Gross:             search.RoundAmount(sale.Gross * percent / 100),
+		CommissionPercent: sale.CommissionPercent,
+		Commission:        search.RoundAmount(sale.Commission * percent / 100),
+		RefundPercent:     percent,
+	}
+	transaction.Amount = search.RoundAmount(transaction.Gross - transaction.Commission)
+	return transaction
+}
+
+// saleOf obtiene la venta de una reserva de agencia, siguiendo las reubicaciones hasta la reserva original
+func (m *Manager) saleOf(ctx context.Context, reservation *search.Reservation) (*Transaction, error) {
+	current := reservation
+	for {
+		sale, err := m.repo.GetReservationTransaction(ctx, current.ID, TransactionSale)
+		if err != nil || sale != nil {
+			return sale, err
+		}
+		if current.PreviousReservationID == "" {
+			return nil, fmt.Errorf("la reserva %s no tiene una venta de agencia registrada", reservation.ID)
+		}
+		current, err = m.reservations.GetReservationByID(ctx, current.PreviousReservationID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, search.ErrReservationNotFound
+		}
+	}
+}
+
+// OnRouteCancelled reembolsa por completo a las agencias las reservas de una ruta cancelada que no se pudieron
+// reubicar, y las que ya tenían el embarque denegado. Las reubicadas conservan la agencia y se reembolsan si
+// luego se cancelan.
+func (m *Manager) OnRouteCancelled(ctx context.Context, route *search.Route, reservations []*search.Reservation) error {
+	return m.refundAll(ctx, reservations, search.ReservationCancelled, search.ReservationDeniedBoarding)
+}
+
+// OnBoardingDenied reembolsa por completo a las agencias las reservas de una salida sobrevendida que quedaron
+// con el embarque denegado. Las reubicadas en otra salida conservan la venta.
+func (m *Manager) OnBoardingDenied(ctx context.Context, route *search.Route, reservations []*search.Reservation) error {
+	return m.refundAll(ctx, reservations, search.ReservationDeniedBoarding)
+}
+
+// refundAll reembolsa por completo las reservas de agencia que tengan alguno de los estados indicados. Las ya
+// reembolsadas se omiten, así un mismo reembolso nunca se abona dos veces.
+func (m *Manager) refundAll(ctx context.Context, reservations []*search.Reservation, statuses ...string) error {
+	var errs []error
+
+	for _, reservation := range reservations {
+		if reservation.AgencyID == "" || !slices.Contains(statuses, reservation.Status) {
+			continue
+		}
+		if _, err := m.refund(ctx, reservation, 100); err != nil && !errors.Is(err, ErrAlreadyRefunded) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// TopUp abona una recarga al saldo de la agencia
+func (m *Manager) TopUp(ctx context.Context, agencyID string, amount float64, reference string) (*Transaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: el monto de la recarga debe ser positivo", ErrInvalidSale)
+	}
+
+	transaction := &Transaction{
+		AgencyID:  agencyID,
+		Type:      TransactionTopUp,
+		Amount:    search.RoundAmount(amount),
+		Reference: reference,
+	}
+
+	credited, err := m.repo.AdjustBalance(ctx, agencyID, transaction.Amount)
+	if err != nil {
+		return nil, err
+	}
+	transaction.BalanceAfter = credited.Balance
+
+	if err := m.repo.CreateTransaction(ctx, transaction); err != nil {
+		if _, adjustErr := m.repo.AdjustBalance(ctx, agencyID, -transaction.Amount); adjustErr != nil {
+			log.Printf("Error al deshacer la recarga de la agencia %s: %v", agencyID, adjustErr)
+		}
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// Statement arma el estado de cuenta de la agencia en el periodo [from, to): sus movimientos, el saldo al inicio
+// y al cierre, y los totales de ventas, comisiones y recargas
+func (m *Manager) Statement(ctx context.Context, agencyID string, from, to time.Time) (*Statement, error) {
+	if _, err := m.repo.GetAgency(ctx, agencyID); err != nil {
+		return nil, err
+	}
+
+	opening, err := m.repo.BalanceAt(ctx, agencyID, from)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := m.repo.FindTransactions(ctx, agencyID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStatement(agencyID, from, to, opening, transactions), nil
+}
+
+// newStatement calcula el saldo al cierre y los totales del periodo a partir del saldo al inicio y de los
+// movimientos del periodo. Los reembolsos descuentan sus ventas del total y de la comisión.
+func newStatement(agencyID string, from, to time.Time, opening float64, transactions []*Transaction) *Statement {
+	statement := &Statement{
+		AgencyID:       agencyID,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		Transactions:   transactions,
+	}
+	for _, transaction := range transactions {
+		statement.ClosingBalance += transaction.Amount
+		switch transaction.Type {
+		case TransactionSale:
+			statement.Sales++
+			statement.Gross += transaction.Gross
+			statement.Commission += transaction.Commission
+		case TransactionRefund:
+			statement.Refunds++
+			statement.Gross -= transaction.Gross
+			statement.Commission -= transaction.Commission
+		case TransactionTopUp:
+			statement.TopUps += transaction.Amount
+		}
+	}
+	statement.ClosingBalance = search.RoundAmount(statement.ClosingBalance)
+	statement.Gross = search.RoundAmount(statement.Gross)
+	statement.Commission = search.RoundAmount(statement.Commission)
+	statement.Net = search.RoundAmount(statement.Gross - statement.Commission)
+	statement.TopUps = search.RoundAmount(statement.TopUps)
+
+	return statement
+}
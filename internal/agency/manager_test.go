@@ -0,0 +1,231 @@
+package agency
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/fleet"
+	"venta-de-pasajes/internal/search"
+)
+
+func TestCommissionPercent(t *testing.T) {
+	manager := &Manager{config: config.AgencyConfig{DefaultCommission: 8}}
+
+	tests := []struct {
+		name        string
+		commissions []CommissionRule
+		operatorID  string
+		want        float64
+	}{
+		{
+			name:       "sin reglas usa la comisión por defecto",
+			operatorID: "op-1",
+			want:       8,
+		},
+		{
+			name:        "regla general de la agencia",
+			commissions: []CommissionRule{{Percent: 10}},
+			operatorID:  "op-1",
+			want:        10,
+		},
+		{
+			name:        "la regla del operador prevalece sobre la general aunque esté después",
+			commissions: []CommissionRule{{Percent: 10}, {OperatorID: "op-1", Percent: 12}},
+			operatorID:  "op-1",
+			want:        12,
+		},
+		{
+			name:        "la regla del operador prevalece sobre la general aunque esté antes",
+			commissions: []CommissionRule{{OperatorID: "op-1", Percent: 12}, {Percent: 10}},
+			operatorID:  "op-1",
+			want:        12,
+		},
+		{
+			name:        "la regla de otro operador no aplica",
+			commissions: []CommissionRule{{OperatorID: "op-2", Percent: 15}},
+			operatorID:  "op-1",
+			want:        8,
+		},
+		{
+			name:        "una ruta sin operador usa la regla general",
+			commissions: []CommissionRule{{OperatorID: "op-2", Percent: 15}, {Percent: 10}},
+			want:        10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agency := &Agency{Commissions: tt.commissions}
+			if got := manager.CommissionPercent(agency, tt.operatorID); got != tt.want {
+				t.Errorf("CommissionPercent() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSaleTransaction(t *testing.T) {
+	route := &search.Route{ID: "ruta-1", OperatorID: "op-1"}
+
+	tests := []struct {
+		name           string
+		fare           float64
+		seats          int
+		percent        float64
+		wantGross      float64
+		wantCommission float64
+		wantAmount     float64
+	}{
+		{name: "sin comisión", fare: 50, seats: 2, wantGross: 100, wantAmount: -100},
+		{name: "comisión sobre el total", fare: 50, seats: 2, percent: 8, wantGross: 100, wantCommission: 8, wantAmount: -92},
+		{name: "redondea a céntimos", fare: 33.335, seats: 3, percent: 7.5, wantGross: 100.01, wantCommission: 7.5, wantAmount: -92.51},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newSaleTransaction("agencia-1", route, tt.fare, tt.seats, tt.percent)
+			if got.Type != TransactionSale || got.AgencyID != "agencia-1" || got.RouteID != "ruta-1" || got.OperatorID != "op-1" {
+				t.Errorf("newSaleTransaction() = %+v, se esperaba la venta de la agencia-1 en la ruta-1 del op-1", got)
+			}
+			if got.Gross != tt.wantGross || got.Commission != tt.wantCommission || got.Amount != tt.wantAmount {
+				t.Errorf("total, comisión y monto = %v, %v y %v, se esperaba %v, %v y %v",
+					got.Gross, got.Commission, got.Amount, tt.wantGross, tt.wantCommission, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestNewRefundTransaction(t *testing.T) {
+	sale := &Transaction{AgencyID: "agencia-1", Type: TransactionSale, ReservationID: "reserva-1", OperatorID: "op-1",
+		Gross: 100.01, CommissionPercent: 7.5, Commission: 7.5, Amount: -92.51}
+	// Una reserva reubicada se reembolsa con la venta de la original
+	reservation := &search.Reservation{ID: "reserva-2", RouteID: "ruta-2"}
+
+	tests := []struct {
+		name           string
+		percent        float64
+		wantGross      float64
+		wantCommission float64
+		wantAmount     float64
+	}{
+		{name: "reembolso total", percent: 100, wantGross: 100.01, wantCommission: 7.5, wantAmount: 92.51},
+		{name: "reembolso parcial", percent: 50, wantGross: 50.01, wantCommission: 3.75, wantAmount: 46.26},
+		{name: "sin reembolso", percent: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newRefundTransaction(sale, reservation, tt.percent)
+			if got.Type != TransactionRefund || got.ReservationID != "reserva-2" || got.RouteID != "ruta-2" || got.RefundPercent != tt.percent {
+				t.Errorf("newRefundTransaction() = %+v, se esperaba el reembolso del %v%% de la reserva-2", got, tt.percent)
+			}
+			if got.Gross != tt.wantGross || got.Commission != tt.wantCommission || got.Amount != tt.wantAmount {
+				t.Errorf("total, comisión y monto = %v, %v y %v, se esperaba %v, %v y %v",
+					got.Gross, got.Commission, got.Amount, tt.wantGross, tt.wantCommission, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestNewStatement(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	transactions := []*Transaction{
+		{Type: TransactionTopUp, Amount: 500},
+		{Type: TransactionSale, Gross: 200, Commission: 16, Amount: -184},
+		{Type: TransactionSale, Gross: 90.1, Commission: 7.21, Amount: -82.89},
+		{Type: TransactionRefund, Gross: 100, Commission: 8, Amount: 92, RefundPercent: 50},
+	}
+
+	statement := newStatement("agencia-1", from, to, 100, transactions)
+
+	want := Statement{
+		AgencyID:       "agencia-1",
+		From:           from,
+		To:             to,
+		OpeningBalance: 100,
+		ClosingBalance: 425.11, // 100 + 500 - 184 - 82.89 + 92
+		Sales:          2,
+		Refunds:        1,
+		Gross:          190.1, // 200 + 90.1 - 100
+		Commission:     15.21, // 16 + 7.21 - 8
+		Net:            174.89,
+		TopUps:         500,
+	}
+	statement.Transactions = nil
+	if !reflect.DeepEqual(*statement, want) {
+		t.Errorf("newStatement() = %+v, se esperaba %+v", *statement, want)
+	}
+}
+
+func TestNewStatementWithoutTransactions(t *testing.T) {
+	statement := newStatement("agencia-1", time.Time{}, time.Time{}, -35.5, nil)
+
+	if statement.OpeningBalance != -35.5 || statement.ClosingBalance != -35.5 {
+		t.Errorf("saldos = %v y %v, se esperaba -35.5 al inicio y al cierre", statement.OpeningBalance, statement.ClosingBalance)
+	}
+	if statement.Sales != 0 || statement.Refunds != 0 || statement.Gross != 0 || statement.Net != 0 || statement.TopUps != 0 {
+		t.Errorf("totales = %+v, se esperaban en cero", *statement)
+	}
+}
+
+// testReservations simula las reservas de asientos con una sola salida; reservar registra la solicitud
+type testReservations struct {
+	Reservations
+	route    *search.Route
+	reserved []search.ReservationRequest
+}
+
+func (r *testReservations) GetRouteByID(ctx context.Context, routeID string) (*search.Route, error) {
+	if routeID != r.route.ID {
+		return nil, search.ErrRouteNotFound
+	}
+	return r.route, nil
+}
+
+func (r *testReservations) ReserveRoute(ctx context.Context, request search.ReservationRequest) (*search.Reservation, error) {
+	r.reserved = append(r.reserved, request)
+	return nil, errors.New("reserva no esperada en la prueba")
+}
+
+// testVehicles simula la flota con un bus de cuatro asientos
+type testVehicles struct{}
+
+func (testVehicles) GetVehicle(ctx context.Context, vehicleID string) (*fleet.Vehicle, error) {
+	return &fleet.Vehicle{ID: vehicleID, Active: true, Capacity: 4, Layout: fleet.SeatLayout{Seats: []fleet.Seat{
+		{Number: "1A"}, {Number: "1B"}, {Number: "2A"}, {Number: "2B"},
+	}}}, nil
+}
+
+func TestReserveValidatesSeatNumbersBeforeCharging(t *testing.T) {
+	route := &search.Route{ID: "ruta-1", VehicleID: "bus-1", Capacity: 4, Seats: 4, Price: 50, Departure: time.Now().Add(72 * time.Hour)}
+	reservations := &testReservations{route: route}
+	// Sin repositorio: cualquier cargo a la agencia haría fallar la prueba
+	manager := NewManager(nil, reservations, nil, nil, testVehicles{}, config.AgencyConfig{})
+	agency := &Agency{ID: "agencia-1"}
+
+	tests := []struct {
+		name        string
+		seats       int
+		seatNumbers []string
+	}{
+		{name: "asiento repetido", seatNumbers: []string{"1A", "1A"}},
+		{name: "asiento que no existe", seatNumbers: []string{"1A", "9Z"}},
+		{name: "cantidad distinta de la pedida", seats: 3, seatNumbers: []string{"1A", "1B"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := search.ReservationRequest{RouteID: route.ID, Seats: tt.seats, SeatNumbers: tt.seatNumbers}
+			if _, err := manager.Reserve(context.Background(), agency, request); !errors.Is(err, search.ErrInvalidSeats) {
+				t.Errorf("Reserve(%v): error = %v, se esperaba ErrInvalidSeats", tt.seatNumbers, err)
+			}
+		})
+	}
+	if len(reservations.reserved) != 0 {
+		t.Errorf("se reservaron %d solicitudes con asientos inválidos", len(reservations.reserved))
+	}
+}
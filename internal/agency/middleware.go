@@ -0,0 +1,86 @@
+package agency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyHeader es la cabecera con la que las agencias envían su clave de API
+const APIKeyHeader = "X-API-Key"
+
+// keyPrefix antecede a las claves de API para reconocerlas en los registros y en los escáneres de secretos
+const keyPrefix = "vdp_"
+
+// ErrInvalidAPIKey indica que la solicitud no trae una clave de API vigente
+var ErrInvalidAPIKey = errors.New("clave de API inválida o revocada")
+
+// agencyKey es la clave de la agencia autenticada en el contexto de la solicitud
+type agencyKey struct{}
+
+// FromContext obtiene la agencia autenticada con su clave de API
+func FromContext(ctx context.Context) (*Agency, bool) {
+	agency, ok := ctx.Value(agencyKey{}).(*Agency)
+	return agency, ok && agency != nil
+}
+
+// newAPIKey genera una clave de API aleatoria de 256 bits
+func newAPIKey() (*IssuedKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	return &IssuedKey{
+		APIKey: APIKey{
+			ID:        uuid.New().String(),
+			Prefix:    key[:len(keyPrefix)+6],
+			Hash:      hashAPIKey(key),
+			CreatedAt: time.Now(),
+		},
+		Key: key,
+	}, nil
+}
+
+// hashAPIKey calcula el hash con el que se guarda y se busca una clave de API. Las claves son aleatorias y
+// largas, por lo que basta un SHA-256 sin sal para no guardarlas en claro.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequireAPIKey exige una clave de API vigente de una agencia activa en la cabecera X-API-Key y agrega la
+// agencia al contexto de la solicitud. Sin clave, o con una inválida, responde 401; si la agencia está
+// desactivada, 403.
+func (m *Manager) RequireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			http.Error(w, ErrInvalidAPIKey.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		agency, err := m.repo.GetAgencyByKeyHash(r.Context(), hashAPIKey(key))
+		switch {
+		case errors.Is(err, ErrAPIKeyNotFound):
+			http.Error(w, ErrInvalidAPIKey.Error(), http.StatusUnauthorized)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		case !agency.Active:
+			http.Error(w, ErrAgencyInactive.Error(), http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), agencyKey{}, agency)))
+	}
+}
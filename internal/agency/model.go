@@ -0,0 +1,106 @@
+package agency
+
+import (
+	"time"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// Tipos de movimiento de la cuenta de una agencia
+const (
+	TransactionSale   = "venta"     // Cargo del neto de una reserva vendida por la agencia
+	TransactionRefund = "reembolso" // Devolución del neto de una reserva cancelada
+	TransactionTopUp  = "recarga"   // Abono de la agencia: prepago o pago del crédito usado
+)
+
+// Agency representa una agencia de viajes que vende pasajes por la API con claves de API propias.
+// Cada venta descuenta de su saldo el total menos su comisión; el saldo puede quedar negativo hasta su
+// límite de crédito.
+type Agency struct {
+	ID           string           `json:"id,omitempty" bson:"_id,omitempty"`
+	Name         string           `json:"name" bson:"name"`             // Nombre comercial
+	LegalName    string           `json:"legal_name" bson:"legal_name"` // Razón social
+	RUC          string           `json:"ruc" bson:"ruc"`
+	ContactEmail string           `json:"contact_email,omitempty" bson:"contact_email,omitempty"`
+	Commissions  []CommissionRule `json:"commissions,omitempty" bson:"commissions,omitempty"`
+	CreditLimit  float64          `json:"credit_limit" bson:"credit_limit"` // Saldo negativo hasta el que puede vender
+	Balance      float64          `json:"balance" bson:"balance"`           // Saldo prepagado; negativo es crédito usado
+	APIKeys      []APIKey         `json:"api_keys,omitempty" bson:"api_keys,omitempty"`
+	Active       bool             `json:"active" bson:"active"`
+	CreatedAt    time.Time        `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt    time.Time        `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Available devuelve el monto que la agencia todavía puede vender con su saldo y su crédito
+func (a *Agency) Available() float64 {
+	return search.RoundAmount(a.Balance + a.CreditLimit)
+}
+
+// CommissionRule representa la comisión de la agencia sobre las ventas de un operador
+type CommissionRule struct {
+	OperatorID string  `json:"operator_id,omitempty" bson:"operator_id,omitempty"` // Vacío aplica a los operadores sin regla propia
+	Percent    float64 `json:"percent" bson:"percent"`                             // Porcentaje sobre el total de la reserva
+}
+
+// APIKey representa una clave de API de la agencia. Solo se guarda su hash; la clave se muestra una vez, al emitirla.
+type APIKey struct {
+	ID        string    `json:"id" bson:"id"`
+	Prefix    string    `json:"prefix" bson:"prefix"` // Primeros caracteres de la clave, para reconocerla
+	Hash      string    `json:"-" bson:"hash"`        // SHA-256 de la clave en hexadecimal
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	RevokedAt time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// IssuedKey representa una clave de API recién emitida, con la clave en claro
+type IssuedKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Transaction representa un movimiento del saldo de una agencia. Amount es negativo en las ventas y positivo
+// en los reembolsos y las recargas.
+type Transaction struct {
+	ID                string    `json:"id,omitempty" bson:"_id,omitempty"`
+	AgencyID          string    `json:"agency_id" bson:"agency_id"`
+	Type              string    `json:"type" bson:"type"`
+	ReservationID     string    `json:"reservation_id,omitempty" bson:"reservation_id,omitempty"`
+	RouteID           string    `json:"route_id,omitempty" bson:"route_id,omitempty"`
+	OperatorID        string    `json:"operator_id,omitempty" bson:"operator_id,omitempty"`
+	Gross             float64   `json:"gross,omitempty" bson:"gross,omitempty"` // Total de la reserva
+	CommissionPercent float64   `json:"commission_percent,omitempty" bson:"commission_percent,omitempty"`
+	Commission        float64   `json:"commission,omitempty" bson:"commission,omitempty"`
+	RefundPercent     float64   `json:"refund_percent,omitempty" bson:"refund_percent,omitempty"` // Parte de la venta devuelta en un reembolso
+	Amount            float64   `json:"amount" bson:"amount"`
+	BalanceAfter      float64   `json:"balance_after" bson:"balance_after"`
+	Reference         string    `json:"reference,omitempty" bson:"reference,omitempty"` // Número de operación de una recarga
+	CreatedAt         time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Sale representa una reserva de la agencia junto con el movimiento que generó en su saldo
+type Sale struct {
+	Reservation *search.Reservation `json:"reservation"`
+	Transaction *Transaction        `json:"transaction"`
+}
+
+// Account representa el estado de la cuenta de una agencia
+type Account struct {
+	*Agency
+	Available float64 `json:"available"`
+}
+
+// Statement representa el estado de cuenta de una agencia en un periodo. Las ventas, las comisiones y el neto
+// descuentan las reservas reembolsadas en el periodo.
+type Statement struct {
+	AgencyID       string         `json:"agency_id"`
+	From           time.Time      `json:"from"`
+	To             time.Time      `json:"to"`
+	OpeningBalance float64        `json:"opening_balance"`
+	ClosingBalance float64        `json:"closing_balance"`
+	Sales          int            `json:"sales"`
+	Refunds        int            `json:"refunds"`
+	Gross          float64        `json:"gross"`
+	Commission     float64        `json:"commission"`
+	Net            float64        `json:"net"` // Total menos comisión: lo que la agencia debe por sus ventas
+	TopUps         float64        `json:"top_ups"`
+	Transactions   []*Transaction `json:"transactions"`
+}
@@ -0,0 +1,363 @@
+package agency
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/search"
+)
+
+var (
+	// ErrAgencyNotFound indica que la agencia no existe
+	ErrAgencyNotFound = errors.New("agencia no encontrada")
+	// ErrAgencyExists indica que ya existe una agencia con el mismo RUC
+	ErrAgencyExists = errors.New("ya existe una agencia con el mismo RUC")
+	// ErrAgencyInactive indica que la agencia está desactivada
+	ErrAgencyInactive = errors.New("la agencia está desactivada")
+	// ErrInsufficientCredit indica que el saldo y el crédito de la agencia no alcanzan para la venta
+	ErrInsufficientCredit = errors.New("saldo y crédito insuficientes")
+	// ErrAPIKeyNotFound indica que la clave de API no existe o ya fue revocada
+	ErrAPIKeyNotFound = errors.New("clave de API no encontrada o revocada")
+	// ErrAlreadyRefunded indica que la reserva ya fue reembolsada a la agencia
+	ErrAlreadyRefunded = errors.New("la reserva ya fue reembolsada")
+)
+
+// Repository es el repositorio de agencias y de los movimientos de su saldo en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para las agencias")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// agencies devuelve la colección de agencias
+func (r *Repository) agencies() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.AgenciesCollection)
+}
+
+// transactions devuelve la colección de movimientos del saldo de las agencias
+func (r *Repository) transactions() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.AgencyTransactionsCollection)
+}
+
+// EnsureIndexes crea el índice único por RUC, el de búsqueda por clave de API, el de los estados de cuenta
+// y el que impide registrar dos veces la venta o el reembolso de una reserva
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.agencies().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "ruc", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "api_keys.hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.transactions().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "agency_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "reservation_id", Value: 1}, {Key: "type", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"reservation_id": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// CreateAgency registra una nueva agencia activa con saldo cero
+func (r *Repository) CreateAgency(ctx context.Context, agency *Agency) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	agency.ID = uuid.New().String()
+	agency.Balance = 0
+	agency.APIKeys = nil
+	agency.Active = true
+	agency.CreatedAt = time.Now()
+	agency.UpdatedAt = agency.CreatedAt
+
+	_, err := r.agencies().InsertOne(ctx, agency)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAgencyExists
+	}
+	return err
+}
+
+// UpdateAgency reemplaza los datos, las comisiones y el límite de crédito de la agencia, conservando su saldo,
+// sus claves y su estado
+func (r *Repository) UpdateAgency(ctx context.Context, agency *Agency) (*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	updated, err := r.updateAgency(ctx, bson.M{"_id": agency.ID}, bson.M{"$set": bson.M{
+		"name":          agency.Name,
+		"legal_name":    agency.LegalName,
+		"ruc":           agency.RUC,
+		"contact_email": agency.ContactEmail,
+		"commissions":   agency.Commissions,
+		"credit_limit":  agency.CreditLimit,
+		"updated_at":    time.Now(),
+	}})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrAgencyExists
+	}
+	return updated, err
+}
+
+// SetAgencyActive activa o desactiva una agencia; una agencia desactivada no puede usar la API
+func (r *Repository) SetAgencyActive(ctx context.Context, agencyID string, active bool) (*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateAgency(ctx, bson.M{"_id": agencyID}, bson.M{"$set": bson.M{"active": active, "updated_at": time.Now()}})
+}
+
+// AddAPIKey agrega una clave de API a la agencia
+func (r *Repository) AddAPIKey(ctx context.Context, agencyID string, key APIKey) (*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateAgency(ctx, bson.M{"_id": agencyID}, bson.M{
+		"$push": bson.M{"api_keys": key},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+// RevokeAPIKey revoca una clave de API vigente de la agencia
+func (r *Repository) RevokeAPIKey(ctx context.Context, agencyID, keyID string) (*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	agency, err := r.updateAgency(ctx,
+		bson.M{"_id": agencyID, "api_keys": bson.M{"$elemMatch": bson.M{"id": keyID, "revoked_at": bson.M{"$exists": false}}}},
+		bson.M{"$set": bson.M{"api_keys.$.revoked_at": now, "updated_at": now}},
+	)
+	if errors.Is(err, ErrAgencyNotFound) {
+		if _, err := r.GetAgency(ctx, agencyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrAPIKeyNotFound
+	}
+	return agency, err
+}
+
+// GetAgencyByKeyHash obtiene la agencia de una clave de API vigente a partir de su hash
+func (r *Repository) GetAgencyByKeyHash(ctx context.Context, hash string) (*Agency, error) {
+	agency, err := r.findAgency(ctx, bson.M{"api_keys": bson.M{"$elemMatch": bson.M{"hash": hash, "revoked_at": bson.M{"$exists": false}}}})
+	if errors.Is(err, ErrAgencyNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return agency, err
+}
+
+// GetAgency obtiene una agencia por su ID
+func (r *Repository) GetAgency(ctx context.Context, agencyID string) (*Agency, error) {
+	return r.findAgency(ctx, bson.M{"_id": agencyID})
+}
+
+// findAgency obtiene la agencia que cumple el filtro
+func (r *Repository) findAgency(ctx context.Context, filter bson.M) (*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var agency Agency
+	err := r.agencies().FindOne(ctx, filter).Decode(&agency)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAgencyNotFound
+		}
+		return nil, err
+	}
+
+	return &agency, nil
+}
+
+// GetAgencies obtiene las agencias ordenadas por nombre
+func (r *Repository) GetAgencies(ctx context.Context) ([]*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.agencies().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	agencies := []*Agency{}
+	if err := cursor.All(ctx, &agencies); err != nil {
+		return nil, err
+	}
+
+	return agencies, nil
+}
+
+// Debit descuenta el monto del saldo de una agencia activa de forma atómica, solo si el saldo más el crédito
+// alcanzan, y devuelve la agencia con el saldo actualizado
+func (r *Repository) Debit(ctx context.Context, agencyID string, amount float64) (*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	agency, err := r.updateAgency(ctx,
+		bson.M{
+			"_id":    agencyID,
+			"active": true,
+			"$expr":  bson.M{"$gte": bson.A{bson.M{"$add": bson.A{"$balance", "$credit_limit"}}, amount}},
+		},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"balance":    bson.M{"$round": bson.A{bson.M{"$subtract": bson.A{"$balance", amount}}, 2}},
+			"updated_at": time.Now(),
+		}}}},
+	)
+	if errors.Is(err, ErrAgencyNotFound) {
+		current, err := r.GetAgency(ctx, agencyID)
+		if err != nil {
+			return nil, err
+		}
+		if !current.Active {
+			return nil, ErrAgencyInactive
+		}
+		return nil, ErrInsufficientCredit
+	}
+	return agency, err
+}
+
+// AdjustBalance suma el monto, positivo o negativo, al saldo de la agencia sin verificar su crédito, y devuelve
+// la agencia con el saldo actualizado. Se usa para recargas, reembolsos y para deshacer un cargo.
+func (r *Repository) AdjustBalance(ctx context.Context, agencyID string, amount float64) (*Agency, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateAgency(ctx, bson.M{"_id": agencyID}, mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"balance":    bson.M{"$round": bson.A{bson.M{"$add": bson.A{"$balance", amount}}, 2}},
+		"updated_at": time.Now(),
+	}}}})
+}
+
+// updateAgency aplica una actualización a la agencia que cumple el filtro y la devuelve actualizada
+func (r *Repository) updateAgency(ctx context.Context, filter bson.M, update interface{}) (*Agency, error) {
+	var agency Agency
+	err := r.agencies().FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&agency)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAgencyNotFound
+		}
+		return nil, err
+	}
+
+	return &agency, nil
+}
+
+// CreateTransaction registra un movimiento del saldo de una agencia. Si no trae ID se le asigna uno.
+func (r *Repository) CreateTransaction(ctx context.Context, transaction *Transaction) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if transaction.ID == "" {
+		transaction.ID = uuid.New().String()
+	}
+	transaction.CreatedAt = time.Now()
+
+	_, err := r.transactions().InsertOne(ctx, transaction)
+	if mongo.IsDuplicateKeyError(err) && transaction.Type == TransactionRefund {
+		return ErrAlreadyRefunded
+	}
+	return err
+}
+
+// GetReservationTransaction obtiene el movimiento del tipo indicado de una reserva, o nil si no existe
+func (r *Repository) GetReservationTransaction(ctx context.Context, reservationID, transactionType string) (*Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var transaction Transaction
+	err := r.transactions().FindOne(ctx, bson.M{"reservation_id": reservationID, "type": transactionType}).Decode(&transaction)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// FindTransactions obtiene los movimientos de una agencia en el periodo [from, to), del más antiguo al más reciente
+func (r *Repository) FindTransactions(ctx context.Context, agencyID string, from, to time.Time) ([]*Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.transactions().Find(ctx,
+		bson.M{"agency_id": agencyID, "created_at": bson.M{"$gte": from, "$lt": to}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []*Transaction{}
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// BalanceAt calcula el saldo de una agencia al inicio del instante indicado sumando sus movimientos anteriores
+func (r *Repository) BalanceAt(ctx context.Context, agencyID string, at time.Time) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.transactions().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"agency_id": agencyID, "created_at": bson.M{"$lt": at}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "balance": bson.M{"$sum": "$amount"}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Balance float64 `bson:"balance"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	return search.RoundAmount(result[0].Balance), nil
+}
@@ -0,0 +1,195 @@
+package agency
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"venta-de-pasajes/internal/search"
+	"venta-de-pasajes/internal/testutil"
+)
+
+// newTestRepository crea un repositorio sobre una base de datos de prueba que se elimina al terminar.
+// Las pruebas se omiten si MONGO_TEST_URL no está definida.
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	repo, err := NewRepository(testutil.MongoConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.client.Disconnect(context.Background()) })
+
+	if err := repo.EnsureIndexes(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// createTestAgency registra una agencia activa con el límite de crédito indicado
+func createTestAgency(t *testing.T, repo *Repository, creditLimit float64) *Agency {
+	t.Helper()
+
+	agency := &Agency{Name: "Agencia de prueba", RUC: uuid.New().String(), CreditLimit: creditLimit}
+	if err := repo.CreateAgency(context.Background(), agency); err != nil {
+		t.Fatal(err)
+	}
+	return agency
+}
+
+func TestDebitRejectsWhenBalanceAndCreditAreShort(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	agency := createTestAgency(t, repo, 100)
+
+	if _, err := repo.AdjustBalance(ctx, agency.ID, 50); err != nil {
+		t.Fatal(err)
+	}
+
+	charged, err := repo.Debit(ctx, agency.ID, 120)
+	if err != nil {
+		t.Fatalf("Debit(120) con 150 disponibles: %v", err)
+	}
+	if charged.Balance != -70 {
+		t.Errorf("saldo = %v, se esperaba -70", charged.Balance)
+	}
+
+	if _, err := repo.Debit(ctx, agency.ID, 30.01); !errors.Is(err, ErrInsufficientCredit) {
+		t.Errorf("Debit(30.01) con 30 disponibles: error = %v, se esperaba ErrInsufficientCredit", err)
+	}
+
+	// El crédito se puede usar hasta el límite exacto
+	charged, err = repo.Debit(ctx, agency.ID, 30)
+	if err != nil {
+		t.Fatalf("Debit(30) con 30 disponibles: %v", err)
+	}
+	if charged.Balance != -100 {
+		t.Errorf("saldo = %v, se esperaba -100", charged.Balance)
+	}
+
+	current, err := repo.GetAgency(ctx, agency.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Available() != 0 {
+		t.Errorf("disponible = %v, se esperaba 0", current.Available())
+	}
+}
+
+func TestDebitRejectsInactiveAgency(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	agency := createTestAgency(t, repo, 100)
+
+	if _, err := repo.SetAgencyActive(ctx, agency.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Debit(ctx, agency.ID, 10); !errors.Is(err, ErrAgencyInactive) {
+		t.Errorf("error = %v, se esperaba ErrAgencyInactive", err)
+	}
+}
+
+func TestRefundIsCreditedOnce(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	manager := &Manager{repo: repo}
+	agency := createTestAgency(t, repo, 500)
+
+	sale := &Transaction{
+		AgencyID:          agency.ID,
+		Type:              TransactionSale,
+		ReservationID:     "reserva-1",
+		Gross:             200,
+		CommissionPercent: 8,
+		Commission:        16,
+		Amount:            -184,
+	}
+	if _, err := repo.Debit(ctx, agency.ID, -sale.Amount); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateTransaction(ctx, sale); err != nil {
+		t.Fatal(err)
+	}
+
+	reservation := &search.Reservation{ID: "reserva-1", AgencyID: agency.ID, Status: search.ReservationCancelled}
+
+	refund, err := manager.refund(ctx, reservation, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Gross != 100 || refund.Commission != 8 || refund.Amount != 92 {
+		t.Errorf("reembolso = %+v, se esperaba total 100, comisión 8 y monto 92", *refund)
+	}
+
+	if _, err := manager.refund(ctx, reservation, 50); !errors.Is(err, ErrAlreadyRefunded) {
+		t.Errorf("segundo reembolso: error = %v, se esperaba ErrAlreadyRefunded", err)
+	}
+
+	// Repetir la cancelación devuelve el reembolso registrado sin abonarlo de nuevo
+	completed, err := manager.completeRefund(ctx, reservation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.Transaction.ID != refund.ID {
+		t.Errorf("completeRefund() devolvió el movimiento %s, se esperaba %s", completed.Transaction.ID, refund.ID)
+	}
+
+	current, err := repo.GetAgency(ctx, agency.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Balance != -92 {
+		t.Errorf("saldo = %v, se esperaba -92", current.Balance)
+	}
+}
+
+func TestDeniedBoardingIsRefundedInFull(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	manager := &Manager{repo: repo}
+	agency := createTestAgency(t, repo, 500)
+
+	for _, id := range []string{"denegada", "reubicada"} {
+		sale := &Transaction{AgencyID: agency.ID, Type: TransactionSale, ReservationID: id, Gross: 100, CommissionPercent: 10, Commission: 10, Amount: -90}
+		if _, err := repo.Debit(ctx, agency.ID, -sale.Amount); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.CreateTransaction(ctx, sale); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	denied := []*search.Reservation{
+		{ID: "denegada", AgencyID: agency.ID, Status: search.ReservationDeniedBoarding},
+		{ID: "reubicada", AgencyID: agency.ID, Status: search.ReservationRebooked, ReplacedBy: "nueva"},
+	}
+	if err := manager.OnBoardingDenied(ctx, &search.Route{ID: "ruta-1"}, denied); err != nil {
+		t.Fatal(err)
+	}
+	// Si luego se cancela la salida, el embarque denegado ya reembolsado no se abona de nuevo
+	if err := manager.OnRouteCancelled(ctx, &search.Route{ID: "ruta-1"}, denied); err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := repo.GetReservationTransaction(ctx, "denegada", TransactionRefund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund == nil || refund.RefundPercent != 100 || refund.Amount != 90 {
+		t.Errorf("reembolso del embarque denegado = %+v, se esperaba el 100%% por 90", refund)
+	}
+	if rebooked, err := repo.GetReservationTransaction(ctx, "reubicada", TransactionRefund); err != nil || rebooked != nil {
+		t.Errorf("reembolso de la reserva reubicada = %+v (%v), la venta debe seguir a la nueva reserva", rebooked, err)
+	}
+
+	current, err := repo.GetAgency(ctx, agency.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Balance != -90 {
+		t.Errorf("saldo = %v, se esperaba -90", current.Balance)
+	}
+}
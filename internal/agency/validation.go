@@ -0,0 +1,49 @@
+package agency
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+// ErrInvalidAgency indica que los datos de la agencia no son válidos
+var ErrInvalidAgency = errors.New("agencia inválida")
+
+// maxCommissionPercent es la comisión máxima que se puede pactar con una agencia
+const maxCommissionPercent = 30
+
+// validateAgency verifica los datos, las comisiones y el límite de crédito de la agencia
+func validateAgency(agency *Agency) error {
+	agency.Name = strings.TrimSpace(agency.Name)
+	agency.LegalName = strings.TrimSpace(agency.LegalName)
+	agency.RUC = strings.TrimSpace(agency.RUC)
+	agency.ContactEmail = strings.ToLower(strings.TrimSpace(agency.ContactEmail))
+
+	if agency.Name == "" || agency.LegalName == "" {
+		return fmt.Errorf("%w: el nombre comercial y la razón social son obligatorios", ErrInvalidAgency)
+	}
+	if err := operator.ValidateRUC(agency.RUC); err != nil {
+		return err
+	}
+	if agency.CreditLimit < 0 {
+		return fmt.Errorf("%w: el límite de crédito no puede ser negativo", ErrInvalidAgency)
+	}
+
+	operators := make(map[string]bool, len(agency.Commissions))
+	for _, rule := range agency.Commissions {
+		if rule.Percent < 0 || rule.Percent > maxCommissionPercent {
+			return fmt.Errorf("%w: la comisión debe estar entre 0 y %d%%", ErrInvalidAgency, maxCommissionPercent)
+		}
+		if operators[rule.OperatorID] && rule.OperatorID == "" {
+			return fmt.Errorf("%w: hay más de una comisión general", ErrInvalidAgency)
+		}
+		if operators[rule.OperatorID] {
+			return fmt.Errorf("%w: hay más de una comisión para el operador %s", ErrInvalidAgency, rule.OperatorID)
+		}
+		operators[rule.OperatorID] = true
+	}
+
+	return nil
+}
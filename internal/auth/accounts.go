@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Límites de la contraseña; bcrypt solo considera los primeros 72 bytes
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+var (
+	// ErrInvalidRegistration indica que los datos de registro no son válidos
+	ErrInvalidRegistration = errors.New("registro inválido")
+	// ErrInvalidCredentials indica que el correo o la contraseña no son correctos
+	ErrInvalidCredentials = errors.New("correo o contraseña incorrectos")
+)
+
+// Accounts registra usuarios con su contraseña cifrada con bcrypt e inicia sus sesiones
+type Accounts struct {
+	repo       *Repository
+	tokens     *TokenManager
+	bcryptCost int
+	dummyHash  []byte // Hash con el que se compara la contraseña de un correo sin cuenta
+}
+
+// NewAccounts crea una nueva instancia de Accounts. El hash para los correos sin cuenta se genera con el mismo
+// costo que las contraseñas, para que el login tarde lo mismo exista o no la cuenta.
+func NewAccounts(repo *Repository, tokens *TokenManager, bcryptCost int) (*Accounts, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("cuenta-inexistente"), bcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	return &Accounts{
+		repo:       repo,
+		tokens:     tokens,
+		bcryptCost: bcryptCost,
+		dummyHash:  dummyHash,
+	}, nil
+}
+
+// Register crea una cuenta de usuario con el correo en minúsculas y la contraseña cifrada
+func (a *Accounts) Register(ctx context.Context, request RegisterRequest) (*User, error) {
+	email := normalizeEmail(request.Email)
+	if _, err := mail.ParseAddress(email); err != nil || email == "" {
+		return nil, fmt.Errorf("%w: correo inválido", ErrInvalidRegistration)
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: el nombre es obligatorio", ErrInvalidRegistration)
+	}
+	if len(request.Password) < minPasswordLength || len(request.Password) > maxPasswordLength {
+		return nil, fmt.Errorf("%w: la contraseña debe tener entre %d y %d caracteres", ErrInvalidRegistration, minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), a.bcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// Toda cuenta nueva es de cliente; los demás roles los asigna un superadmin
+	user := &User{Email: email, Name: name, PasswordHash: string(hash), Role: RoleCustomer}
+	if err := a.repo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login verifica las credenciales y emite un token de acceso. No distingue un correo inexistente de una
+// contraseña incorrecta, ni en la respuesta ni en el tiempo que tarda.
+func (a *Accounts) Login(ctx context.Context, request LoginRequest) (*Session, error) {
+	user, err := a.repo.GetUserByEmail(ctx, normalizeEmail(request.Email))
+	if errors.Is(err, ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(a.dummyHash, []byte(request.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(request.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, expiresAt, err := a.tokens.Issue(user)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Token: token, TokenType: "Bearer", ExpiresAt: expiresAt, User: user}, nil
+}
+
+// normalizeEmail devuelve el correo sin espacios y en minúsculas
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewAccountsDummyHashUsesPasswordCost(t *testing.T) {
+	accounts, err := NewAccounts(nil, nil, bcrypt.MinCost+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Con el mismo costo, comparar contra el hash de referencia tarda lo mismo que contra una contraseña real
+	if cost, err := bcrypt.Cost(accounts.dummyHash); err != nil || cost != bcrypt.MinCost+1 {
+		t.Errorf("costo del hash de referencia = %d (%v), se esperaba %d", cost, err, bcrypt.MinCost+1)
+	}
+
+	if _, err := NewAccounts(nil, nil, bcrypt.MaxCost+1); err == nil {
+		t.Error("NewAccounts() con un costo inválido no devolvió error")
+	}
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// OperatorValidator verifica que un operador exista y esté activo
+type OperatorValidator interface {
+	ValidateOperator(ctx context.Context, operatorID string) error
+}
+
+// AuthHandler maneja las solicitudes de registro, inicio de sesión y asignación de roles
+type AuthHandler struct {
+	repo      *Repository
+	accounts  *Accounts
+	operators OperatorValidator
+}
+
+// NewAuthHandler crea una nueva instancia de AuthHandler
+func NewAuthHandler(repo *Repository, accounts *Accounts, operators OperatorValidator) *AuthHandler {
+	return &AuthHandler{
+		repo:      repo,
+		accounts:  accounts,
+		operators: operators,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error de las cuentas de usuario
+func (h *AuthHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidRegistration), errors.Is(err, ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RegisterHandler maneja las solicitudes de registro de un usuario
+func (h *AuthHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var request RegisterRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.accounts.Register(r.Context(), request)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// LoginHandler maneja las solicitudes de inicio de sesión y responde con el token de acceso
+func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var request LoginRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.accounts.Login(r.Context(), request)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// MeHandler maneja las solicitudes de la cuenta del usuario autenticado
+func (h *AuthHandler) MeHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := h.repo.GetUserByID(r.Context(), UserID(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// GetUsersHandler maneja las solicitudes para consultar una cuenta de usuario por su ID (id) o su correo (email)
+func (h *AuthHandler) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
+	var user *User
+	var err error
+	switch {
+	case r.URL.Query().Get("id") != "":
+		user, err = h.repo.GetUserByID(r.Context(), r.URL.Query().Get("id"))
+	case r.URL.Query().Get("email") != "":
+		user, err = h.repo.GetUserByEmail(r.Context(), normalizeEmail(r.URL.Query().Get("email")))
+	default:
+		http.Error(w, "el parámetro id o email es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// SetRoleHandler maneja las solicitudes para asignar el rol de un usuario. El rol admin_operador requiere el
+// operador que administra; los demás roles no llevan operador. El cambio rige desde la siguiente solicitud del usuario.
+func (h *AuthHandler) SetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var request RoleUpdate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if request.UserID == "" || request.Role == "" {
+		http.Error(w, "los campos user_id y role son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validateRole(r.Context(), &request); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	user, err := h.repo.SetUserRole(r.Context(), request.UserID, request.Role, request.OperatorID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// validateRole verifica el rol y su operador; fuera de admin_operador el operador se descarta
+func (h *AuthHandler) validateRole(ctx context.Context, request *RoleUpdate) error {
+	if !ValidRole(request.Role) {
+		return fmt.Errorf("%w: %s", ErrInvalidRole, request.Role)
+	}
+	if request.Role != RoleOperatorAdmin {
+		request.OperatorID = ""
+		return nil
+	}
+	if request.OperatorID == "" {
+		return fmt.Errorf("%w: el rol %s requiere operator_id", ErrInvalidRole, RoleOperatorAdmin)
+	}
+	if err := h.operators.ValidateOperator(ctx, request.OperatorID); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRole, err)
+	}
+	return nil
+}
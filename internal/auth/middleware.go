@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthenticated indica que la solicitud no trae un token de acceso
+var ErrUnauthenticated = errors.New("se requiere iniciar sesión")
+
+// principalKey es la clave del usuario autenticado en el contexto de la solicitud
+type principalKey struct{}
+
+// WithPrincipal devuelve un contexto con el usuario autenticado
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom obtiene el usuario autenticado del contexto de la solicitud
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// UserID devuelve el ID del usuario autenticado, o vacío si la solicitud no pasó por Require
+func UserID(ctx context.Context) string {
+	if principal, ok := PrincipalFrom(ctx); ok {
+		return principal.UserID
+	}
+	return ""
+}
+
+// UserDirectory obtiene las cuentas de usuario con su rol vigente
+type UserDirectory interface {
+	GetUserByID(ctx context.Context, userID string) (*User, error)
+}
+
+// Authenticator verifica el token de acceso de las solicitudes
+type Authenticator struct {
+	tokens *TokenManager
+	users  UserDirectory
+}
+
+// NewAuthenticator crea una nueva instancia de Authenticator
+func NewAuthenticator(tokens *TokenManager, users UserDirectory) *Authenticator {
+	return &Authenticator{tokens: tokens, users: users}
+}
+
+// Require exige un token de acceso válido en la cabecera Authorization y agrega el usuario autenticado al
+// contexto de la solicitud. El rol y el operador se leen de la cuenta en cada solicitud, no del token, para que
+// un cambio de rol rija de inmediato. Sin token, con uno inválido o de una cuenta que ya no existe, responde 401.
+func (a *Authenticator) Require(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="venta-de-pasajes"`)
+			http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := a.tokens.Parse(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="venta-de-pasajes", error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		user, err := a.users.GetUserByID(r.Context(), claims.Subject)
+		if errors.Is(err, ErrUserNotFound) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="venta-de-pasajes", error="invalid_token"`)
+			http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := WithPrincipal(r.Context(), &Principal{
+			UserID:     user.ID,
+			Email:      user.Email,
+			Role:       roleOrDefault(user.Role),
+			OperatorID: user.OperatorID,
+		})
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import "time"
+
+// User representa una cuenta de usuario
+type User struct {
+	ID           string    `json:"id,omitempty" bson:"_id,omitempty"`
+	Email        string    `json:"email" bson:"email"` // En minúsculas; identifica la cuenta al iniciar sesión
+	Name         string    `json:"name" bson:"name"`
+	PasswordHash string    `json:"-" bson:"password_hash"`
+	Role         string    `json:"role" bson:"role"`
+	OperatorID   string    `json:"operator_id,omitempty" bson:"operator_id,omitempty"` // Operador que administra un admin_operador
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
+
+// RegisterRequest representa una solicitud de registro de usuario
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// LoginRequest representa las credenciales para iniciar sesión
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Session representa el token de acceso emitido al iniciar sesión
+type Session struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      *User     `json:"user"`
+}
+
+// RoleUpdate representa la asignación del rol de un usuario
+type RoleUpdate struct {
+	UserID     string `json:"user_id"`
+	Role       string `json:"role"`
+	OperatorID string `json:"operator_id,omitempty"`
+}
+
+// Principal representa al usuario autenticado de una solicitud
+type Principal struct {
+	UserID     string `json:"user_id"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	OperatorID string `json:"operator_id,omitempty"`
+}
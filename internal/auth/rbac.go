@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// Roles de los usuarios
+const (
+	RoleCustomer      = "cliente"        // Compra y gestiona sus propias reservas
+	RoleAgent         = "agente"         // Personal de terminal: check-in en mostrador, embarque, pagos y equipaje
+	RoleOperatorAdmin = "admin_operador" // Administra las salidas, la flota y la tripulación de su operador
+	RoleSuperadmin    = "superadmin"     // Administra toda la plataforma
+)
+
+// Permisos de los endpoints de administración y del personal
+const (
+	PermRoutesManage    = "rutas:administrar"       // Crear, modificar, reprogramar y cancelar salidas
+	PermSchedulesManage = "horarios:administrar"    // Horarios recurrentes y sus excepciones
+	PermSchedulesRun    = "horarios:generar"        // Generar las salidas de todos los horarios
+	PermHolidaysManage  = "feriados:administrar"    // Calendario de feriados
+	PermFleetManage     = "flota:administrar"       // Vehículos y su asignación a salidas
+	PermCrewManage      = "tripulacion:administrar" // Tripulantes, asignaciones y rol
+	PermOperatorsManage = "operadores:administrar"  // Alta y baja de operadores
+	PermOperatorProfile = "operadores:perfil"       // Perfil, políticas y reglas de equipaje del operador
+	PermReports         = "reportes:consultar"      // Reportes de salidas y ventas
+	PermBoardingManage  = "embarque:administrar"    // Check-in en mostrador, escaneo, manifiesto y no presentados
+	PermOversaleResolve = "embarque:sobreventa"     // Resolver la sobreventa de una salida
+	PermGroupPayments   = "grupos:pagos"            // Registrar pagos de reservas de grupo
+	PermGroupFares      = "grupos:tarifas"          // Reservar grupos con tarifa negociada
+	PermLocationsManage = "ubicaciones:administrar" // Catálogo de ubicaciones
+	PermBaggageCatalog  = "equipaje:catalogo"       // Tipos, categorías y franquicias de equipaje
+	PermBaggageHandling = "equipaje:manejo"         // Revisión, etiquetado y rastreo de piezas
+	PermBaggageHolds    = "equipaje:bodegas"        // Capacidad y carga de la bodega de las salidas
+	PermUsersManage     = "usuarios:administrar"    // Asignar roles
+	PermAgenciesManage  = "agencias:administrar"    // Agencias de viaje, sus claves de API, comisiones y saldo
+)
+
+// rolePermissions define los permisos de cada rol. El cliente no tiene permisos de administración: sus
+// endpoints solo exigen iniciar sesión y trabajan sobre sus propias reservas.
+var rolePermissions = map[string][]string{
+	RoleCustomer: {},
+	RoleAgent: {
+		PermBoardingManage, PermOversaleResolve, PermGroupPayments, PermGroupFares, PermBaggageHandling,
+		PermBaggageHolds,
+	},
+	RoleOperatorAdmin: {
+		PermRoutesManage, PermSchedulesManage, PermFleetManage, PermCrewManage, PermOperatorProfile, PermReports,
+		PermBoardingManage, PermOversaleResolve, PermBaggageHolds,
+	},
+	RoleSuperadmin: {
+		PermRoutesManage, PermSchedulesManage, PermSchedulesRun, PermHolidaysManage, PermFleetManage, PermCrewManage,
+		PermOperatorsManage, PermOperatorProfile, PermReports, PermBoardingManage, PermOversaleResolve,
+		PermGroupPayments, PermGroupFares, PermLocationsManage, PermBaggageCatalog, PermBaggageHandling, PermBaggageHolds,
+		PermUsersManage, PermAgenciesManage,
+	},
+}
+
+var (
+	// ErrForbidden indica que el usuario autenticado no tiene permiso para la operación
+	ErrForbidden = errors.New("no tiene permiso para esta operación")
+	// ErrInvalidRole indica que el rol no existe o le falta el operador
+	ErrInvalidRole = errors.New("rol inválido")
+)
+
+// ValidRole indica si el rol existe
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// roleOrDefault devuelve el rol, o el de cliente para las cuentas anteriores a los roles
+func roleOrDefault(role string) string {
+	if role == "" {
+		return RoleCustomer
+	}
+	return role
+}
+
+// Can indica si el usuario tiene el permiso
+func (p *Principal) Can(permission string) bool {
+	return slices.Contains(rolePermissions[p.Role], permission)
+}
+
+// OperatorScope devuelve el operador al que se limita el usuario autenticado, o vacío si puede ver todos
+func OperatorScope(ctx context.Context) string {
+	if principal, ok := PrincipalFrom(ctx); ok && principal.Role == RoleOperatorAdmin {
+		return principal.OperatorID
+	}
+	return ""
+}
+
+// AuthorizeOperator verifica que el usuario autenticado pueda gestionar los recursos del operador. Un
+// admin_operador solo gestiona los de su operador; los demás roles con el permiso del endpoint, todos.
+func AuthorizeOperator(ctx context.Context, operatorID string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+	if principal.Role == RoleOperatorAdmin && (operatorID == "" || operatorID != principal.OperatorID) {
+		return fmt.Errorf("%w: el recurso pertenece a otro operador", ErrForbidden)
+	}
+	return nil
+}
+
+// RequirePermission exige un token de acceso válido, como Require, y además el permiso indicado. Sin permiso
+// responde 403.
+func (a *Authenticator) RequirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return a.Require(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFrom(r.Context())
+		if !principal.Can(permission) {
+			http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRolePermissions(t *testing.T) {
+	// Roles que tienen cada permiso; el cliente no tiene ninguno
+	tests := []struct {
+		permission string
+		agent      bool
+		operator   bool
+		superadmin bool
+	}{
+		{permission: PermRoutesManage, operator: true, superadmin: true},
+		{permission: PermSchedulesManage, operator: true, superadmin: true},
+		{permission: PermSchedulesRun, superadmin: true},
+		{permission: PermHolidaysManage, superadmin: true},
+		{permission: PermFleetManage, operator: true, superadmin: true},
+		{permission: PermCrewManage, operator: true, superadmin: true},
+		{permission: PermOperatorsManage, superadmin: true},
+		{permission: PermOperatorProfile, operator: true, superadmin: true},
+		{permission: PermReports, operator: true, superadmin: true},
+		{permission: PermBoardingManage, agent: true, operator: true, superadmin: true},
+		{permission: PermOversaleResolve, agent: true, operator: true, superadmin: true},
+		{permission: PermGroupPayments, agent: true, superadmin: true},
+		{permission: PermGroupFares, agent: true, superadmin: true},
+		{permission: PermLocationsManage, superadmin: true},
+		{permission: PermBaggageCatalog, superadmin: true},
+		{permission: PermBaggageHandling, agent: true, superadmin: true},
+		{permission: PermBaggageHolds, agent: true, operator: true, superadmin: true},
+		{permission: PermUsersManage, superadmin: true},
+		{permission: PermAgenciesManage, superadmin: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.permission, func(t *testing.T) {
+			want := map[string]bool{RoleCustomer: false, RoleAgent: tt.agent, RoleOperatorAdmin: tt.operator, RoleSuperadmin: tt.superadmin}
+			for role, allowed := range want {
+				principal := &Principal{Role: role}
+				if got := principal.Can(tt.permission); got != allowed {
+					t.Errorf("%s.Can(%s) = %v, se esperaba %v", role, tt.permission, got, allowed)
+				}
+			}
+		})
+	}
+
+	// Cada permiso de un rol debe estar en la tabla, para que un permiso nuevo no quede sin probar
+	covered := make(map[string]bool, len(tests))
+	for _, tt := range tests {
+		covered[tt.permission] = true
+	}
+	for role, permissions := range rolePermissions {
+		for _, permission := range permissions {
+			if !covered[permission] {
+				t.Errorf("el permiso %s del rol %s no está en la tabla de la prueba", permission, role)
+			}
+		}
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	for _, role := range []string{RoleCustomer, RoleAgent, RoleOperatorAdmin, RoleSuperadmin} {
+		if !ValidRole(role) {
+			t.Errorf("ValidRole(%q) = false, se esperaba true", role)
+		}
+	}
+	for _, role := range []string{"", "admin", "Superadmin"} {
+		if ValidRole(role) {
+			t.Errorf("ValidRole(%q) = true, se esperaba false", role)
+		}
+	}
+	if principal := (&Principal{Role: "desconocido"}); principal.Can(PermReports) {
+		t.Error("un rol desconocido no debe tener permisos")
+	}
+}
+
+func TestOperatorScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      string
+	}{
+		{name: "admin de operador", principal: &Principal{Role: RoleOperatorAdmin, OperatorID: "op-1"}, want: "op-1"},
+		{name: "superadmin ve todos", principal: &Principal{Role: RoleSuperadmin}, want: ""},
+		{name: "agente ve todos", principal: &Principal{Role: RoleAgent, OperatorID: "op-1"}, want: ""},
+		{name: "sin sesión", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+			if got := OperatorScope(ctx); got != tt.want {
+				t.Errorf("OperatorScope() = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeOperator(t *testing.T) {
+	operatorAdmin := &Principal{Role: RoleOperatorAdmin, OperatorID: "op-1"}
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		operatorID string
+		wantErr    bool
+	}{
+		{name: "admin de su operador", principal: operatorAdmin, operatorID: "op-1"},
+		{name: "admin de otro operador", principal: operatorAdmin, operatorID: "op-2", wantErr: true},
+		{name: "admin sobre un recurso sin operador", principal: operatorAdmin, operatorID: "", wantErr: true},
+		{name: "admin sin operador asignado", principal: &Principal{Role: RoleOperatorAdmin}, operatorID: "", wantErr: true},
+		{name: "superadmin sobre cualquier operador", principal: &Principal{Role: RoleSuperadmin}, operatorID: "op-2"},
+		{name: "superadmin sobre un recurso sin operador", principal: &Principal{Role: RoleSuperadmin}, operatorID: ""},
+		{name: "agente", principal: &Principal{Role: RoleAgent}, operatorID: "op-2"},
+		{name: "sin sesión", operatorID: "op-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+			err := AuthorizeOperator(ctx, tt.operatorID)
+			if tt.wantErr && !errors.Is(err, ErrForbidden) {
+				t.Errorf("AuthorizeOperator(%q): error = %v, se esperaba ErrForbidden", tt.operatorID, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("AuthorizeOperator(%q): %v", tt.operatorID, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+var (
+	// ErrUserNotFound indica que el usuario no existe
+	ErrUserNotFound = errors.New("usuario no encontrado")
+	// ErrEmailTaken indica que ya existe una cuenta con el correo
+	ErrEmailTaken = errors.New("ya existe una cuenta con ese correo")
+)
+
+// Repository es el repositorio de cuentas de usuario en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para las cuentas de usuario")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// users devuelve la colección de cuentas de usuario
+func (r *Repository) users() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.UsersCollection)
+}
+
+// EnsureIndexes crea el índice único por correo
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.users().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateUser registra una cuenta de usuario con un ID nuevo
+func (r *Repository) CreateUser(ctx context.Context, user *User) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	user.ID = uuid.New().String()
+	user.CreatedAt = time.Now()
+
+	_, err := r.users().InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+// GetUserByEmail obtiene una cuenta de usuario por su correo
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return r.findUser(ctx, bson.M{"email": email})
+}
+
+// GetUserByID obtiene una cuenta de usuario por su ID
+func (r *Repository) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	return r.findUser(ctx, bson.M{"_id": userID})
+}
+
+// findUser obtiene la cuenta de usuario que cumple el filtro
+func (r *Repository) findUser(ctx context.Context, filter bson.M) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var user User
+	err := r.users().FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SetUserRole asigna el rol y el operador de una cuenta de usuario y devuelve la cuenta actualizada
+func (r *Repository) SetUserRole(ctx context.Context, userID, role, operatorID string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"role": role}, "$unset": bson.M{"operator_id": ""}}
+	if operatorID != "" {
+		update = bson.M{"$set": bson.M{"role": role, "operator_id": operatorID}}
+	}
+
+	var user User
+	err := r.users().FindOneAndUpdate(ctx, bson.M{"_id": userID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"venta-de-pasajes/config"
+)
+
+// tokenHeader es la cabecera de los tokens: JWT firmados con HMAC SHA-256
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+var (
+	// ErrMissingSecret indica que no se configuró el secreto para firmar los tokens
+	ErrMissingSecret = errors.New("JWT_SECRET no configurado")
+	// ErrInvalidToken indica que el token no tiene el formato esperado, su firma no es válida o venció
+	ErrInvalidToken = errors.New("token de acceso inválido")
+)
+
+// Claims representa el contenido de un token de acceso
+type Claims struct {
+	Subject    string `json:"sub"` // ID del usuario
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	OperatorID string `json:"operator_id,omitempty"`
+	Issuer     string `json:"iss"`
+	IssuedAt   int64  `json:"iat"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// TokenManager emite y verifica los tokens de acceso. Los servicios comparten el secreto, por lo que un token
+// emitido por uno es válido en los demás. El token lleva el rol del usuario solo como referencia: los permisos
+// se evalúan con el rol vigente de la cuenta.
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+	issuer string
+}
+
+// NewTokenManager crea un TokenManager con el secreto configurado. Sin secreto no se inicia: cada réplica
+// firmaría con uno distinto.
+func NewTokenManager(cfg config.AuthConfig) (*TokenManager, error) {
+	if cfg.JWTSecret == "" {
+		return nil, ErrMissingSecret
+	}
+	return &TokenManager{
+		secret: []byte(cfg.JWTSecret),
+		ttl:    cfg.TokenTTL,
+		issuer: cfg.Issuer,
+	}, nil
+}
+
+// Issue emite un token de acceso para el usuario y devuelve su vencimiento
+func (m *TokenManager) Issue(user *User) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	data, err := json.Marshal(Claims{
+		Subject:    user.ID,
+		Email:      user.Email,
+		Role:       user.Role,
+		OperatorID: user.OperatorID,
+		Issuer:     m.issuer,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signed := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(data)
+	return signed + "." + m.sign(signed), expiresAt, nil
+}
+
+// Parse verifica la firma, el emisor y la vigencia de un token y devuelve su contenido
+func (m *TokenManager) Parse(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: formato desconocido", ErrInvalidToken)
+	}
+	// Solo se aceptan tokens con la cabecera propia, lo que descarta otros algoritmos y "none"
+	if parts[0] != tokenHeader {
+		return nil, fmt.Errorf("%w: algoritmo no soportado", ErrInvalidToken)
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(m.sign(parts[0]+"."+parts[1]))) {
+		return nil, fmt.Errorf("%w: la firma no corresponde", ErrInvalidToken)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: contenido ilegible", ErrInvalidToken)
+	}
+	var claims Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("%w: contenido ilegible", ErrInvalidToken)
+	}
+	if claims.Issuer != m.issuer || claims.Subject == "" {
+		return nil, fmt.Errorf("%w: emisor desconocido", ErrInvalidToken)
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("%w: el token venció", ErrInvalidToken)
+	}
+
+	return &claims, nil
+}
+
+// sign devuelve la firma HMAC SHA-256 en base64 URL
+func (m *TokenManager) sign(signed string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
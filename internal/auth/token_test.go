@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/config"
+)
+
+// newTestTokenManager crea un TokenManager con un secreto de prueba y la vigencia indicada
+func newTestTokenManager(t *testing.T, secret string, ttl time.Duration) *TokenManager {
+	t.Helper()
+
+	manager, err := NewTokenManager(config.AuthConfig{JWTSecret: secret, TokenTTL: ttl, Issuer: "venta-de-pasajes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+// testUser es la cuenta para la que se emiten los tokens de prueba
+var testUser = &User{ID: "usuario-1", Email: "ana@ejemplo.com", Role: RoleOperatorAdmin, OperatorID: "op-1"}
+
+// forgeToken firma con el secreto del manager un token con la cabecera y el contenido indicados
+func forgeToken(t *testing.T, manager *TokenManager, header string, claims Claims) string {
+	t.Helper()
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(data)
+	return signed + "." + manager.sign(signed)
+}
+
+func TestNewTokenManagerRequiresSecret(t *testing.T) {
+	if _, err := NewTokenManager(config.AuthConfig{TokenTTL: time.Hour}); !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("NewTokenManager() sin secreto = %v, se esperaba ErrMissingSecret", err)
+	}
+}
+
+func TestParseIssuedToken(t *testing.T) {
+	manager := newTestTokenManager(t, "secreto-de-prueba", time.Hour)
+
+	token, expiresAt, err := manager.Issue(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := manager.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse() de un token emitido: %v", err)
+	}
+	if claims.Subject != testUser.ID || claims.Email != testUser.Email || claims.Role != testUser.Role || claims.OperatorID != testUser.OperatorID {
+		t.Errorf("Parse() = %+v, no corresponde al usuario %+v", *claims, *testUser)
+	}
+	if claims.ExpiresAt != expiresAt.Unix() {
+		t.Errorf("vencimiento = %d, se esperaba %d", claims.ExpiresAt, expiresAt.Unix())
+	}
+}
+
+func TestParseRejectsInvalidTokens(t *testing.T) {
+	manager := newTestTokenManager(t, "secreto-de-prueba", time.Hour)
+	token, _, err := manager.Issue(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	otherSecret, _, err := newTestTokenManager(t, "otro-secreto", time.Hour).Issue(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, err := newTestTokenManager(t, "secreto-de-prueba", -time.Minute).Issue(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Un contenido modificado conserva la firma del original
+	escalated, err := json.Marshal(Claims{Subject: testUser.ID, Role: RoleSuperadmin, Issuer: "venta-de-pasajes", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(escalated) + "." + parts[2]
+
+	valid := Claims{Subject: testUser.ID, Issuer: "venta-de-pasajes", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	foreignIssuer := valid
+	foreignIssuer.Issuer = "otro-emisor"
+	withoutSubject := valid
+	withoutSubject.Subject = ""
+	if _, err := manager.Parse(forgeToken(t, manager, `{"alg":"HS256","typ":"JWT"}`, valid)); err != nil {
+		t.Fatalf("Parse() de un token armado con la cabecera propia: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "vacío", token: ""},
+		{name: "sin firma", token: parts[0] + "." + parts[1]},
+		{name: "contenido modificado", token: tampered},
+		{name: "firmado con otro secreto", token: otherSecret},
+		{name: "vencido", token: expired},
+		{name: "algoritmo none", token: forgeToken(t, manager, `{"alg":"none","typ":"JWT"}`, valid)},
+		{name: "otro algoritmo", token: forgeToken(t, manager, `{"alg":"HS512","typ":"JWT"}`, valid)},
+		{name: "otro emisor", token: forgeToken(t, manager, `{"alg":"HS256","typ":"JWT"}`, foreignIssuer)},
+		{name: "sin usuario", token: forgeToken(t, manager, `{"alg":"HS256","typ":"JWT"}`, withoutSubject)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := manager.Parse(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Parse() = %v, se esperaba ErrInvalidToken", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package baggage
+
+// selectAllowance elige la franquicia más específica para la reserva.
+// Una franquicia por ruta prevalece sobre una por operador, y ésta sobre una genérica de la clase tarifaria.
+func selectAllowance(allowances []*BaggageAllowance, reservation *BaggageReservation) *BaggageAllowance {
+	var selected *BaggageAllowance
+	bestScore := -1
+
+	for _, allowance := range allowances {
+		if allowance.FareClass != reservation.FareClass {
+			continue
+		}
+		if allowance.RouteID != "" && allowance.RouteID != reservation.RouteID {
+			continue
+		}
+		if allowance.OperatorID != "" && allowance.OperatorID != reservation.OperatorID {
+			continue
+		}
+
+		score := 0
+		if allowance.RouteID != "" {
+			score += 2
+		}
+		if allowance.OperatorID != "" {
+			score++
+		}
+		if score > bestScore {
+			selected = allowance
+			bestScore = score
+		}
+	}
+
+	return selected
+}
+
+// forTickets devuelve la franquicia de una reserva con el número de pasajes indicado: las piezas se multiplican
+// por los pasajes y el peso máximo, que es por pieza, no cambia. Las reservas registradas antes de guardar los
+// pasajes tienen 0 y se cuentan como un pasaje.
+func (a *BaggageAllowance) forTickets(tickets int) *BaggageAllowance {
+	if a == nil || tickets <= 1 {
+		return a
+	}
+	scaled := *a
+	scaled.Pieces *= tickets
+	return &scaled
+}
+
+// covers indica si la franquicia incluye piezas del tipo y peso indicados. Las solicitudes exigen un peso
+// mayor a cero; solo las líneas registradas antes de exigirlo tienen peso 0 y se consideran dentro del límite.
+func (a *BaggageAllowance) covers(baggageType string, weight float64) bool {
+	if a.MaxWeight > 0 && weight > a.MaxWeight {
+		return false
+	}
+	if len(a.BaggageTypes) == 0 {
+		return true
+	}
+	for _, t := range a.BaggageTypes {
+		if t == baggageType {
+			return true
+		}
+	}
+	return false
+}
+
+// usedPieces cuenta las piezas ya cubiertas por la franquicia en las líneas de equipaje
+func usedPieces(lines []Baggage) int {
+	used := 0
+	for _, line := range lines {
+		used += line.IncludedQuantity
+	}
+	return used
+}
+
+// applyAllowance determina cuántas piezas de la línea quedan incluidas en la franquicia
+// considerando lo ya consumido por las líneas existentes, y calcula el precio del exceso
+func applyAllowance(allowance *BaggageAllowance, existing []Baggage, line *Baggage) {
+	line.IncludedQuantity = 0
+	if allowance != nil && allowance.covers(line.Type, line.Weight) {
+		remaining := allowance.Pieces - usedPieces(existing)
+		if remaining > 0 {
+			line.IncludedQuantity = min(remaining, line.Quantity)
+		}
+	}
+
+	line.Price = float64(line.Quantity-line.IncludedQuantity) * line.UnitPrice
+}
+
+// newAllowanceUsage resume el consumo de la franquicia para las líneas de equipaje dadas
+func newAllowanceUsage(allowance *BaggageAllowance, lines []Baggage) *AllowanceUsage {
+	if allowance == nil {
+		return nil
+	}
+
+	used := usedPieces(lines)
+	return &AllowanceUsage{
+		AllowanceID:     allowance.ID,
+		Pieces:          allowance.Pieces,
+		UsedPieces:      used,
+		RemainingPieces: max(allowance.Pieces-used, 0),
+		MaxWeight:       allowance.MaxWeight,
+	}
+}
+
+// priceBaggageLines recalcula las piezas incluidas y el precio de cada línea en orden,
+// a partir de los precios unitarios registrados, y devuelve el precio y el peso totales
+func priceBaggageLines(allowance *BaggageAllowance, lines []Baggage) (price, weight float64) {
+	for i := range lines {
+		// Las líneas rechazadas no consumen franquicia ni se cobran
+		if !isLineActive(lines[i]) {
+			lines[i].IncludedQuantity = 0
+			lines[i].Price = 0
+			continue
+		}
+
+		applyAllowance(allowance, lines[:i], &lines[i])
+		price += lines[i].Price
+		weight += lines[i].Weight * float64(lines[i].Quantity)
+	}
+	return price, weight
+}
+
+// newBaggageQuote arma el desglose de la cotización con las líneas a partir de offset,
+// ya valorizadas junto con las líneas previas de la reserva
+func newBaggageQuote(allowance *BaggageAllowance, lines []Baggage, offset int) *BaggageQuote {
+	quote := &BaggageQuote{
+		Lines:     []QuotedLine{},
+		Allowance: newAllowanceUsage(allowance, lines),
+	}
+
+	for _, line := range lines[offset:] {
+		quote.Lines = append(quote.Lines, QuotedLine{
+			BaggageType:      line.Type,
+			Quantity:         line.Quantity,
+			IncludedQuantity: line.IncludedQuantity,
+			UnitPrice:        line.UnitPrice,
+			Subtotal:         line.Price,
+		})
+		quote.TotalQuantity += line.Quantity
+		quote.IncludedQuantity += line.IncludedQuantity
+		quote.Total += line.Price
+	}
+
+	return quote
+}
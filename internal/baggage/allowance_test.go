@@ -0,0 +1,203 @@
+package baggage
+
+import "testing"
+
+func TestSelectAllowance(t *testing.T) {
+	generic := &BaggageAllowance{ID: "generica", FareClass: "economica", Pieces: 1}
+	byOperator := &BaggageAllowance{ID: "operador", FareClass: "economica", OperatorID: "op-1", Pieces: 2}
+	byRoute := &BaggageAllowance{ID: "ruta", FareClass: "economica", RouteID: "ruta-1", Pieces: 3}
+	otherClass := &BaggageAllowance{ID: "vip", FareClass: "vip", Pieces: 4}
+	allowances := []*BaggageAllowance{generic, byOperator, byRoute, otherClass}
+
+	tests := []struct {
+		name        string
+		reservation *BaggageReservation
+		want        string
+	}{
+		{
+			name:        "la franquicia de la ruta prevalece",
+			reservation: &BaggageReservation{FareClass: "economica", RouteID: "ruta-1", OperatorID: "op-1"},
+			want:        "ruta",
+		},
+		{
+			name:        "la del operador prevalece sobre la genérica",
+			reservation: &BaggageReservation{FareClass: "economica", RouteID: "ruta-2", OperatorID: "op-1"},
+			want:        "operador",
+		},
+		{
+			name:        "sin franquicia de su ruta ni de su operador usa la genérica",
+			reservation: &BaggageReservation{FareClass: "economica", RouteID: "ruta-2", OperatorID: "op-2"},
+			want:        "generica",
+		},
+		{
+			name:        "otra clase tarifaria",
+			reservation: &BaggageReservation{FareClass: "vip", RouteID: "ruta-1", OperatorID: "op-1"},
+			want:        "vip",
+		},
+		{
+			name:        "clase tarifaria sin franquicia",
+			reservation: &BaggageReservation{FareClass: "ejecutiva", RouteID: "ruta-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectAllowance(allowances, tt.reservation)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("selectAllowance() = %s, no se esperaba franquicia", got.ID)
+				}
+				return
+			}
+			if got == nil || got.ID != tt.want {
+				t.Errorf("selectAllowance() = %+v, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowanceForTickets(t *testing.T) {
+	allowance := &BaggageAllowance{ID: "generica", FareClass: "economica", Pieces: 2, MaxWeight: 23}
+
+	tests := []struct {
+		name       string
+		tickets    int
+		wantPieces int
+	}{
+		{name: "reserva anterior sin pasajes", tickets: 0, wantPieces: 2},
+		{name: "un pasaje", tickets: 1, wantPieces: 2},
+		{name: "tres pasajes", tickets: 3, wantPieces: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allowance.forTickets(tt.tickets)
+			if got.Pieces != tt.wantPieces || got.MaxWeight != 23 {
+				t.Errorf("forTickets(%d) = %d piezas de %v kg, se esperaba %d de 23 kg", tt.tickets, got.Pieces, got.MaxWeight, tt.wantPieces)
+			}
+		})
+	}
+	if allowance.Pieces != 2 {
+		t.Errorf("forTickets() modificó la franquicia original: %d piezas", allowance.Pieces)
+	}
+
+	var none *BaggageAllowance
+	if none.forTickets(3) != nil {
+		t.Error("forTickets() sin franquicia debía devolver nil")
+	}
+}
+
+func TestPriceBaggageLinesPerTicket(t *testing.T) {
+	allowance := (&BaggageAllowance{ID: "generica", FareClass: "economica", Pieces: 1}).forTickets(2)
+	lines := []Baggage{{Type: "maleta", Quantity: 3, UnitPrice: 20}}
+
+	price, _ := priceBaggageLines(allowance, lines)
+	if lines[0].IncludedQuantity != 2 || price != 20 {
+		t.Errorf("priceBaggageLines() = %d piezas incluidas y %v, se esperaba 2 y 20", lines[0].IncludedQuantity, price)
+	}
+}
+
+func TestAllowanceCovers(t *testing.T) {
+	allowance := &BaggageAllowance{BaggageTypes: []string{"maleta", "mochila"}, MaxWeight: 23}
+
+	tests := []struct {
+		name        string
+		baggageType string
+		weight      float64
+		want        bool
+	}{
+		{name: "tipo incluido bajo el peso máximo", baggageType: "maleta", weight: 20, want: true},
+		{name: "tipo incluido en el peso máximo", baggageType: "mochila", weight: 23, want: true},
+		{name: "tipo incluido sobre el peso máximo", baggageType: "maleta", weight: 23.5},
+		{name: "tipo no incluido", baggageType: "bicicleta", weight: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowance.covers(tt.baggageType, tt.weight); got != tt.want {
+				t.Errorf("covers(%q, %v) = %v, se esperaba %v", tt.baggageType, tt.weight, got, tt.want)
+			}
+		})
+	}
+
+	if !(&BaggageAllowance{}).covers("bicicleta", 40) {
+		t.Error("una franquicia sin tipos ni peso máximo debe cubrir cualquier pieza")
+	}
+}
+
+func TestPriceBaggageLines(t *testing.T) {
+	allowance := &BaggageAllowance{Pieces: 2, MaxWeight: 23}
+	lines := []Baggage{
+		{Type: "maleta", Quantity: 1, Weight: 20, UnitPrice: 30},
+		{Type: "maleta", Quantity: 1, Weight: 30, UnitPrice: 30},                         // Excede el peso de la franquicia
+		{Type: "mochila", Quantity: 3, Weight: 8, UnitPrice: 10},                         // Le queda una pieza incluida
+		{Type: "maleta", Quantity: 2, Weight: 15, UnitPrice: 30, Status: LineRejected},   // No se cobra
+		{Type: "maleta", Quantity: 1, Weight: 15, UnitPrice: 30, Status: LineWaitlisted}, // No se cobra
+	}
+
+	price, weight := priceBaggageLines(allowance, lines)
+
+	wantIncluded := []int{1, 0, 1, 0, 0}
+	wantPrice := []float64{0, 30, 20, 0, 0}
+	for i, line := range lines {
+		if line.IncludedQuantity != wantIncluded[i] || line.Price != wantPrice[i] {
+			t.Errorf("línea %d: incluidas %d y precio %v, se esperaba %d y %v", i, line.IncludedQuantity, line.Price, wantIncluded[i], wantPrice[i])
+		}
+	}
+	if price != 50 {
+		t.Errorf("precio = %v, se esperaba 50", price)
+	}
+	if weight != 74 { // 20 + 30 + 3*8
+		t.Errorf("peso = %v, se esperaba 74", weight)
+	}
+
+	usage := newAllowanceUsage(allowance, lines)
+	if usage.UsedPieces != 2 || usage.RemainingPieces != 0 {
+		t.Errorf("franquicia usada = %+v, se esperaban 2 piezas usadas y ninguna restante", *usage)
+	}
+}
+
+func TestPriceBaggageLinesWithoutAllowance(t *testing.T) {
+	lines := []Baggage{{Type: "maleta", Quantity: 2, Weight: 20, UnitPrice: 30}}
+
+	price, _ := priceBaggageLines(nil, lines)
+
+	if price != 60 || lines[0].IncludedQuantity != 0 {
+		t.Errorf("precio = %v con %d piezas incluidas, se esperaba 60 sin piezas incluidas", price, lines[0].IncludedQuantity)
+	}
+	if newAllowanceUsage(nil, lines) != nil {
+		t.Error("sin franquicia no debe haber resumen de consumo")
+	}
+}
+
+func TestNewBaggageQuote(t *testing.T) {
+	allowance := &BaggageAllowance{ID: "franquicia-1", Pieces: 2}
+	// La reserva ya tiene una maleta incluida; se cotizan dos líneas adicionales
+	lines := []Baggage{
+		{Type: "maleta", Quantity: 1, Weight: 20, UnitPrice: 30},
+		{Type: "maleta", Quantity: 2, Weight: 18, UnitPrice: 30},
+		{Type: "bicicleta", Quantity: 1, Weight: 15, UnitPrice: 40},
+	}
+	priceBaggageLines(allowance, lines)
+
+	quote := newBaggageQuote(allowance, lines, 1)
+
+	want := []QuotedLine{
+		{BaggageType: "maleta", Quantity: 2, IncludedQuantity: 1, UnitPrice: 30, Subtotal: 30},
+		{BaggageType: "bicicleta", Quantity: 1, IncludedQuantity: 0, UnitPrice: 40, Subtotal: 40},
+	}
+	if len(quote.Lines) != len(want) {
+		t.Fatalf("líneas cotizadas = %d, se esperaban %d", len(quote.Lines), len(want))
+	}
+	for i := range want {
+		if quote.Lines[i] != want[i] {
+			t.Errorf("línea %d = %+v, se esperaba %+v", i, quote.Lines[i], want[i])
+		}
+	}
+	if quote.TotalQuantity != 3 || quote.IncludedQuantity != 1 || quote.Total != 70 {
+		t.Errorf("totales = %d piezas, %d incluidas y %v, se esperaban 3, 1 y 70", quote.TotalQuantity, quote.IncludedQuantity, quote.Total)
+	}
+	if quote.Allowance == nil || quote.Allowance.RemainingPieces != 0 {
+		t.Errorf("franquicia = %+v, se esperaba consumida por completo", quote.Allowance)
+	}
+}
@@ -0,0 +1,89 @@
+package baggage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxBaggageTypePrice es el precio máximo admitido para un tipo de equipaje
+const maxBaggageTypePrice = 10000
+
+// ErrInvalidPrice indica que el precio de un tipo de equipaje no es válido
+var ErrInvalidPrice = errors.New("el precio debe ser un monto no negativo de hasta 10000 con máximo dos decimales")
+
+// validateBaggageTypePrice verifica que el precio sea no negativo, acotado y con a lo más dos decimales
+func validateBaggageTypePrice(price float64) error {
+	if math.IsNaN(price) || math.IsInf(price, 0) || price < 0 || price > maxBaggageTypePrice {
+		return ErrInvalidPrice
+	}
+	if cents := price * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+		return ErrInvalidPrice
+	}
+	return nil
+}
+
+// priceAt devuelve el precio del tipo de equipaje vigente en la fecha indicada según su historial. Una fecha
+// anterior al historial, o vacía como en las líneas registradas antes de guardar su fecha, toma el precio más
+// antiguo conocido; un tipo sin historial, su precio actual.
+func (t *BaggageType) priceAt(at time.Time) float64 {
+	if len(t.PriceHistory) == 0 {
+		return t.Price
+	}
+
+	price := t.PriceHistory[0].Price
+	for _, change := range t.PriceHistory[1:] {
+		if change.EffectiveFrom.After(at) {
+			break
+		}
+		price = change.Price
+	}
+	return price
+}
+
+// DeduplicateBaggageTypes depura los tipos de equipaje con nombres duplicados, registrados antes del índice único
+// por nombre, y devuelve cuántos depuró. De cada nombre se conserva el tipo activo registrado primero; los demás
+// se desactivan y se renombran con su ID. No se eliminan: las líneas ya vendidas conservan su tipo y su precio.
+func DeduplicateBaggageTypes(ctx context.Context, collection *mongo.Collection) (int, error) {
+	// Los activos primero y, entre ellos, los registrados primero. El _id se lee sin convertir porque los tipos
+	// más antiguos pueden tener un ObjectID.
+	cursor, err := collection.Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "active", Value: -1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+	var types []struct {
+		ID   interface{} `bson:"_id"`
+		Name string      `bson:"name"`
+	}
+	if err := cursor.All(ctx, &types); err != nil {
+		return 0, err
+	}
+
+	kept := make(map[string]bool)
+	renamed := 0
+	for _, baggageType := range types {
+		if !kept[baggageType.Name] {
+			kept[baggageType.Name] = true
+			continue
+		}
+
+		name := fmt.Sprintf("%s (duplicado %v)", baggageType.Name, baggageType.ID)
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": baggageType.ID},
+			bson.M{"$set": bson.M{"name": name, "active": false, "updated_at": time.Now()}})
+		if err != nil {
+			return renamed, err
+		}
+		log.Printf("Tipo de equipaje %v renombrado a %q y desactivado", baggageType.ID, name)
+		renamed++
+	}
+
+	return renamed, nil
+}
@@ -0,0 +1,40 @@
+package baggage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBaggageTypePriceAt(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	baggageType := &BaggageType{Price: 40, PriceHistory: []PriceChange{
+		{Price: 20, EffectiveFrom: day(1)},
+		{Price: 30, EffectiveFrom: day(10)},
+		{Price: 40, EffectiveFrom: day(20)},
+	}}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{name: "línea sin fecha", want: 20},
+		{name: "antes del historial", at: day(1).Add(-time.Hour), want: 20},
+		{name: "primer precio", at: day(5), want: 20},
+		{name: "el día del cambio", at: day(10), want: 30},
+		{name: "precio actual", at: day(25), want: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := baggageType.priceAt(tt.at); got != tt.want {
+				t.Errorf("priceAt(%s) = %v, se esperaba %v", tt.at, got, tt.want)
+			}
+		})
+	}
+
+	withoutHistory := &BaggageType{Price: 25}
+	if got := withoutHistory.priceAt(day(5)); got != 25 {
+		t.Errorf("priceAt() sin historial = %v, se esperaba el precio actual 25", got)
+	}
+}
@@ -0,0 +1,90 @@
+package baggage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Categorías de equipaje especial sugeridas para el catálogo
+const (
+	CategoryStandard   = "estandar"
+	CategorySports     = "deportivo"
+	CategoryPet        = "mascota"
+	CategoryFragile    = "fragil"
+	CategoryRestricted = "restringido"
+)
+
+// Estados de una línea de equipaje
+const (
+	LineConfirmed       = "confirmado"
+	LinePendingApproval = "pendiente_aprobacion"
+	LineRejected        = "rechazado"
+	LineWaitlisted      = "en_espera" // Sin espacio en la bodega de la salida
+)
+
+var (
+	// ErrCategoryNotFound indica que la categoría de equipaje no existe
+	ErrCategoryNotFound = errors.New("categoría de equipaje no encontrada")
+	// ErrMissingDocuments indica que faltan documentos requeridos por la categoría
+	ErrMissingDocuments = errors.New("faltan documentos requeridos por la categoría de equipaje")
+	// ErrDeclarationRequired indica que la categoría exige una declaración de contenido aceptada
+	ErrDeclarationRequired = errors.New("la categoría de equipaje requiere una declaración de contenido aceptada")
+	// ErrCategoryCapacity indica que la salida no admite más piezas de la categoría
+	ErrCategoryCapacity = errors.New("se alcanzó el máximo de piezas de la categoría para esta salida")
+	// ErrRouteRequired indica que la reserva de equipaje no indica la salida
+	ErrRouteRequired = errors.New("la reserva de equipaje debe indicar route_id para esta categoría")
+	// ErrItemNotPending indica que la línea no está pendiente de aprobación
+	ErrItemNotPending = errors.New("la línea de equipaje no está pendiente de aprobación")
+)
+
+// validateCategoryRules verifica que la solicitud cumpla los documentos y la declaración que exige la categoría
+func validateCategoryRules(category *BaggageCategory, req *AddBaggageRequest) error {
+	if category == nil {
+		return nil
+	}
+
+	presented := make(map[string]bool)
+	for _, document := range req.Documents {
+		if strings.TrimSpace(document.Reference) != "" {
+			presented[document.Type] = true
+		}
+	}
+
+	var missing []string
+	for _, required := range category.RequiredDocuments {
+		if !presented[required] {
+			missing = append(missing, required)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingDocuments, strings.Join(missing, ", "))
+	}
+
+	if category.RequiresDeclaration {
+		declaration := req.Declaration
+		if declaration == nil || !declaration.Accepted || len(declaration.Items) == 0 || strings.TrimSpace(declaration.Description) == "" {
+			return ErrDeclarationRequired
+		}
+	}
+
+	return nil
+}
+
+// initialLineStatus devuelve el estado con el que se registra una línea de la categoría
+func initialLineStatus(category *BaggageCategory) string {
+	if category != nil && category.RequiresApproval {
+		return LinePendingApproval
+	}
+	return LineConfirmed
+}
+
+// isLineActive indica si la línea ocupa capacidad y se cobra (no fue rechazada ni está en espera)
+func isLineActive(line Baggage) bool {
+	return line.Status != LineRejected && line.Status != LineWaitlisted
+}
+
+// isLineConfirmed indica si la línea está confirmada; las líneas anteriores a los estados se consideran confirmadas
+func isLineConfirmed(line Baggage) bool {
+	return line.Status == "" || line.Status == LineConfirmed
+}
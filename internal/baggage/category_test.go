@@ -0,0 +1,94 @@
+package baggage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestValidateCategoryRules(t *testing.T) {
+	pet := &BaggageCategory{Code: CategoryPet, RequiredDocuments: []string{"certificado_sanitario", "carnet_vacunacion"}}
+	restricted := &BaggageCategory{Code: CategoryRestricted, RequiresDeclaration: true}
+	declaration := &RestrictedDeclaration{Items: []string{"baterías"}, Description: "Baterías de litio", Accepted: true}
+
+	tests := []struct {
+		name     string
+		category *BaggageCategory
+		req      *AddBaggageRequest
+		wantErr  error
+	}{
+		{
+			name: "equipaje estándar",
+			req:  &AddBaggageRequest{},
+		},
+		{
+			name:     "con todos los documentos",
+			category: pet,
+			req: &AddBaggageRequest{Documents: []BaggageDocument{
+				{Type: "certificado_sanitario", Reference: "CS-1"},
+				{Type: "carnet_vacunacion", Reference: "CV-1"},
+			}},
+		},
+		{
+			name:     "falta un documento",
+			category: pet,
+			req:      &AddBaggageRequest{Documents: []BaggageDocument{{Type: "certificado_sanitario", Reference: "CS-1"}}},
+			wantErr:  ErrMissingDocuments,
+		},
+		{
+			name:     "un documento sin referencia no cuenta",
+			category: pet,
+			req: &AddBaggageRequest{Documents: []BaggageDocument{
+				{Type: "certificado_sanitario", Reference: "CS-1"},
+				{Type: "carnet_vacunacion", Reference: " "},
+			}},
+			wantErr: ErrMissingDocuments,
+		},
+		{
+			name:     "con la declaración aceptada",
+			category: restricted,
+			req:      &AddBaggageRequest{Declaration: declaration},
+		},
+		{
+			name:     "sin declaración",
+			category: restricted,
+			req:      &AddBaggageRequest{},
+			wantErr:  ErrDeclarationRequired,
+		},
+		{
+			name:     "declaración no aceptada",
+			category: restricted,
+			req:      &AddBaggageRequest{Declaration: &RestrictedDeclaration{Items: declaration.Items, Description: declaration.Description}},
+			wantErr:  ErrDeclarationRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCategoryRules(tt.category, tt.req)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("validateCategoryRules() = %v, no se esperaba error", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateCategoryRules() = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitialLineStatus(t *testing.T) {
+	if status := initialLineStatus(nil); status != LineConfirmed {
+		t.Errorf("equipaje estándar: estado = %q, se esperaba %q", status, LineConfirmed)
+	}
+	if status := initialLineStatus(&BaggageCategory{RequiresApproval: true}); status != LinePendingApproval {
+		t.Errorf("categoría con aprobación: estado = %q, se esperaba %q", status, LinePendingApproval)
+	}
+}
+
+func TestUnknownCategoryIsBadRequest(t *testing.T) {
+	err := fmt.Errorf("%w: %s", ErrCategoryNotFound, "acuatico")
+	if status := (&BaggageHandler{}).errorStatus(err); status != http.StatusBadRequest {
+		t.Errorf("estado HTTP = %d, se esperaba %d", status, http.StatusBadRequest)
+	}
+}
@@ -1,13 +1,20 @@
 package baggage
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"venta-de-pasajes/internal/auth"
 )
 
+// maxQuoteLines es el número máximo de líneas que admite una cotización
+const maxQuoteLines = 50
+
 // BaggageHandler contiene los métodos HTTP para el manejo de equipaje
 type BaggageHandler struct {
 	repo *BaggageRepository
@@ -23,6 +30,28 @@ func (h *BaggageHandler) handleError(w http.ResponseWriter, err error, status in
 	http.Error(w, err.Error(), status)
 }
 
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *BaggageHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrReservationNotFound), errors.Is(err, ErrPassengerReservationNotFound), errors.Is(err, ErrBaggageItemNotFound),
+		errors.Is(err, ErrPieceNotFound), errors.Is(err, ErrPassengerRouteNotFound), errors.Is(err, ErrBaggageTypeNotFound),
+		errors.Is(err, ErrOperatorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVersionConflict), errors.Is(err, ErrBaggageItemTagged), errors.Is(err, ErrInvalidTransition),
+		errors.Is(err, ErrBaggageTypeExists), errors.Is(err, ErrCategoryCapacity), errors.Is(err, ErrItemNotPending),
+		errors.Is(err, ErrHoldFull), errors.Is(err, ErrHoldBelowUsage):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnknownTrackingStatus), errors.Is(err, ErrInvalidPrice), errors.Is(err, ErrBaggageTypeInactive),
+		errors.Is(err, ErrMissingDocuments), errors.Is(err, ErrDeclarationRequired), errors.Is(err, ErrRouteRequired),
+		errors.Is(err, ErrOperatorBaggageRule), errors.Is(err, ErrCategoryNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (h *BaggageHandler) CreateReservationBaggageHandler(w http.ResponseWriter, r *http.Request) {
 	// Decodificar la solicitud JSON en una estructura de reserva de equipaje
 	var reservation BaggageReservation
@@ -32,10 +61,24 @@ func (h *BaggageHandler) CreateReservationBaggageHandler(w http.ResponseWriter,
 		return
 	}
 
+	// Validar las líneas enviadas como en la adición de equipaje
+	for _, line := range reservation.Baggage {
+		if line.Type == "" || line.Quantity <= 0 || line.Weight <= 0 {
+			h.handleError(w, errors.New("cada línea de equipaje requiere type, quantity y weight mayores a cero"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Solo el titular de la reserva de pasaje puede registrar su equipaje
+	if err := h.repo.CheckPassengerReservationOwner(reservation.ReservationID, auth.UserID(r.Context())); err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
 	// Llamar a la función del repositorio para crear la reserva y obtener su ID
 	reservationID, err := h.repo.CreateReservation(&reservation)
 	if err != nil {
-		h.handleError(w, err, http.StatusInternalServerError)
+		h.handleError(w, err, h.errorStatus(err))
 		return
 	}
 
@@ -52,10 +95,11 @@ func (h *BaggageHandler) CreateReservationBaggageHandler(w http.ResponseWriter,
 func (h *BaggageHandler) GetBaggageTypesByNameBaggageHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el nombre del tipo de equipaje de los parámetros de la URL
 	name := r.URL.Query().Get("name")
+	includeInactive := r.URL.Query().Get("include_inactive") == "true"
 
 	// Si el nombre está vacío, obtiene todas las colecciones
 	if name == "" {
-		types, err := h.repo.getAllBaggageTypes()
+		types, err := h.repo.getAllBaggageTypes(includeInactive)
 		if err != nil {
 			h.handleError(w, err, http.StatusInternalServerError)
 			return
@@ -72,12 +116,110 @@ func (h *BaggageHandler) GetBaggageTypesByNameBaggageHandler(w http.ResponseWrit
 		h.handleError(w, err, http.StatusInternalServerError)
 		return
 	}
+	if types != nil && !types.Active && !includeInactive {
+		types = nil
+	}
 
 	// Escribir la respuesta con los tipos de equipaje encontrados
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(types)
 }
 
+// CreateBaggageTypeBaggageHandler maneja el registro de un nuevo tipo de equipaje
+func (h *BaggageHandler) CreateBaggageTypeBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string   `json:"name"`
+		Price    *float64 `json:"price"`
+		Category string   `json:"category"`
+		Weight   float64  `json:"weight"` // Peso típico por pieza (kg)
+		Volume   float64  `json:"volume"` // Volumen por pieza (m³)
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if strings.TrimSpace(req.Name) == "" || req.Price == nil {
+		h.handleError(w, errors.New("los campos name y price son obligatorios"), http.StatusBadRequest)
+		return
+	}
+
+	if req.Weight < 0 || req.Volume < 0 {
+		h.handleError(w, errors.New("el peso y el volumen no pueden ser negativos"), http.StatusBadRequest)
+		return
+	}
+
+	baggageType, err := h.repo.CreateBaggageType(&BaggageType{
+		Name:     strings.TrimSpace(req.Name),
+		Price:    *req.Price,
+		Category: req.Category,
+		Weight:   req.Weight,
+		Volume:   req.Volume,
+	})
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(baggageType)
+}
+
+// UpdateBaggageTypePriceBaggageHandler maneja el cambio de precio de un tipo de equipaje
+func (h *BaggageHandler) UpdateBaggageTypePriceBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID    string   `json:"id"`
+		Price *float64 `json:"price"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if req.ID == "" || req.Price == nil {
+		h.handleError(w, errors.New("los campos id y price son obligatorios"), http.StatusBadRequest)
+		return
+	}
+
+	baggageType, err := h.repo.UpdateBaggageTypePrice(req.ID, *req.Price)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(baggageType)
+}
+
+// DeactivateBaggageTypeBaggageHandler maneja la desactivación de un tipo de equipaje
+func (h *BaggageHandler) DeactivateBaggageTypeBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.ID == "" {
+		h.handleError(w, errors.New("el campo id es obligatorio"), http.StatusBadRequest)
+		return
+	}
+
+	baggageType, err := h.repo.DeactivateBaggageType(req.ID)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(baggageType)
+}
+
 // parseQuantity convierte la cantidad de equipaje de string a int y maneja los errores
 func (h *BaggageHandler) parseQuantity(quantityStr string) (int, error) {
 	quantity, err := strconv.Atoi(quantityStr)
@@ -89,16 +231,7 @@ func (h *BaggageHandler) parseQuantity(quantityStr string) (int, error) {
 
 // AddBaggageToReservationBaggageHandler maneja la adición de equipaje a una reserva existente
 func (h *BaggageHandler) AddBaggageToReservationBaggageHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		BaggageReservationID string `json:"baggage_reservation_id"`
-		BaggageType          string `json:"baggage_type"`
-		Quantity             int    `json:"quantity"`
-	}
-
-	log.Printf("Valor de BaggageReservationID: %s\n", req.BaggageReservationID)
-	log.Printf("Valor de BaggageType: %s\n", req.BaggageType)
-	log.Printf("Valor de Quantity: %s\n", req.Quantity)
-
+	var req AddBaggageRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
@@ -110,11 +243,19 @@ func (h *BaggageHandler) AddBaggageToReservationBaggageHandler(w http.ResponseWr
 		h.handleError(w, errors.New("los campos baggage_reservation_id, baggage_type y quantity son obligatorios"), http.StatusBadRequest)
 		return
 	}
+	if req.Weight <= 0 {
+		h.handleError(w, errors.New("el peso por pieza debe ser mayor a cero"), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.CheckReservationOwner(req.BaggageReservationID, auth.UserID(r.Context())); err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
 
 	// Llamar a la función del repositorio para agregar equipaje a la reserva
-	insertedBaggage, err := h.repo.AddBaggageToReservation(req.BaggageReservationID, req.BaggageType, req.Quantity)
+	insertedBaggage, err := h.repo.AddBaggageToReservation(&req)
 	if err != nil {
-		h.handleError(w, err, http.StatusInternalServerError)
+		h.handleError(w, err, h.errorStatus(err))
 		return
 	}
 
@@ -124,27 +265,549 @@ func (h *BaggageHandler) AddBaggageToReservationBaggageHandler(w http.ResponseWr
 	json.NewEncoder(w).Encode(insertedBaggage)
 }
 
-// CalculateBaggagePriceBaggageHandler maneja el cálculo del precio del equipaje
+// GetReservationBaggageHandler maneja la consulta de una reserva de equipaje por su ID
+func (h *BaggageHandler) GetReservationBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	reservationID := r.URL.Query().Get("id")
+	if reservationID == "" {
+		h.handleError(w, errors.New("el parámetro id es obligatorio"), http.StatusBadRequest)
+		return
+	}
+
+	err := h.repo.CheckReservationOwner(reservationID, auth.UserID(r.Context()))
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	reservation, err := h.repo.GetReservation(reservationID)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// UpdateBaggageItemBaggageHandler maneja la modificación de una línea de equipaje de la reserva
+func (h *BaggageHandler) UpdateBaggageItemBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		BaggageReservationID string   `json:"baggage_reservation_id"`
+		ItemID               string   `json:"item_id"`
+		Quantity             int      `json:"quantity"`
+		Weight               *float64 `json:"weight"` // Si se omite, se conserva el peso registrado
+		Version              *int     `json:"version"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if req.BaggageReservationID == "" || req.ItemID == "" || req.Quantity <= 0 || req.Version == nil {
+		h.handleError(w, errors.New("los campos baggage_reservation_id, item_id, quantity y version son obligatorios"), http.StatusBadRequest)
+		return
+	}
+	if req.Weight != nil && *req.Weight <= 0 {
+		h.handleError(w, errors.New("el peso por pieza debe ser mayor a cero"), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.CheckReservationOwner(req.BaggageReservationID, auth.UserID(r.Context())); err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	reservation, err := h.repo.UpdateBaggageItem(req.BaggageReservationID, req.ItemID, req.Quantity, req.Weight, *req.Version)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// RemoveBaggageItemBaggageHandler maneja la eliminación de una línea de equipaje de la reserva
+func (h *BaggageHandler) RemoveBaggageItemBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		BaggageReservationID string `json:"baggage_reservation_id"`
+		ItemID               string `json:"item_id"`
+		Version              *int   `json:"version"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if req.BaggageReservationID == "" || req.ItemID == "" || req.Version == nil {
+		h.handleError(w, errors.New("los campos baggage_reservation_id, item_id y version son obligatorios"), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.CheckReservationOwner(req.BaggageReservationID, auth.UserID(r.Context())); err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	reservation, err := h.repo.RemoveBaggageItem(req.BaggageReservationID, req.ItemID, *req.Version)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// CalculateBaggagePriceBaggageHandler maneja la cotización de una canasta de equipaje.
+// Con POST recibe la canasta completa; con GET acepta una sola línea por los parámetros baggage_type y quantity.
 func (h *BaggageHandler) CalculateBaggagePriceBaggageHandler(w http.ResponseWriter, r *http.Request) {
-	// Obtener los parámetros de la solicitud
-	baggageType := r.URL.Query().Get("baggage_type")
-	quantityStr := r.URL.Query().Get("quantity")
+	var req QuoteRequest
 
-	// Convertir la cantidad de equipaje a entero
-	quantity, err := h.parseQuantity(quantityStr)
+	if r.Method == http.MethodGet {
+		// Obtener los parámetros de la solicitud
+		baggageType := r.URL.Query().Get("baggage_type")
+		quantityStr := r.URL.Query().Get("quantity")
+
+		// Convertir la cantidad de equipaje a entero
+		quantity, err := h.parseQuantity(quantityStr)
+		if err != nil {
+			h.handleError(w, errors.New("el parámetro quantity debe ser un número entero"), http.StatusBadRequest)
+			return
+		}
+		weight, err := strconv.ParseFloat(r.URL.Query().Get("weight"), 64)
+		if err != nil {
+			h.handleError(w, errors.New("el parámetro weight debe ser un número"), http.StatusBadRequest)
+			return
+		}
+
+		req = QuoteRequest{
+			Lines:      []QuoteLine{{BaggageType: baggageType, Quantity: quantity, Weight: weight}},
+			FareClass:  r.URL.Query().Get("fare_class"),
+			RouteID:    r.URL.Query().Get("route_id"),
+			OperatorID: r.URL.Query().Get("operator_id"),
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar las líneas de la canasta
+	if len(req.Lines) == 0 || len(req.Lines) > maxQuoteLines {
+		h.handleError(w, fmt.Errorf("la cotización debe tener entre 1 y %d líneas", maxQuoteLines), http.StatusBadRequest)
+		return
+	}
+	for _, line := range req.Lines {
+		if line.BaggageType == "" || line.Quantity <= 0 || line.Weight <= 0 {
+			h.handleError(w, errors.New("cada línea requiere baggage_type, quantity y weight mayores a cero"), http.StatusBadRequest)
+			return
+		}
+	}
+	// Solo se cotiza sobre una reserva de equipaje del propio usuario
+	if req.BaggageReservationID != "" {
+		if err := h.repo.CheckReservationOwner(req.BaggageReservationID, auth.UserID(r.Context())); err != nil {
+			h.handleError(w, err, h.errorStatus(err))
+			return
+		}
+	}
+
+	// Llamar a la función del repositorio para cotizar el equipaje
+	quote, err := h.repo.QuoteBaggage(&req)
 	if err != nil {
-		h.handleError(w, err, http.StatusBadRequest)
+		h.handleError(w, err, h.errorStatus(err))
 		return
 	}
 
-	// Llamar a la función del repositorio para calcular el precio del equipaje
-	price, err := h.repo.calculateBaggagePrice(baggageType, quantity)
+	// Escribir la respuesta con el desglose de la cotización
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+// CreateAllowanceBaggageHandler maneja el registro de una franquicia de equipaje
+func (h *BaggageHandler) CreateAllowanceBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var allowance BaggageAllowance
+	err := json.NewDecoder(r.Body).Decode(&allowance)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la franquicia
+	if allowance.FareClass == "" || allowance.Pieces <= 0 {
+		h.handleError(w, errors.New("los campos fare_class y pieces son obligatorios"), http.StatusBadRequest)
+		return
+	}
+	if allowance.MaxWeight < 0 {
+		h.handleError(w, errors.New("el peso máximo no puede ser negativo"), http.StatusBadRequest)
+		return
+	}
+
+	allowanceID, err := h.repo.CreateAllowance(&allowance)
 	if err != nil {
 		h.handleError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Escribir la respuesta con el precio calculado
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]float64{"price": price})
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		AllowanceID string `json:"allowance_id"`
+	}{
+		AllowanceID: allowanceID,
+	})
+}
+
+// GetAllowancesBaggageHandler maneja la consulta de franquicias de equipaje por clase tarifaria
+func (h *BaggageHandler) GetAllowancesBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	allowances, err := h.repo.GetAllowances(r.URL.Query().Get("fare_class"))
+	if err != nil {
+		h.handleError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(allowances)
+}
+
+// TagBaggageBaggageHandler maneja la generación de números de etiqueta para las piezas de una reserva
+func (h *BaggageHandler) TagBaggageBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		BaggageReservationID string `json:"baggage_reservation_id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.BaggageReservationID == "" {
+		h.handleError(w, errors.New("el campo baggage_reservation_id es obligatorio"), http.StatusBadRequest)
+		return
+	}
+
+	pieces, err := h.repo.TagBaggage(req.BaggageReservationID)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pieces)
+}
+
+// GetPiecesBaggageHandler maneja la consulta de piezas por número de etiqueta o por reserva de equipaje
+func (h *BaggageHandler) GetPiecesBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	tagNumber := r.URL.Query().Get("tag_number")
+	baggageReservationID := r.URL.Query().Get("baggage_reservation_id")
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch {
+	case tagNumber != "":
+		result, err = h.repo.GetPieceByTag(tagNumber)
+	case baggageReservationID != "":
+		result, err = h.repo.GetPiecesByReservation(baggageReservationID)
+	default:
+		h.handleError(w, errors.New("debe indicar tag_number o baggage_reservation_id"), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetTagBarcodeBaggageHandler maneja la generación de la imagen PNG del código de barras de una etiqueta
+func (h *BaggageHandler) GetTagBarcodeBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	tagNumber := r.URL.Query().Get("tag_number")
+	format := r.URL.Query().Get("format")
+
+	piece, err := h.repo.GetPieceByTag(tagNumber)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writeTagBarcodePNG(&buf, piece.TagNumber, format); err != nil {
+		if errors.Is(err, ErrUnsupportedBarcode) {
+			h.handleError(w, err, http.StatusBadRequest)
+			return
+		}
+		h.handleError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// GetTagLabelBaggageHandler maneja la generación del PDF imprimible de etiquetas,
+// para una pieza (tag_number) o para todas las piezas de una reserva (baggage_reservation_id)
+func (h *BaggageHandler) GetTagLabelBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	tagNumber := r.URL.Query().Get("tag_number")
+	baggageReservationID := r.URL.Query().Get("baggage_reservation_id")
+
+	var pieces []*BaggagePiece
+	switch {
+	case tagNumber != "":
+		piece, err := h.repo.GetPieceByTag(tagNumber)
+		if err != nil {
+			h.handleError(w, err, h.errorStatus(err))
+			return
+		}
+		pieces = append(pieces, piece)
+	case baggageReservationID != "":
+		reservationPieces, err := h.repo.GetPiecesByReservation(baggageReservationID)
+		if err != nil {
+			h.handleError(w, err, h.errorStatus(err))
+			return
+		}
+		if len(reservationPieces) == 0 {
+			h.handleError(w, errors.New("la reserva de equipaje no tiene piezas etiquetadas"), http.StatusNotFound)
+			return
+		}
+		pieces = reservationPieces
+	default:
+		h.handleError(w, errors.New("debe indicar tag_number o baggage_reservation_id"), http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writeBaggageLabels(&buf, pieces); err != nil {
+		h.handleError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "inline; filename=\"etiquetas-equipaje.pdf\"")
+	w.Write(buf.Bytes())
+}
+
+// ScanPieceBaggageHandler maneja el registro de un evento de seguimiento al escanear una pieza
+func (h *BaggageHandler) ScanPieceBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var event TrackingEvent
+	err := json.NewDecoder(r.Body).Decode(&event)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if event.TagNumber == "" || event.Status == "" || event.Location == "" || event.StaffID == "" {
+		h.handleError(w, errors.New("los campos tag_number, status, location y staff_id son obligatorios"), http.StatusBadRequest)
+		return
+	}
+
+	recorded, err := h.repo.RecordTrackingEvent(&event)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(recorded)
+}
+
+// GetTrackingHistoryBaggageHandler maneja la consulta del historial de seguimiento de una pieza
+func (h *BaggageHandler) GetTrackingHistoryBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	tagNumber := r.URL.Query().Get("tag_number")
+	if tagNumber == "" {
+		h.handleError(w, errors.New("el parámetro tag_number es obligatorio"), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.repo.GetTrackingHistory(tagNumber)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetTrackingStatusBaggageHandler maneja la consulta del estado del equipaje de una reserva de pasaje
+func (h *BaggageHandler) GetTrackingStatusBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	reservationID := r.URL.Query().Get("reservation_id")
+	if reservationID == "" {
+		h.handleError(w, errors.New("el parámetro reservation_id es obligatorio"), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.CheckPassengerReservationOwner(reservationID, auth.UserID(r.Context())); err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	statuses, err := h.repo.GetPieceStatusesByReservation(reservationID)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// ReviewBaggageItemBaggageHandler maneja la aprobación o el rechazo de una línea de equipaje especial
+func (h *BaggageHandler) ReviewBaggageItemBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		BaggageReservationID string `json:"baggage_reservation_id"`
+		ItemID               string `json:"item_id"`
+		Approved             *bool  `json:"approved"`
+		Version              *int   `json:"version"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if req.BaggageReservationID == "" || req.ItemID == "" || req.Approved == nil || req.Version == nil {
+		h.handleError(w, errors.New("los campos baggage_reservation_id, item_id, approved y version son obligatorios"), http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.repo.ReviewBaggageItem(req.BaggageReservationID, req.ItemID, *req.Approved, *req.Version)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// SaveCategoryBaggageHandler maneja el registro o la actualización de una categoría de equipaje especial
+func (h *BaggageHandler) SaveCategoryBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var category BaggageCategory
+	err := json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la categoría
+	if category.Code == "" || category.Name == "" {
+		h.handleError(w, errors.New("los campos code y name son obligatorios"), http.StatusBadRequest)
+		return
+	}
+	if category.MaxPerDeparture < 0 {
+		h.handleError(w, errors.New("el campo max_per_departure no puede ser negativo"), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.repo.SaveCategory(&category)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// GetCategoriesBaggageHandler maneja la consulta de las categorías de equipaje especial
+func (h *BaggageHandler) GetCategoriesBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.repo.GetCategories()
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// GetCategoryUsageBaggageHandler maneja la consulta del cupo usado por categoría en una salida
+func (h *BaggageHandler) GetCategoryUsageBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	routeID := r.URL.Query().Get("route_id")
+	if routeID == "" {
+		h.handleError(w, errors.New("el parámetro route_id es obligatorio"), http.StatusBadRequest)
+		return
+	}
+
+	usages, err := h.repo.GetCategoryUsage(routeID)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usages)
+}
+
+// ConfigureHoldBaggageHandler maneja la configuración de la capacidad de la bodega de una salida
+func (h *BaggageHandler) ConfigureHoldBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RouteID        string  `json:"route_id"`
+		WeightCapacity float64 `json:"weight_capacity"`
+		VolumeCapacity float64 `json:"volume_capacity"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.handleError(w, errors.New("error al decodificar la solicitud"), http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if req.RouteID == "" || req.WeightCapacity <= 0 || req.VolumeCapacity <= 0 {
+		h.handleError(w, errors.New("los campos route_id, weight_capacity y volume_capacity son obligatorios y positivos"), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authorizeRoute(r, req.RouteID); err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	hold, err := h.repo.ConfigureHold(req.RouteID, req.WeightCapacity, req.VolumeCapacity)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// GetHoldLoadReportBaggageHandler maneja la consulta del reporte de carga de la bodega de una salida
+func (h *BaggageHandler) GetHoldLoadReportBaggageHandler(w http.ResponseWriter, r *http.Request) {
+	routeID := r.URL.Query().Get("route_id")
+	if routeID == "" {
+		h.handleError(w, errors.New("el parámetro route_id es obligatorio"), http.StatusBadRequest)
+		return
+	}
+	if err := h.authorizeRoute(r, routeID); err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	report, err := h.repo.GetHoldLoadReport(routeID)
+	if err != nil {
+		h.handleError(w, err, h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// authorizeRoute verifica que el usuario autenticado pueda gestionar la bodega de la salida. Un admin_operador
+// solo gestiona las salidas de su operador.
+func (h *BaggageHandler) authorizeRoute(r *http.Request, routeID string) error {
+	operatorID, err := h.repo.GetRouteOperator(routeID)
+	if err != nil {
+		return err
+	}
+	return auth.AuthorizeOperator(r.Context(), operatorID)
 }
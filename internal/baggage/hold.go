@@ -0,0 +1,100 @@
+package baggage
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	// ErrHoldFull indica que la bodega de la salida no tiene espacio para las piezas
+	ErrHoldFull = errors.New("la bodega de la salida no tiene capacidad suficiente para el equipaje")
+	// ErrHoldBelowUsage indica que la capacidad configurada es menor a la carga ya registrada
+	ErrHoldBelowUsage = errors.New("la capacidad de la bodega no puede ser menor a la carga registrada")
+)
+
+// effectiveWeight devuelve el peso por pieza considerado en la bodega: el declarado o el típico del tipo
+func effectiveWeight(line *Baggage) float64 {
+	if line.Weight > 0 {
+		return line.Weight
+	}
+	return line.DefaultWeight
+}
+
+// lineFootprint devuelve las piezas, el peso y el volumen que ocupa la línea en la salida.
+// Una línea ausente (nil), rechazada o en espera no ocupa capacidad.
+func lineFootprint(line *Baggage) (pieces int, weight, volume float64) {
+	if line == nil || !isLineActive(*line) {
+		return 0, 0, 0
+	}
+
+	pieces = line.Quantity
+	return pieces, float64(pieces) * effectiveWeight(line), float64(pieces) * line.Volume
+}
+
+// holdUsageFilter selecciona la bodega de la salida solo si la carga resultante de los aumentos cabe en su
+// capacidad, para verificarlo en la misma operación que la actualiza. Las disminuciones siempre se aplican.
+func holdUsageFilter(routeID string, weightDelta, volumeDelta float64) bson.M {
+	var conditions bson.A
+	if weightDelta > 0 {
+		conditions = append(conditions, bson.M{"$lte": bson.A{bson.M{"$add": bson.A{"$used_weight", weightDelta}}, "$weight_capacity"}})
+	}
+	if volumeDelta > 0 {
+		conditions = append(conditions, bson.M{"$lte": bson.A{bson.M{"$add": bson.A{"$used_volume", volumeDelta}}, "$volume_capacity"}})
+	}
+	filter := bson.M{"_id": routeID}
+	if len(conditions) > 0 {
+		filter["$expr"] = bson.M{"$and": conditions}
+	}
+	return filter
+}
+
+// buildHoldLoadReport resume la carga de la bodega a partir de las reservas de equipaje de la salida
+func buildHoldLoadReport(hold *CargoHold, reservations []*BaggageReservation) *HoldLoadReport {
+	report := &HoldLoadReport{
+		CargoHold:    *hold,
+		Reservations: len(reservations),
+		ByType:       []HoldLoadLine{},
+	}
+	if hold.WeightCapacity > 0 {
+		report.WeightUtilization = math.Round(hold.UsedWeight/hold.WeightCapacity*10000) / 100
+	}
+	if hold.VolumeCapacity > 0 {
+		report.VolumeUtilization = math.Round(hold.UsedVolume/hold.VolumeCapacity*10000) / 100
+	}
+
+	byType := make(map[string]*HoldLoadLine)
+	for _, reservation := range reservations {
+		for i := range reservation.Baggage {
+			line := &reservation.Baggage[i]
+			if line.Status == LineWaitlisted {
+				report.WaitlistedPieces += line.Quantity
+				continue
+			}
+
+			pieces, weight, volume := lineFootprint(line)
+			if pieces == 0 {
+				continue
+			}
+
+			entry, ok := byType[line.Type]
+			if !ok {
+				entry = &HoldLoadLine{Type: line.Type, Category: line.Category}
+				byType[line.Type] = entry
+			}
+			entry.Pieces += pieces
+			entry.Weight += weight
+			entry.Volume += volume
+			report.Pieces += pieces
+		}
+	}
+
+	for _, entry := range byType {
+		report.ByType = append(report.ByType, *entry)
+	}
+	sort.Slice(report.ByType, func(i, j int) bool { return report.ByType[i].Type < report.ByType[j].Type })
+
+	return report
+}
@@ -0,0 +1,95 @@
+package baggage
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLineFootprint(t *testing.T) {
+	tests := []struct {
+		name           string
+		line           *Baggage
+		pieces         int
+		weight, volume float64
+	}{
+		{name: "línea ausente"},
+		{
+			name:   "con peso declarado",
+			line:   &Baggage{Quantity: 2, Weight: 20, DefaultWeight: 23, Volume: 0.1},
+			pieces: 2, weight: 40, volume: 0.2,
+		},
+		{
+			name:   "sin peso declarado usa el típico del tipo",
+			line:   &Baggage{Quantity: 3, DefaultWeight: 10, Volume: 0.25},
+			pieces: 3, weight: 30, volume: 0.75,
+		},
+		{name: "rechazada", line: &Baggage{Quantity: 2, Weight: 20, Status: LineRejected}},
+		{name: "en espera", line: &Baggage{Quantity: 2, Weight: 20, Status: LineWaitlisted}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces, weight, volume := lineFootprint(tt.line)
+			if pieces != tt.pieces || weight != tt.weight || volume != tt.volume {
+				t.Errorf("lineFootprint() = %d, %v, %v; se esperaba %d, %v, %v", pieces, weight, volume, tt.pieces, tt.weight, tt.volume)
+			}
+		})
+	}
+}
+
+func TestHoldUsageFilter(t *testing.T) {
+	weightFits := bson.M{"$lte": bson.A{bson.M{"$add": bson.A{"$used_weight", 20.0}}, "$weight_capacity"}}
+	volumeFits := bson.M{"$lte": bson.A{bson.M{"$add": bson.A{"$used_volume", 0.1}}, "$volume_capacity"}}
+
+	tests := []struct {
+		name                     string
+		weightDelta, volumeDelta float64
+		want                     bson.M
+	}{
+		{
+			name: "un aumento debe caber en el peso y el volumen", weightDelta: 20, volumeDelta: 0.1,
+			want: bson.M{"_id": "ruta-1", "$expr": bson.M{"$and": bson.A{weightFits, volumeFits}}},
+		},
+		{
+			name: "solo se verifica lo que aumenta", weightDelta: 20, volumeDelta: -0.2,
+			want: bson.M{"_id": "ruta-1", "$expr": bson.M{"$and": bson.A{weightFits}}},
+		},
+		{name: "una disminución siempre se aplica", weightDelta: -20, volumeDelta: -0.1, want: bson.M{"_id": "ruta-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := holdUsageFilter("ruta-1", tt.weightDelta, tt.volumeDelta); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("holdUsageFilter() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildHoldLoadReport(t *testing.T) {
+	hold := &CargoHold{RouteID: "ruta-1", WeightCapacity: 200, VolumeCapacity: 2, UsedWeight: 70, UsedVolume: 0.5}
+	reservations := []*BaggageReservation{
+		{Baggage: []Baggage{
+			{Type: "maleta", Quantity: 2, Weight: 20, Volume: 0.1},
+			{Type: "bicicleta", Category: CategorySports, Quantity: 1, Weight: 15, Volume: 0.3, Status: LineWaitlisted},
+		}},
+		{Baggage: []Baggage{
+			{Type: "maleta", Quantity: 1, Weight: 30, Volume: 0.1},
+			{Type: "mascota", Category: CategoryPet, Quantity: 1, Weight: 12, Volume: 0.2, Status: LineRejected},
+		}},
+	}
+
+	report := buildHoldLoadReport(hold, reservations)
+
+	if report.WeightUtilization != 35 || report.VolumeUtilization != 25 {
+		t.Errorf("utilización = %v%% y %v%%, se esperaba 35%% y 25%%", report.WeightUtilization, report.VolumeUtilization)
+	}
+	if report.Reservations != 2 || report.Pieces != 3 || report.WaitlistedPieces != 1 {
+		t.Errorf("reporte = %d reservas, %d piezas y %d en espera; se esperaba 2, 3 y 1", report.Reservations, report.Pieces, report.WaitlistedPieces)
+	}
+	if len(report.ByType) != 1 || report.ByType[0].Type != "maleta" || report.ByType[0].Pieces != 3 || report.ByType[0].Weight != 70 {
+		t.Errorf("carga por tipo = %+v, se esperaban 3 maletas con 70 kg", report.ByType)
+	}
+}
@@ -0,0 +1,118 @@
+package baggage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+// Formatos de código de barras soportados para las etiquetas
+const (
+	BarcodeCode128 = "code128"
+	BarcodeQR      = "qr"
+)
+
+// ErrUnsupportedBarcode indica que el formato de código de barras solicitado no existe
+var ErrUnsupportedBarcode = errors.New("formato de código de barras no soportado, use code128 o qr")
+
+// tagBarcode genera la imagen del código de barras del número de etiqueta en el formato indicado
+func tagBarcode(tagNumber, format string) (image.Image, error) {
+	var (
+		code          barcode.Barcode
+		err           error
+		width, height int
+	)
+
+	switch format {
+	case BarcodeCode128, "":
+		code, err = code128.Encode(tagNumber)
+		width, height = 600, 160
+	case BarcodeQR:
+		code, err = qr.Encode(tagNumber, qr.M, qr.Auto)
+		width, height = 300, 300
+	default:
+		return nil, ErrUnsupportedBarcode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return barcode.Scale(code, width, height)
+}
+
+// writeTagBarcodePNG escribe el código de barras de la etiqueta como imagen PNG
+func writeTagBarcodePNG(w io.Writer, tagNumber, format string) error {
+	img, err := tagBarcode(tagNumber, format)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// writeBaggageLabels genera un PDF imprimible con una etiqueta de 100x150 mm por pieza
+func writeBaggageLabels(w io.Writer, pieces []*BaggagePiece) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: 100, Ht: 150},
+	})
+	pdf.SetMargins(6, 6, 6)
+	pdf.SetAutoPageBreak(false, 0)
+
+	for _, piece := range pieces {
+		pdf.AddPage()
+
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(88, 8, "VENTA DE PASAJES", "", 1, "C", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(88, 5, "Etiqueta de equipaje", "", 1, "C", false, 0, "")
+		pdf.Ln(3)
+
+		pdf.SetFont("Helvetica", "B", 22)
+		pdf.CellFormat(88, 12, piece.TagNumber, "1", 1, "C", false, 0, "")
+		pdf.Ln(3)
+
+		pdf.SetFont("Helvetica", "", 10)
+		tr := pdf.UnicodeTranslatorFromDescriptor("")
+		pdf.CellFormat(88, 6, tr("Reserva: "+piece.ReservationID), "", 1, "L", false, 0, "")
+		if piece.RouteID != "" {
+			pdf.CellFormat(88, 6, tr("Ruta: "+piece.RouteID), "", 1, "L", false, 0, "")
+		}
+		pdf.CellFormat(88, 6, tr("Tipo: "+piece.Type), "", 1, "L", false, 0, "")
+		if piece.Weight > 0 {
+			pdf.CellFormat(88, 6, fmt.Sprintf("Peso: %.1f kg", piece.Weight), "", 1, "L", false, 0, "")
+		}
+		pdf.CellFormat(88, 6, fmt.Sprintf("Pieza: %d", piece.PieceNumber), "", 1, "L", false, 0, "")
+
+		// Códigos de barras de la pieza
+		if err := addBarcodeImage(pdf, piece.TagNumber, BarcodeCode128, 6, 88, 88, 24); err != nil {
+			return err
+		}
+		if err := addBarcodeImage(pdf, piece.TagNumber, BarcodeQR, 32, 114, 34, 34); err != nil {
+			return err
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// addBarcodeImage inserta el código de barras de la etiqueta en la página actual del PDF
+func addBarcodeImage(pdf *fpdf.Fpdf, tagNumber, format string, x, y, width, height float64) error {
+	var buf bytes.Buffer
+	if err := writeTagBarcodePNG(&buf, tagNumber, format); err != nil {
+		return err
+	}
+
+	name := format + "-" + tagNumber
+	pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, &buf)
+	pdf.ImageOptions(name, x, y, width, height, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	return pdf.Error()
+}
@@ -0,0 +1,57 @@
+package baggage
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+)
+
+func TestTagBarcode(t *testing.T) {
+	tests := []struct {
+		format        string
+		width, height int
+	}{
+		{format: "", width: 600, height: 160},
+		{format: BarcodeCode128, width: 600, height: 160},
+		{format: BarcodeQR, width: 300, height: 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeTagBarcodePNG(&buf, "VP00000042", tt.format); err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(&buf)
+			if err != nil {
+				t.Fatalf("la imagen no es un PNG válido: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != tt.width || size.Y != tt.height {
+				t.Errorf("tamaño = %v, se esperaba %dx%d", size, tt.width, tt.height)
+			}
+		})
+	}
+
+	if _, err := tagBarcode("VP00000042", "ean13"); !errors.Is(err, ErrUnsupportedBarcode) {
+		t.Errorf("formato desconocido: error = %v, se esperaba ErrUnsupportedBarcode", err)
+	}
+}
+
+func TestWriteBaggageLabels(t *testing.T) {
+	pieces := []*BaggagePiece{
+		{TagNumber: "VP00000001", ReservationID: "reserva-1", RouteID: "ruta-1", Type: "maleta", Weight: 20, PieceNumber: 1},
+		{TagNumber: "VP00000002", ReservationID: "reserva-1", Type: "mochila", PieceNumber: 2},
+	}
+
+	var buf bytes.Buffer
+	if err := writeBaggageLabels(&buf, pieces); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatal("writeBaggageLabels() no generó un PDF")
+	}
+	if pages := bytes.Count(buf.Bytes(), []byte("/Type /Page\n")); pages != len(pieces) {
+		t.Errorf("páginas = %d, se esperaba una por pieza (%d)", pages, len(pieces))
+	}
+}
@@ -1,24 +1,229 @@
 package baggage
 
+import "time"
+
 // Baggage representa un objeto de equipaje
 type Baggage struct {
-	Quantity int    `json:"quantity" bson:"quantity"`
-	Type     string `json:"type" bson:"type"`
+	ID               string  `json:"id" bson:"id"`
+	Quantity         int     `json:"quantity" bson:"quantity"`
+	Type             string  `json:"type" bson:"type"`
+	Weight           float64 `json:"weight" bson:"weight"`                       // Peso declarado por pieza (kg)
+	IncludedQuantity int     `json:"included_quantity" bson:"included_quantity"` // Piezas cubiertas por la franquicia
+	UnitPrice        float64 `json:"unit_price" bson:"unit_price"`
+	Price            float64 `json:"price" bson:"price"` // Precio de las piezas en exceso
+	Category         string  `json:"category,omitempty" bson:"category,omitempty"`
+	Status           string  `json:"status,omitempty" bson:"status,omitempty"` // Vacío equivale a confirmado
+	Tagged           int     `json:"tagged,omitempty" bson:"tagged,omitempty"` // Piezas de la línea ya etiquetadas
+
+	DefaultWeight float64   `json:"default_weight,omitempty" bson:"default_weight,omitempty"` // Peso por pieza considerado en bodega si no se declara
+	Volume        float64   `json:"volume,omitempty" bson:"volume,omitempty"`                 // Volumen por pieza (m³)
+	AddedAt       time.Time `json:"added_at,omitempty" bson:"added_at,omitempty"`
+
+	Documents   []BaggageDocument      `json:"documents,omitempty" bson:"documents,omitempty"`
+	Declaration *RestrictedDeclaration `json:"declaration,omitempty" bson:"declaration,omitempty"`
+}
+
+// BaggageDocument representa un documento presentado para una pieza de categoría especial
+type BaggageDocument struct {
+	Type      string `json:"type" bson:"type"` // Por ejemplo: "certificado_sanitario", "carnet_vacunacion"
+	Reference string `json:"reference" bson:"reference"`
+}
+
+// RestrictedDeclaration representa la declaración jurada del contenido de una pieza restringida
+type RestrictedDeclaration struct {
+	Items       []string `json:"items" bson:"items"`
+	Description string   `json:"description" bson:"description"`
+	Accepted    bool     `json:"accepted" bson:"accepted"` // El pasajero acepta las condiciones de transporte
+}
+
+// AddBaggageRequest representa la solicitud para agregar equipaje a una reserva
+type AddBaggageRequest struct {
+	BaggageReservationID string                 `json:"baggage_reservation_id"`
+	BaggageType          string                 `json:"baggage_type"`
+	Quantity             int                    `json:"quantity"`
+	Weight               float64                `json:"weight"`   // Peso declarado por pieza (kg), mayor a cero
+	Waitlist             bool                   `json:"waitlist"` // Dejar en espera si la bodega está llena en vez de rechazar
+	Documents            []BaggageDocument      `json:"documents,omitempty"`
+	Declaration          *RestrictedDeclaration `json:"declaration,omitempty"`
 }
 
 // BaggageReservation representa la información de reserva de equipaje
 type BaggageReservation struct {
-	ID            string    `json:"id,omitempty" bson:"_id,omitempty"`
-	ReservationID string    `json:"reservation_id" bson:"reservation_id"`
-	Weight        float64   `json:"weight" bson:"weight"`
-	Price         float64   `json:"price" bson:"price"`
-	Type          string    `json:"type" bson:"type"`
-	Baggage       []Baggage `json:"baggage" bson:"baggage"`
+	ID            string          `json:"id,omitempty" bson:"_id,omitempty"`
+	ReservationID string          `json:"reservation_id" bson:"reservation_id"`
+	FareClass     string          `json:"fare_class,omitempty" bson:"fare_class,omitempty"`
+	RouteID       string          `json:"route_id,omitempty" bson:"route_id,omitempty"`
+	OperatorID    string          `json:"operator_id,omitempty" bson:"operator_id,omitempty"`
+	Tickets       int             `json:"tickets,omitempty" bson:"tickets,omitempty"` // Pasajes de la reserva de pasaje; la franquicia es por pasaje
+	Weight        float64         `json:"weight" bson:"weight"`
+	Price         float64         `json:"price" bson:"price"`
+	Type          string          `json:"type" bson:"type"`
+	Baggage       []Baggage       `json:"baggage" bson:"baggage"`
+	Allowance     *AllowanceUsage `json:"allowance,omitempty" bson:"allowance,omitempty"`
+	Version       int             `json:"version" bson:"version"` // Control de concurrencia optimista
 }
 
 // BaggageType representa los tipos de equipaje disponibles
 type BaggageType struct {
-	ID    string  `json:"id,omitempty" bson:"_id,omitempty"`
-	Name  string  `json:"name" bson:"name"`
-	Price float64 `json:"price" bson:"price"`
+	ID           string        `json:"id,omitempty" bson:"_id,omitempty"`
+	Name         string        `json:"name" bson:"name"`
+	Price        float64       `json:"price" bson:"price"`
+	Active       bool          `json:"active" bson:"active"`
+	Category     string        `json:"category,omitempty" bson:"category,omitempty"` // Vacío equivale a estándar
+	Weight       float64       `json:"weight" bson:"weight"`                         // Peso típico por pieza (kg) para la bodega
+	Volume       float64       `json:"volume" bson:"volume"`                         // Volumen por pieza (m³) para la bodega
+	PriceHistory []PriceChange `json:"price_history,omitempty" bson:"price_history,omitempty"`
+	CreatedAt    time.Time     `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt    time.Time     `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// PriceChange representa un precio vigente de un tipo de equipaje desde una fecha.
+// Las líneas de equipaje ya vendidas conservan su precio unitario aunque el tipo cambie de precio.
+type PriceChange struct {
+	Price         float64   `json:"price" bson:"price"`
+	EffectiveFrom time.Time `json:"effective_from" bson:"effective_from"`
+}
+
+// BaggageAllowance representa la franquicia de equipaje incluida con el pasaje.
+// Los campos RouteID y OperatorID vacíos aplican a cualquier ruta u operador.
+type BaggageAllowance struct {
+	ID           string   `json:"id,omitempty" bson:"_id,omitempty"`
+	FareClass    string   `json:"fare_class" bson:"fare_class"`
+	RouteID      string   `json:"route_id,omitempty" bson:"route_id,omitempty"`
+	OperatorID   string   `json:"operator_id,omitempty" bson:"operator_id,omitempty"`
+	BaggageTypes []string `json:"baggage_types,omitempty" bson:"baggage_types,omitempty"` // Vacío: cualquier tipo
+	Pieces       int      `json:"pieces" bson:"pieces"`
+	MaxWeight    float64  `json:"max_weight" bson:"max_weight"` // Peso máximo por pieza incluida (kg), 0 sin límite
+}
+
+// AllowanceUsage resume la franquicia consumida por una reserva de equipaje
+type AllowanceUsage struct {
+	AllowanceID     string  `json:"allowance_id" bson:"allowance_id"`
+	Pieces          int     `json:"pieces" bson:"pieces"`
+	UsedPieces      int     `json:"used_pieces" bson:"used_pieces"`
+	RemainingPieces int     `json:"remaining_pieces" bson:"remaining_pieces"`
+	MaxWeight       float64 `json:"max_weight" bson:"max_weight"`
+}
+
+// BaggagePiece representa una pieza de equipaje documentada con su número de etiqueta
+type BaggagePiece struct {
+	TagNumber            string    `json:"tag_number" bson:"_id"`
+	BaggageReservationID string    `json:"baggage_reservation_id" bson:"baggage_reservation_id"`
+	ReservationID        string    `json:"reservation_id" bson:"reservation_id"`
+	RouteID              string    `json:"route_id,omitempty" bson:"route_id,omitempty"`
+	ItemID               string    `json:"item_id" bson:"item_id"`
+	PieceNumber          int       `json:"piece_number" bson:"piece_number"` // Número de pieza dentro de la línea (1..cantidad)
+	Type                 string    `json:"type" bson:"type"`
+	Weight               float64   `json:"weight" bson:"weight"`
+	Status               string    `json:"status,omitempty" bson:"status,omitempty"` // Último estado de seguimiento
+	Location             string    `json:"location,omitempty" bson:"location,omitempty"`
+	LastEventID          string    `json:"last_event_id,omitempty" bson:"last_event_id,omitempty"` // Evento que registró el último estado
+	UpdatedAt            time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	CreatedAt            time.Time `json:"created_at" bson:"created_at"`
+}
+
+// TrackingEvent representa un evento de seguimiento registrado al escanear una pieza.
+// El historial de una pieza es de solo inserción.
+type TrackingEvent struct {
+	ID                   string    `json:"id" bson:"_id"`
+	TagNumber            string    `json:"tag_number" bson:"tag_number"`
+	BaggageReservationID string    `json:"baggage_reservation_id" bson:"baggage_reservation_id"`
+	ReservationID        string    `json:"reservation_id" bson:"reservation_id"`
+	Status               string    `json:"status" bson:"status"`
+	Location             string    `json:"location" bson:"location"`
+	StaffID              string    `json:"staff_id" bson:"staff_id"`
+	Notes                string    `json:"notes,omitempty" bson:"notes,omitempty"`
+	OccurredAt           time.Time `json:"occurred_at" bson:"occurred_at"`
+}
+
+// PieceStatus representa el estado de una pieza visible para el cliente
+type PieceStatus struct {
+	TagNumber string    `json:"tag_number"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	Location  string    `json:"location,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// QuoteLine representa una línea de la canasta de equipaje a cotizar
+type QuoteLine struct {
+	BaggageType string  `json:"baggage_type"`
+	Quantity    int     `json:"quantity"`
+	Weight      float64 `json:"weight"` // Peso declarado por pieza (kg), mayor a cero
+}
+
+// QuoteRequest representa una solicitud de cotización de equipaje
+type QuoteRequest struct {
+	Lines                []QuoteLine `json:"lines"`
+	BaggageReservationID string      `json:"baggage_reservation_id,omitempty"`
+	FareClass            string      `json:"fare_class,omitempty"`
+	RouteID              string      `json:"route_id,omitempty"`
+	OperatorID           string      `json:"operator_id,omitempty"`
+}
+
+// QuotedLine representa el desglose de precio de una línea cotizada
+type QuotedLine struct {
+	BaggageType      string  `json:"baggage_type"`
+	Quantity         int     `json:"quantity"`
+	IncludedQuantity int     `json:"included_quantity"`
+	UnitPrice        float64 `json:"unit_price"`
+	Subtotal         float64 `json:"subtotal"`
+}
+
+// BaggageQuote representa la cotización de una canasta de equipaje
+type BaggageQuote struct {
+	Lines            []QuotedLine    `json:"lines"`
+	TotalQuantity    int             `json:"total_quantity"`
+	IncludedQuantity int             `json:"included_quantity"`
+	Total            float64         `json:"total"`
+	Allowance        *AllowanceUsage `json:"allowance,omitempty"`
+}
+
+// BaggageCategory representa una categoría de equipaje especial con sus reglas de transporte
+type BaggageCategory struct {
+	Code                string   `json:"code" bson:"_id"`
+	Name                string   `json:"name" bson:"name"`
+	RequiresApproval    bool     `json:"requires_approval" bson:"requires_approval"`
+	MaxPerDeparture     int      `json:"max_per_departure" bson:"max_per_departure"` // Piezas por salida, 0 sin límite
+	RequiredDocuments   []string `json:"required_documents,omitempty" bson:"required_documents,omitempty"`
+	RequiresDeclaration bool     `json:"requires_declaration" bson:"requires_declaration"`
+}
+
+// CategoryUsage representa las piezas de una categoría registradas en una salida
+type CategoryUsage struct {
+	ID       string `json:"-" bson:"_id"`
+	RouteID  string `json:"route_id" bson:"route_id"`
+	Category string `json:"category" bson:"category"`
+	Used     int    `json:"used" bson:"used"`
+	Max      int    `json:"max" bson:"-"`
+}
+
+// CargoHold representa la capacidad de la bodega de equipaje de una salida y lo ocupado
+type CargoHold struct {
+	RouteID        string    `json:"route_id" bson:"_id"`
+	WeightCapacity float64   `json:"weight_capacity" bson:"weight_capacity"` // kg
+	VolumeCapacity float64   `json:"volume_capacity" bson:"volume_capacity"` // m³
+	UsedWeight     float64   `json:"used_weight" bson:"used_weight"`
+	UsedVolume     float64   `json:"used_volume" bson:"used_volume"`
+	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// HoldLoadReport representa el reporte de carga de la bodega de una salida para el operador
+type HoldLoadReport struct {
+	CargoHold
+	WeightUtilization float64        `json:"weight_utilization"` // Porcentaje
+	VolumeUtilization float64        `json:"volume_utilization"` // Porcentaje
+	Reservations      int            `json:"reservations"`
+	Pieces            int            `json:"pieces"`
+	WaitlistedPieces  int            `json:"waitlisted_pieces"`
+	ByType            []HoldLoadLine `json:"by_type"`
+}
+
+// HoldLoadLine representa la carga de un tipo de equipaje en la bodega
+type HoldLoadLine struct {
+	Type     string  `json:"type"`
+	Category string  `json:"category,omitempty"`
+	Pieces   int     `json:"pieces"`
+	Weight   float64 `json:"weight"`
+	Volume   float64 `json:"volume"`
 }
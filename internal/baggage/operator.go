@@ -0,0 +1,89 @@
+package baggage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+var (
+	// ErrOperatorBaggageRule indica que el equipaje no cumple las reglas del operador de la reserva
+	ErrOperatorBaggageRule = errors.New("el equipaje no cumple las reglas del operador")
+	// ErrOperatorNotFound indica que el operador de la salida no está registrado
+	ErrOperatorNotFound = errors.New("operador de la salida no encontrado")
+)
+
+// validateOperatorRules verifica las líneas no rechazadas de la reserva contra las reglas de equipaje del operador
+func validateOperatorRules(rules *operator.BaggageRules, lines []Baggage) error {
+	if rules == nil {
+		return nil
+	}
+
+	pieces := 0
+	for _, line := range lines {
+		if line.Status == LineRejected {
+			continue
+		}
+		for _, category := range rules.DisallowedCategories {
+			if line.Category == category {
+				return fmt.Errorf("%w: no transporta equipaje de la categoría %s", ErrOperatorBaggageRule, category)
+			}
+		}
+		if rules.MaxPieceWeight > 0 && line.Weight > rules.MaxPieceWeight {
+			return fmt.Errorf("%w: el peso máximo por pieza es %.1f kg", ErrOperatorBaggageRule, rules.MaxPieceWeight)
+		}
+		pieces += line.Quantity
+	}
+
+	if rules.MaxPieces > 0 && pieces > rules.MaxPieces {
+		return fmt.Errorf("%w: se admiten como máximo %d piezas por reserva", ErrOperatorBaggageRule, rules.MaxPieces)
+	}
+	return nil
+}
+
+// getOperatorBaggageRules obtiene las reglas de equipaje del operador, o nil si la salida no tiene operador. Un
+// operador que no está registrado es un error: sin sus reglas no se acepta el equipaje.
+func (r *BaggageRepository) getOperatorBaggageRules(operatorID string) (*operator.BaggageRules, error) {
+	if operatorID == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.OperatorsCollection)
+
+	var profile operator.Operator
+	err := collection.FindOne(ctx, bson.M{"_id": operatorID}).Decode(&profile)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: %s, sin sus reglas no se acepta el equipaje", ErrOperatorNotFound, operatorID)
+		}
+		return nil, err
+	}
+
+	return &profile.BaggageRules, nil
+}
+
+// checkOperatorRules verifica las líneas resultantes de la reserva contra las reglas del operador de su salida.
+// El operador se obtiene de la reserva de pasaje y no del operador guardado en la reserva de equipaje; si el
+// llamador ya tiene la salida de la reserva de pasaje la indica en trip para no volver a consultarla.
+func (r *BaggageRepository) checkOperatorRules(reservation *BaggageReservation, trip *passengerTrip, lines []Baggage) error {
+	if trip == nil {
+		var err error
+		if trip, err = r.getPassengerTrip(reservation.ReservationID); err != nil {
+			return err
+		}
+	}
+	rules, err := r.getOperatorBaggageRules(trip.OperatorID)
+	if err != nil {
+		return err
+	}
+	return validateOperatorRules(rules, lines)
+}
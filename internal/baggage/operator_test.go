@@ -0,0 +1,51 @@
+package baggage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+func TestValidateOperatorRules(t *testing.T) {
+	rules := &operator.BaggageRules{MaxPieceWeight: 23, MaxPieces: 3, DisallowedCategories: []string{"mascota"}}
+
+	tests := []struct {
+		name    string
+		rules   *operator.BaggageRules
+		lines   []Baggage
+		wantErr bool
+	}{
+		{name: "sin reglas", lines: []Baggage{{Quantity: 9, Weight: 50, Category: "mascota"}}},
+		{name: "dentro de las reglas", rules: rules, lines: []Baggage{{Quantity: 2, Weight: 23}, {Quantity: 1, Weight: 10}}},
+		{name: "pieza demasiado pesada", rules: rules, lines: []Baggage{{Quantity: 1, Weight: 23.5}}, wantErr: true},
+		{name: "demasiadas piezas", rules: rules, lines: []Baggage{{Quantity: 2, Weight: 10}, {Quantity: 2, Weight: 10}}, wantErr: true},
+		{name: "categoría no transportada", rules: rules, lines: []Baggage{{Quantity: 1, Weight: 8, Category: "mascota"}}, wantErr: true},
+		{
+			name:  "las líneas rechazadas no cuentan",
+			rules: rules,
+			lines: []Baggage{{Quantity: 3, Weight: 10}, {Quantity: 1, Weight: 40, Category: "mascota", Status: LineRejected}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOperatorRules(tt.rules, tt.lines)
+			if tt.wantErr && !errors.Is(err, ErrOperatorBaggageRule) {
+				t.Errorf("validateOperatorRules(): error = %v, se esperaba ErrOperatorBaggageRule", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("validateOperatorRules(): %v", err)
+			}
+		})
+	}
+}
+
+func TestUnknownOperatorIsNotFound(t *testing.T) {
+	err := fmt.Errorf("%w: %s", ErrOperatorNotFound, "op-1")
+	if status := (&BaggageHandler{}).errorStatus(err); status != http.StatusNotFound {
+		t.Errorf("estado HTTP = %d, se esperaba %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,162 @@
+package baggage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/internal/fleet"
+)
+
+var (
+	// ErrPassengerReservationNotFound indica que la reserva de pasaje no existe o no pertenece al usuario
+	ErrPassengerReservationNotFound = errors.New("reserva de pasaje no encontrada")
+	// ErrPassengerRouteNotFound indica que la salida de la reserva de pasaje no existe
+	ErrPassengerRouteNotFound = errors.New("la salida de la reserva de pasaje no existe")
+)
+
+// passengerTrip representa los datos de la reserva de pasaje que definen su equipaje. Se leen siempre de la
+// reserva y su salida, nunca de la solicitud.
+type passengerTrip struct {
+	RouteID    string
+	OperatorID string
+	FareClass  string // Tipo de asiento de la reserva, según los asientos elegidos o el bus de la salida
+	Tickets    int    // Pasajes de la reserva
+}
+
+// CheckPassengerReservationOwner verifica que la reserva de pasaje pertenezca al usuario. Una reserva ajena se
+// informa como inexistente para no revelar qué reservas existen.
+func (r *BaggageRepository) CheckPassengerReservationOwner(reservationID, userID string) error {
+	if reservationID == "" || userID == "" {
+		return ErrPassengerReservationNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	err := collection.FindOne(ctx, bson.M{"_id": reservationID, "user_id": userID},
+		options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrPassengerReservationNotFound
+	}
+	return err
+}
+
+// CheckReservationOwner verifica que la reserva de equipaje corresponda a una reserva de pasaje del usuario.
+// Una reserva ajena se informa como inexistente.
+func (r *BaggageRepository) CheckReservationOwner(baggageReservationID, userID string) error {
+	reservation, err := r.getReservationByID(baggageReservationID)
+	if err != nil {
+		return err
+	}
+	if err := r.CheckPassengerReservationOwner(reservation.ReservationID, userID); err != nil {
+		if errors.Is(err, ErrPassengerReservationNotFound) {
+			return fmt.Errorf("%w: %s", ErrReservationNotFound, baggageReservationID)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetRouteOperator obtiene el operador de una salida, o vacío si la salida no existe o no indica operador
+func (r *BaggageRepository) GetRouteOperator(routeID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	var route struct {
+		OperatorID string `bson:"operator_id"`
+	}
+	err := collection.FindOne(ctx, bson.M{"_id": routeID}, options.FindOne().SetProjection(bson.M{"operator_id": 1})).Decode(&route)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	return route.OperatorID, err
+}
+
+// getPassengerTrip obtiene la salida, el operador, la clase tarifaria y los pasajes de una reserva de pasaje
+func (r *BaggageRepository) getPassengerTrip(reservationID string) (*passengerTrip, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := r.client.Database(r.config.MongoDB.DatabaseName)
+
+	var reservation struct {
+		RouteID     string   `bson:"route_id"`
+		Seats       int      `bson:"seats"`
+		SeatNumbers []string `bson:"seat_numbers"`
+	}
+	err := db.Collection(r.config.MongoDB.ReservationsCollection).FindOne(ctx, bson.M{"_id": reservationID},
+		options.FindOne().SetProjection(bson.M{"route_id": 1, "seats": 1, "seat_numbers": 1})).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrPassengerReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var route struct {
+		OperatorID string `bson:"operator_id"`
+		VehicleID  string `bson:"vehicle_id"`
+	}
+	err = db.Collection(r.config.MongoDB.RoutesCollection).FindOne(ctx, bson.M{"_id": reservation.RouteID},
+		options.FindOne().SetProjection(bson.M{"operator_id": 1, "vehicle_id": 1})).Decode(&route)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: %s", ErrPassengerRouteNotFound, reservation.RouteID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicle fleet.Vehicle
+	if route.VehicleID != "" {
+		err = db.Collection(r.config.MongoDB.VehiclesCollection).FindOne(ctx, bson.M{"_id": route.VehicleID},
+			options.FindOne().SetProjection(bson.M{"layout.seats": 1})).Decode(&vehicle)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	}
+
+	return &passengerTrip{
+		RouteID:    reservation.RouteID,
+		OperatorID: route.OperatorID,
+		FareClass:  seatsClass(vehicle.Layout.Seats, reservation.SeatNumbers),
+		Tickets:    max(reservation.Seats, len(reservation.SeatNumbers), 1),
+	}, nil
+}
+
+// seatClassRank ordena los tipos de asiento del más económico al más caro
+var seatClassRank = map[string]int{fleet.SeatStandard: 1, fleet.SeatSemiSleep: 2, fleet.SeatSleep: 3}
+
+// seatsClass devuelve la clase tarifaria de una reserva: la de los asientos elegidos o, si no eligió asientos,
+// la del bus de la salida. Si los asientos son de distintos tipos se toma el más económico, y sin asientos
+// conocidos del croquis, la clase estándar.
+func seatsClass(layout []fleet.Seat, seatNumbers []string) string {
+	chosen := make(map[string]bool, len(seatNumbers))
+	for _, number := range seatNumbers {
+		chosen[number] = true
+	}
+
+	class := ""
+	for _, seat := range layout {
+		if len(chosen) > 0 && !chosen[seat.Number] {
+			continue
+		}
+		rank, ok := seatClassRank[seat.Type]
+		if ok && (class == "" || rank < seatClassRank[class]) {
+			class = seat.Type
+		}
+	}
+	if class == "" {
+		return fleet.SeatStandard
+	}
+	return class
+}
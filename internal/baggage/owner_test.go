@@ -0,0 +1,39 @@
+package baggage
+
+import (
+	"testing"
+
+	"venta-de-pasajes/internal/fleet"
+)
+
+func TestSeatsClass(t *testing.T) {
+	mixed := []fleet.Seat{
+		{Number: "1A", Type: fleet.SeatSleep},
+		{Number: "1B", Type: fleet.SeatSleep},
+		{Number: "2A", Type: fleet.SeatSemiSleep},
+		{Number: "3A", Type: fleet.SeatStandard},
+	}
+	sleepers := []fleet.Seat{{Number: "1A", Type: fleet.SeatSleep}, {Number: "1B", Type: fleet.SeatSleep}}
+
+	tests := []struct {
+		name        string
+		layout      []fleet.Seat
+		seatNumbers []string
+		want        string
+	}{
+		{name: "asientos del mismo tipo", layout: mixed, seatNumbers: []string{"1A", "1B"}, want: fleet.SeatSleep},
+		{name: "asientos de distintos tipos toma el más económico", layout: mixed, seatNumbers: []string{"1A", "2A"}, want: fleet.SeatSemiSleep},
+		{name: "sin asientos elegidos toma la clase del bus", layout: sleepers, want: fleet.SeatSleep},
+		{name: "sin asientos elegidos en un bus mixto", layout: mixed, want: fleet.SeatStandard},
+		{name: "asiento que no está en el croquis", layout: sleepers, seatNumbers: []string{"9Z"}, want: fleet.SeatStandard},
+		{name: "salida sin bus", want: fleet.SeatStandard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seatsClass(tt.layout, tt.seatNumbers); got != tt.want {
+				t.Errorf("seatsClass(%v) = %q, se esperaba %q", tt.seatNumbers, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"venta-de-pasajes/config"
@@ -15,6 +16,35 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// tagPrefix es el prefijo de los números de etiqueta de equipaje
+const tagPrefix = "VP"
+
+// maxWriteAttempts es el número de reintentos ante conflictos de versión en escrituras que no dependen del estado previo
+const maxWriteAttempts = 3
+
+var (
+	// ErrReservationNotFound indica que la reserva de equipaje no existe
+	ErrReservationNotFound = errors.New("reserva de equipaje no encontrada")
+	// ErrBaggageItemNotFound indica que la línea de equipaje no existe en la reserva
+	ErrBaggageItemNotFound = errors.New("línea de equipaje no encontrada")
+	// ErrBaggageItemTagged indica que la línea tiene piezas etiquetadas que no pueden quitarse
+	ErrBaggageItemTagged = errors.New("la línea de equipaje tiene piezas etiquetadas, no se puede reducir ni eliminar")
+	// ErrPieceNotFound indica que no existe una pieza con el número de etiqueta dado
+	ErrPieceNotFound = errors.New("pieza de equipaje no encontrada")
+	// ErrUnknownTrackingStatus indica que el estado de seguimiento no existe
+	ErrUnknownTrackingStatus = errors.New("estado de seguimiento desconocido")
+	// ErrInvalidTransition indica que la pieza no puede pasar al estado solicitado desde su estado actual
+	ErrInvalidTransition = errors.New("transición de estado no permitida para la pieza")
+	// ErrBaggageTypeNotFound indica que el tipo de equipaje no existe
+	ErrBaggageTypeNotFound = errors.New("tipo de equipaje no encontrado")
+	// ErrBaggageTypeExists indica que ya existe un tipo de equipaje con el mismo nombre
+	ErrBaggageTypeExists = errors.New("ya existe un tipo de equipaje con ese nombre")
+	// ErrBaggageTypeInactive indica que el tipo de equipaje fue desactivado y no puede venderse
+	ErrBaggageTypeInactive = errors.New("el tipo de equipaje está desactivado")
+	// ErrVersionConflict indica que la reserva fue modificada por otra solicitud
+	ErrVersionConflict = errors.New("la reserva de equipaje fue modificada por otra solicitud, vuelva a consultarla")
+)
+
 // BaggageRepository representa un repositorio para el manejo del equipaje
 type BaggageRepository struct {
 	config *config.Config // Configuración de MongoDB
@@ -44,10 +74,43 @@ func NewRepository(cfg *config.Config) (*BaggageRepository, error) {
 	}, nil
 }
 
-// CreateReservation crea una nueva reserva de equipaje en la base de datos y devuelve su ID
+// CreateReservation crea una nueva reserva de equipaje en la base de datos y devuelve su ID. La salida, el
+// operador, la clase tarifaria y los pasajes se toman de la reserva de pasaje, no de la solicitud, y el precio, el peso, la
+// franquicia y la versión se calculan en vez de tomarse de la solicitud. Las líneas enviadas se
+// agregan una a una como en AddBaggageToReservation, que verifica su categoría y descuenta la capacidad de la
+// salida; si alguna no se puede agregar, la reserva se descarta y se libera lo descontado por las anteriores.
 func (r *BaggageRepository) CreateReservation(reservation *BaggageReservation) (string, error) {
-	// Generar un nuevo ID único UUID
+	trip, err := r.getPassengerTrip(reservation.ReservationID)
+	if err != nil {
+		return "", err
+	}
+	reservation.RouteID, reservation.OperatorID, reservation.FareClass = trip.RouteID, trip.OperatorID, trip.FareClass
+	reservation.Tickets = trip.Tickets
+
+	// De cada línea enviada solo se toma lo que declara el pasajero; el precio, la franquicia, la categoría y el
+	// estado se calculan al agregarla
+	requested := make([]Baggage, len(reservation.Baggage))
+	for i, line := range reservation.Baggage {
+		requested[i] = Baggage{Quantity: line.Quantity, Type: line.Type, Weight: line.Weight, Documents: line.Documents, Declaration: line.Declaration}
+	}
+
+	// Verificar las líneas enviadas contra las reglas del operador de la salida antes de registrar la reserva
+	if len(requested) > 0 {
+		if err := r.checkOperatorRules(reservation, trip, requested); err != nil {
+			return "", err
+		}
+	}
+
+	// La reserva se registra sin líneas, sin precio ni peso y con la franquicia sin consumir
+	allowance, err := r.findAllowance(reservation)
+	if err != nil {
+		return "", err
+	}
 	reservation.ID = uuid.New().String()
+	reservation.Version = 0
+	reservation.Price, reservation.Weight = 0, 0
+	reservation.Baggage = []Baggage{}
+	reservation.Allowance = newAllowanceUsage(allowance, nil)
 
 	// Contexto con timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -57,15 +120,53 @@ func (r *BaggageRepository) CreateReservation(reservation *BaggageReservation) (
 	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageReservationsCollection)
 
 	// Insertar la reserva de equipaje en la base de datos
-	_, err := collection.InsertOne(ctx, reservation)
+	_, err = collection.InsertOne(ctx, reservation)
 	if err != nil {
 		return "erro al registrar reserva de equipaje", err
 	}
 
+	for _, line := range requested {
+		_, err := r.AddBaggageToReservation(&AddBaggageRequest{
+			BaggageReservationID: reservation.ID,
+			BaggageType:          line.Type,
+			Quantity:             line.Quantity,
+			Weight:               line.Weight,
+			Documents:            line.Documents,
+			Declaration:          line.Declaration,
+		})
+		if err != nil {
+			r.discardReservation(reservation.ID)
+			return "", err
+		}
+	}
+
 	// Retornar el ID generado
 	return reservation.ID, nil
 }
 
+// discardReservation elimina una reserva de equipaje recién creada cuyas líneas no se pudieron agregar y libera
+// la capacidad que ocuparon las ya agregadas
+func (r *BaggageRepository) discardReservation(reservationID string) {
+	reservation, err := r.getReservationByID(reservationID)
+	if err != nil {
+		log.Printf("Error al obtener la reserva de equipaje %s para descartarla: %v", reservationID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageReservationsCollection)
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": reservationID}); err != nil {
+		log.Printf("Error al descartar la reserva de equipaje %s: %v", reservationID, err)
+		return
+	}
+
+	for i := range reservation.Baggage {
+		r.releaseLineCapacity(reservation, &reservation.Baggage[i], nil)
+	}
+}
+
 // Asignar el precio del tipo de equipaje a la reserva
 func (r *BaggageRepository) assignBaggageTypePrice(reservation *BaggageReservation) error {
 	baggageType, err := r.getBaggageTypeByName(reservation.Type)
@@ -102,10 +203,15 @@ func (r *BaggageRepository) insertReservation(reservation *BaggageReservation) e
 	return nil
 }
 
-// Obtener todos los tipos de equipaje
-func (r *BaggageRepository) getAllBaggageTypes() ([]*BaggageType, error) {
+// Obtener todos los tipos de equipaje, incluyendo opcionalmente los desactivados
+func (r *BaggageRepository) getAllBaggageTypes(includeInactive bool) ([]*BaggageType, error) {
+	filter := bson.M{"active": true}
+	if includeInactive {
+		filter = bson.M{}
+	}
+
 	// Llamar a la función helper para realizar la búsqueda en la colección de equipajes
-	return r.findBaggageTypes(context.Background(), bson.M{})
+	return r.findBaggageTypes(context.Background(), filter)
 }
 
 // Obtener el tipo de equipaje por nombre
@@ -165,36 +271,249 @@ func (r *BaggageRepository) findSingleBaggageType(ctx context.Context, filter bs
 	return &baggageType, nil
 }
 
-// AddBaggageToReservation agrega equipaje a una reserva existente
-func (r *BaggageRepository) AddBaggageToReservation(reservationID, baggageType string, quantity int) (*BaggageReservation, error) {
-	// Verificar si la reserva existe
+// AddBaggageToReservation agrega equipaje a una reserva existente.
+// Las piezas cubiertas por la franquicia de la reserva se registran sin costo y solo se cobra el exceso.
+// Las categorías especiales exigen sus documentos y declaración, quedan pendientes si requieren aprobación
+// y descuentan el cupo de la categoría en la salida.
+func (r *BaggageRepository) AddBaggageToReservation(req *AddBaggageRequest) (*BaggageReservation, error) {
+	// Obtener el precio unitario del tipo de equipaje
+	typeData, err := r.getBaggageTypeForSale(req.BaggageType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verificar las reglas de la categoría del tipo de equipaje
+	category, err := r.getCategory(typeData.Category)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCategoryRules(category, req); err != nil {
+		return nil, err
+	}
+
+	reservation, err := r.getReservationByID(req.BaggageReservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	line := Baggage{
+		ID:            uuid.New().String(),
+		Quantity:      req.Quantity,
+		Type:          req.BaggageType,
+		Weight:        req.Weight,
+		UnitPrice:     typeData.Price,
+		Category:      typeData.Category,
+		Status:        initialLineStatus(category),
+		Documents:     req.Documents,
+		Declaration:   req.Declaration,
+		DefaultWeight: typeData.Weight,
+		Volume:        typeData.Volume,
+		AddedAt:       time.Now(),
+	}
+
+	// Verificar las reglas de equipaje del operador de la reserva
+	if err := r.checkOperatorRules(reservation, nil, append(append([]Baggage(nil), reservation.Baggage...), line)); err != nil {
+		return nil, err
+	}
+
+	// Descontar la capacidad de la salida antes de registrar la línea.
+	// Si la bodega está llena y el cliente lo acepta, la línea queda en espera sin ocupar capacidad.
+	err = r.adjustLineCapacity(reservation, nil, &line)
+	if errors.Is(err, ErrHoldFull) && req.Waitlist {
+		line.Status = LineWaitlisted
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Agregar una línea no depende del estado previo, por lo que se reintenta ante escrituras concurrentes
+	for attempt := 0; attempt < maxWriteAttempts; attempt++ {
+		current := reservation
+		if attempt > 0 {
+			// Volver a leer la reserva para obtener su versión actual
+			if current, err = r.getReservationByID(req.BaggageReservationID); err != nil {
+				break
+			}
+		}
+
+		err = r.saveReservationBaggage(current, append(current.Baggage, line))
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			break
+		}
+
+		log.Printf("Equipaje agregado a la reserva %s: %d unidades de tipo %s", req.BaggageReservationID, req.Quantity, req.BaggageType)
+
+		// Obtener la reserva actualizada
+		return r.getReservationByID(req.BaggageReservationID)
+	}
+	if err == nil {
+		err = ErrVersionConflict
+	}
+
+	// Devolver la capacidad descontada si la línea no pudo registrarse
+	r.releaseLineCapacity(reservation, &line, nil)
+	return nil, err
+}
+
+// UpdateBaggageItem modifica la cantidad y el peso de una línea de equipaje.
+// La versión debe coincidir con la de la reserva para evitar sobrescribir cambios concurrentes.
+func (r *BaggageRepository) UpdateBaggageItem(reservationID, itemID string, quantity int, weight *float64, version int) (*BaggageReservation, error) {
 	reservation, err := r.getReservationByID(reservationID)
 	if err != nil {
 		return nil, err
 	}
-	if reservation == nil {
-		return nil, errors.New("reserva no encontrada")
+	if reservation.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	lines := append([]Baggage(nil), reservation.Baggage...)
+	index := findBaggageLine(lines, itemID)
+	if index < 0 {
+		return nil, ErrBaggageItemNotFound
+	}
+
+	// No se puede reducir la cantidad por debajo de las piezas ya etiquetadas. El etiquetado anota las piezas
+	// en la línea y cambia la versión, por lo que no puede etiquetar una pieza que esta modificación quite.
+	tagged, err := r.countItemPieces(reservationID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if quantity < max(tagged, lines[index].Tagged) {
+		return nil, ErrBaggageItemTagged
+	}
+
+	previous := lines[index]
+	lines[index].Quantity = quantity
+	if weight != nil {
+		lines[index].Weight = *weight
+	}
+
+	// Verificar las reglas de equipaje del operador de la reserva
+	if err := r.checkOperatorRules(reservation, nil, lines); err != nil {
+		return nil, err
+	}
+
+	// Ajustar la capacidad ocupada en la salida según la diferencia de piezas, peso y volumen
+	updated := lines[index]
+	if err := r.adjustLineCapacity(reservation, &previous, &updated); err != nil {
+		return nil, err
+	}
+
+	if err := r.saveReservationBaggage(reservation, lines); err != nil {
+		r.releaseLineCapacity(reservation, &updated, &previous)
+		return nil, err
+	}
+
+	// Las piezas ya etiquetadas llevan el peso declarado de la línea
+	if updated.Weight != previous.Weight {
+		if err := r.updatePiecesWeight(reservationID, itemID, updated.Weight); err != nil {
+			log.Printf("Error al actualizar el peso de las piezas de la línea %s: %v", itemID, err)
+		}
+	}
+
+	// Si la línea ocupa menos espacio, puede entrar equipaje en espera
+	_, weightBefore, volumeBefore := lineFootprint(&previous)
+	_, weightAfter, volumeAfter := lineFootprint(&updated)
+	if reservation.RouteID != "" && (weightAfter < weightBefore || volumeAfter < volumeBefore) {
+		r.promoteWaitlistedBaggage(reservation.RouteID)
 	}
 
-	// Calcular el precio total del equipaje
-	baggagePrice, err := r.calculateBaggagePrice(baggageType, quantity)
+	log.Printf("Línea de equipaje %s de la reserva %s actualizada a %d unidades", itemID, reservationID, quantity)
+	return r.getReservationByID(reservationID)
+}
+
+// RemoveBaggageItem elimina una línea de equipaje de la reserva.
+// La versión debe coincidir con la de la reserva para evitar sobrescribir cambios concurrentes.
+func (r *BaggageRepository) RemoveBaggageItem(reservationID, itemID string, version int) (*BaggageReservation, error) {
+	reservation, err := r.getReservationByID(reservationID)
 	if err != nil {
 		return nil, err
 	}
+	if reservation.Version != version {
+		return nil, ErrVersionConflict
+	}
 
-	// Actualizar la reserva con el equipaje agregado
-	err = r.updateReservationWithBaggage(reservationID, baggageType, quantity, baggagePrice)
+	index := findBaggageLine(reservation.Baggage, itemID)
+	if index < 0 {
+		return nil, ErrBaggageItemNotFound
+	}
+
+	// Una línea con piezas etiquetadas no puede eliminarse
+	tagged, err := r.countItemPieces(reservationID, itemID)
 	if err != nil {
 		return nil, err
 	}
+	if tagged > 0 || reservation.Baggage[index].Tagged > 0 {
+		return nil, ErrBaggageItemTagged
+	}
+
+	removed := reservation.Baggage[index]
+	lines := append(append([]Baggage(nil), reservation.Baggage[:index]...), reservation.Baggage[index+1:]...)
+	if err := r.saveReservationBaggage(reservation, lines); err != nil {
+		return nil, err
+	}
+
+	// Liberar la capacidad ocupada por la línea en la salida
+	r.releaseLineCapacity(reservation, &removed, nil)
+
+	log.Printf("Línea de equipaje %s eliminada de la reserva %s", itemID, reservationID)
+	return r.getReservationByID(reservationID)
+}
 
-	// Obtener la reserva actualizada
-	reservationUpdate, err := r.getReservationByID(reservationID)
+// ReviewBaggageItem aprueba o rechaza una línea de equipaje pendiente de aprobación.
+// Al rechazarla deja de cobrarse y libera el cupo de su categoría en la salida.
+func (r *BaggageRepository) ReviewBaggageItem(reservationID, itemID string, approved bool, version int) (*BaggageReservation, error) {
+	reservation, err := r.getReservationByID(reservationID)
 	if err != nil {
 		return nil, err
 	}
+	if reservation.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	lines := append([]Baggage(nil), reservation.Baggage...)
+	index := findBaggageLine(lines, itemID)
+	if index < 0 {
+		return nil, ErrBaggageItemNotFound
+	}
+	if lines[index].Status != LinePendingApproval {
+		return nil, ErrItemNotPending
+	}
+
+	previous := lines[index]
+	lines[index].Status = LineConfirmed
+	if !approved {
+		lines[index].Status = LineRejected
+	}
+
+	if err := r.saveReservationBaggage(reservation, lines); err != nil {
+		return nil, err
+	}
+
+	// Al rechazarla se libera su capacidad en la salida
+	r.releaseLineCapacity(reservation, &previous, &lines[index])
+
+	log.Printf("Línea de equipaje %s de la reserva %s: %s", itemID, reservationID, lines[index].Status)
+	return r.getReservationByID(reservationID)
+}
+
+// GetReservation obtiene una reserva de equipaje por su ID
+func (r *BaggageRepository) GetReservation(reservationID string) (*BaggageReservation, error) {
+	return r.getReservationByID(reservationID)
+}
 
-	return reservationUpdate, nil
+// findBaggageLine devuelve la posición de la línea con el ID dado, o -1 si no existe
+func findBaggageLine(lines []Baggage, itemID string) int {
+	for i, line := range lines {
+		if line.ID == itemID {
+			return i
+		}
+	}
+	return -1
 }
 
 // Obtener la reserva por su ID
@@ -212,7 +531,7 @@ func (r *BaggageRepository) getReservationByID(reservationID string) (*BaggageRe
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			// Reserva no encontrada
-			return nil, fmt.Errorf("reserva con ID %s no encontrada", reservationID)
+			return nil, fmt.Errorf("%w: %s", ErrReservationNotFound, reservationID)
 		}
 		// Otro tipo de error
 		return nil, fmt.Errorf("error al buscar la reserva con ID %s: %v", reservationID, err)
@@ -221,8 +540,33 @@ func (r *BaggageRepository) getReservationByID(reservationID string) (*BaggageRe
 	return &reservation, nil
 }
 
-// Actualizar la reserva con el equipaje agregado
-func (r *BaggageRepository) updateReservationWithBaggage(reservationID, baggageType string, quantity int, baggagePrice float64) error {
+// saveReservationBaggage guarda las líneas de equipaje recalculando el precio y el peso a partir de ellas.
+// La escritura solo se aplica si la reserva conserva la versión leída; en caso contrario devuelve ErrVersionConflict.
+func (r *BaggageRepository) saveReservationBaggage(reservation *BaggageReservation, lines []Baggage) error {
+	// Las líneas registradas antes de guardar el precio unitario toman el precio que tenía el tipo al agregarlas
+	for i := range lines {
+		if lines[i].ID == "" {
+			lines[i].ID = uuid.New().String()
+		}
+		if lines[i].UnitPrice == 0 {
+			baggageType, err := r.getBaggageTypeByName(lines[i].Type)
+			if err != nil {
+				return err
+			}
+			if baggageType != nil {
+				lines[i].UnitPrice = baggageType.priceAt(lines[i].AddedAt)
+			}
+		}
+	}
+
+	// Obtener la franquicia aplicable a la reserva
+	allowance, err := r.findAllowance(reservation)
+	if err != nil {
+		return err
+	}
+
+	price, weight := priceBaggageLines(allowance, lines)
+
 	// Contexto con timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -230,51 +574,1009 @@ func (r *BaggageRepository) updateReservationWithBaggage(reservationID, baggageT
 	// Colección de reservas de equipaje
 	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageReservationsCollection)
 
-	// Actualizar la reserva con el equipaje agregado
-	filter := bson.M{"_id": reservationID}
+	filter := versionFilter(reservation.ID, reservation.Version)
+	update := bson.M{
+		"$set": bson.M{
+			"baggage":   lines,
+			"price":     price,
+			"weight":    weight,
+			"allowance": newAllowanceUsage(allowance, lines),
+		},
+		"$inc": bson.M{"version": 1},
+	}
 
-	// Obtener la reserva existente
-	var existingReservation BaggageReservation
-	err := collection.FindOne(ctx, filter).Decode(&existingReservation)
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
 
-	// Combinar el equipaje existente con el nuevo equipaje
-	updatedBaggage := append(existingReservation.Baggage, Baggage{Quantity: quantity, Type: baggageType})
+// versionFilter selecciona la reserva de equipaje solo si sigue en la versión leída. Las reservas creadas antes
+// del control de versiones no tienen el campo y se tratan como versión 0.
+func versionFilter(reservationID string, version int) bson.M {
+	if version == 0 {
+		return bson.M{"_id": reservationID, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": reservationID, "version": version}
+}
 
-	update := bson.M{
-		"$set": bson.M{"baggage": updatedBaggage},
-		"$inc": bson.M{
-			"price":  baggagePrice,
-			"weight": float64(quantity), // Agregar el peso del equipaje
-		},
+// getBaggageTypeForSale obtiene un tipo de equipaje que pueda venderse en nuevas líneas
+func (r *BaggageRepository) getBaggageTypeForSale(name string) (*BaggageType, error) {
+	baggageType, err := r.getBaggageTypeByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if baggageType == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBaggageTypeNotFound, name)
+	}
+	if !baggageType.Active {
+		return nil, fmt.Errorf("%w: %s", ErrBaggageTypeInactive, name)
+	}
+
+	return baggageType, nil
+}
+
+// QuoteBaggage cotiza una canasta de líneas de equipaje con su desglose por línea.
+// Si la solicitud indica una reserva de equipaje, se cotiza como equipaje adicional a sus líneas actuales;
+// si indica clase tarifaria, ruta u operador, se aplica la franquicia correspondiente.
+func (r *BaggageRepository) QuoteBaggage(req *QuoteRequest) (*BaggageQuote, error) {
+	reservation := &BaggageReservation{FareClass: req.FareClass, RouteID: req.RouteID, OperatorID: req.OperatorID}
+	if req.BaggageReservationID != "" {
+		existing, err := r.getReservationByID(req.BaggageReservationID)
+		if err != nil {
+			return nil, err
+		}
+		reservation = existing
+	}
+
+	// Las líneas cotizadas se agregan después de las ya registradas en la reserva
+	lines := append([]Baggage(nil), reservation.Baggage...)
+	offset := len(lines)
+	for _, item := range req.Lines {
+		baggageType, err := r.getBaggageTypeForSale(item.BaggageType)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, Baggage{Quantity: item.Quantity, Type: item.BaggageType, Weight: item.Weight, UnitPrice: baggageType.Price})
+	}
+
+	allowance, err := r.findAllowance(reservation)
+	if err != nil {
+		return nil, err
+	}
+	priceBaggageLines(allowance, lines)
+
+	return newBaggageQuote(allowance, lines, offset), nil
+}
+
+// CreateAllowance registra una nueva franquicia de equipaje y devuelve su ID
+func (r *BaggageRepository) CreateAllowance(allowance *BaggageAllowance) (string, error) {
+	// Generar un nuevo ID único UUID
+	allowance.ID = uuid.New().String()
+
+	// Contexto con timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Colección de franquicias de equipaje
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageAllowancesCollection)
+
+	_, err := collection.InsertOne(ctx, allowance)
+	if err != nil {
+		return "", err
+	}
+
+	return allowance.ID, nil
+}
+
+// GetAllowances obtiene las franquicias de equipaje, opcionalmente filtradas por clase tarifaria
+func (r *BaggageRepository) GetAllowances(fareClass string) ([]*BaggageAllowance, error) {
+	filter := bson.M{}
+	if fareClass != "" {
+		filter["fare_class"] = fareClass
+	}
+	return r.findAllowances(context.Background(), filter)
+}
+
+// findAllowance obtiene la franquicia más específica aplicable a la reserva, por todos sus pasajes, o nil si no tiene
+func (r *BaggageRepository) findAllowance(reservation *BaggageReservation) (*BaggageAllowance, error) {
+	if reservation.FareClass == "" {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"fare_class":  reservation.FareClass,
+		"route_id":    bson.M{"$in": bson.A{reservation.RouteID, nil}},
+		"operator_id": bson.M{"$in": bson.A{reservation.OperatorID, nil}},
+	}
+
+	allowances, err := r.findAllowances(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return selectAllowance(allowances, reservation).forTickets(reservation.Tickets), nil
+}
+
+// Función helper para buscar franquicias de equipaje
+func (r *BaggageRepository) findAllowances(ctx context.Context, filter bson.M) ([]*BaggageAllowance, error) {
+	// Colección de franquicias de equipaje
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageAllowancesCollection)
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var allowances []*BaggageAllowance
+	for cursor.Next(ctx) {
+		var allowance BaggageAllowance
+		if err := cursor.Decode(&allowance); err != nil {
+			return nil, err
+		}
+		allowances = append(allowances, &allowance)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return allowances, nil
+}
+
+// EnsureIndexes crea los índices requeridos por las colecciones del servicio de equipaje
+func (r *BaggageRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Los tipos de equipaje registrados antes de poder desactivarlos se consideran activos
+	types := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageTypesCollection)
+	_, err := types.UpdateMany(ctx, bson.M{"active": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"active": true}})
+	if err != nil {
+		return err
+	}
+
+	// Nombre único por tipo de equipaje. Con nombres duplicados de versiones anteriores el índice no se puede
+	// crear: el servicio sigue funcionando, y CreateBaggageType verifica el nombre, hasta que se depuren con
+	// scripts/dedupeBaggageTypes.go
+	_, err = types.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		log.Printf("No se creó el índice único de tipos de equipaje porque hay nombres duplicados; ejecute scripts/dedupeBaggageTypes.go: %v", err)
+	} else if err != nil {
+		return fmt.Errorf("error al crear el índice único de tipos de equipaje: %w", err)
 	}
 
-	_, err = collection.UpdateOne(ctx, filter, update)
+	// Una pieza por posición dentro de cada línea, para que el etiquetado concurrente no duplique piezas
+	pieces := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggagePiecesCollection)
+	_, err = pieces.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "baggage_reservation_id", Value: 1}, {Key: "item_id", Value: 1}, {Key: "piece_number", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "reservation_id", Value: 1}}},
+	})
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Equipaje agregado a la reserva %s: %d unidades de tipo %s", reservationID, quantity, baggageType)
-	return nil
+	// Historial de seguimiento por pieza en orden cronológico
+	events := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageEventsCollection)
+	_, err = events.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tag_number", Value: 1}, {Key: "occurred_at", Value: 1}},
+	})
+	return err
 }
 
-// CalculateBaggagePrice calcula el precio total del equipaje en función del tipo y la cantidad
-func (r *BaggageRepository) calculateBaggagePrice(baggageType string, quantity int) (float64, error) {
-	ctx := context.Background()
-	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageTypesCollection)
+// TagBaggage asigna un número de etiqueta único a cada pieza de la reserva que aún no lo tenga
+// y devuelve todas las piezas etiquetadas de la reserva. Es idempotente. Cada pieza se anota antes
+// en su línea con una actualización condicionada a la cantidad vigente, para no etiquetar piezas
+// que una modificación concurrente haya quitado.
+func (r *BaggageRepository) TagBaggage(baggageReservationID string) ([]*BaggagePiece, error) {
+	reservation, err := r.getReservationByID(baggageReservationID)
+	if err != nil {
+		return nil, err
+	}
 
-	var baggageTypeData BaggageType
-	err := collection.FindOne(ctx, bson.M{"name": baggageType}).Decode(&baggageTypeData)
+	existing, err := r.GetPiecesByReservation(baggageReservationID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			log.Printf("Tipo de equipaje %s no encontrado", baggageType)
-			return 0, nil
+		return nil, err
+	}
+
+	tagged := make(map[string]bool)
+	for _, piece := range existing {
+		tagged[fmt.Sprintf("%s/%d", piece.ItemID, piece.PieceNumber)] = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Colección de piezas de equipaje
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggagePiecesCollection)
+
+	for _, line := range reservation.Baggage {
+		for n := 1; n <= line.Quantity; n++ {
+			if line.ID == "" || !isLineConfirmed(line) || tagged[fmt.Sprintf("%s/%d", line.ID, n)] {
+				continue
+			}
+
+			current, err := r.claimPiece(ctx, reservation.ID, line.ID, n)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				// La línea se redujo, se quitó o dejó de estar confirmada
+				continue
+			}
+
+			tagNumber, err := r.nextTagNumber(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			piece := &BaggagePiece{
+				TagNumber:            tagNumber,
+				BaggageReservationID: reservation.ID,
+				ReservationID:        reservation.ReservationID,
+				RouteID:              reservation.RouteID,
+				ItemID:               line.ID,
+				PieceNumber:          n,
+				Type:                 current.Type,
+				Weight:               current.Weight,
+				CreatedAt:            time.Now(),
+			}
+
+			_, err = collection.InsertOne(ctx, piece)
+			if mongo.IsDuplicateKeyError(err) {
+				// Otra solicitud etiquetó la pieza al mismo tiempo
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			log.Printf("Pieza %d de la línea %s etiquetada con %s", n, line.ID, tagNumber)
+		}
+	}
+
+	return r.GetPiecesByReservation(baggageReservationID)
+}
+
+// GetPieceByTag obtiene una pieza de equipaje por su número de etiqueta
+func (r *BaggageRepository) GetPieceByTag(tagNumber string) (*BaggagePiece, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggagePiecesCollection)
+
+	var piece BaggagePiece
+	err := collection.FindOne(ctx, bson.M{"_id": tagNumber}).Decode(&piece)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: %s", ErrPieceNotFound, tagNumber)
 		}
-		return 0, err
+		return nil, err
 	}
 
-	totalPrice := float64(quantity) * baggageTypeData.Price
-	return totalPrice, nil
+	return &piece, nil
+}
+
+// GetPiecesByReservation obtiene las piezas etiquetadas de una reserva de equipaje
+func (r *BaggageRepository) GetPiecesByReservation(baggageReservationID string) ([]*BaggagePiece, error) {
+	return r.findPieces(context.Background(), bson.M{"baggage_reservation_id": baggageReservationID})
+}
+
+// claimPiece anota en la línea que su pieza n se etiqueta, solo si la línea sigue confirmada y tiene al menos n piezas,
+// y cambia la versión de la reserva para que las modificaciones basadas en una lectura anterior se rechacen.
+// Devuelve la línea vigente, o nil si la pieza ya no puede etiquetarse.
+func (r *BaggageRepository) claimPiece(ctx context.Context, baggageReservationID, itemID string, n int) (*Baggage, error) {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageReservationsCollection)
+
+	filter := bson.M{
+		"_id": baggageReservationID,
+		"baggage": bson.M{"$elemMatch": bson.M{
+			"id":       itemID,
+			"quantity": bson.M{"$gte": n},
+			"status":   bson.M{"$in": bson.A{"", nil, LineConfirmed}},
+		}},
+	}
+	update := bson.M{
+		"$max": bson.M{"baggage.$.tagged": n},
+		"$inc": bson.M{"version": 1},
+	}
+
+	var reservation BaggageReservation
+	err := collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := findBaggageLine(reservation.Baggage, itemID)
+	if index < 0 {
+		return nil, nil
+	}
+	return &reservation.Baggage[index], nil
+}
+
+// updatePiecesWeight actualiza el peso de las piezas etiquetadas de una línea de equipaje
+func (r *BaggageRepository) updatePiecesWeight(baggageReservationID, itemID string, weight float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggagePiecesCollection)
+
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"baggage_reservation_id": baggageReservationID, "item_id": itemID},
+		bson.M{"$set": bson.M{"weight": weight}})
+	return err
+}
+
+// countItemPieces cuenta las piezas etiquetadas de una línea de equipaje
+func (r *BaggageRepository) countItemPieces(baggageReservationID, itemID string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggagePiecesCollection)
+
+	count, err := collection.CountDocuments(ctx, bson.M{"baggage_reservation_id": baggageReservationID, "item_id": itemID})
+	return int(count), err
+}
+
+// nextTagNumber obtiene el siguiente número de etiqueta a partir de un contador atómico
+func (r *BaggageRepository) nextTagNumber(ctx context.Context) (string, error) {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.CountersCollection)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": "baggage_tag"},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%08d", tagPrefix, counter.Seq), nil
+}
+
+// Función helper para buscar piezas de equipaje ordenadas por línea y número de pieza
+func (r *BaggageRepository) findPieces(ctx context.Context, filter bson.M) ([]*BaggagePiece, error) {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggagePiecesCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "piece_number", Value: 1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var pieces []*BaggagePiece
+	for cursor.Next(ctx) {
+		var piece BaggagePiece
+		if err := cursor.Decode(&piece); err != nil {
+			return nil, err
+		}
+		pieces = append(pieces, &piece)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return pieces, nil
+}
+
+// RecordTrackingEvent registra el escaneo de una pieza validando la transición desde su estado actual.
+// El evento, con su ID asignado de antemano, se inserta primero en el historial y luego se actualiza el estado
+// de la pieza de forma condicional, para que dos escaneos simultáneos no se pisen. Si la actualización no se
+// aplica, el evento se elimina: un escaneo rechazado no deja eventos y una pieza nunca queda en un estado sin
+// su evento.
+func (r *BaggageRepository) RecordTrackingEvent(event *TrackingEvent) (*TrackingEvent, error) {
+	if !isTrackingStatus(event.Status) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTrackingStatus, event.Status)
+	}
+
+	piece, err := r.GetPieceByTag(event.TagNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(piece.Status, event.Status) {
+		return nil, fmt.Errorf("%w: de %q a %q", ErrInvalidTransition, piece.Status, event.Status)
+	}
+
+	event.ID = uuid.New().String()
+	event.BaggageReservationID = piece.BaggageReservationID
+	event.ReservationID = piece.ReservationID
+	event.OccurredAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageEventsCollection)
+	if _, err := events.InsertOne(ctx, event); err != nil {
+		return nil, err
+	}
+
+	// Actualizar el estado de la pieza solo si no cambió desde la lectura
+	pieces := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggagePiecesCollection)
+	filter := bson.M{"_id": piece.TagNumber, "status": piece.Status}
+	if piece.Status == "" {
+		filter["status"] = bson.M{"$in": bson.A{"", nil}}
+	}
+	result, err := pieces.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"status":        event.Status,
+		"location":      event.Location,
+		"last_event_id": event.ID,
+		"updated_at":    event.OccurredAt,
+	}})
+	if err == nil && result.MatchedCount == 0 {
+		err = fmt.Errorf("%w: la pieza fue escaneada por otra solicitud", ErrInvalidTransition)
+	}
+	if err != nil {
+		// Retirar el evento del escaneo que no se aplicó
+		if _, deleteErr := events.DeleteOne(ctx, bson.M{"_id": event.ID}); deleteErr != nil {
+			log.Printf("Error al eliminar el evento %s del escaneo no aplicado de la pieza %s: %v", event.ID, event.TagNumber, deleteErr)
+		}
+		return nil, err
+	}
+
+	log.Printf("Pieza %s escaneada: %s en %s por %s", event.TagNumber, event.Status, event.Location, event.StaffID)
+	return event, nil
+}
+
+// GetTrackingHistory obtiene el historial de eventos de una pieza en orden cronológico
+func (r *BaggageRepository) GetTrackingHistory(tagNumber string) ([]*TrackingEvent, error) {
+	// Verificar que la pieza exista
+	if _, err := r.GetPieceByTag(tagNumber); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageEventsCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "occurred_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"tag_number": tagNumber}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []*TrackingEvent{}
+	for cursor.Next(ctx) {
+		var event TrackingEvent
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetPieceStatusesByReservation obtiene el estado de las piezas asociadas a una reserva de pasaje
+func (r *BaggageRepository) GetPieceStatusesByReservation(reservationID string) ([]*PieceStatus, error) {
+	pieces, err := r.findPieces(context.Background(), bson.M{"reservation_id": reservationID})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []*PieceStatus{}
+	for _, piece := range pieces {
+		statuses = append(statuses, &PieceStatus{
+			TagNumber: piece.TagNumber,
+			Type:      piece.Type,
+			Status:    piece.Status,
+			Location:  piece.Location,
+			UpdatedAt: piece.UpdatedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// CreateBaggageType registra un nuevo tipo de equipaje activo con su precio inicial en el historial
+func (r *BaggageRepository) CreateBaggageType(baggageType *BaggageType) (*BaggageType, error) {
+	if err := validateBaggageTypePrice(baggageType.Price); err != nil {
+		return nil, err
+	}
+	if _, err := r.getCategory(baggageType.Category); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	baggageType.ID = uuid.New().String()
+	baggageType.Active = true
+	baggageType.PriceHistory = []PriceChange{{Price: baggageType.Price, EffectiveFrom: now}}
+	baggageType.CreatedAt = now
+	baggageType.UpdatedAt = now
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageTypesCollection)
+
+	// El índice único rechaza los nombres repetidos; esta verificación cubre las bases donde aún no se pudo crear
+	existing, err := r.getBaggageTypeByName(baggageType.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBaggageTypeExists, baggageType.Name)
+	}
+
+	_, err = collection.InsertOne(ctx, baggageType)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("%w: %s", ErrBaggageTypeExists, baggageType.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return baggageType, nil
+}
+
+// UpdateBaggageTypePrice cambia el precio de un tipo de equipaje y lo agrega a su historial.
+// Las reservas existentes conservan el precio unitario con el que se vendieron.
+func (r *BaggageRepository) UpdateBaggageTypePrice(baggageTypeID string, price float64) (*BaggageType, error) {
+	if err := validateBaggageTypePrice(price); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return r.updateBaggageType(baggageTypeID, bson.M{
+		"$set":  bson.M{"price": price, "updated_at": now},
+		"$push": bson.M{"price_history": PriceChange{Price: price, EffectiveFrom: now}},
+	})
+}
+
+// DeactivateBaggageType desactiva un tipo de equipaje para que no pueda agregarse a nuevas reservas
+func (r *BaggageRepository) DeactivateBaggageType(baggageTypeID string) (*BaggageType, error) {
+	return r.updateBaggageType(baggageTypeID, bson.M{
+		"$set": bson.M{"active": false, "updated_at": time.Now()},
+	})
+}
+
+// updateBaggageType aplica la actualización al tipo de equipaje y devuelve el documento actualizado
+func (r *BaggageRepository) updateBaggageType(baggageTypeID string, update bson.M) (*BaggageType, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageTypesCollection)
+
+	var baggageType BaggageType
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": baggageTypeID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&baggageType)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: %s", ErrBaggageTypeNotFound, baggageTypeID)
+		}
+		return nil, err
+	}
+
+	log.Printf("Tipo de equipaje %s actualizado", baggageType.Name)
+	return &baggageType, nil
+}
+
+// SaveCategory registra o actualiza una categoría de equipaje especial
+func (r *BaggageRepository) SaveCategory(category *BaggageCategory) (*BaggageCategory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageCategoriesCollection)
+
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": category.Code}, category, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// GetCategories obtiene todas las categorías de equipaje especial
+func (r *BaggageRepository) GetCategories() ([]*BaggageCategory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageCategoriesCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categories := []*BaggageCategory{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// GetCategoryUsage obtiene las piezas de cada categoría con cupo registradas en una salida
+func (r *BaggageRepository) GetCategoryUsage(routeID string) ([]*CategoryUsage, error) {
+	categories, err := r.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.CategoryUsageCollection)
+
+	usages := []*CategoryUsage{}
+	for _, category := range categories {
+		if category.MaxPerDeparture == 0 {
+			continue
+		}
+
+		usage := CategoryUsage{RouteID: routeID, Category: category.Code}
+		err := collection.FindOne(ctx, bson.M{"_id": categoryUsageID(routeID, category.Code)}).Decode(&usage)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		usage.Max = category.MaxPerDeparture
+		usages = append(usages, &usage)
+	}
+
+	return usages, nil
+}
+
+// getCategory obtiene la categoría de equipaje por su código, o nil si el código está vacío o es el estándar.
+// Un código que no está registrado devuelve ErrCategoryNotFound: no se trata como equipaje estándar.
+func (r *BaggageRepository) getCategory(code string) (*BaggageCategory, error) {
+	if code == "" || code == CategoryStandard {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageCategoriesCollection)
+
+	var category BaggageCategory
+	err := collection.FindOne(ctx, bson.M{"_id": code}).Decode(&category)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, code)
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// categoryUsageID compone el ID del contador de piezas de una categoría en una salida
+func categoryUsageID(routeID, category string) string {
+	return routeID + "|" + category
+}
+
+// adjustLineCapacity ajusta la capacidad ocupada en la salida al pasar la línea del estado before al estado after.
+// Un puntero nil representa la ausencia de la línea. Los aumentos se descuentan de forma atómica y fallan si no hay
+// cupo de la categoría o espacio en la bodega; las disminuciones siempre se aplican.
+func (r *BaggageRepository) adjustLineCapacity(reservation *BaggageReservation, before, after *Baggage) error {
+	piecesBefore, weightBefore, volumeBefore := lineFootprint(before)
+	piecesAfter, weightAfter, volumeAfter := lineFootprint(after)
+
+	categoryCode := ""
+	if after != nil {
+		categoryCode = after.Category
+	} else if before != nil {
+		categoryCode = before.Category
+	}
+
+	routeID := reservation.RouteID
+	if err := r.adjustCategoryUsage(routeID, categoryCode, piecesAfter-piecesBefore); err != nil {
+		return err
+	}
+
+	// Sin salida asignada no hay bodega que controlar
+	if routeID == "" {
+		return nil
+	}
+
+	if err := r.adjustHoldUsage(routeID, weightAfter-weightBefore, volumeAfter-volumeBefore); err != nil {
+		// Revertir el cupo de la categoría descontado
+		if revertErr := r.adjustCategoryUsage(routeID, categoryCode, piecesBefore-piecesAfter); revertErr != nil {
+			log.Printf("Error al revertir el cupo de la categoría %s en la ruta %s: %v", categoryCode, routeID, revertErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// adjustCategoryUsage suma o resta piezas al contador de la categoría en la salida.
+// Los aumentos solo se aplican si queda cupo en la categoría.
+func (r *BaggageRepository) adjustCategoryUsage(routeID, categoryCode string, delta int) error {
+	if delta == 0 || categoryCode == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.CategoryUsageCollection)
+	id := categoryUsageID(routeID, categoryCode)
+
+	// Liberar piezas sin dejar el contador en negativo
+	if delta < 0 {
+		if routeID == "" {
+			return nil
+		}
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": id, "used": bson.M{"$gte": -delta}},
+			bson.M{"$inc": bson.M{"used": delta}},
+		)
+		return err
+	}
+
+	category, err := r.getCategory(categoryCode)
+	if err != nil {
+		return err
+	}
+	if category == nil || category.MaxPerDeparture == 0 {
+		return nil
+	}
+	if routeID == "" {
+		return ErrRouteRequired
+	}
+	if delta > category.MaxPerDeparture {
+		return fmt.Errorf("%w: %s", ErrCategoryCapacity, category.Name)
+	}
+
+	// Crear el contador de la salida si no existe
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$setOnInsert": bson.M{"route_id": routeID, "category": category.Code, "used": 0}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	// Incrementar solo si queda cupo suficiente
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "used": bson.M{"$lte": category.MaxPerDeparture - delta}},
+		bson.M{"$inc": bson.M{"used": delta}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrCategoryCapacity, category.Name)
+	}
+
+	return nil
+}
+
+// adjustHoldUsage suma o resta peso y volumen a la bodega de la salida.
+// Los aumentos solo se aplican si la carga resultante no supera la capacidad.
+func (r *BaggageRepository) adjustHoldUsage(routeID string, weightDelta, volumeDelta float64) error {
+	if weightDelta == 0 && volumeDelta == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.ensureHold(ctx, routeID); err != nil {
+		return err
+	}
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageHoldsCollection)
+
+	result, err := collection.UpdateOne(ctx, holdUsageFilter(routeID, weightDelta, volumeDelta), bson.M{
+		"$inc": bson.M{"used_weight": weightDelta, "used_volume": volumeDelta},
+		"$set": bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrHoldFull
+	}
+
+	return nil
+}
+
+// ensureHold crea la bodega de la salida con la capacidad por defecto si aún no existe
+func (r *BaggageRepository) ensureHold(ctx context.Context, routeID string) error {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageHoldsCollection)
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": routeID},
+		bson.M{"$setOnInsert": bson.M{
+			"weight_capacity": r.config.Baggage.DefaultHoldWeight,
+			"volume_capacity": r.config.Baggage.DefaultHoldVolume,
+			"used_weight":     0.0,
+			"used_volume":     0.0,
+			"updated_at":      time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ConfigureHold establece la capacidad de la bodega de una salida.
+// La capacidad no puede ser menor a la carga ya registrada.
+func (r *BaggageRepository) ConfigureHold(routeID string, weightCapacity, volumeCapacity float64) (*CargoHold, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.ensureHold(ctx, routeID); err != nil {
+		return nil, err
+	}
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageHoldsCollection)
+
+	var hold CargoHold
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": routeID, "used_weight": bson.M{"$lte": weightCapacity}, "used_volume": bson.M{"$lte": volumeCapacity}},
+		bson.M{"$set": bson.M{"weight_capacity": weightCapacity, "volume_capacity": volumeCapacity, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&hold)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrHoldBelowUsage
+		}
+		return nil, err
+	}
+
+	// Con más capacidad pueden entrar piezas en espera
+	r.promoteWaitlistedBaggage(routeID)
+
+	log.Printf("Bodega de la ruta %s configurada: %.1f kg, %.2f m³", routeID, weightCapacity, volumeCapacity)
+	return &hold, nil
+}
+
+// GetHoldLoadReport obtiene el reporte de carga de la bodega de una salida
+func (r *BaggageRepository) GetHoldLoadReport(routeID string) (*HoldLoadReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.ensureHold(ctx, routeID); err != nil {
+		return nil, err
+	}
+
+	holds := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageHoldsCollection)
+	var hold CargoHold
+	if err := holds.FindOne(ctx, bson.M{"_id": routeID}).Decode(&hold); err != nil {
+		return nil, err
+	}
+
+	reservations, err := r.findReservations(ctx, bson.M{"route_id": routeID})
+	if err != nil {
+		return nil, err
+	}
+
+	return buildHoldLoadReport(&hold, reservations), nil
+}
+
+// promoteWaitlistedBaggage confirma, en orden de llegada, las líneas en espera de la salida que ahora caben en la bodega.
+// Las líneas que aún no caben se mantienen en espera sin bloquear a las siguientes.
+func (r *BaggageRepository) promoteWaitlistedBaggage(routeID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reservations, err := r.findReservations(ctx, bson.M{"route_id": routeID, "baggage.status": LineWaitlisted})
+	if err != nil {
+		log.Printf("Error al buscar equipaje en espera de la ruta %s: %v", routeID, err)
+		return
+	}
+
+	type waitlisted struct {
+		reservation *BaggageReservation
+		line        Baggage
+	}
+	var queue []waitlisted
+	for _, reservation := range reservations {
+		for _, line := range reservation.Baggage {
+			if line.Status == LineWaitlisted {
+				queue = append(queue, waitlisted{reservation: reservation, line: line})
+			}
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].line.AddedAt.Before(queue[j].line.AddedAt) })
+
+	for _, item := range queue {
+		category, err := r.getCategory(item.line.Category)
+		if err != nil {
+			log.Printf("Error al obtener la categoría %s: %v", item.line.Category, err)
+			continue
+		}
+
+		promoted := item.line
+		promoted.Status = initialLineStatus(category)
+		if err := r.adjustLineCapacity(item.reservation, &item.line, &promoted); err != nil {
+			continue
+		}
+
+		// La reserva pudo cambiar desde la lectura; se vuelve a leer antes de guardar
+		current, err := r.getReservationByID(item.reservation.ID)
+		if err == nil {
+			lines := append([]Baggage(nil), current.Baggage...)
+			if index := findBaggageLine(lines, item.line.ID); index >= 0 && lines[index].Status == LineWaitlisted {
+				lines[index].Status = promoted.Status
+				err = r.saveReservationBaggage(current, lines)
+			} else {
+				err = ErrBaggageItemNotFound
+			}
+		}
+		if err != nil {
+			// Devolver la capacidad si la línea no pudo confirmarse
+			if revertErr := r.adjustLineCapacity(item.reservation, &promoted, &item.line); revertErr != nil {
+				log.Printf("Error al revertir la capacidad de la línea %s: %v", item.line.ID, revertErr)
+			}
+			continue
+		}
+
+		log.Printf("Línea de equipaje %s de la reserva %s sale de espera: %s", item.line.ID, item.reservation.ID, promoted.Status)
+	}
+}
+
+// Función helper para buscar reservas de equipaje
+func (r *BaggageRepository) findReservations(ctx context.Context, filter bson.M) ([]*BaggageReservation, error) {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BaggageReservationsCollection)
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []*BaggageReservation
+	for cursor.Next(ctx) {
+		var reservation BaggageReservation
+		if err := cursor.Decode(&reservation); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, &reservation)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// releaseLineCapacity devuelve a la salida la capacidad que ocupaba la línea y confirma el equipaje en espera que ahora quepa
+func (r *BaggageRepository) releaseLineCapacity(reservation *BaggageReservation, before, after *Baggage) {
+	if err := r.adjustLineCapacity(reservation, before, after); err != nil {
+		log.Printf("Error al liberar capacidad en la ruta %s: %v", reservation.RouteID, err)
+		return
+	}
+	if reservation.RouteID != "" {
+		r.promoteWaitlistedBaggage(reservation.RouteID)
+	}
 }
@@ -0,0 +1,378 @@
+package baggage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"venta-de-pasajes/internal/testutil"
+)
+
+// newTestRepository crea un repositorio sobre una base de datos de prueba que se elimina al terminar.
+// Las pruebas se omiten si MONGO_TEST_URL no está definida.
+func newTestRepository(t *testing.T) *BaggageRepository {
+	t.Helper()
+
+	repo, err := NewRepository(testutil.MongoConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.client.Disconnect(context.Background()) })
+
+	if err := repo.EnsureIndexes(); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// createTestBaggageReservation registra una salida sin operador, una reserva de pasaje en ella, el tipo de
+// equipaje "maleta" y una reserva de equipaje con una línea de dos maletas de 20 kg
+func createTestBaggageReservation(t *testing.T, repo *BaggageRepository) *BaggageReservation {
+	t.Helper()
+	ctx := context.Background()
+	db := repo.client.Database(repo.config.MongoDB.DatabaseName)
+
+	routeID := uuid.New().String()
+	if _, err := db.Collection(repo.config.MongoDB.RoutesCollection).InsertOne(ctx, bson.M{"_id": routeID}); err != nil {
+		t.Fatal(err)
+	}
+	reservationID := uuid.New().String()
+	if _, err := db.Collection(repo.config.MongoDB.ReservationsCollection).InsertOne(ctx, bson.M{"_id": reservationID, "route_id": routeID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateBaggageType(&BaggageType{Name: "maleta", Price: 25, Weight: 20, Volume: 0.1}); err != nil && !errors.Is(err, ErrBaggageTypeExists) {
+		t.Fatal(err)
+	}
+
+	baggageReservationID, err := repo.CreateReservation(&BaggageReservation{ReservationID: reservationID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation, err := repo.AddBaggageToReservation(&AddBaggageRequest{
+		BaggageReservationID: baggageReservationID,
+		BaggageType:          "maleta",
+		Quantity:             2,
+		Weight:               20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reservation
+}
+
+func TestVersionFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		want    bson.M
+	}{
+		{
+			name: "reserva sin el campo de versión", version: 0,
+			want: bson.M{"_id": "equipaje-1", "version": bson.M{"$in": bson.A{0, nil}}},
+		},
+		{name: "reserva versionada", version: 3, want: bson.M{"_id": "equipaje-1", "version": 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionFilter("equipaje-1", tt.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versionFilter() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateBaggageItemRejectsStaleVersion(t *testing.T) {
+	repo := newTestRepository(t)
+	reservation := createTestBaggageReservation(t, repo)
+	itemID := reservation.Baggage[0].ID
+
+	updated, err := repo.UpdateBaggageItem(reservation.ID, itemID, 3, nil, reservation.Version)
+	if err != nil {
+		t.Fatalf("UpdateBaggageItem() con la versión vigente: %v", err)
+	}
+	if updated.Version != reservation.Version+1 || updated.Baggage[0].Quantity != 3 {
+		t.Errorf("reserva = versión %d con %d piezas, se esperaba versión %d con 3 piezas", updated.Version, updated.Baggage[0].Quantity, reservation.Version+1)
+	}
+	if updated.Price != 75 {
+		t.Errorf("precio = %v, se esperaba 75", updated.Price)
+	}
+
+	// Una modificación basada en la lectura anterior no pisa la anterior
+	if _, err := repo.UpdateBaggageItem(reservation.ID, itemID, 1, nil, reservation.Version); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("UpdateBaggageItem() con una versión anterior: error = %v, se esperaba ErrVersionConflict", err)
+	}
+	if _, err := repo.RemoveBaggageItem(reservation.ID, itemID, reservation.Version); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("RemoveBaggageItem() con una versión anterior: error = %v, se esperaba ErrVersionConflict", err)
+	}
+
+	current, err := repo.GetReservation(reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != updated.Version || len(current.Baggage) != 1 || current.Baggage[0].Quantity != 3 {
+		t.Errorf("reserva = %+v, las modificaciones rechazadas no deben aplicarse", *current)
+	}
+}
+
+func TestRemoveBaggageItemReleasesHold(t *testing.T) {
+	repo := newTestRepository(t)
+	reservation := createTestBaggageReservation(t, repo)
+
+	report, err := repo.GetHoldLoadReport(reservation.RouteID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UsedWeight != 40 {
+		t.Fatalf("peso en bodega = %v, se esperaba 40", report.UsedWeight)
+	}
+
+	removed, err := repo.RemoveBaggageItem(reservation.ID, reservation.Baggage[0].ID, reservation.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed.Baggage) != 0 || removed.Price != 0 {
+		t.Errorf("reserva = %+v, se esperaba sin líneas ni precio", *removed)
+	}
+
+	report, err = repo.GetHoldLoadReport(reservation.RouteID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UsedWeight != 0 {
+		t.Errorf("peso en bodega = %v, se esperaba 0 al quitar la línea", report.UsedWeight)
+	}
+}
+
+func TestTagBaggageIsIdempotent(t *testing.T) {
+	repo := newTestRepository(t)
+	reservation := createTestBaggageReservation(t, repo)
+
+	pieces, err := repo.TagBaggage(reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pieces) != 2 {
+		t.Fatalf("piezas = %d, se esperaba una por maleta (2)", len(pieces))
+	}
+	if pieces[0].TagNumber == pieces[1].TagNumber || !strings.HasPrefix(pieces[0].TagNumber, tagPrefix) {
+		t.Errorf("etiquetas = %s y %s, se esperaban distintas y con el prefijo %s", pieces[0].TagNumber, pieces[1].TagNumber, tagPrefix)
+	}
+
+	again, err := repo.TagBaggage(reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 2 || again[0].TagNumber != pieces[0].TagNumber || again[1].TagNumber != pieces[1].TagNumber {
+		t.Errorf("volver a etiquetar devolvió %d piezas, se esperaban las mismas 2", len(again))
+	}
+
+	// Etiquetar cambia la versión, por lo que no se puede quitar una pieza etiquetada con la lectura anterior
+	current, err := repo.GetReservation(reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.UpdateBaggageItem(reservation.ID, reservation.Baggage[0].ID, 1, nil, current.Version); !errors.Is(err, ErrBaggageItemTagged) {
+		t.Errorf("reducir por debajo de las piezas etiquetadas: error = %v, se esperaba ErrBaggageItemTagged", err)
+	}
+}
+
+func TestRecordTrackingEventKeepsHistoryAppendOnly(t *testing.T) {
+	repo := newTestRepository(t)
+	reservation := createTestBaggageReservation(t, repo)
+
+	pieces, err := repo.TagBaggage(reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagNumber := pieces[0].TagNumber
+
+	checkedIn, err := repo.RecordTrackingEvent(&TrackingEvent{TagNumber: tagNumber, Status: TrackingCheckedIn, Location: "LIM", StaffID: "staff-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Un escaneo con una transición no permitida no cambia la pieza ni deja eventos
+	if _, err := repo.RecordTrackingEvent(&TrackingEvent{TagNumber: tagNumber, Status: TrackingClaimed, Location: "LIM", StaffID: "staff-1"}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("de registrado a entregado: error = %v, se esperaba ErrInvalidTransition", err)
+	}
+
+	if _, err := repo.RecordTrackingEvent(&TrackingEvent{TagNumber: tagNumber, Status: TrackingLoaded, Location: "LIM", StaffID: "staff-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := repo.GetTrackingHistory(tagNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].ID != checkedIn.ID || history[1].Status != TrackingLoaded {
+		t.Errorf("historial = %d eventos, se esperaban registrado y cargado", len(history))
+	}
+
+	piece, err := repo.GetPieceByTag(tagNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if piece.Status != TrackingLoaded || piece.LastEventID != history[1].ID {
+		t.Errorf("pieza = %s con el evento %s, se esperaba cargado con el evento %s", piece.Status, piece.LastEventID, history[1].ID)
+	}
+}
+
+func TestUnknownCategoryIsRejected(t *testing.T) {
+	repo := newTestRepository(t)
+
+	if _, err := repo.CreateBaggageType(&BaggageType{Name: "Kayak", Price: 60, Category: "acuatico"}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("CreateBaggageType() con una categoría inexistente: error = %v, se esperaba ErrCategoryNotFound", err)
+	}
+	if _, err := repo.CreateBaggageType(&BaggageType{Name: "Maleta estándar", Price: 20, Category: CategoryStandard}); err != nil {
+		t.Errorf("CreateBaggageType() con la categoría estándar: %v", err)
+	}
+}
+
+func TestHoldCapacityWaitlistsAndPromotes(t *testing.T) {
+	repo := newTestRepository(t)
+	reservation := createTestBaggageReservation(t, repo)
+
+	// La bodega solo admite las dos maletas ya registradas
+	if _, err := repo.ConfigureHold(reservation.RouteID, 40, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ConfigureHold(reservation.RouteID, 30, 1); !errors.Is(err, ErrHoldBelowUsage) {
+		t.Errorf("capacidad menor a la carga: error = %v, se esperaba ErrHoldBelowUsage", err)
+	}
+
+	req := &AddBaggageRequest{BaggageReservationID: reservation.ID, BaggageType: "maleta", Quantity: 1, Weight: 20}
+	if _, err := repo.AddBaggageToReservation(req); !errors.Is(err, ErrHoldFull) {
+		t.Fatalf("bodega llena: error = %v, se esperaba ErrHoldFull", err)
+	}
+
+	req.Waitlist = true
+	waitlisted, err := repo.AddBaggageToReservation(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := waitlisted.Baggage[1].Status; status != LineWaitlisted {
+		t.Fatalf("estado = %q, se esperaba %q", status, LineWaitlisted)
+	}
+
+	// Al ampliar la bodega la línea en espera se confirma y ocupa su peso
+	if _, err := repo.ConfigureHold(reservation.RouteID, 60, 1); err != nil {
+		t.Fatal(err)
+	}
+	current, err := repo.GetReservation(reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := current.Baggage[1].Status; status != LineConfirmed {
+		t.Errorf("estado = %q, se esperaba %q", status, LineConfirmed)
+	}
+	report, err := repo.GetHoldLoadReport(reservation.RouteID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UsedWeight != 60 || report.WaitlistedPieces != 0 {
+		t.Errorf("bodega = %v kg con %d piezas en espera, se esperaba 60 kg sin piezas en espera", report.UsedWeight, report.WaitlistedPieces)
+	}
+}
+
+func TestUnknownOperatorRejectsBaggage(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	db := repo.client.Database(repo.config.MongoDB.DatabaseName)
+
+	routeID, reservationID := uuid.New().String(), uuid.New().String()
+	if _, err := db.Collection(repo.config.MongoDB.RoutesCollection).InsertOne(ctx, bson.M{"_id": routeID, "operator_id": "op-inexistente"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Collection(repo.config.MongoDB.ReservationsCollection).InsertOne(ctx, bson.M{"_id": reservationID, "route_id": routeID}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := repo.CreateReservation(&BaggageReservation{
+		ReservationID: reservationID,
+		Baggage:       []Baggage{{Type: "maleta", Quantity: 1, Weight: 20}},
+	})
+	if !errors.Is(err, ErrOperatorNotFound) {
+		t.Errorf("CreateReservation() con un operador no registrado: error = %v, se esperaba ErrOperatorNotFound", err)
+	}
+}
+
+func TestCreateReservationAddsLinesThroughHold(t *testing.T) {
+	repo := newTestRepository(t)
+	existing := createTestBaggageReservation(t, repo)
+
+	// La bodega admite las dos maletas ya registradas y dos más
+	if _, err := repo.ConfigureHold(existing.RouteID, 80, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := repo.CreateReservation(&BaggageReservation{
+		ReservationID: existing.ReservationID,
+		Baggage:       []Baggage{{Type: "maleta", Quantity: 1, Weight: 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := repo.GetReservation(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Baggage) != 1 || created.Baggage[0].UnitPrice != 25 || created.Price != 25 {
+		t.Errorf("reserva = %+v, se esperaba una línea cobrada al precio del tipo", *created)
+	}
+
+	// Solo queda espacio para una maleta: la reserva con dos líneas se descarta y la primera no queda ocupando peso
+	_, err = repo.CreateReservation(&BaggageReservation{
+		ReservationID: existing.ReservationID,
+		Baggage:       []Baggage{{Type: "maleta", Quantity: 1, Weight: 20}, {Type: "maleta", Quantity: 1, Weight: 20}},
+	})
+	if err == nil {
+		t.Fatal("CreateReservation() con la bodega llena debe fallar")
+	}
+	if !errors.Is(err, ErrHoldFull) {
+		t.Errorf("error = %v, se esperaba ErrHoldFull", err)
+	}
+
+	report, err := repo.GetHoldLoadReport(existing.RouteID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UsedWeight != 60 || report.Reservations != 2 {
+		t.Errorf("bodega = %v kg en %d reservas, se esperaba 60 kg en 2 reservas", report.UsedWeight, report.Reservations)
+	}
+}
+
+func TestCreateReservationIgnoresClientPrices(t *testing.T) {
+	repo := newTestRepository(t)
+	existing := createTestBaggageReservation(t, repo)
+
+	id, err := repo.CreateReservation(&BaggageReservation{
+		ReservationID: existing.ReservationID,
+		Price:         1,
+		Version:       7,
+		Allowance:     &AllowanceUsage{Pieces: 10, RemainingPieces: 10},
+		Baggage: []Baggage{{
+			Type: "maleta", Quantity: 2, Weight: 20,
+			UnitPrice: 1, Price: 0, IncludedQuantity: 2, Status: LineConfirmed, Tagged: 2, Volume: 0.001, DefaultWeight: 1,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := repo.GetReservation(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := created.Baggage[0]
+	if line.UnitPrice != 25 || line.IncludedQuantity != 0 || line.Price != 50 || line.Tagged != 0 || line.Volume != 0.1 || line.DefaultWeight != 20 {
+		t.Errorf("línea = %+v, se esperaba el precio, la franquicia y la capacidad del tipo de equipaje", line)
+	}
+	if created.Price != 50 || created.Version != 1 || created.Allowance != nil {
+		t.Errorf("reserva = precio %v, versión %d y franquicia %+v, se esperaba 50, 1 y sin franquicia", created.Price, created.Version, created.Allowance)
+	}
+}
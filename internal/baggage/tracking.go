@@ -0,0 +1,37 @@
+package baggage
+
+// Estados del seguimiento de una pieza de equipaje
+const (
+	TrackingCheckedIn = "registrado" // Recibida en el counter
+	TrackingLoaded    = "cargado"    // Cargada en la bodega del bus
+	TrackingUnloaded  = "descargado" // Descargada en el destino
+	TrackingClaimed   = "entregado"  // Entregada al pasajero
+	TrackingLost      = "perdido"
+)
+
+// trackingTransitions define los estados a los que puede pasar una pieza desde cada estado.
+// Una pieza recién etiquetada no tiene estado. Una pieza perdida puede aparecer en el destino.
+var trackingTransitions = map[string][]string{
+	"":                {TrackingCheckedIn},
+	TrackingCheckedIn: {TrackingLoaded, TrackingLost},
+	TrackingLoaded:    {TrackingUnloaded, TrackingLost},
+	TrackingUnloaded:  {TrackingClaimed, TrackingLost},
+	TrackingLost:      {TrackingUnloaded, TrackingClaimed},
+	TrackingClaimed:   {},
+}
+
+// isTrackingStatus indica si el estado es un estado de seguimiento conocido
+func isTrackingStatus(status string) bool {
+	_, ok := trackingTransitions[status]
+	return ok && status != ""
+}
+
+// canTransition indica si una pieza puede pasar del estado actual al siguiente
+func canTransition(current, next string) bool {
+	for _, allowed := range trackingTransitions[current] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
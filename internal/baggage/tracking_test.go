@@ -0,0 +1,43 @@
+package baggage
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		current, next string
+		want          bool
+	}{
+		{current: "", next: TrackingCheckedIn, want: true},
+		{current: "", next: TrackingLoaded},
+		{current: TrackingCheckedIn, next: TrackingLoaded, want: true},
+		{current: TrackingCheckedIn, next: TrackingClaimed},
+		{current: TrackingLoaded, next: TrackingUnloaded, want: true},
+		{current: TrackingLoaded, next: TrackingCheckedIn},
+		{current: TrackingUnloaded, next: TrackingClaimed, want: true},
+		{current: TrackingLoaded, next: TrackingLost, want: true},
+		{current: TrackingLost, next: TrackingUnloaded, want: true},
+		{current: TrackingLost, next: TrackingClaimed, want: true},
+		{current: TrackingLost, next: TrackingLoaded},
+		{current: TrackingClaimed, next: TrackingLost},
+		{current: TrackingClaimed, next: TrackingClaimed},
+	}
+
+	for _, tt := range tests {
+		if got := canTransition(tt.current, tt.next); got != tt.want {
+			t.Errorf("canTransition(%q, %q) = %v, se esperaba %v", tt.current, tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestIsTrackingStatus(t *testing.T) {
+	for _, status := range []string{TrackingCheckedIn, TrackingLoaded, TrackingUnloaded, TrackingClaimed, TrackingLost} {
+		if !isTrackingStatus(status) {
+			t.Errorf("isTrackingStatus(%q) = false, se esperaba true", status)
+		}
+	}
+	for _, status := range []string{"", "en_transito"} {
+		if isTrackingStatus(status) {
+			t.Errorf("isTrackingStatus(%q) = true, se esperaba false", status)
+		}
+	}
+}
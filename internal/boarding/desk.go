@@ -0,0 +1,277 @@
+package boarding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/search"
+)
+
+var (
+	// ErrCheckInClosed indica que el check-in aún no abre o ya cerró para la salida
+	ErrCheckInClosed = errors.New("el check-in no está abierto")
+	// ErrBoardingNotOpen indica que el embarque de la salida aún no empieza
+	ErrBoardingNotOpen = errors.New("el embarque aún no empieza")
+	// ErrWrongDeparture indica que la tarjeta es de otra salida o de otra parada de subida
+	ErrWrongDeparture = errors.New("la tarjeta de embarque no corresponde a esta salida")
+	// ErrNotDeparted indica que la salida aún no partió
+	ErrNotDeparted = errors.New("la salida aún no partió")
+	// ErrBoardingDenied indica que la salida está sobrevendida y la reserva se quedó sin asiento
+	ErrBoardingDenied = errors.New("la salida está sobrevendida y se retiró la reserva")
+)
+
+// Departures es el acceso a las reservas de las salidas en las que se hace el check-in y el embarque
+type Departures interface {
+	Reservations
+	FindReservationsByRoute(ctx context.Context, routeID string) ([]*search.Reservation, error)
+	UpdateReservationStatus(ctx context.Context, reservationID, from, to string) error
+}
+
+// Records es el registro del check-in, el embarque y la no presentación de los pasajeros de cada tarjeta
+type Records interface {
+	CheckIn(ctx context.Context, record *Record) (*Record, error)
+	Board(ctx context.Context, passID, staffID string) (*Record, error)
+	MarkNoShow(ctx context.Context, record *Record) (*Record, error)
+}
+
+// OverbookingResolver retira de una salida sobrevendida a los pasajeros sin check-in que no tendrán asiento
+type OverbookingResolver interface {
+	Resolve(ctx context.Context, routeID, priority string) (*search.DeniedBoardingResult, error)
+}
+
+// Desk registra el check-in de los pasajeros, en línea o en el mostrador, valida sus tarjetas en la puerta de
+// embarque y, tras la salida, marca como no presentados a quienes no embarcaron
+type Desk struct {
+	repo        Records
+	departures  Departures
+	issuer      *Issuer
+	overbooking OverbookingResolver
+	config      config.BoardingConfig
+}
+
+// NewDesk crea una nueva instancia de Desk
+func NewDesk(repo Records, departures Departures, issuer *Issuer, overbooking OverbookingResolver, cfg config.BoardingConfig) *Desk {
+	return &Desk{
+		repo:        repo,
+		departures:  departures,
+		issuer:      issuer,
+		overbooking: overbooking,
+		config:      cfg,
+	}
+}
+
+// CheckIn registra el check-in de los pasajeros indicados de una reserva confirmada, o de todos si passIDs está
+// vacío. En línea solo lo hace el titular de la reserva y cierra antes que en el mostrador, donde lo registra
+// el agente staffID. En una salida sobrevendida primero se retira a los pasajeros que no tendrán asiento; si la
+// reserva es una de ellas, no se registra el check-in.
+func (d *Desk) CheckIn(ctx context.Context, reservationID, userID string, passIDs []string, channel, staffID string) ([]*Record, error) {
+	reservation, err := d.departures.GetReservationByID(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil || (channel == ChannelOnline && reservation.UserID != userID) {
+		return nil, search.ErrReservationNotFound
+	}
+	if reservation.Status != search.ReservationConfirmed {
+		return nil, search.ErrReservationNotConfirmed
+	}
+
+	route, err := d.departures.GetRouteByID(ctx, reservation.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == search.RouteCancelled {
+		return nil, search.ErrRouteCancelled
+	}
+	passes, err := d.issuer.passesOf(reservation, route)
+	if err != nil {
+		return nil, err
+	}
+
+	// El check-in abre a la misma hora en ambos canales y se cuenta desde la salida de la parada de subida
+	departure := passes[0].Departure
+	opens := departure.Add(-d.config.OnlineOpens)
+	closes := departure.Add(-d.config.OnlineCloses)
+	if channel == ChannelCounter {
+		closes = departure.Add(-d.config.CounterCloses)
+	}
+	now := time.Now()
+	if now.Before(opens) {
+		return nil, fmt.Errorf("%w: abre el %s", ErrCheckInClosed, opens.In(passLocation).Format("02/01/2006 15:04"))
+	}
+	if now.After(closes) {
+		return nil, fmt.Errorf("%w: cerró el %s", ErrCheckInClosed, closes.In(passLocation).Format("02/01/2006 15:04"))
+	}
+	if route.Seats < 0 {
+		if err := d.resolveOverbooking(ctx, route, reservationID); err != nil {
+			return nil, err
+		}
+	}
+
+	selected, err := selectPasses(passes, passIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(selected))
+	for _, pass := range selected {
+		record, err := d.repo.CheckIn(ctx, &Record{
+			PassID:        pass.ID,
+			ReservationID: pass.ReservationID,
+			RouteID:       pass.RouteID,
+			PassengerName: pass.PassengerName,
+			SeatNumber:    pass.SeatNumber,
+			From:          pass.From,
+			Channel:       channel,
+			CheckedInBy:   staffID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// resolveOverbooking resuelve la sobreventa de la salida antes del check-in de la reserva, para que nadie con
+// check-in se quede sin asiento, y verifica que la reserva siga confirmada
+func (d *Desk) resolveOverbooking(ctx context.Context, route *search.Route, reservationID string) error {
+	if _, err := d.overbooking.Resolve(ctx, route.ID, ""); err != nil && !errors.Is(err, search.ErrNotOversold) {
+		return err
+	}
+
+	reservation, err := d.departures.GetReservationByID(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case reservation == nil:
+		return search.ErrReservationNotFound
+	case reservation.Status == search.ReservationRebooked:
+		return fmt.Errorf("%w: fue reubicada en la reserva %s", ErrBoardingDenied, reservation.ReplacedBy)
+	case reservation.Status == search.ReservationDeniedBoarding:
+		return fmt.Errorf("%w: no hay otra salida disponible", ErrBoardingDenied)
+	case reservation.Status != search.ReservationConfirmed:
+		return search.ErrReservationNotConfirmed
+	}
+	return nil
+}
+
+// Scan valida en la puerta de embarque la tarjeta escaneada: su firma y vigencia, que sea de la salida y de la
+// parada de subida (stop, opcional), que el pasajero haya hecho el check-in y que la tarjeta no se haya usado
+func (d *Desk) Scan(ctx context.Context, payload, routeID, stop, staffID string) (*Record, error) {
+	claims, err := d.issuer.Validate(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if claims.RouteID != routeID {
+		return nil, fmt.Errorf("%w: la tarjeta es de la salida %s", ErrWrongDeparture, claims.RouteID)
+	}
+	if stop != "" && claims.From != stop {
+		return nil, fmt.Errorf("%w: el pasajero sube en %s", ErrWrongDeparture, claims.From)
+	}
+
+	opens := time.Unix(claims.Departure, 0).Add(-d.config.BoardingOpens)
+	if time.Now().Before(opens) {
+		return nil, fmt.Errorf("%w: empieza el %s", ErrBoardingNotOpen, opens.In(passLocation).Format("02/01/2006 15:04"))
+	}
+
+	return d.repo.Board(ctx, claims.PassID, staffID)
+}
+
+// MarkNoShows marca como no presentados a los pasajeros de una salida que no embarcaron antes de partir de su
+// parada de subida. Las reservas en las que no embarcó nadie pasan a no presentadas.
+func (d *Desk) MarkNoShows(ctx context.Context, routeID string) (*NoShowResult, error) {
+	route, err := d.departures.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == search.RouteCancelled {
+		return nil, search.ErrRouteCancelled
+	}
+	now := time.Now()
+	if now.Before(route.Departure) {
+		return nil, ErrNotDeparted
+	}
+
+	reservations, err := d.departures.FindReservationsByRoute(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NoShowResult{RouteID: routeID, NoShows: []*Record{}, Reservations: []string{}}
+	var errs []error
+	for _, reservation := range reservations {
+		if reservation.Status != search.ReservationConfirmed {
+			continue
+		}
+
+		// Un grupo sin lista de pasajeros no tiene tarjetas: nadie pudo embarcar
+		passes, err := d.issuer.passesOf(reservation, route)
+		if err != nil && !errors.Is(err, ErrPassNotAvailable) {
+			errs = append(errs, err)
+			continue
+		}
+		if len(passes) > 0 && now.Before(passes[0].Departure) {
+			continue
+		}
+
+		boarded := false
+		for _, pass := range passes {
+			record, err := d.repo.MarkNoShow(ctx, &Record{
+				PassID:        pass.ID,
+				ReservationID: pass.ReservationID,
+				RouteID:       pass.RouteID,
+				PassengerName: pass.PassengerName,
+				SeatNumber:    pass.SeatNumber,
+				From:          pass.From,
+			})
+			if errors.Is(err, ErrAlreadyBoarded) {
+				boarded = true
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+				boarded = true // Sin certeza de que no embarcó, la reserva no se marca
+				continue
+			}
+			result.NoShows = append(result.NoShows, record)
+		}
+
+		if boarded {
+			continue
+		}
+		if err := d.departures.UpdateReservationStatus(ctx, reservation.ID, search.ReservationConfirmed, search.ReservationNoShow); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.Reservations = append(result.Reservations, reservation.ID)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// selectPasses devuelve las tarjetas indicadas, o todas si no se indica ninguna
+func selectPasses(passes []*Pass, passIDs []string) ([]*Pass, error) {
+	if len(passIDs) == 0 {
+		return passes, nil
+	}
+
+	byID := make(map[string]*Pass, len(passes))
+	for _, pass := range passes {
+		byID[pass.ID] = pass
+	}
+	selected := make([]*Pass, 0, len(passIDs))
+	for _, id := range passIDs {
+		pass, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPassNotFound, id)
+		}
+		selected = append(selected, pass)
+	}
+	return selected, nil
+}
@@ -0,0 +1,345 @@
+package boarding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/search"
+)
+
+// testDepartures simula las salidas y sus reservas
+type testDepartures struct {
+	routes       map[string]*search.Route
+	reservations map[string]*search.Reservation
+}
+
+func (d *testDepartures) GetRouteByID(ctx context.Context, routeID string) (*search.Route, error) {
+	route, ok := d.routes[routeID]
+	if !ok {
+		return nil, search.ErrRouteNotFound
+	}
+	return route, nil
+}
+
+func (d *testDepartures) GetReservationByID(ctx context.Context, reservationID string) (*search.Reservation, error) {
+	return d.reservations[reservationID], nil
+}
+
+func (d *testDepartures) FindReservationsByRoute(ctx context.Context, routeID string) ([]*search.Reservation, error) {
+	var reservations []*search.Reservation
+	for _, reservation := range d.reservations {
+		if reservation.RouteID == routeID {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations, nil
+}
+
+func (d *testDepartures) UpdateReservationStatus(ctx context.Context, reservationID, from, to string) error {
+	reservation := d.reservations[reservationID]
+	if reservation == nil || reservation.Status != from {
+		return search.ErrReservationNotConfirmed
+	}
+	reservation.Status = to
+	return nil
+}
+
+// testResolver simula la resolución de la sobreventa retirando la reserva deny con el estado indicado
+type testResolver struct {
+	departures *testDepartures
+	deny       string
+	status     string
+	calls      int
+}
+
+func (r *testResolver) Resolve(ctx context.Context, routeID, priority string) (*search.DeniedBoardingResult, error) {
+	r.calls++
+	if r.deny == "" {
+		return nil, search.ErrNotOversold
+	}
+	denied := r.departures.reservations[r.deny]
+	denied.Status = r.status
+	if r.status == search.ReservationRebooked {
+		denied.ReplacedBy = "reserva-nueva"
+	}
+	return &search.DeniedBoardingResult{Denied: []*search.Reservation{denied}}, nil
+}
+
+// testRecords simula el registro de las tarjetas con la misma semántica que el repositorio
+type testRecords map[string]*Record
+
+func (r testRecords) CheckIn(ctx context.Context, record *Record) (*Record, error) {
+	if current, ok := r[record.PassID]; ok {
+		return current, nil
+	}
+	current := *record
+	current.Status, current.CheckedInAt = RecordCheckedIn, time.Now()
+	r[record.PassID] = &current
+	return &current, nil
+}
+
+func (r testRecords) Board(ctx context.Context, passID, staffID string) (*Record, error) {
+	current, ok := r[passID]
+	switch {
+	case ok && current.Status == RecordBoarded:
+		return current, ErrAlreadyBoarded
+	case !ok || current.Status != RecordCheckedIn:
+		return current, ErrNotCheckedIn
+	}
+	current.Status, current.BoardedAt, current.BoardedBy = RecordBoarded, time.Now(), staffID
+	return current, nil
+}
+
+func (r testRecords) MarkNoShow(ctx context.Context, record *Record) (*Record, error) {
+	current, ok := r[record.PassID]
+	if ok && current.Status == RecordBoarded {
+		return nil, ErrAlreadyBoarded
+	}
+	if !ok {
+		copied := *record
+		current = &copied
+		r[record.PassID] = current
+	}
+	current.Status, current.NoShowAt = RecordNoShow, time.Now()
+	return current, nil
+}
+
+func testBoardingConfig() config.BoardingConfig {
+	return config.BoardingConfig{
+		ValidAfter:    2 * time.Hour,
+		OnlineOpens:   24 * time.Hour,
+		OnlineCloses:  2 * time.Hour,
+		CounterCloses: 30 * time.Minute,
+		BoardingOpens: time.Hour,
+	}
+}
+
+// newTestDesk crea un mostrador con una salida LIM-ICA que parte en departsIn y una reserva confirmada de dos
+// asientos del usuario u1. Sin repo solo se prueban los rechazos previos al registro.
+func newTestDesk(t *testing.T, repo Records, departsIn time.Duration) (*Desk, *testDepartures, *testResolver) {
+	t.Helper()
+
+	departures := &testDepartures{
+		routes: map[string]*search.Route{"ruta-1": {ID: "ruta-1", OriginCode: "LIM", DestCode: "ICA", Capacity: 4, Seats: 2,
+			Departure: time.Now().Add(departsIn), Arrival: time.Now().Add(departsIn + 4*time.Hour), Status: search.RouteScheduled}},
+		reservations: map[string]*search.Reservation{
+			"reserva-1": {ID: "reserva-1", RouteID: "ruta-1", UserID: "u1", Seats: 2, SeatNumbers: []string{"1A", "1B"}, Status: search.ReservationConfirmed},
+		},
+	}
+	resolver := &testResolver{departures: departures}
+	issuer := NewIssuer(departures, newTestSigner(t, testSeed(1), "k1", ""), testBoardingConfig())
+	return NewDesk(repo, departures, issuer, resolver, testBoardingConfig()), departures, resolver
+}
+
+func TestCheckInRejections(t *testing.T) {
+	tests := []struct {
+		name      string
+		departsIn time.Duration
+		userID    string
+		channel   string
+		prepare   func(*testDepartures)
+		wantErr   error
+	}{
+		{name: "antes de que abra", departsIn: 30 * time.Hour, userID: "u1", channel: ChannelOnline, wantErr: ErrCheckInClosed},
+		{name: "en mostrador abre a la misma hora", departsIn: 30 * time.Hour, channel: ChannelCounter, wantErr: ErrCheckInClosed},
+		{name: "en línea después del cierre", departsIn: time.Hour, userID: "u1", channel: ChannelOnline, wantErr: ErrCheckInClosed},
+		{name: "en mostrador después del cierre", departsIn: 10 * time.Minute, channel: ChannelCounter, wantErr: ErrCheckInClosed},
+		{name: "en línea de otro usuario", departsIn: 5 * time.Hour, userID: "u2", channel: ChannelOnline, wantErr: search.ErrReservationNotFound},
+		{
+			name: "reserva no confirmada", departsIn: 5 * time.Hour, userID: "u1", channel: ChannelOnline,
+			prepare: func(d *testDepartures) { d.reservations["reserva-1"].Status = search.ReservationCancelled },
+			wantErr: search.ErrReservationNotConfirmed,
+		},
+		{
+			name: "salida cancelada", departsIn: 5 * time.Hour, userID: "u1", channel: ChannelOnline,
+			prepare: func(d *testDepartures) { d.routes["ruta-1"].Status = search.RouteCancelled },
+			wantErr: search.ErrRouteCancelled,
+		},
+		{
+			name: "reserva sin asientos", departsIn: 5 * time.Hour, userID: "u1", channel: ChannelOnline,
+			prepare: func(d *testDepartures) {
+				d.reservations["reserva-1"].Seats, d.reservations["reserva-1"].SeatNumbers = 0, nil
+			},
+			wantErr: ErrPassNotAvailable,
+		},
+		{
+			name: "grupo sin lista de pasajeros", departsIn: 5 * time.Hour, userID: "u1", channel: ChannelOnline,
+			prepare: func(d *testDepartures) { d.reservations["reserva-1"].GroupID = "grupo-1" },
+			wantErr: ErrPassNotAvailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desk, departures, _ := newTestDesk(t, nil, tt.departsIn)
+			if tt.prepare != nil {
+				tt.prepare(departures)
+			}
+			if _, err := desk.CheckIn(context.Background(), "reserva-1", tt.userID, nil, tt.channel, "agente-1"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckIn(): error = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckInOversoldDepartureDeniesReservation(t *testing.T) {
+	for _, status := range []string{search.ReservationRebooked, search.ReservationDeniedBoarding} {
+		t.Run(status, func(t *testing.T) {
+			desk, departures, resolver := newTestDesk(t, nil, 5*time.Hour)
+			departures.routes["ruta-1"].Seats = -1
+			resolver.deny, resolver.status = "reserva-1", status
+
+			if _, err := desk.CheckIn(context.Background(), "reserva-1", "u1", nil, ChannelOnline, ""); !errors.Is(err, ErrBoardingDenied) {
+				t.Errorf("CheckIn(): error = %v, se esperaba ErrBoardingDenied", err)
+			}
+			if resolver.calls != 1 {
+				t.Errorf("la sobreventa se resolvió %d veces, se esperaba 1", resolver.calls)
+			}
+		})
+	}
+}
+
+func TestCheckInSkipsResolutionWithoutOverbooking(t *testing.T) {
+	desk, _, resolver := newTestDesk(t, nil, 30*time.Hour)
+
+	desk.CheckIn(context.Background(), "reserva-1", "u1", nil, ChannelOnline, "")
+	if resolver.calls != 0 {
+		t.Errorf("la sobreventa se resolvió %d veces en una salida con asientos libres", resolver.calls)
+	}
+}
+
+func TestScanRejections(t *testing.T) {
+	desk, _, _ := newTestDesk(t, nil, 3*time.Hour)
+	passes, err := desk.issuer.Issue(context.Background(), "reserva-1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := passes[0].QRPayload
+
+	tests := []struct {
+		name    string
+		payload string
+		routeID string
+		stop    string
+		wantErr error
+	}{
+		{name: "tarjeta de otra salida", payload: payload, routeID: "ruta-2", wantErr: ErrWrongDeparture},
+		{name: "otra parada de subida", payload: payload, routeID: "ruta-1", stop: "ICA", wantErr: ErrWrongDeparture},
+		{name: "antes de que empiece el embarque", payload: payload, routeID: "ruta-1", stop: "LIM", wantErr: ErrBoardingNotOpen},
+		{name: "código alterado", payload: payload + "x", routeID: "ruta-1", wantErr: ErrInvalidPass},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := desk.Scan(context.Background(), tt.payload, tt.routeID, tt.stop, "personal-1"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Scan(): error = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScanRevokedReservation(t *testing.T) {
+	desk, departures, _ := newTestDesk(t, nil, 30*time.Minute)
+	passes, err := desk.issuer.Issue(context.Background(), "reserva-1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	departures.reservations["reserva-1"].Status = search.ReservationRebooked
+
+	if _, err := desk.Scan(context.Background(), passes[0].QRPayload, "ruta-1", "", "personal-1"); !errors.Is(err, ErrPassRevoked) {
+		t.Errorf("Scan() de una reserva reubicada: error = %v, se esperaba ErrPassRevoked", err)
+	}
+}
+
+func TestMarkNoShowsRejections(t *testing.T) {
+	desk, departures, _ := newTestDesk(t, nil, time.Hour)
+
+	if _, err := desk.MarkNoShows(context.Background(), "ruta-1"); !errors.Is(err, ErrNotDeparted) {
+		t.Errorf("MarkNoShows() antes de la salida: error = %v, se esperaba ErrNotDeparted", err)
+	}
+	departures.routes["ruta-1"].Status = search.RouteCancelled
+	if _, err := desk.MarkNoShows(context.Background(), "ruta-1"); !errors.Is(err, search.ErrRouteCancelled) {
+		t.Errorf("MarkNoShows() de una salida cancelada: error = %v, se esperaba ErrRouteCancelled", err)
+	}
+}
+
+func TestIssueWithoutPasses(t *testing.T) {
+	desk, departures, _ := newTestDesk(t, nil, 5*time.Hour)
+	departures.reservations["reserva-1"].Seats, departures.reservations["reserva-1"].SeatNumbers = 0, nil
+
+	if _, err := desk.issuer.Issue(context.Background(), "reserva-1", "u1"); !errors.Is(err, ErrPassNotAvailable) {
+		t.Errorf("Issue(): error = %v, se esperaba ErrPassNotAvailable", err)
+	}
+}
+
+func TestCheckInAndScan(t *testing.T) {
+	records := testRecords{}
+	ctx := context.Background()
+	desk, _, _ := newTestDesk(t, records, 45*time.Minute)
+	passes, err := desk.issuer.Issue(ctx, "reserva-1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := desk.Scan(ctx, passes[1].QRPayload, "ruta-1", "LIM", "personal-1"); !errors.Is(err, ErrNotCheckedIn) {
+		t.Errorf("Scan() sin check-in: error = %v, se esperaba ErrNotCheckedIn", err)
+	}
+
+	checkedIn, err := desk.CheckIn(ctx, "reserva-1", "", []string{passes[0].ID}, ChannelCounter, "agente-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkedIn) != 1 || checkedIn[0].PassID != passes[0].ID || checkedIn[0].CheckedInBy != "agente-1" {
+		t.Fatalf("registros = %+v, se esperaba el check-in de la tarjeta %s en el mostrador", checkedIn, passes[0].ID)
+	}
+	if _, ok := records[passes[1].ID]; ok {
+		t.Errorf("se registró el check-in de la tarjeta %s, que no se eligió", passes[1].ID)
+	}
+
+	record, err := desk.Scan(ctx, passes[0].QRPayload, "ruta-1", "LIM", "personal-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != RecordBoarded || record.BoardedBy != "personal-1" {
+		t.Errorf("registro = %+v, se esperaba embarcado por personal-1", record)
+	}
+	if _, err := desk.Scan(ctx, passes[0].QRPayload, "ruta-1", "LIM", "personal-2"); !errors.Is(err, ErrAlreadyBoarded) {
+		t.Errorf("segundo Scan(): error = %v, se esperaba ErrAlreadyBoarded", err)
+	}
+	if records[passes[0].ID].BoardedBy != "personal-1" {
+		t.Errorf("el segundo escaneo cambió el registro a %+v", records[passes[0].ID])
+	}
+}
+
+func TestMarkNoShowsWithoutBoarding(t *testing.T) {
+	records := testRecords{}
+	ctx := context.Background()
+	desk, departures, _ := newTestDesk(t, records, -10*time.Minute)
+	departures.reservations["reserva-2"] = &search.Reservation{ID: "reserva-2", RouteID: "ruta-1", UserID: "u2", Seats: 1, Status: search.ReservationConfirmed}
+	departures.reservations["reserva-3"] = &search.Reservation{ID: "reserva-3", RouteID: "ruta-1", UserID: "u3", Seats: 1, Status: search.ReservationCancelled}
+	records["reserva-1-1"] = &Record{PassID: "reserva-1-1", ReservationID: "reserva-1", RouteID: "ruta-1", Status: RecordBoarded}
+
+	result, err := desk.MarkNoShows(ctx, "ruta-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.NoShows) != 2 {
+		t.Errorf("no presentados = %d, se esperaban la tarjeta reserva-1-2 y la de la reserva 2", len(result.NoShows))
+	}
+	if len(result.Reservations) != 1 || result.Reservations[0] != "reserva-2" {
+		t.Errorf("reservas no presentadas = %v, se esperaba [reserva-2]", result.Reservations)
+	}
+	if departures.reservations["reserva-1"].Status != search.ReservationConfirmed || departures.reservations["reserva-2"].Status != search.ReservationNoShow {
+		t.Errorf("estados = %s y %s, se esperaba confirmado y no presentado",
+			departures.reservations["reserva-1"].Status, departures.reservations["reserva-2"].Status)
+	}
+	if records["reserva-1-1"].Status != RecordBoarded {
+		t.Errorf("estado de reserva-1-1 = %s, quien embarcó debe conservar su registro", records["reserva-1-1"].Status)
+	}
+	if _, ok := records["reserva-3-1"]; ok {
+		t.Error("se marcó como no presentada una reserva cancelada")
+	}
+}
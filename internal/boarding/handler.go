@@ -0,0 +1,367 @@
+package boarding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/auth"
+	"venta-de-pasajes/internal/search"
+)
+
+// Formatos de salida de las tarjetas de embarque
+const (
+	FormatJSON   = "json"
+	FormatPDF    = "pdf"
+	FormatWallet = "pkpass"
+)
+
+var (
+	// ErrPassNotFound indica que la reserva no tiene una tarjeta con el número indicado
+	ErrPassNotFound = errors.New("tarjeta de embarque no encontrada")
+	// ErrUnsupportedFormat indica que el formato de salida solicitado no existe
+	ErrUnsupportedFormat = errors.New("formato no soportado, use json, pdf o pkpass")
+)
+
+// BoardingHandler maneja las solicitudes de tarjetas de embarque, check-in y embarque
+type BoardingHandler struct {
+	repo   *Repository
+	desk   *Desk
+	issuer *Issuer
+	signer *Signer
+	config config.BoardingConfig
+}
+
+// NewBoardingHandler crea una nueva instancia de BoardingHandler
+func NewBoardingHandler(repo *Repository, desk *Desk, issuer *Issuer, signer *Signer, cfg config.BoardingConfig) *BoardingHandler {
+	return &BoardingHandler{
+		repo:   repo,
+		desk:   desk,
+		issuer: issuer,
+		signer: signer,
+		config: cfg,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error de las tarjetas de embarque
+func (h *BoardingHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrPassNotFound), errors.Is(err, search.ErrReservationNotFound), errors.Is(err, search.ErrRouteNotFound),
+		errors.Is(err, ErrUnknownKey):
+		return http.StatusNotFound
+	case errors.Is(err, ErrPassNotAvailable), errors.Is(err, ErrPassExpired), errors.Is(err, ErrPassRevoked),
+		errors.Is(err, ErrCheckInClosed), errors.Is(err, ErrBoardingNotOpen), errors.Is(err, ErrWrongDeparture),
+		errors.Is(err, ErrNotDeparted), errors.Is(err, ErrNotCheckedIn), errors.Is(err, ErrAlreadyBoarded),
+		errors.Is(err, search.ErrRouteCancelled), errors.Is(err, search.ErrReservationNotConfirmed), errors.Is(err, ErrBoardingDenied):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidPass), errors.Is(err, ErrUnsupportedFormat), errors.Is(err, search.ErrInvalidSegment):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetPassesHandler maneja las solicitudes de las tarjetas de embarque de una reserva (reservation_id) del
+// usuario autenticado, en JSON, en PDF imprimible o en formato de billetera digital (format). Con pass_id se
+// obtiene la tarjeta de un solo pasajero.
+func (h *BoardingHandler) GetPassesHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatJSON
+	}
+	if format != FormatJSON && format != FormatPDF && format != FormatWallet {
+		http.Error(w, ErrUnsupportedFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reservationID := r.URL.Query().Get("reservation_id")
+	if reservationID == "" {
+		http.Error(w, "el parámetro reservation_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	passes, err := h.passes(r.Context(), reservationID, auth.UserID(r.Context()), r.URL.Query().Get("pass_id"))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	switch format {
+	case FormatPDF:
+		var buf bytes.Buffer
+		if err := writeBoardingPasses(&buf, passes); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", "inline; filename=\"tarjetas-embarque.pdf\"")
+		w.Write(buf.Bytes())
+	case FormatWallet:
+		wallets := make([]*WalletPass, 0, len(passes))
+		for _, pass := range passes {
+			wallets = append(wallets, walletPass(pass, h.config))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(wallets)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(passes)
+	}
+}
+
+// GetQRCodeHandler maneja las solicitudes de la imagen PNG del código QR de la tarjeta de un pasajero
+// (reservation_id y pass_id)
+func (h *BoardingHandler) GetQRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	reservationID := r.URL.Query().Get("reservation_id")
+	passID := r.URL.Query().Get("pass_id")
+	if reservationID == "" || passID == "" {
+		http.Error(w, "los parámetros reservation_id y pass_id son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	passes, err := h.passes(r.Context(), reservationID, auth.UserID(r.Context()), passID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writeQRCodePNG(&buf, passes[0].QRPayload); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// passes emite las tarjetas de la reserva del usuario o, con passID, solo la de ese pasajero
+func (h *BoardingHandler) passes(ctx context.Context, reservationID, userID, passID string) ([]*Pass, error) {
+	passes, err := h.issuer.Issue(ctx, reservationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if passID == "" {
+		return passes, nil
+	}
+	for _, pass := range passes {
+		if pass.ID == passID {
+			return []*Pass{pass}, nil
+		}
+	}
+	return nil, ErrPassNotFound
+}
+
+// VerifyPassHandler maneja la verificación en línea del contenido de un código QR: la firma, la vigencia y que
+// la reserva y el pasaje sigan vigentes. Responde con los datos de la tarjeta.
+func (h *BoardingHandler) VerifyPassHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		Payload string `json:"payload"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Payload == "" {
+		http.Error(w, "el campo payload es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.issuer.Validate(r.Context(), requestBody.Payload)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claims)
+}
+
+// PublicKeyHandler maneja las solicitudes de la clave pública con la que las terminales verifican las
+// tarjetas sin conexión: la vigente, o la del kid de la tarjeta con key_id
+func (h *BoardingHandler) PublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	publicKey, err := h.signer.PublicKey(r.URL.Query().Get("key_id"))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publicKey)
+}
+
+// CheckInHandler maneja el check-in en línea del titular de una reserva, para los pasajeros indicados
+// (pass_ids) o para todos
+func (h *BoardingHandler) CheckInHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ReservationID string   `json:"reservation_id"`
+		PassIDs       []string `json:"pass_ids"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ReservationID == "" {
+		http.Error(w, "el campo reservation_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	records, err := h.desk.CheckIn(r.Context(), requestBody.ReservationID, auth.UserID(r.Context()), requestBody.PassIDs, ChannelOnline, "")
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// CounterCheckInHandler maneja el check-in en el mostrador de la terminal, registrado a nombre del agente
+// autenticado
+func (h *BoardingHandler) CounterCheckInHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ReservationID string   `json:"reservation_id"`
+		PassIDs       []string `json:"pass_ids"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ReservationID == "" {
+		http.Error(w, "el campo reservation_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.issuer.reservations.GetReservationByID(r.Context(), requestBody.ReservationID)
+	if err == nil && reservation == nil {
+		err = search.ErrReservationNotFound
+	}
+	if err == nil {
+		err = h.authorizeRoute(r.Context(), reservation.RouteID)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	records, err := h.desk.CheckIn(r.Context(), requestBody.ReservationID, "", requestBody.PassIDs, ChannelCounter, auth.UserID(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// ScanHandler maneja el escaneo de una tarjeta en la puerta de embarque de una salida (route_id) y, opcionalmente,
+// de una parada de subida (stop), registrado a nombre del agente autenticado. Rechaza las tarjetas de otra
+// salida, sin check-in o ya usadas.
+func (h *BoardingHandler) ScanHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		Payload string `json:"payload"`
+		RouteID string `json:"route_id"`
+		Stop    string `json:"stop"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Payload == "" || requestBody.RouteID == "" {
+		http.Error(w, "los campos payload y route_id son obligatorios", http.StatusBadRequest)
+		return
+	}
+	if err := h.authorizeRoute(r.Context(), requestBody.RouteID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	record, err := h.desk.Scan(r.Context(), requestBody.Payload, requestBody.RouteID, requestBody.Stop, auth.UserID(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// NoShowHandler maneja las solicitudes para marcar, tras la salida, a los pasajeros que no embarcaron
+func (h *BoardingHandler) NoShowHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		RouteID string `json:"route_id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.RouteID == "" {
+		http.Error(w, "el campo route_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if err := h.authorizeRoute(r.Context(), requestBody.RouteID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	result, err := h.desk.MarkNoShows(r.Context(), requestBody.RouteID)
+	if err != nil {
+		if result == nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		log.Printf("Error al marcar los no presentados de la ruta %s: %v", requestBody.RouteID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ManifestHandler maneja las solicitudes de los registros de check-in y embarque de una salida (route_id)
+func (h *BoardingHandler) ManifestHandler(w http.ResponseWriter, r *http.Request) {
+	routeID := r.URL.Query().Get("route_id")
+	if routeID == "" {
+		http.Error(w, "el parámetro route_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if err := h.authorizeRoute(r.Context(), routeID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	records, err := h.repo.GetRouteRecords(r.Context(), routeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// authorizeRoute verifica que el usuario autenticado pueda gestionar el embarque de la salida. Un admin_operador
+// solo gestiona las salidas de su operador; los agentes de terminal, todas.
+func (h *BoardingHandler) authorizeRoute(ctx context.Context, routeID string) error {
+	route, err := h.issuer.reservations.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return err
+	}
+	return auth.AuthorizeOperator(ctx, route.OperatorID)
+}
@@ -0,0 +1,190 @@
+package boarding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/search"
+)
+
+var (
+	// ErrPassNotAvailable indica que la reserva no tiene tarjetas de embarque
+	ErrPassNotAvailable = errors.New("la reserva no tiene tarjetas de embarque disponibles")
+	// ErrPassRevoked indica que la tarjeta es auténtica pero su reserva o su pasaje ya no están vigentes
+	ErrPassRevoked = errors.New("la tarjeta de embarque ya no es válida")
+)
+
+// Reservations es el acceso a las reservas y salidas para las que se emiten tarjetas
+type Reservations interface {
+	GetRouteByID(ctx context.Context, routeID string) (*search.Route, error)
+	GetReservationByID(ctx context.Context, reservationID string) (*search.Reservation, error)
+}
+
+// Issuer emite las tarjetas de embarque de las reservas confirmadas. Las tarjetas no se guardan: se generan
+// a partir de la reserva cada vez, por lo que reflejan los cambios de asiento o de pasajeros.
+type Issuer struct {
+	reservations Reservations
+	signer       *Signer
+	config       config.BoardingConfig
+}
+
+// NewIssuer crea una nueva instancia de Issuer
+func NewIssuer(reservations Reservations, signer *Signer, cfg config.BoardingConfig) *Issuer {
+	return &Issuer{
+		reservations: reservations,
+		signer:       signer,
+		config:       cfg,
+	}
+}
+
+// Issue emite una tarjeta por pasajero de una reserva confirmada del usuario. Las reservas con pasajeros
+// nombrados tienen una tarjeta por pasaje vigente; las demás, una por asiento.
+func (i *Issuer) Issue(ctx context.Context, reservationID, userID string) ([]*Pass, error) {
+	reservation, err := i.reservations.GetReservationByID(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil || reservation.UserID != userID {
+		return nil, search.ErrReservationNotFound
+	}
+	if reservation.Status != search.ReservationConfirmed {
+		return nil, fmt.Errorf("%w: %v", ErrPassNotAvailable, search.ErrReservationNotConfirmed)
+	}
+
+	route, err := i.reservations.GetRouteByID(ctx, reservation.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == search.RouteCancelled {
+		return nil, search.ErrRouteCancelled
+	}
+
+	passes, err := i.passesOf(reservation, route)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.After(passes[0].ExpiresAt) {
+		return nil, fmt.Errorf("%w: la salida ya partió", ErrPassNotAvailable)
+	}
+
+	for _, pass := range passes {
+		pass.QRPayload, err = i.signer.Sign(Claims{
+			PassID:        pass.ID,
+			ReservationID: pass.ReservationID,
+			RouteID:       pass.RouteID,
+			SeatNumber:    pass.SeatNumber,
+			From:          pass.From,
+			To:            pass.To,
+			Departure:     pass.Departure.Unix(),
+			ExpiresAt:     pass.ExpiresAt.Unix(),
+			IssuedAt:      now.Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return passes, nil
+}
+
+// passesOf arma las tarjetas, aún sin firmar, de los pasajeros de una reserva: una por pasaje vigente si tiene
+// pasajeros nombrados o, si no, una por asiento. Una reserva sin tarjetas devuelve ErrPassNotAvailable, así
+// Issue y CheckIn siempre reciben al menos una.
+func (i *Issuer) passesOf(reservation *search.Reservation, route *search.Route) ([]*Pass, error) {
+	segment, err := route.SegmentBetween(reservation.FromStop, reservation.ToStop)
+	if err != nil {
+		return nil, err
+	}
+
+	template := Pass{
+		ReservationID: reservation.ID,
+		RouteID:       route.ID,
+		OperatorID:    route.OperatorID,
+		From:          segment.From,
+		FromName:      stopName(route, segment.FromIndex),
+		To:            segment.To,
+		ToName:        stopName(route, segment.ToIndex),
+		Departure:     segment.Departure,
+		Arrival:       segment.Arrival,
+		ExpiresAt:     segment.Departure.Add(i.config.ValidAfter),
+	}
+
+	var passes []*Pass
+	if reservation.GroupID != "" || len(reservation.Passengers) > 0 {
+		for _, passenger := range reservation.Passengers {
+			if passenger.Status != search.PassengerActive {
+				continue
+			}
+			pass := template
+			pass.ID = passenger.ID
+			pass.PassengerName = passenger.Name
+			pass.Document = passenger.DocumentNumber
+			pass.SeatNumber = passenger.SeatNumber
+			passes = append(passes, &pass)
+		}
+		if len(passes) == 0 {
+			return nil, fmt.Errorf("%w: la reserva aún no tiene la lista de pasajeros", ErrPassNotAvailable)
+		}
+		return passes, nil
+	}
+
+	for seat := 0; seat < reservation.Seats; seat++ {
+		pass := template
+		pass.ID = fmt.Sprintf("%s-%d", reservation.ID, seat+1)
+		if seat < len(reservation.SeatNumbers) {
+			pass.SeatNumber = reservation.SeatNumbers[seat]
+		}
+		passes = append(passes, &pass)
+	}
+	if len(passes) == 0 {
+		return nil, fmt.Errorf("%w: la reserva no tiene asientos", ErrPassNotAvailable)
+	}
+	return passes, nil
+}
+
+// Validate verifica la firma y la vigencia de un código QR y, además, que su reserva siga confirmada y su
+// pasaje vigente. Es la verificación en línea; sin conexión basta con Verify y la clave pública.
+func (i *Issuer) Validate(ctx context.Context, payload string) (*Claims, error) {
+	claims, err := i.signer.Verify(payload, time.Now())
+	if err != nil {
+		return claims, err
+	}
+
+	reservation, err := i.reservations.GetReservationByID(ctx, claims.ReservationID)
+	if err != nil {
+		return claims, err
+	}
+	if reservation == nil || reservation.Status != search.ReservationConfirmed || reservation.RouteID != claims.RouteID {
+		return claims, fmt.Errorf("%w: la reserva no está confirmada en esta salida", ErrPassRevoked)
+	}
+	if reservation.GroupID == "" && len(reservation.Passengers) == 0 {
+		if claims.SeatNumber != "" && !slices.Contains(reservation.SeatNumbers, claims.SeatNumber) {
+			return claims, fmt.Errorf("%w: el asiento %s ya no pertenece a la reserva", ErrPassRevoked, claims.SeatNumber)
+		}
+		return claims, nil
+	}
+
+	// Con pasajeros nombrados la tarjeta es de un pasaje que debe seguir en la lista, con el mismo asiento
+	for _, passenger := range reservation.Passengers {
+		if passenger.ID == claims.PassID && passenger.Status == search.PassengerActive && passenger.SeatNumber == claims.SeatNumber {
+			return claims, nil
+		}
+	}
+	return claims, fmt.Errorf("%w: el pasaje fue cancelado o modificado", ErrPassRevoked)
+}
+
+// stopName devuelve el nombre de la parada indicada; las rutas sin paradas solo tienen origen y destino
+func stopName(route *search.Route, index int) string {
+	if len(route.Stops) > 0 {
+		return route.Stops[index].Name
+	}
+	if index == 0 {
+		return route.Origin
+	}
+	return route.Destination
+}
@@ -0,0 +1,90 @@
+package boarding
+
+import "time"
+
+// Pass representa la tarjeta de embarque de un pasajero: los datos del viaje y el código QR firmado
+// que el personal de la terminal verifica sin conexión con la clave pública
+type Pass struct {
+	ID            string    `json:"id"` // Número de pasaje o, sin pasajeros nombrados, reserva y número de asiento
+	ReservationID string    `json:"reservation_id"`
+	RouteID       string    `json:"route_id"`
+	OperatorID    string    `json:"operator_id,omitempty"`
+	PassengerName string    `json:"passenger_name,omitempty"`
+	Document      string    `json:"document_number,omitempty"`
+	SeatNumber    string    `json:"seat_number,omitempty"` // Vacío cuando el asiento se asigna al embarcar
+	From          string    `json:"from"`
+	FromName      string    `json:"from_name"`
+	To            string    `json:"to"`
+	ToName        string    `json:"to_name"`
+	Departure     time.Time `json:"departure"` // Salida desde la parada de subida
+	Arrival       time.Time `json:"arrival"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	QRPayload     string    `json:"qr_payload"` // Contenido firmado del código QR
+}
+
+// Claims representa el contenido firmado del código QR. Las claves son cortas para que el código sea legible
+// con los lectores de la terminal.
+type Claims struct {
+	PassID        string `json:"pid"`
+	ReservationID string `json:"rid"`
+	RouteID       string `json:"rte"`
+	SeatNumber    string `json:"seat,omitempty"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Departure     int64  `json:"dep"` // Segundos Unix
+	ExpiresAt     int64  `json:"exp"` // Segundos Unix
+	IssuedAt      int64  `json:"iat"` // Segundos Unix
+	KeyID         string `json:"kid"` // Clave con la que se firmó
+}
+
+// Expired indica si la tarjeta ya no es válida en el instante indicado
+func (c *Claims) Expired(now time.Time) bool {
+	return now.Unix() > c.ExpiresAt
+}
+
+// PublicKey representa una clave pública con la que se verifican sin conexión las tarjetas de su kid
+type PublicKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"` // Clave en base64 estándar
+	PEM       string `json:"pem"`
+	Current   bool   `json:"current"` // Es la clave con la que se firman las tarjetas nuevas
+}
+
+// Estados del registro de embarque de un pasajero
+const (
+	RecordCheckedIn = "registrado"    // Hizo el check-in y puede embarcar
+	RecordBoarded   = "embarcado"     // Su tarjeta se escaneó en la puerta de embarque
+	RecordNoShow    = "no_presentado" // No embarcó antes de la salida
+)
+
+// Canales de check-in
+const (
+	ChannelOnline  = "en_linea"
+	ChannelCounter = "mostrador"
+)
+
+// Record representa el check-in y el embarque del pasajero de una tarjeta. Hay un registro por tarjeta, por lo
+// que una tarjeta no puede embarcar dos veces.
+type Record struct {
+	PassID        string    `json:"pass_id" bson:"_id"`
+	ReservationID string    `json:"reservation_id" bson:"reservation_id"`
+	RouteID       string    `json:"route_id" bson:"route_id"`
+	PassengerName string    `json:"passenger_name,omitempty" bson:"passenger_name,omitempty"`
+	SeatNumber    string    `json:"seat_number,omitempty" bson:"seat_number,omitempty"`
+	From          string    `json:"from" bson:"from"`
+	Status        string    `json:"status" bson:"status"`
+	Channel       string    `json:"channel,omitempty" bson:"channel,omitempty"`
+	CheckedInAt   time.Time `json:"checked_in_at,omitempty" bson:"checked_in_at,omitempty"`
+	CheckedInBy   string    `json:"checked_in_by,omitempty" bson:"checked_in_by,omitempty"` // Agente del mostrador
+	BoardedAt     time.Time `json:"boarded_at,omitempty" bson:"boarded_at,omitempty"`
+	BoardedBy     string    `json:"boarded_by,omitempty" bson:"boarded_by,omitempty"` // Personal que escaneó la tarjeta
+	NoShowAt      time.Time `json:"no_show_at,omitempty" bson:"no_show_at,omitempty"`
+}
+
+// NoShowResult representa los pasajeros de una salida marcados como no presentados
+type NoShowResult struct {
+	RouteID      string    `json:"route_id"`
+	NoShows      []*Record `json:"no_shows"`
+	Reservations []string  `json:"reservations"` // Reservas en las que no embarcó ningún pasajero
+}
@@ -0,0 +1,176 @@
+package boarding
+
+import (
+	"bytes"
+	"image/png"
+	"io"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+
+	"venta-de-pasajes/config"
+)
+
+// passLocation es la zona horaria de Perú (UTC-5, sin horario de verano) para las horas impresas
+var passLocation = time.FixedZone("PET", -5*60*60)
+
+// writeQRCodePNG escribe el código QR del contenido firmado como imagen PNG
+func writeQRCodePNG(w io.Writer, payload string) error {
+	code, err := qr.Encode(payload, qr.M, qr.Auto)
+	if err != nil {
+		return err
+	}
+	scaled, err := barcode.Scale(code, 400, 400)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, scaled)
+}
+
+// writeBoardingPasses genera un PDF imprimible con una tarjeta de 100x170 mm por pasajero
+func writeBoardingPasses(w io.Writer, passes []*Pass) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: 100, Ht: 170},
+	})
+	pdf.SetMargins(6, 6, 6)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, pass := range passes {
+		pdf.AddPage()
+
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(88, 8, "VENTA DE PASAJES", "", 1, "C", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(88, 5, "Tarjeta de embarque", "", 1, "C", false, 0, "")
+		pdf.Ln(3)
+
+		pdf.SetFont("Helvetica", "B", 20)
+		pdf.CellFormat(40, 10, pass.From, "", 0, "L", false, 0, "")
+		pdf.CellFormat(8, 10, "-", "", 0, "C", false, 0, "")
+		pdf.CellFormat(40, 10, pass.To, "", 1, "R", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(44, 5, tr(pass.FromName), "", 0, "L", false, 0, "")
+		pdf.CellFormat(44, 5, tr(pass.ToName), "", 1, "R", false, 0, "")
+		pdf.Ln(3)
+
+		pdf.SetFont("Helvetica", "", 10)
+		if pass.PassengerName != "" {
+			pdf.CellFormat(88, 6, tr("Pasajero: "+pass.PassengerName), "", 1, "L", false, 0, "")
+			pdf.CellFormat(88, 6, tr("Documento: "+pass.Document), "", 1, "L", false, 0, "")
+		}
+		pdf.CellFormat(88, 6, tr("Salida: "+pass.Departure.In(passLocation).Format("02/01/2006 15:04")), "", 1, "L", false, 0, "")
+		pdf.CellFormat(88, 6, tr("Llegada: "+pass.Arrival.In(passLocation).Format("02/01/2006 15:04")), "", 1, "L", false, 0, "")
+		pdf.CellFormat(88, 6, tr("Asiento: "+seatLabel(pass.SeatNumber)), "", 1, "L", false, 0, "")
+		pdf.CellFormat(88, 6, tr("Pasaje: "+pass.ID), "", 1, "L", false, 0, "")
+		pdf.CellFormat(88, 6, tr("Reserva: "+pass.ReservationID), "", 1, "L", false, 0, "")
+
+		// Código QR firmado
+		var buf bytes.Buffer
+		if err := writeQRCodePNG(&buf, pass.QRPayload); err != nil {
+			return err
+		}
+		name := "qr-" + pass.ID
+		pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, &buf)
+		pdf.ImageOptions(name, 20, 100, 60, 60, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+		pdf.SetY(162)
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.CellFormat(88, 4, tr("Válida hasta "+pass.ExpiresAt.In(passLocation).Format("02/01/2006 15:04")), "", 1, "C", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+// seatLabel devuelve el asiento a mostrar; sin asiento numerado se asigna al embarcar
+func seatLabel(seatNumber string) string {
+	if seatNumber == "" {
+		return "libre, se asigna al embarcar"
+	}
+	return seatNumber
+}
+
+// WalletPass representa una tarjeta con la estructura del pass.json de los pases de billetera digital
+// (Apple Wallet y Google Wallet), lista para que un servicio de firma de pases la empaquete
+type WalletPass struct {
+	FormatVersion      int            `json:"formatVersion"`
+	PassTypeIdentifier string         `json:"passTypeIdentifier"`
+	SerialNumber       string         `json:"serialNumber"`
+	OrganizationName   string         `json:"organizationName"`
+	Description        string         `json:"description"`
+	RelevantDate       string         `json:"relevantDate"`
+	ExpirationDate     string         `json:"expirationDate"`
+	BoardingPass       WalletFields   `json:"boardingPass"`
+	Barcodes           []WalletCode   `json:"barcodes"`
+	UserInfo           map[string]any `json:"userInfo,omitempty"`
+}
+
+// WalletFields representa los campos visibles de una tarjeta de embarque en la billetera digital
+type WalletFields struct {
+	TransitType     string        `json:"transitType"`
+	PrimaryFields   []WalletField `json:"primaryFields"`
+	SecondaryFields []WalletField `json:"secondaryFields,omitempty"`
+	AuxiliaryFields []WalletField `json:"auxiliaryFields,omitempty"`
+	BackFields      []WalletField `json:"backFields,omitempty"`
+}
+
+// WalletField representa un campo de la tarjeta en la billetera digital
+type WalletField struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// WalletCode representa el código que muestra la billetera digital
+type WalletCode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+// walletPass convierte una tarjeta de embarque al formato de las billeteras digitales
+func walletPass(pass *Pass, cfg config.BoardingConfig) *WalletPass {
+	wallet := &WalletPass{
+		FormatVersion:      1,
+		PassTypeIdentifier: cfg.PassTypeID,
+		SerialNumber:       pass.ID,
+		OrganizationName:   cfg.Organization,
+		Description:        "Tarjeta de embarque " + pass.From + " - " + pass.To,
+		RelevantDate:       pass.Departure.Format(time.RFC3339),
+		ExpirationDate:     pass.ExpiresAt.Format(time.RFC3339),
+		BoardingPass: WalletFields{
+			TransitType: "PKTransitTypeBus",
+			PrimaryFields: []WalletField{
+				{Key: "origin", Label: pass.FromName, Value: pass.From},
+				{Key: "destination", Label: pass.ToName, Value: pass.To},
+			},
+			SecondaryFields: []WalletField{
+				{Key: "departure", Label: "Salida", Value: pass.Departure.In(passLocation).Format("02/01/2006 15:04")},
+				{Key: "seat", Label: "Asiento", Value: seatLabel(pass.SeatNumber)},
+			},
+			BackFields: []WalletField{
+				{Key: "ticket", Label: "Pasaje", Value: pass.ID},
+				{Key: "reservation", Label: "Reserva", Value: pass.ReservationID},
+				{Key: "arrival", Label: "Llegada", Value: pass.Arrival.In(passLocation).Format("02/01/2006 15:04")},
+			},
+		},
+		Barcodes: []WalletCode{{
+			Format:          "PKBarcodeFormatQR",
+			Message:         pass.QRPayload,
+			MessageEncoding: "iso-8859-1",
+			AltText:         pass.ID,
+		}},
+		UserInfo: map[string]any{"reservation_id": pass.ReservationID, "route_id": pass.RouteID},
+	}
+	if pass.PassengerName != "" {
+		wallet.BoardingPass.AuxiliaryFields = []WalletField{
+			{Key: "passenger", Label: "Pasajero", Value: pass.PassengerName},
+			{Key: "document", Label: "Documento", Value: pass.Document},
+		}
+	}
+	return wallet
+}
@@ -0,0 +1,227 @@
+package boarding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+var (
+	// ErrNotCheckedIn indica que el pasajero no hizo el check-in
+	ErrNotCheckedIn = errors.New("el pasajero no hizo el check-in")
+	// ErrAlreadyBoarded indica que la tarjeta ya se usó para embarcar
+	ErrAlreadyBoarded = errors.New("la tarjeta de embarque ya fue usada")
+)
+
+// Repository es el repositorio de los registros de check-in y embarque en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para el check-in y el embarque")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// records devuelve la colección de registros de check-in y embarque
+func (r *Repository) records() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.BoardingRecordsCollection)
+}
+
+// EnsureIndexes crea los índices de consulta de los registros de una salida y de una reserva
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.records().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "route_id", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "reservation_id", Value: 1}}},
+	})
+	return err
+}
+
+// CheckIn registra el check-in del pasajero de una tarjeta. Repetir el check-in no lo modifica y devuelve el
+// registro existente.
+func (r *Repository) CheckIn(ctx context.Context, record *Record) (*Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var current Record
+	err := r.records().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": record.PassID},
+		bson.M{"$setOnInsert": bson.M{
+			"reservation_id": record.ReservationID,
+			"route_id":       record.RouteID,
+			"passenger_name": record.PassengerName,
+			"seat_number":    record.SeatNumber,
+			"from":           record.From,
+			"status":         RecordCheckedIn,
+			"channel":        record.Channel,
+			"checked_in_at":  time.Now(),
+			"checked_in_by":  record.CheckedInBy,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&current)
+	if err != nil {
+		return nil, err
+	}
+
+	return &current, nil
+}
+
+// Board marca como embarcado al pasajero de una tarjeta con check-in. Solo el primer escaneo de la tarjeta
+// tiene efecto.
+func (r *Repository) Board(ctx context.Context, passID, staffID string) (*Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var record Record
+	err := r.records().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": passID, "status": RecordCheckedIn},
+		bson.M{"$set": bson.M{"status": RecordBoarded, "boarded_at": time.Now(), "boarded_by": staffID}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, err := r.GetRecord(ctx, passID)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.Status == RecordBoarded {
+			return current, fmt.Errorf("%w: embarcó el %s", ErrAlreadyBoarded, current.BoardedAt.In(passLocation).Format("02/01/2006 15:04"))
+		}
+		return current, ErrNotCheckedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// MarkNoShow marca como no presentado al pasajero de una tarjeta que no embarcó, tenga o no check-in
+func (r *Repository) MarkNoShow(ctx context.Context, record *Record) (*Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var current Record
+	err := r.records().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": record.PassID, "status": bson.M{"$ne": RecordBoarded}},
+		bson.M{
+			"$set": bson.M{"status": RecordNoShow, "no_show_at": time.Now()},
+			"$setOnInsert": bson.M{
+				"reservation_id": record.ReservationID,
+				"route_id":       record.RouteID,
+				"passenger_name": record.PassengerName,
+				"seat_number":    record.SeatNumber,
+				"from":           record.From,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&current)
+	if mongo.IsDuplicateKeyError(err) {
+		// La tarjeta ya embarcó: el filtro no la encontró y el upsert chocó con su registro
+		return nil, ErrAlreadyBoarded
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &current, nil
+}
+
+// GetRecord obtiene el registro de una tarjeta; nil si el pasajero aún no hizo el check-in
+func (r *Repository) GetRecord(ctx context.Context, passID string) (*Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var record Record
+	err := r.records().FindOne(ctx, bson.M{"_id": passID}).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// HasReservationRecords indica si algún pasajero de la reserva ya hizo el check-in o embarcó
+func (r *Repository) HasReservationRecords(ctx context.Context, reservationID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	count, err := r.records().CountDocuments(ctx, bson.M{"reservation_id": reservationID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RecordedReservations devuelve las reservas de una salida con algún pasajero que ya hizo el check-in o embarcó
+func (r *Repository) RecordedReservations(ctx context.Context, routeID string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	ids, err := r.records().Distinct(ctx, "reservation_id", bson.M{"route_id": routeID})
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if reservationID, ok := id.(string); ok {
+			recorded[reservationID] = true
+		}
+	}
+	return recorded, nil
+}
+
+// GetRouteRecords obtiene los registros de check-in y embarque de una salida
+func (r *Repository) GetRouteRecords(ctx context.Context, routeID string) ([]*Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.records().Find(ctx, bson.M{"route_id": routeID}, options.Find().SetSort(bson.D{{Key: "from", Value: 1}, {Key: "checked_in_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := []*Record{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,118 @@
+package boarding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/internal/search"
+	"venta-de-pasajes/internal/testutil"
+)
+
+// newTestRepository crea un repositorio sobre una base de datos de prueba que se elimina al terminar.
+// Las pruebas se omiten si MONGO_TEST_URL no está definida.
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	repo, err := NewRepository(testutil.MongoConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.client.Disconnect(context.Background()) })
+
+	if err := repo.EnsureIndexes(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestCheckInAndBoard(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	// El check-in en línea ya cerró pero el del mostrador sigue abierto, y el embarque ya empezó
+	desk, _, _ := newTestDesk(t, repo, 45*time.Minute)
+	passes, err := desk.issuer.Issue(ctx, "reserva-1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := desk.Scan(ctx, passes[0].QRPayload, "ruta-1", "LIM", "personal-1"); !errors.Is(err, ErrNotCheckedIn) {
+		t.Errorf("Scan() sin check-in: error = %v, se esperaba ErrNotCheckedIn", err)
+	}
+	if _, err := desk.CheckIn(ctx, "reserva-1", "u1", nil, ChannelOnline, ""); !errors.Is(err, ErrCheckInClosed) {
+		t.Errorf("CheckIn() en línea: error = %v, se esperaba ErrCheckInClosed", err)
+	}
+
+	records, err := desk.CheckIn(ctx, "reserva-1", "", []string{passes[0].ID}, ChannelCounter, "agente-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Status != RecordCheckedIn || records[0].CheckedInBy != "agente-1" {
+		t.Fatalf("registros = %+v, se esperaba el check-in de una tarjeta en el mostrador", records)
+	}
+
+	record, err := desk.Scan(ctx, passes[0].QRPayload, "ruta-1", "LIM", "personal-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != RecordBoarded {
+		t.Errorf("estado = %s, se esperaba %s", record.Status, RecordBoarded)
+	}
+	if _, err := desk.Scan(ctx, passes[0].QRPayload, "ruta-1", "LIM", "personal-1"); !errors.Is(err, ErrAlreadyBoarded) {
+		t.Errorf("segundo Scan(): error = %v, se esperaba ErrAlreadyBoarded", err)
+	}
+}
+
+func TestCheckInResolvesOverbookingFirst(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	desk, departures, resolver := newTestDesk(t, repo, 5*time.Hour)
+	departures.routes["ruta-1"].Seats = -1
+	departures.reservations["reserva-2"] = &search.Reservation{ID: "reserva-2", RouteID: "ruta-1", UserID: "u2", Seats: 1, Status: search.ReservationConfirmed}
+	resolver.deny, resolver.status = "reserva-2", search.ReservationRebooked
+
+	records, err := desk.CheckIn(ctx, "reserva-1", "u1", nil, ChannelOnline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || resolver.calls != 1 {
+		t.Errorf("registros = %d y resoluciones = %d, se esperaba el check-in de las dos tarjetas tras resolver la sobreventa", len(records), resolver.calls)
+	}
+}
+
+func TestMarkNoShows(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	desk, departures, _ := newTestDesk(t, repo, -10*time.Minute)
+	departures.reservations["reserva-2"] = &search.Reservation{ID: "reserva-2", RouteID: "ruta-1", UserID: "u2", Seats: 1, Status: search.ReservationConfirmed}
+
+	// De la reserva 1 embarcó un solo pasajero; de la reserva 2, nadie
+	if _, err := repo.CheckIn(ctx, &Record{PassID: "reserva-1-1", ReservationID: "reserva-1", RouteID: "ruta-1", From: "LIM"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Board(ctx, "reserva-1-1", "personal-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := desk.MarkNoShows(ctx, "ruta-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.NoShows) != 2 {
+		t.Errorf("no presentados = %d, se esperaban la tarjeta reserva-1-2 y la de la reserva 2", len(result.NoShows))
+	}
+	if len(result.Reservations) != 1 || result.Reservations[0] != "reserva-2" {
+		t.Errorf("reservas no presentadas = %v, se esperaba [reserva-2]", result.Reservations)
+	}
+	if departures.reservations["reserva-1"].Status != search.ReservationConfirmed || departures.reservations["reserva-2"].Status != search.ReservationNoShow {
+		t.Errorf("estados = %s y %s, se esperaba confirmado y no presentado",
+			departures.reservations["reserva-1"].Status, departures.reservations["reserva-2"].Status)
+	}
+
+	// Quien embarcó conserva su registro
+	record, err := repo.GetRecord(ctx, "reserva-1-1")
+	if err != nil || record.Status != RecordBoarded {
+		t.Errorf("registro de reserva-1-1 = %+v (%v), se esperaba embarcado", record, err)
+	}
+}
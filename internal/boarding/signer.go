@@ -0,0 +1,168 @@
+package boarding
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"venta-de-pasajes/config"
+)
+
+// payloadPrefix identifica el formato del contenido del código QR: prefijo, datos y firma separados por puntos
+const payloadPrefix = "VP1"
+
+var (
+	// ErrInvalidPass indica que el código QR no tiene el formato esperado o su firma no es válida
+	ErrInvalidPass = errors.New("tarjeta de embarque inválida")
+	// ErrPassExpired indica que la tarjeta de embarque venció
+	ErrPassExpired = errors.New("la tarjeta de embarque venció")
+	// ErrUnknownKey indica que no hay una clave pública con el identificador pedido
+	ErrUnknownKey = errors.New("clave de las tarjetas de embarque desconocida")
+	// ErrMissingSigningKey indica que no se configuró la clave para firmar las tarjetas de embarque
+	ErrMissingSigningKey = errors.New("BOARDING_SIGNING_KEY no configurada")
+)
+
+// KeySet agrupa por identificador (kid) las claves públicas con las que se verifican las tarjetas
+type KeySet map[string]ed25519.PublicKey
+
+// Signer firma el contenido de los códigos QR de las tarjetas de embarque con una clave Ed25519. Verifica con
+// la clave vigente y con las anteriores configuradas, para que rotar la clave no invalide las tarjetas emitidas.
+type Signer struct {
+	keyID      string
+	privateKey ed25519.PrivateKey
+	keys       KeySet
+}
+
+// NewSigner crea un Signer con la semilla configurada. La semilla es obligatoria: con una clave temporal las
+// tarjetas emitidas dejarían de verificarse al reiniciar el servicio.
+func NewSigner(cfg config.BoardingConfig) (*Signer, error) {
+	if cfg.SigningKey == "" {
+		return nil, ErrMissingSigningKey
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(cfg.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("clave de firma de las tarjetas de embarque inválida: %v", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("clave de firma de las tarjetas de embarque inválida: se esperaban %d bytes", ed25519.SeedSize)
+	}
+
+	keys, err := parseRetiredKeys(cfg.RetiredKeys)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := keys[cfg.KeyID]; ok {
+		return nil, fmt.Errorf("la clave %s figura como vigente y como anterior", cfg.KeyID)
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	keys[cfg.KeyID] = privateKey.Public().(ed25519.PublicKey)
+
+	return &Signer{keyID: cfg.KeyID, privateKey: privateKey, keys: keys}, nil
+}
+
+// parseRetiredKeys lee las claves públicas anteriores con el formato "kid:clave en base64", separadas por comas
+func parseRetiredKeys(value string) (KeySet, error) {
+	keys := KeySet{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		keyID, encoded, ok := strings.Cut(entry, ":")
+		if !ok || keyID == "" {
+			return nil, fmt.Errorf("clave anterior de las tarjetas de embarque inválida: se esperaba kid:clave")
+		}
+		publicKey, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("clave anterior %s de las tarjetas de embarque inválida: se esperaban %d bytes en base64", keyID, ed25519.PublicKeySize)
+		}
+		keys[keyID] = ed25519.PublicKey(publicKey)
+	}
+	return keys, nil
+}
+
+// Sign devuelve el contenido del código QR: los datos en base64 URL y su firma
+func (s *Signer) Sign(claims Claims) (string, error) {
+	claims.KeyID = s.keyID
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := payloadPrefix + "." + base64.RawURLEncoding.EncodeToString(data)
+	signature := ed25519.Sign(s.privateKey, []byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// PublicKey devuelve la clave pública indicada, o la vigente si keyID está vacío, para verificar las tarjetas
+// sin conexión
+func (s *Signer) PublicKey(keyID string) (*PublicKey, error) {
+	if keyID == "" {
+		keyID = s.keyID
+	}
+	publicKey, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKey{
+		KeyID:     keyID,
+		Algorithm: "Ed25519",
+		PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+		PEM:       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		Current:   keyID == s.keyID,
+	}, nil
+}
+
+// Verify comprueba la firma y la vigencia del contenido de un código QR con la clave pública de su kid y
+// devuelve sus datos. No consulta el servicio, por lo que las terminales la pueden usar sin conexión.
+func Verify(keys KeySet, payload string, now time.Time) (*Claims, error) {
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 || parts[0] != payloadPrefix {
+		return nil, fmt.Errorf("%w: formato desconocido", ErrInvalidPass)
+	}
+
+	// Los datos se leen antes de verificar solo para elegir la clave; no se devuelven si la firma no corresponde
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: datos ilegibles", ErrInvalidPass)
+	}
+	var claims Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("%w: datos ilegibles", ErrInvalidPass)
+	}
+	publicKey, ok := keys[claims.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: clave de firma desconocida", ErrInvalidPass)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: firma ilegible", ErrInvalidPass)
+	}
+	if !ed25519.Verify(publicKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, fmt.Errorf("%w: la firma no corresponde", ErrInvalidPass)
+	}
+
+	if claims.Expired(now) {
+		return &claims, ErrPassExpired
+	}
+
+	return &claims, nil
+}
+
+// Verify comprueba un código QR con las claves públicas del firmante
+func (s *Signer) Verify(payload string, now time.Time) (*Claims, error) {
+	return Verify(s.keys, payload, now)
+}
@@ -0,0 +1,218 @@
+package boarding
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/config"
+)
+
+// testSeed devuelve una semilla Ed25519 de prueba en base64, formada por el byte indicado
+func testSeed(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, ed25519.SeedSize))
+}
+
+// newTestSigner crea un Signer con la semilla, el kid y las claves anteriores indicadas
+func newTestSigner(t *testing.T, seed, keyID, retiredKeys string) *Signer {
+	t.Helper()
+
+	signer, err := NewSigner(config.BoardingConfig{SigningKey: seed, KeyID: keyID, RetiredKeys: retiredKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// testClaims son los datos de una tarjeta vigente hasta expiresAt
+func testClaims(expiresAt time.Time) Claims {
+	return Claims{
+		PassID:        "tarjeta-1",
+		ReservationID: "reserva-1",
+		RouteID:       "ruta-1",
+		SeatNumber:    "12",
+		From:          "LIM",
+		To:            "AQP",
+		Departure:     expiresAt.Add(-6 * time.Hour).Unix(),
+		ExpiresAt:     expiresAt.Unix(),
+		IssuedAt:      expiresAt.Add(-24 * time.Hour).Unix(),
+	}
+}
+
+func TestNewSignerRejectsMissingOrInvalidKey(t *testing.T) {
+	if _, err := NewSigner(config.BoardingConfig{KeyID: "k1"}); !errors.Is(err, ErrMissingSigningKey) {
+		t.Errorf("sin clave: NewSigner() = %v, se esperaba ErrMissingSigningKey", err)
+	}
+	if _, err := NewSigner(config.BoardingConfig{SigningKey: base64.StdEncoding.EncodeToString([]byte("corta")), KeyID: "k1"}); err == nil {
+		t.Error("semilla corta: NewSigner() no devolvió error")
+	}
+	if _, err := NewSigner(config.BoardingConfig{SigningKey: testSeed(1), KeyID: "k1", RetiredKeys: "k0:no-es-base64"}); err == nil {
+		t.Error("clave anterior inválida: NewSigner() no devolvió error")
+	}
+}
+
+func TestVerifySignedPass(t *testing.T) {
+	signer := newTestSigner(t, testSeed(1), "k1", "")
+	now := time.Now()
+
+	payload, err := signer.Sign(testClaims(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := signer.Verify(payload, now)
+	if err != nil {
+		t.Fatalf("Verify() de una tarjeta firmada: %v", err)
+	}
+	if claims.PassID != "tarjeta-1" || claims.SeatNumber != "12" || claims.KeyID != "k1" {
+		t.Errorf("Verify() = %+v, no corresponde a la tarjeta firmada con k1", *claims)
+	}
+
+	// Las terminales verifican sin conexión con la clave pública publicada
+	published, err := signer.PublicKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(published.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(KeySet{published.KeyID: publicKey}, payload, now); err != nil {
+		t.Errorf("Verify() con la clave publicada: %v", err)
+	}
+}
+
+func TestVerifyRejectsTamperedPass(t *testing.T) {
+	signer := newTestSigner(t, testSeed(1), "k1", "")
+	now := time.Now()
+
+	payload, err := signer.Sign(testClaims(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(payload, ".")
+
+	// Otra tarjeta firmada con otra clave que declara el mismo kid
+	forged, err := newTestSigner(t, testSeed(2), "k1", "").Sign(testClaims(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSeat := testClaims(now.Add(time.Hour))
+	otherSeat.SeatNumber = "1"
+	otherPayload, err := signer.Sign(otherSeat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped := parts[0] + "." + strings.Split(otherPayload, ".")[1] + "." + parts[2]
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "vacío", payload: ""},
+		{name: "otro prefijo", payload: "VP2." + parts[1] + "." + parts[2]},
+		{name: "sin firma", payload: parts[0] + "." + parts[1]},
+		{name: "datos de otra tarjeta", payload: swapped},
+		{name: "firma ilegible", payload: parts[0] + "." + parts[1] + ".%%%"},
+		{name: "firmada con otra clave", payload: forged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Verify(tt.payload, now); !errors.Is(err, ErrInvalidPass) {
+				t.Errorf("Verify() = %v, se esperaba ErrInvalidPass", err)
+			}
+		})
+	}
+}
+
+func TestVerifyExpiredPass(t *testing.T) {
+	signer := newTestSigner(t, testSeed(1), "k1", "")
+	expiresAt := time.Now().Add(-time.Minute)
+
+	payload, err := signer.Sign(testClaims(expiresAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := signer.Verify(payload, time.Now())
+	if !errors.Is(err, ErrPassExpired) {
+		t.Fatalf("Verify() = %v, se esperaba ErrPassExpired", err)
+	}
+	if claims == nil || claims.PassID != "tarjeta-1" {
+		t.Errorf("Verify() de una tarjeta vencida debe devolver sus datos, devolvió %+v", claims)
+	}
+
+	if _, err := signer.Verify(payload, expiresAt.Add(-time.Second)); err != nil {
+		t.Errorf("Verify() antes del vencimiento: %v", err)
+	}
+}
+
+func TestVerifyAfterKeyRotation(t *testing.T) {
+	previous := newTestSigner(t, testSeed(1), "k1", "")
+	now := time.Now()
+
+	issued, err := previous.Sign(testClaims(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousKey, err := previous.PublicKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin la clave anterior configurada, las tarjetas ya emitidas no se reconocen
+	if _, err := newTestSigner(t, testSeed(2), "k2", "").Verify(issued, now); !errors.Is(err, ErrInvalidPass) {
+		t.Errorf("sin la clave anterior: Verify() = %v, se esperaba ErrInvalidPass", err)
+	}
+
+	rotated := newTestSigner(t, testSeed(2), "k2", "k1:"+previousKey.PublicKey)
+	if _, err := rotated.Verify(issued, now); err != nil {
+		t.Errorf("tarjeta emitida con k1: Verify() = %v", err)
+	}
+
+	payload, err := rotated.Sign(testClaims(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := rotated.Verify(payload, now)
+	if err != nil {
+		t.Fatalf("tarjeta emitida con k2: Verify() = %v", err)
+	}
+	if claims.KeyID != "k2" {
+		t.Errorf("kid = %q, se esperaba k2", claims.KeyID)
+	}
+
+	current, err := rotated.PublicKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := rotated.PublicKey("k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.KeyID != "k2" || !current.Current || retired.Current || retired.PublicKey != previousKey.PublicKey {
+		t.Errorf("PublicKey() = %+v y %+v, se esperaba k2 vigente y k1 anterior", *current, *retired)
+	}
+	if _, err := rotated.PublicKey("k0"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("PublicKey(k0) = %v, se esperaba ErrUnknownKey", err)
+	}
+}
+
+func TestNewSignerRejectsCurrentKeyAsRetired(t *testing.T) {
+	previous := newTestSigner(t, testSeed(1), "k1", "")
+	previousKey, err := previous.PublicKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewSigner(config.BoardingConfig{SigningKey: testSeed(2), KeyID: "k1", RetiredKeys: "k1:" + previousKey.PublicKey})
+	if err == nil {
+		t.Error("NewSigner() con el kid vigente entre las claves anteriores no devolvió error")
+	}
+}
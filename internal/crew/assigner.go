@@ -0,0 +1,266 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// ErrInvalidAssignment indica que la asignación no es válida para la salida
+var ErrInvalidAssignment = errors.New("asignación inválida")
+
+// RouteDirectory obtiene las salidas a las que se asigna la tripulación
+type RouteDirectory interface {
+	GetRouteByID(ctx context.Context, routeID string) (*search.Route, error)
+}
+
+// Assigner asigna tripulantes a las salidas validando los límites de jornada de los conductores.
+// También mantiene las asignaciones al cancelar o reprogramar una salida.
+type Assigner struct {
+	repo   *Repository
+	routes RouteDirectory
+	rules  DutyRules
+}
+
+// NewAssigner crea una nueva instancia de Assigner
+func NewAssigner(repo *Repository, routes RouteDirectory, rules DutyRules) *Assigner {
+	return &Assigner{
+		repo:   repo,
+		routes: routes,
+		rules:  rules,
+	}
+}
+
+// Assign asigna un tripulante a una salida. Sin inicio ni fin el turno cubre toda la salida;
+// en rutas largas los conductores se asignan por tramos para relevarse.
+func (a *Assigner) Assign(ctx context.Context, routeID, memberID string, start, end time.Time) (*Assignment, error) {
+	route, err := a.routes.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == search.RouteCancelled {
+		return nil, search.ErrRouteCancelled
+	}
+
+	member, err := a.repo.GetMember(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if !member.Active {
+		return nil, ErrMemberInactive
+	}
+	if member.OperatorID != route.OperatorID {
+		return nil, fmt.Errorf("%w: el tripulante pertenece a otro operador", ErrInvalidAssignment)
+	}
+
+	if start.IsZero() {
+		start = route.Departure
+	}
+	if end.IsZero() {
+		end = route.Arrival
+	}
+	if !end.After(start) || start.Before(route.Departure) || end.After(route.Arrival) {
+		return nil, fmt.Errorf("%w: el turno debe estar dentro de la salida, entre %s y %s",
+			ErrInvalidAssignment, route.Departure.Format("2006-01-02 15:04"), route.Arrival.Format("2006-01-02 15:04"))
+	}
+	if member.Role == RoleDriver && member.LicenseExpiry.Before(end) {
+		return nil, fmt.Errorf("%w: el brevete del conductor vence el %s", ErrInvalidAssignment, member.LicenseExpiry.Format("2006-01-02"))
+	}
+
+	assignment := &Assignment{
+		RouteID:    route.ID,
+		MemberID:   member.ID,
+		OperatorID: member.OperatorID,
+		Role:       member.Role,
+		Start:      start,
+		End:        end,
+	}
+
+	// Validar contra los turnos cercanos del tripulante
+	existing, err := a.repo.GetMemberAssignments(ctx, member.ID, start.Add(-dutyWindow), end.Add(dutyWindow))
+	if err != nil {
+		return nil, err
+	}
+	shifts := append(existing, assignment)
+	if err := checkOverlap(shifts); err != nil {
+		return nil, err
+	}
+	if member.Role == RoleDriver {
+		if err := a.rules.Validate(shifts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.repo.CreateAssignment(ctx, assignment, member.Version); err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// RouteCrew obtiene la tripulación de una salida e indica si todo el viaje tiene conductor
+func (a *Assigner) RouteCrew(ctx context.Context, routeID string) (*RouteCrew, error) {
+	route, err := a.routes.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := a.repo.GetRouteAssignments(ctx, route.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Las asignaciones vienen ordenadas por inicio
+	covered := route.Departure
+	for _, assignment := range assignments {
+		if assignment.Role != RoleDriver || assignment.Start.After(covered) {
+			continue
+		}
+		if assignment.End.After(covered) {
+			covered = assignment.End
+		}
+	}
+
+	return &RouteCrew{
+		RouteID:        route.ID,
+		OperatorID:     route.OperatorID,
+		Departure:      route.Departure,
+		Arrival:        route.Arrival,
+		Assignments:    assignments,
+		DrivingCovered: !covered.Before(route.Arrival),
+	}, nil
+}
+
+// Roster obtiene la programación de los tripulantes activos de un operador en el periodo [from, to)
+func (a *Assigner) Roster(ctx context.Context, operatorID string, from, to time.Time) ([]*RosterEntry, error) {
+	members, err := a.repo.GetMembers(ctx, operatorID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := a.repo.GetOperatorAssignments(ctx, operatorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]*RosterEntry, len(members))
+	roster := make([]*RosterEntry, 0, len(members))
+	for _, member := range members {
+		entry := &RosterEntry{Member: member, Assignments: []*Assignment{}}
+		entries[member.ID] = entry
+		roster = append(roster, entry)
+	}
+
+	for _, assignment := range assignments {
+		entry, ok := entries[assignment.MemberID]
+		if !ok {
+			continue
+		}
+		entry.Assignments = append(entry.Assignments, assignment)
+		if assignment.Role == RoleDriver {
+			entry.DrivingHours += overlap(assignment.Start, assignment.End, from, to).Hours()
+		}
+	}
+
+	return roster, nil
+}
+
+// OnRouteCancelled libera a la tripulación de una salida cancelada
+func (a *Assigner) OnRouteCancelled(ctx context.Context, route *search.Route, reservations []*search.Reservation) error {
+	released, err := a.repo.DeleteRouteAssignments(ctx, route.ID)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		log.Printf("Se liberaron %d asignaciones de tripulación de la ruta cancelada %s", released, route.ID)
+	}
+	return nil
+}
+
+// ValidateReschedule verifica que los turnos de la salida, desplazados al nuevo horario, terminen antes de la
+// nueva llegada y sigan cumpliendo el brevete, los cruces y los límites de jornada de cada tripulante. Si no,
+// rechaza la reprogramación para que primero se reasigne la tripulación.
+func (a *Assigner) ValidateReschedule(ctx context.Context, previous, route *search.Route) error {
+	assignments, err := a.repo.GetRouteAssignments(ctx, route.ID)
+	if err != nil {
+		return err
+	}
+
+	// Los turnos de la salida de cada tripulante, ya desplazados
+	delta := route.Departure.Sub(previous.Departure)
+	shifted := make(map[string][]*Assignment)
+	var members []string
+	for _, assignment := range assignments {
+		moved := *assignment
+		moved.Start, moved.End = moved.Start.Add(delta), moved.End.Add(delta)
+		if moved.End.After(route.Arrival) {
+			return fmt.Errorf("%w: el turno %s terminaría después de la nueva llegada", search.ErrRescheduleRejected, assignment.ID)
+		}
+		if _, ok := shifted[moved.MemberID]; !ok {
+			members = append(members, moved.MemberID)
+		}
+		shifted[moved.MemberID] = append(shifted[moved.MemberID], &moved)
+	}
+
+	for _, memberID := range members {
+		if err := a.validateShiftedDuty(ctx, route.ID, memberID, shifted[memberID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateShiftedDuty valida los turnos desplazados de un tripulante junto con sus turnos en otras salidas
+func (a *Assigner) validateShiftedDuty(ctx context.Context, routeID, memberID string, moved []*Assignment) error {
+	member, err := a.repo.GetMember(ctx, memberID)
+	if err != nil {
+		return err
+	}
+
+	start, end := moved[0].Start, moved[0].End
+	for _, assignment := range moved[1:] {
+		if assignment.Start.Before(start) {
+			start = assignment.Start
+		}
+		if assignment.End.After(end) {
+			end = assignment.End
+		}
+	}
+	if member.Role == RoleDriver && member.LicenseExpiry.Before(end) {
+		return fmt.Errorf("%w: el brevete del conductor %s vence el %s", search.ErrRescheduleRejected, member.ID, member.LicenseExpiry.Format("2006-01-02"))
+	}
+
+	existing, err := a.repo.GetMemberAssignments(ctx, memberID, start.Add(-dutyWindow), end.Add(dutyWindow))
+	if err != nil {
+		return err
+	}
+	shifts := append([]*Assignment(nil), moved...)
+	for _, assignment := range existing {
+		if assignment.RouteID != routeID {
+			shifts = append(shifts, assignment)
+		}
+	}
+
+	if err := checkOverlap(shifts); err != nil {
+		return fmt.Errorf("%w: tripulante %s: %w", search.ErrRescheduleRejected, memberID, err)
+	}
+	if member.Role == RoleDriver {
+		if err := a.rules.Validate(shifts); err != nil {
+			return fmt.Errorf("%w: conductor %s: %w", search.ErrRescheduleRejected, memberID, err)
+		}
+	}
+	return nil
+}
+
+// OnRouteRescheduled desplaza los turnos de la salida reprogramada, ya validados por ValidateReschedule
+func (a *Assigner) OnRouteRescheduled(ctx context.Context, previous, route *search.Route) error {
+	delta := route.Departure.Sub(previous.Departure)
+	if delta == 0 {
+		return nil
+	}
+	return a.repo.ShiftRouteAssignments(ctx, route.ID, delta)
+}
@@ -0,0 +1,137 @@
+package crew
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"venta-de-pasajes/config"
+)
+
+var (
+	// ErrDutyTimeExceeded indica que la asignación supera los límites de conducción o no respeta el descanso
+	ErrDutyTimeExceeded = errors.New("la asignación no cumple los límites de jornada del conductor")
+	// ErrMemberUnavailable indica que el tripulante ya está asignado en ese horario
+	ErrMemberUnavailable = errors.New("el tripulante ya tiene una asignación en ese horario")
+)
+
+// dutyWindow es el periodo alrededor de una asignación que se revisa para validar la jornada
+const dutyWindow = 72 * time.Hour
+
+// DutyRules contiene los límites de jornada de los conductores en rutas interprovinciales
+type DutyRules struct {
+	MaxContinuousDriving time.Duration // Conducción continua máxima
+	MaxDailyDriving      time.Duration // Conducción máxima en cualquier periodo de 24 horas y por jornada
+	MinBreak             time.Duration // Pausas más cortas no interrumpen la conducción continua
+	MinRest              time.Duration // Descansos más cortos no separan una jornada de la siguiente
+}
+
+// NewDutyRules crea los límites de jornada a partir de la configuración
+func NewDutyRules(cfg config.CrewConfig) DutyRules {
+	return DutyRules{
+		MaxContinuousDriving: cfg.MaxContinuousDriving,
+		MaxDailyDriving:      cfg.MaxDailyDriving,
+		MinBreak:             cfg.MinBreak,
+		MinRest:              cfg.MinRest,
+	}
+}
+
+// checkOverlap verifica que los turnos de un tripulante no se superpongan
+func checkOverlap(assignments []*Assignment) error {
+	sorted := sortedByStart(assignments)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Start.Before(sorted[i-1].End) {
+			return fmt.Errorf("%w: el turno de la ruta %s se cruza con el de la ruta %s", ErrMemberUnavailable, sorted[i].RouteID, sorted[i-1].RouteID)
+		}
+	}
+	return nil
+}
+
+// Validate verifica los turnos de conducción de un conductor: la conducción continua, la conducción
+// acumulada por jornada y en cualquier periodo de 24 horas, y el descanso entre jornadas
+func (d DutyRules) Validate(assignments []*Assignment) error {
+	sorted := sortedByStart(assignments)
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	// Conducción continua: los turnos separados por menos de la pausa mínima se suman
+	blockStart, blockEnd := sorted[0].Start, sorted[0].End
+	for i := 1; i <= len(sorted); i++ {
+		if i < len(sorted) && sorted[i].Start.Sub(blockEnd) < d.MinBreak {
+			if sorted[i].End.After(blockEnd) {
+				blockEnd = sorted[i].End
+			}
+			continue
+		}
+		if driving := blockEnd.Sub(blockStart); driving > d.MaxContinuousDriving {
+			return fmt.Errorf("%w: %s de conducción continua desde %s, el máximo es %s",
+				ErrDutyTimeExceeded, formatHours(driving), blockStart.Format("2006-01-02 15:04"), formatHours(d.MaxContinuousDriving))
+		}
+		if i < len(sorted) {
+			blockStart, blockEnd = sorted[i].Start, sorted[i].End
+		}
+	}
+
+	// Jornada: los turnos separados por menos del descanso mínimo pertenecen a la misma jornada
+	dutyStart, dutyEnd := sorted[0].Start, sorted[0].End
+	driving := sorted[0].End.Sub(sorted[0].Start)
+	for i := 1; i <= len(sorted); i++ {
+		if i < len(sorted) && sorted[i].Start.Sub(dutyEnd) < d.MinRest {
+			driving += sorted[i].End.Sub(sorted[i].Start)
+			dutyEnd = sorted[i].End
+			continue
+		}
+		if driving > d.MaxDailyDriving {
+			return fmt.Errorf("%w: la jornada iniciada el %s suma %s de conducción sin un descanso de %s, el máximo es %s",
+				ErrDutyTimeExceeded, dutyStart.Format("2006-01-02 15:04"), formatHours(driving), formatHours(d.MinRest), formatHours(d.MaxDailyDriving))
+		}
+		if i < len(sorted) {
+			dutyStart, dutyEnd = sorted[i].Start, sorted[i].End
+			driving = sorted[i].End.Sub(sorted[i].Start)
+		}
+	}
+
+	// Conducción en cualquier periodo de 24 horas que empiece con un turno
+	for _, first := range sorted {
+		windowEnd := first.Start.Add(24 * time.Hour)
+		var total time.Duration
+		for _, a := range sorted {
+			total += overlap(a.Start, a.End, first.Start, windowEnd)
+		}
+		if total > d.MaxDailyDriving {
+			return fmt.Errorf("%w: %s de conducción en las 24 horas desde %s, el máximo es %s",
+				ErrDutyTimeExceeded, formatHours(total), first.Start.Format("2006-01-02 15:04"), formatHours(d.MaxDailyDriving))
+		}
+	}
+
+	return nil
+}
+
+// sortedByStart devuelve una copia de los turnos ordenada por inicio
+func sortedByStart(assignments []*Assignment) []*Assignment {
+	sorted := make([]*Assignment, len(assignments))
+	copy(sorted, assignments)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	return sorted
+}
+
+// overlap devuelve cuánto tiempo del intervalo [start, end) cae dentro de [from, to)
+func overlap(start, end, from, to time.Time) time.Duration {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// formatHours muestra una duración en horas con un decimal, por ejemplo "5.5h"
+func formatHours(d time.Duration) string {
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
@@ -0,0 +1,97 @@
+package crew
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testRules son los límites de jornada por defecto de la configuración
+var testRules = DutyRules{
+	MaxContinuousDriving: 5 * time.Hour,
+	MaxDailyDriving:      10 * time.Hour,
+	MinBreak:             30 * time.Minute,
+	MinRest:              8 * time.Hour,
+}
+
+// shift arma un turno que empieza start horas después del inicio de la prueba y dura hours horas
+func shift(routeID string, start, hours float64) *Assignment {
+	base := time.Date(2026, 5, 4, 6, 0, 0, 0, time.UTC)
+	from := base.Add(time.Duration(start * float64(time.Hour)))
+	return &Assignment{RouteID: routeID, Start: from, End: from.Add(time.Duration(hours * float64(time.Hour)))}
+}
+
+func TestDutyRulesValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		assignments []*Assignment
+		wantErr     bool
+	}{
+		{
+			name: "sin turnos",
+		},
+		{
+			name:        "un turno en el límite de conducción continua",
+			assignments: []*Assignment{shift("r1", 0, 5)},
+		},
+		{
+			name:        "un turno que supera la conducción continua",
+			assignments: []*Assignment{shift("r1", 0, 5.5)},
+			wantErr:     true,
+		},
+		{
+			name:        "una pausa más corta que la mínima no interrumpe la conducción continua",
+			assignments: []*Assignment{shift("r1", 0, 3), shift("r2", 3.25, 3)},
+			wantErr:     true,
+		},
+		{
+			name:        "una pausa de la duración mínima interrumpe la conducción continua",
+			assignments: []*Assignment{shift("r1", 0, 4), shift("r2", 4.5, 4)},
+		},
+		{
+			name:        "una jornada que supera la conducción máxima",
+			assignments: []*Assignment{shift("r1", 0, 4), shift("r2", 5, 4), shift("r3", 10, 4)},
+			wantErr:     true,
+		},
+		{
+			name:        "un descanso mínimo separa las jornadas",
+			assignments: []*Assignment{shift("r1", 0, 5), shift("r2", 13, 5), shift("r3", 26, 5)},
+		},
+		{
+			name: "jornadas válidas que superan la conducción en 24 horas",
+			assignments: []*Assignment{
+				shift("r1", 0, 5), shift("r2", 5.5, 4.5), // Jornada de 9.5h
+				shift("r3", 18, 2), // Tras 8h de descanso, pero dentro de las 24 horas
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testRules.Validate(tt.assignments)
+			if tt.wantErr && !errors.Is(err, ErrDutyTimeExceeded) {
+				t.Errorf("Validate() = %v, se esperaba ErrDutyTimeExceeded", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() = %v, se esperaba nil", err)
+			}
+		})
+	}
+}
+
+func TestDutyRulesValidateIgnoresOrder(t *testing.T) {
+	assignments := []*Assignment{shift("r3", 10, 4), shift("r1", 0, 4), shift("r2", 5, 4)}
+	if err := testRules.Validate(assignments); !errors.Is(err, ErrDutyTimeExceeded) {
+		t.Errorf("Validate() = %v, se esperaba ErrDutyTimeExceeded", err)
+	}
+}
+
+func TestCheckOverlap(t *testing.T) {
+	if err := checkOverlap([]*Assignment{shift("r2", 4, 2), shift("r1", 0, 4)}); err != nil {
+		t.Errorf("turnos contiguos: checkOverlap() = %v, se esperaba nil", err)
+	}
+	if err := checkOverlap([]*Assignment{shift("r1", 0, 4), shift("r2", 3.5, 2)}); !errors.Is(err, ErrMemberUnavailable) {
+		t.Errorf("turnos superpuestos: checkOverlap() = %v, se esperaba ErrMemberUnavailable", err)
+	}
+}
@@ -0,0 +1,322 @@
+package crew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"venta-de-pasajes/internal/auth"
+	"venta-de-pasajes/internal/search"
+)
+
+// rosterLocation es la zona horaria de Perú (UTC-5, sin horario de verano) para las fechas del rol
+var rosterLocation = time.FixedZone("PET", -5*60*60)
+
+// OperatorValidator verifica que un operador exista y esté activo
+type OperatorValidator interface {
+	ValidateOperator(ctx context.Context, operatorID string) error
+}
+
+// CrewHandler maneja las solicitudes de administración de la tripulación
+type CrewHandler struct {
+	repo      *Repository
+	assigner  *Assigner
+	operators OperatorValidator
+}
+
+// NewCrewHandler crea una nueva instancia de CrewHandler
+func NewCrewHandler(repo *Repository, assigner *Assigner, operators OperatorValidator) *CrewHandler {
+	return &CrewHandler{
+		repo:      repo,
+		assigner:  assigner,
+		operators: operators,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *CrewHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrMemberNotFound), errors.Is(err, ErrAssignmentNotFound), errors.Is(err, search.ErrRouteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrMemberExists), errors.Is(err, ErrMemberUnavailable), errors.Is(err, ErrDutyTimeExceeded),
+		errors.Is(err, ErrAssignmentConflict), errors.Is(err, search.ErrRouteCancelled):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidMember), errors.Is(err, ErrInvalidAssignment), errors.Is(err, ErrMemberInactive):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetMembersHandler maneja las solicitudes para listar los tripulantes activos de un operador (operator_id),
+// o consultar uno solo con el parámetro id. Con all=true se incluyen los dados de baja.
+// Un admin_operador solo ve los tripulantes de su operador.
+func (h *CrewHandler) GetMembersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if memberID := r.URL.Query().Get("id"); memberID != "" {
+		member, err := h.authorizeMember(r.Context(), memberID)
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(member)
+		return
+	}
+
+	operatorID, err := h.operatorParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	if operatorID == "" {
+		http.Error(w, "el parámetro operator_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.repo.GetMembers(r.Context(), operatorID, r.URL.Query().Get("all") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(members)
+}
+
+// CreateMemberHandler maneja las solicitudes para registrar un tripulante
+func (h *CrewHandler) CreateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	var member Member
+	err := json.NewDecoder(r.Body).Decode(&member)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	// Un admin_operador registra los tripulantes de su operador
+	if member.OperatorID == "" {
+		member.OperatorID = auth.OperatorScope(r.Context())
+	}
+	if err := auth.AuthorizeOperator(r.Context(), member.OperatorID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	if err := validateMember(&member); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	if err := h.operators.ValidateOperator(r.Context(), member.OperatorID); err != nil {
+		http.Error(w, fmt.Errorf("%w: %v", ErrInvalidMember, err).Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateMember(r.Context(), &member); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(member)
+}
+
+// SetMemberActiveHandler maneja las solicitudes para dar de alta o de baja a un tripulante
+func (h *CrewHandler) SetMemberActiveHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID     string `json:"id"`
+		Active *bool  `json:"active"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.ID == "" || requestBody.Active == nil {
+		http.Error(w, "los campos id y active son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.authorizeMember(r.Context(), requestBody.ID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	member, err := h.repo.SetMemberActive(r.Context(), requestBody.ID, *requestBody.Active)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+// AssignHandler maneja las solicitudes para asignar un tripulante a una salida.
+// Los campos start y end son opcionales y permiten asignar un tramo para el relevo de conductores.
+func (h *CrewHandler) AssignHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		RouteID  string    `json:"route_id"`
+		MemberID string    `json:"member_id"`
+		Start    time.Time `json:"start"`
+		End      time.Time `json:"end"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.RouteID == "" || requestBody.MemberID == "" {
+		http.Error(w, "los campos route_id y member_id son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	// El asignador exige que el tripulante y la salida sean del mismo operador
+	if _, err := h.authorizeMember(r.Context(), requestBody.MemberID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	assignment, err := h.assigner.Assign(r.Context(), requestBody.RouteID, requestBody.MemberID, requestBody.Start, requestBody.End)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(assignment)
+}
+
+// UnassignHandler maneja las solicitudes para eliminar una asignación
+func (h *CrewHandler) UnassignHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	assignment, err := h.repo.GetAssignment(r.Context(), requestBody.ID)
+	if err == nil {
+		err = auth.AuthorizeOperator(r.Context(), assignment.OperatorID)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	if err := h.repo.DeleteAssignment(r.Context(), requestBody.ID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RouteCrewHandler maneja las solicitudes para consultar la tripulación de una salida
+func (h *CrewHandler) RouteCrewHandler(w http.ResponseWriter, r *http.Request) {
+	routeID := r.URL.Query().Get("route_id")
+	if routeID == "" {
+		http.Error(w, "el parámetro route_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	routeCrew, err := h.assigner.RouteCrew(r.Context(), routeID)
+	if err == nil {
+		err = auth.AuthorizeOperator(r.Context(), routeCrew.OperatorID)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(routeCrew)
+}
+
+// RosterHandler maneja las solicitudes del rol de la tripulación de un operador.
+// Los parámetros from y to (AAAA-MM-DD) son opcionales; por defecto se muestran los próximos 7 días.
+func (h *CrewHandler) RosterHandler(w http.ResponseWriter, r *http.Request) {
+	operatorID, err := h.operatorParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	if operatorID == "" {
+		http.Error(w, "el parámetro operator_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().In(rosterLocation)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, rosterLocation)
+	to := from.AddDate(0, 0, 7)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, rosterLocation)
+		if err != nil {
+			http.Error(w, "el parámetro from debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, rosterLocation)
+		if err != nil {
+			http.Error(w, "el parámetro to debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+			return
+		}
+		// Incluir el día completo
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		http.Error(w, "el parámetro to debe ser posterior a from", http.StatusBadRequest)
+		return
+	}
+
+	roster, err := h.assigner.Roster(r.Context(), operatorID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roster)
+}
+
+// authorizeMember obtiene el tripulante y verifica que el usuario autenticado pueda gestionar los de su operador
+func (h *CrewHandler) authorizeMember(ctx context.Context, memberID string) (*Member, error) {
+	member, err := h.repo.GetMember(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.AuthorizeOperator(ctx, member.OperatorID); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// operatorParam obtiene el parámetro operator_id, que para un admin_operador es por defecto su operador, y
+// verifica que el usuario autenticado pueda consultarlo. Devuelve vacío si no se indicó.
+func (h *CrewHandler) operatorParam(r *http.Request) (string, error) {
+	operatorID := r.URL.Query().Get("operator_id")
+	if operatorID == "" {
+		operatorID = auth.OperatorScope(r.Context())
+	}
+	if operatorID == "" {
+		return "", nil
+	}
+	if err := auth.AuthorizeOperator(r.Context(), operatorID); err != nil {
+		return "", err
+	}
+	return operatorID, nil
+}
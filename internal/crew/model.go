@@ -0,0 +1,55 @@
+package crew
+
+import "time"
+
+// Funciones de la tripulación
+const (
+	RoleDriver    = "conductor"
+	RoleAttendant = "auxiliar"
+)
+
+// Member representa a un tripulante de un operador
+type Member struct {
+	ID             string    `json:"id,omitempty" bson:"_id,omitempty"`
+	OperatorID     string    `json:"operator_id" bson:"operator_id"`
+	Name           string    `json:"name" bson:"name"`
+	DocumentNumber string    `json:"document_number" bson:"document_number"` // DNI
+	Role           string    `json:"role" bson:"role"`
+	LicenseNumber  string    `json:"license_number,omitempty" bson:"license_number,omitempty"` // Brevete, obligatorio para conductores
+	LicenseExpiry  time.Time `json:"license_expiry,omitempty" bson:"license_expiry,omitempty"`
+	Phone          string    `json:"phone,omitempty" bson:"phone,omitempty"`
+	Active         bool      `json:"active" bson:"active"`
+	Version        int       `json:"-" bson:"version"` // Se incrementa con cada asignación para detectar asignaciones simultáneas
+	CreatedAt      time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Assignment representa la asignación de un tripulante a una salida.
+// Los conductores pueden cubrir solo un tramo de la salida para relevarse en rutas largas.
+type Assignment struct {
+	ID         string    `json:"id,omitempty" bson:"_id,omitempty"`
+	RouteID    string    `json:"route_id" bson:"route_id"`
+	MemberID   string    `json:"member_id" bson:"member_id"`
+	OperatorID string    `json:"operator_id" bson:"operator_id"`
+	Role       string    `json:"role" bson:"role"`
+	Start      time.Time `json:"start" bson:"start"` // Inicio del turno dentro de la salida
+	End        time.Time `json:"end" bson:"end"`     // Fin del turno dentro de la salida
+	CreatedAt  time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+// RosterEntry representa la programación de un tripulante en un periodo
+type RosterEntry struct {
+	Member       *Member       `json:"member"`
+	Assignments  []*Assignment `json:"assignments"`
+	DrivingHours float64       `json:"driving_hours"`
+}
+
+// RouteCrew representa la tripulación asignada a una salida
+type RouteCrew struct {
+	RouteID        string        `json:"route_id"`
+	OperatorID     string        `json:"operator_id"`
+	Departure      time.Time     `json:"departure"`
+	Arrival        time.Time     `json:"arrival"`
+	Assignments    []*Assignment `json:"assignments"`
+	DrivingCovered bool          `json:"driving_covered"` // Todo el viaje tiene un conductor asignado
+}
@@ -0,0 +1,289 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+var (
+	// ErrMemberNotFound indica que el tripulante no existe
+	ErrMemberNotFound = errors.New("tripulante no encontrado")
+	// ErrMemberExists indica que ya existe un tripulante con el mismo DNI
+	ErrMemberExists = errors.New("ya existe un tripulante con el mismo DNI")
+	// ErrMemberInactive indica que el tripulante está dado de baja
+	ErrMemberInactive = errors.New("el tripulante está dado de baja")
+	// ErrAssignmentNotFound indica que la asignación no existe
+	ErrAssignmentNotFound = errors.New("asignación no encontrada")
+	// ErrAssignmentConflict indica que el tripulante recibió otra asignación al mismo tiempo
+	ErrAssignmentConflict = errors.New("el tripulante recibió otra asignación simultánea, intente nuevamente")
+)
+
+// Repository es el repositorio de tripulantes y asignaciones en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para la tripulación")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// members devuelve la colección de tripulantes
+func (r *Repository) members() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.CrewMembersCollection)
+}
+
+// assignments devuelve la colección de asignaciones
+func (r *Repository) assignments() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.CrewAssignmentsCollection)
+}
+
+// EnsureIndexes crea el índice único por DNI y los índices de consulta de asignaciones
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.members().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "document_number", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "operator_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.assignments().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "member_id", Value: 1}, {Key: "start", Value: 1}}},
+		{Keys: bson.D{{Key: "operator_id", Value: 1}, {Key: "start", Value: 1}}},
+		{Keys: bson.D{{Key: "route_id", Value: 1}}},
+	})
+	return err
+}
+
+// CreateMember registra un nuevo tripulante activo
+func (r *Repository) CreateMember(ctx context.Context, member *Member) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	member.ID = uuid.New().String()
+	member.Active = true
+	member.Version = 0
+	member.CreatedAt = time.Now()
+	member.UpdatedAt = member.CreatedAt
+
+	_, err := r.members().InsertOne(ctx, member)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrMemberExists
+	}
+	return err
+}
+
+// SetMemberActive da de alta o de baja a un tripulante
+func (r *Repository) SetMemberActive(ctx context.Context, memberID string, active bool) (*Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var member Member
+	err := r.members().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": memberID},
+		bson.M{"$set": bson.M{"active": active, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&member)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetMember obtiene un tripulante por su ID
+func (r *Repository) GetMember(ctx context.Context, memberID string) (*Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var member Member
+	err := r.members().FindOne(ctx, bson.M{"_id": memberID}).Decode(&member)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetAssignment obtiene una asignación por su ID
+func (r *Repository) GetAssignment(ctx context.Context, assignmentID string) (*Assignment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var assignment Assignment
+	err := r.assignments().FindOne(ctx, bson.M{"_id": assignmentID}).Decode(&assignment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, err
+	}
+
+	return &assignment, nil
+}
+
+// GetMembers obtiene los tripulantes de un operador ordenados por nombre
+func (r *Repository) GetMembers(ctx context.Context, operatorID string, includeInactive bool) ([]*Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"operator_id": operatorID}
+	if !includeInactive {
+		filter["active"] = true
+	}
+
+	cursor, err := r.members().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	members := []*Member{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// CreateAssignment registra una asignación validada con la versión leída del tripulante.
+// Si el tripulante recibió otra asignación desde entonces, la validación quedó desactualizada y se rechaza.
+func (r *Repository) CreateAssignment(ctx context.Context, assignment *Assignment, memberVersion int) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.members().UpdateOne(
+		ctx,
+		bson.M{"_id": assignment.MemberID, "version": memberVersion},
+		bson.M{"$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAssignmentConflict
+	}
+
+	assignment.ID = uuid.New().String()
+	assignment.CreatedAt = time.Now()
+
+	_, err = r.assignments().InsertOne(ctx, assignment)
+	return err
+}
+
+// DeleteAssignment elimina una asignación
+func (r *Repository) DeleteAssignment(ctx context.Context, assignmentID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.assignments().DeleteOne(ctx, bson.M{"_id": assignmentID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrAssignmentNotFound
+	}
+	return nil
+}
+
+// DeleteRouteAssignments elimina las asignaciones de una salida y devuelve cuántas se eliminaron
+func (r *Repository) DeleteRouteAssignments(ctx context.Context, routeID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.assignments().DeleteMany(ctx, bson.M{"route_id": routeID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// ShiftRouteAssignments desplaza los turnos de una salida reprogramada
+func (r *Repository) ShiftRouteAssignments(ctx context.Context, routeID string, delta time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	milliseconds := delta.Milliseconds()
+	_, err := r.assignments().UpdateMany(
+		ctx,
+		bson.M{"route_id": routeID},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"start": bson.M{"$add": bson.A{"$start", milliseconds}},
+			"end":   bson.M{"$add": bson.A{"$end", milliseconds}},
+		}}}},
+	)
+	return err
+}
+
+// GetRouteAssignments obtiene las asignaciones de una salida ordenadas por inicio
+func (r *Repository) GetRouteAssignments(ctx context.Context, routeID string) ([]*Assignment, error) {
+	return r.findAssignments(ctx, bson.M{"route_id": routeID})
+}
+
+// GetMemberAssignments obtiene las asignaciones de un tripulante que se cruzan con el periodo [from, to)
+func (r *Repository) GetMemberAssignments(ctx context.Context, memberID string, from, to time.Time) ([]*Assignment, error) {
+	return r.findAssignments(ctx, bson.M{"member_id": memberID, "start": bson.M{"$lt": to}, "end": bson.M{"$gt": from}})
+}
+
+// GetOperatorAssignments obtiene las asignaciones de los tripulantes de un operador que se cruzan con el periodo [from, to)
+func (r *Repository) GetOperatorAssignments(ctx context.Context, operatorID string, from, to time.Time) ([]*Assignment, error) {
+	return r.findAssignments(ctx, bson.M{"operator_id": operatorID, "start": bson.M{"$lt": to}, "end": bson.M{"$gt": from}})
+}
+
+// findAssignments obtiene las asignaciones que cumplen el filtro ordenadas por inicio
+func (r *Repository) findAssignments(ctx context.Context, filter bson.M) ([]*Assignment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.assignments().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	assignments := []*Assignment{}
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
@@ -0,0 +1,39 @@
+package crew
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidMember indica que los datos del tripulante no son válidos
+var ErrInvalidMember = errors.New("tripulante inválido")
+
+// validateMember verifica los datos del tripulante; los conductores deben tener brevete vigente registrado
+func validateMember(member *Member) error {
+	member.Name = strings.TrimSpace(member.Name)
+	member.DocumentNumber = strings.TrimSpace(member.DocumentNumber)
+	member.LicenseNumber = strings.ToUpper(strings.TrimSpace(member.LicenseNumber))
+
+	if member.OperatorID == "" {
+		return fmt.Errorf("%w: el campo operator_id es obligatorio", ErrInvalidMember)
+	}
+	if member.Name == "" {
+		return fmt.Errorf("%w: el campo name es obligatorio", ErrInvalidMember)
+	}
+	if len(member.DocumentNumber) != 8 || strings.Trim(member.DocumentNumber, "0123456789") != "" {
+		return fmt.Errorf("%w: el DNI debe tener 8 dígitos", ErrInvalidMember)
+	}
+
+	switch member.Role {
+	case RoleDriver:
+		if member.LicenseNumber == "" || member.LicenseExpiry.IsZero() {
+			return fmt.Errorf("%w: los conductores deben registrar license_number y license_expiry", ErrInvalidMember)
+		}
+	case RoleAttendant:
+	default:
+		return fmt.Errorf("%w: función %q desconocida, use %s o %s", ErrInvalidMember, member.Role, RoleDriver, RoleAttendant)
+	}
+
+	return nil
+}
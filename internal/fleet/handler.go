@@ -0,0 +1,173 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"venta-de-pasajes/internal/auth"
+)
+
+// OperatorValidator verifica que un operador exista y esté activo
+type OperatorValidator interface {
+	ValidateOperator(ctx context.Context, operatorID string) error
+}
+
+// FleetHandler maneja las solicitudes de administración de la flota
+type FleetHandler struct {
+	repo      *Repository
+	operators OperatorValidator
+}
+
+// NewFleetHandler crea una nueva instancia de FleetHandler
+func NewFleetHandler(repo *Repository, operators OperatorValidator) *FleetHandler {
+	return &FleetHandler{
+		repo:      repo,
+		operators: operators,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *FleetHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrVehicleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVehicleExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidVehicle):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetVehiclesHandler maneja las solicitudes para listar los vehículos, opcionalmente de un operador (operator_id),
+// o consultar uno solo con el parámetro id. Un admin_operador solo ve los de su operador.
+func (h *FleetHandler) GetVehiclesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if vehicleID := r.URL.Query().Get("id"); vehicleID != "" {
+		vehicle, err := h.authorizeVehicle(r.Context(), vehicleID)
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(vehicle)
+		return
+	}
+
+	operatorID := r.URL.Query().Get("operator_id")
+	if operatorID == "" {
+		operatorID = auth.OperatorScope(r.Context())
+	}
+	// Sin operador se listan todos, lo que un admin_operador no puede hacer
+	if err := auth.AuthorizeOperator(r.Context(), operatorID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	vehicles, err := h.repo.GetVehicles(r.Context(), operatorID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(vehicles)
+}
+
+// CreateVehicleHandler maneja las solicitudes para registrar un vehículo con su croquis de asientos
+func (h *FleetHandler) CreateVehicleHandler(w http.ResponseWriter, r *http.Request) {
+	var vehicle Vehicle
+	err := json.NewDecoder(r.Body).Decode(&vehicle)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	// Un admin_operador registra los vehículos de su operador
+	if vehicle.OperatorID == "" {
+		vehicle.OperatorID = auth.OperatorScope(r.Context())
+	}
+	if err := auth.AuthorizeOperator(r.Context(), vehicle.OperatorID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	if err := validateVehicle(&vehicle); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	if err := h.operators.ValidateOperator(r.Context(), vehicle.OperatorID); err != nil {
+		http.Error(w, fmt.Errorf("%w: %v", ErrInvalidVehicle, err).Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateVehicle(r.Context(), &vehicle); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(vehicle)
+}
+
+// UpdateVehicleHandler maneja las solicitudes para modificar el modelo, las comodidades o el estado de servicio de un
+// vehículo. Solo se modifican los campos enviados.
+func (h *FleetHandler) UpdateVehicleHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+		VehicleUpdate
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	update := requestBody.VehicleUpdate
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if update.Model == nil && update.Amenities == nil && update.Active == nil {
+		http.Error(w, "indique al menos uno de los campos model, amenities o active", http.StatusBadRequest)
+		return
+	}
+	if update.Amenities != nil {
+		if err := validateAmenities(*update.Amenities); err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+	}
+
+	if _, err := h.authorizeVehicle(r.Context(), requestBody.ID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	vehicle, err := h.repo.UpdateVehicle(r.Context(), requestBody.ID, update)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vehicle)
+}
+
+// authorizeVehicle obtiene el vehículo y verifica que el usuario autenticado pueda gestionar los de su operador
+func (h *FleetHandler) authorizeVehicle(ctx context.Context, vehicleID string) (*Vehicle, error) {
+	vehicle, err := h.repo.GetVehicle(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.AuthorizeOperator(ctx, vehicle.OperatorID); err != nil {
+		return nil, err
+	}
+	return vehicle, nil
+}
@@ -0,0 +1,78 @@
+package fleet
+
+import "time"
+
+// Comodidades de un bus
+const (
+	AmenityWifi            = "wifi"
+	AmenityToilet          = "bano"
+	AmenityUSB             = "usb"
+	AmenityAirConditioning = "aire_acondicionado"
+	AmenityScreen          = "pantalla"
+	AmenityCatering        = "servicio_a_bordo"
+)
+
+// Tipos de asiento
+const (
+	SeatStandard  = "estandar"
+	SeatSemiSleep = "semi_cama"
+	SeatSleep     = "cama"
+)
+
+// Vehicle representa un bus de la flota de un operador
+type Vehicle struct {
+	ID         string     `json:"id,omitempty" bson:"_id,omitempty"`
+	OperatorID string     `json:"operator_id" bson:"operator_id"`
+	Plate      string     `json:"plate" bson:"plate"` // Placa, por ejemplo "ABC-123"
+	Model      string     `json:"model,omitempty" bson:"model,omitempty"`
+	Capacity   int        `json:"capacity" bson:"capacity"` // Cantidad de asientos del croquis
+	Layout     SeatLayout `json:"layout" bson:"layout"`
+	Amenities  []string   `json:"amenities,omitempty" bson:"amenities,omitempty"`
+	Active     bool       `json:"active" bson:"active"`
+	CreatedAt  time.Time  `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// VehicleUpdate representa los cambios de un vehículo; los campos nulos no se modifican
+type VehicleUpdate struct {
+	Model     *string   `json:"model"`
+	Amenities *[]string `json:"amenities"`
+	Active    *bool     `json:"active"`
+}
+
+// SeatLayout representa el croquis de asientos del bus como una grilla por piso
+type SeatLayout struct {
+	Decks   int    `json:"decks" bson:"decks"`     // Pisos (1 o 2)
+	Rows    int    `json:"rows" bson:"rows"`       // Filas por piso
+	Columns int    `json:"columns" bson:"columns"` // Columnas por fila, incluido el pasillo
+	Seats   []Seat `json:"seats" bson:"seats"`
+}
+
+// Seat representa un asiento del croquis
+type Seat struct {
+	Number     string `json:"number" bson:"number"` // Número impreso en el asiento
+	Deck       int    `json:"deck" bson:"deck"`     // Desde 1
+	Row        int    `json:"row" bson:"row"`       // Desde 1
+	Column     int    `json:"column" bson:"column"` // Desde 1
+	Type       string `json:"type" bson:"type"`
+	Accessible bool   `json:"accessible,omitempty" bson:"accessible,omitempty"` // Reservado para pasajeros con movilidad reducida
+}
+
+// HasSeat indica si el croquis tiene un asiento con el número dado
+func (l *SeatLayout) HasSeat(number string) bool {
+	for _, seat := range l.Seats {
+		if seat.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// SeatNumbers devuelve los números de todos los asientos del croquis
+func (l *SeatLayout) SeatNumbers() []string {
+	numbers := make([]string, 0, len(l.Seats))
+	for _, seat := range l.Seats {
+		numbers = append(numbers, seat.Number)
+	}
+	return numbers
+}
@@ -0,0 +1,191 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+var (
+	// ErrVehicleNotFound indica que el vehículo no existe
+	ErrVehicleNotFound = errors.New("vehículo no encontrado")
+	// ErrVehicleExists indica que ya existe un vehículo con la misma placa
+	ErrVehicleExists = errors.New("ya existe un vehículo con la misma placa")
+	// ErrVehicleInactive indica que el vehículo está fuera de servicio
+	ErrVehicleInactive = errors.New("el vehículo está fuera de servicio")
+)
+
+// Repository es el repositorio de la flota en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para la flota")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// collection devuelve la colección de vehículos
+func (r *Repository) collection() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.VehiclesCollection)
+}
+
+// EnsureIndexes crea el índice único por placa y el índice por operador
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "plate", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "operator_id", Value: 1}}},
+	})
+	return err
+}
+
+// CreateVehicle registra un nuevo vehículo en servicio
+func (r *Repository) CreateVehicle(ctx context.Context, vehicle *Vehicle) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	vehicle.ID = uuid.New().String()
+	vehicle.Active = true
+	vehicle.CreatedAt = time.Now()
+	vehicle.UpdatedAt = vehicle.CreatedAt
+
+	_, err := r.collection().InsertOne(ctx, vehicle)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrVehicleExists
+	}
+	return err
+}
+
+// UpdateVehicle modifica el modelo, las comodidades o el estado de servicio de un vehículo; los campos nulos se
+// conservan. El croquis no se modifica porque las salidas asignadas dependen de él; para otro croquis se registra
+// otro vehículo. Las comodidades nuevas se aplican a las salidas futuras del vehículo y a sus horarios.
+func (r *Repository) UpdateVehicle(ctx context.Context, vehicleID string, update VehicleUpdate) (*Vehicle, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"updated_at": time.Now()}
+	if update.Model != nil {
+		set["model"] = *update.Model
+	}
+	if update.Amenities != nil {
+		set["amenities"] = *update.Amenities
+	}
+	if update.Active != nil {
+		set["active"] = *update.Active
+	}
+
+	var vehicle Vehicle
+	err := r.collection().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": vehicleID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&vehicle)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrVehicleNotFound
+		}
+		return nil, err
+	}
+
+	if update.Amenities != nil {
+		if err := r.propagateAmenities(ctx, &vehicle); err != nil {
+			return nil, err
+		}
+	}
+
+	return &vehicle, nil
+}
+
+// propagateAmenities copia las comodidades del vehículo a sus salidas que aún no parten y a sus horarios.
+// Repetir la modificación vuelve a aplicarlas si una actualización anterior falló.
+func (r *Repository) propagateAmenities(ctx context.Context, vehicle *Vehicle) error {
+	db := r.client.Database(r.config.MongoDB.DatabaseName)
+
+	_, err := db.Collection(r.config.MongoDB.RoutesCollection).UpdateMany(
+		ctx,
+		bson.M{"vehicle_id": vehicle.ID, "departure": bson.M{"$gt": time.Now()}},
+		bson.M{"$set": bson.M{"amenities": vehicle.Amenities, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection(r.config.MongoDB.ScheduleTemplatesCollection).UpdateMany(
+		ctx,
+		bson.M{"vehicle_id": vehicle.ID},
+		bson.M{"$set": bson.M{"amenities": vehicle.Amenities, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// GetVehicle obtiene un vehículo por su ID
+func (r *Repository) GetVehicle(ctx context.Context, vehicleID string) (*Vehicle, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var vehicle Vehicle
+	err := r.collection().FindOne(ctx, bson.M{"_id": vehicleID}).Decode(&vehicle)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrVehicleNotFound
+		}
+		return nil, err
+	}
+
+	return &vehicle, nil
+}
+
+// GetVehicles obtiene los vehículos ordenados por placa, opcionalmente de un operador
+func (r *Repository) GetVehicles(ctx context.Context, operatorID string) ([]*Vehicle, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if operatorID != "" {
+		filter["operator_id"] = operatorID
+	}
+
+	cursor, err := r.collection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "plate", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	vehicles := []*Vehicle{}
+	if err := cursor.All(ctx, &vehicles); err != nil {
+		return nil, err
+	}
+
+	return vehicles, nil
+}
@@ -0,0 +1,107 @@
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidVehicle indica que los datos del vehículo no son válidos
+var ErrInvalidVehicle = errors.New("vehículo inválido")
+
+// platePattern valida las placas peruanas de tres caracteres y tres dígitos, por ejemplo "ABC-123" o "A1B-234"
+var platePattern = regexp.MustCompile(`^[A-Z0-9]{3}-[0-9]{3}$`)
+
+// knownAmenities contiene las comodidades admitidas
+var knownAmenities = map[string]bool{
+	AmenityWifi:            true,
+	AmenityToilet:          true,
+	AmenityUSB:             true,
+	AmenityAirConditioning: true,
+	AmenityScreen:          true,
+	AmenityCatering:        true,
+}
+
+// knownSeatTypes contiene los tipos de asiento admitidos
+var knownSeatTypes = map[string]bool{
+	SeatStandard:  true,
+	SeatSemiSleep: true,
+	SeatSleep:     true,
+}
+
+// validateVehicle verifica los datos del vehículo y calcula su capacidad a partir del croquis
+func validateVehicle(vehicle *Vehicle) error {
+	vehicle.Plate = strings.ToUpper(strings.TrimSpace(vehicle.Plate))
+	if !platePattern.MatchString(vehicle.Plate) {
+		return fmt.Errorf("%w: placa %q, use el formato ABC-123", ErrInvalidVehicle, vehicle.Plate)
+	}
+	if vehicle.OperatorID == "" {
+		return fmt.Errorf("%w: el campo operator_id es obligatorio", ErrInvalidVehicle)
+	}
+	if err := validateAmenities(vehicle.Amenities); err != nil {
+		return err
+	}
+	if err := validateLayout(&vehicle.Layout); err != nil {
+		return err
+	}
+
+	vehicle.Capacity = len(vehicle.Layout.Seats)
+	return nil
+}
+
+// validateAmenities verifica que las comodidades sean conocidas
+func validateAmenities(amenities []string) error {
+	for _, amenity := range amenities {
+		if !knownAmenities[amenity] {
+			return fmt.Errorf("%w: comodidad desconocida %q", ErrInvalidVehicle, amenity)
+		}
+	}
+	return nil
+}
+
+// validateLayout verifica que cada asiento tenga un número único y una posición libre dentro de la grilla
+func validateLayout(layout *SeatLayout) error {
+	if layout.Decks < 1 || layout.Decks > 2 {
+		return fmt.Errorf("%w: el croquis debe tener 1 o 2 pisos", ErrInvalidVehicle)
+	}
+	if layout.Rows <= 0 || layout.Columns <= 0 {
+		return fmt.Errorf("%w: el croquis debe indicar filas y columnas", ErrInvalidVehicle)
+	}
+	if len(layout.Seats) == 0 {
+		return fmt.Errorf("%w: el croquis no tiene asientos", ErrInvalidVehicle)
+	}
+
+	numbers := make(map[string]bool, len(layout.Seats))
+	positions := make(map[[3]int]bool, len(layout.Seats))
+	for i := range layout.Seats {
+		seat := &layout.Seats[i]
+		seat.Number = strings.TrimSpace(seat.Number)
+		if seat.Type == "" {
+			seat.Type = SeatStandard
+		}
+
+		if seat.Number == "" {
+			return fmt.Errorf("%w: todos los asientos deben tener número", ErrInvalidVehicle)
+		}
+		if numbers[seat.Number] {
+			return fmt.Errorf("%w: asiento %s repetido", ErrInvalidVehicle, seat.Number)
+		}
+		numbers[seat.Number] = true
+
+		if seat.Deck < 1 || seat.Deck > layout.Decks || seat.Row < 1 || seat.Row > layout.Rows || seat.Column < 1 || seat.Column > layout.Columns {
+			return fmt.Errorf("%w: el asiento %s está fuera del croquis", ErrInvalidVehicle, seat.Number)
+		}
+		position := [3]int{seat.Deck, seat.Row, seat.Column}
+		if positions[position] {
+			return fmt.Errorf("%w: el asiento %s ocupa la posición de otro asiento", ErrInvalidVehicle, seat.Number)
+		}
+		positions[position] = true
+
+		if !knownSeatTypes[seat.Type] {
+			return fmt.Errorf("%w: tipo de asiento desconocido %q", ErrInvalidVehicle, seat.Type)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package fleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// testLayout crea un croquis de un piso y dos filas de dos asientos
+func testLayout() SeatLayout {
+	return SeatLayout{Decks: 1, Rows: 2, Columns: 3, Seats: []Seat{
+		{Number: "1A", Deck: 1, Row: 1, Column: 1, Type: SeatSleep},
+		{Number: "1B", Deck: 1, Row: 1, Column: 3, Type: SeatSleep},
+		{Number: "2A", Deck: 1, Row: 2, Column: 1},
+		{Number: "2B", Deck: 1, Row: 2, Column: 3, Type: SeatSemiSleep},
+	}}
+}
+
+func TestValidateVehicle(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(*Vehicle)
+		wantErr error
+	}{
+		{name: "válido"},
+		{name: "placa en minúsculas y con espacios", prepare: func(v *Vehicle) { v.Plate = " abc-123 " }},
+		{name: "placa con letras y dígitos", prepare: func(v *Vehicle) { v.Plate = "A1B-234" }},
+		{name: "placa sin guion", prepare: func(v *Vehicle) { v.Plate = "ABC123" }, wantErr: ErrInvalidVehicle},
+		{name: "placa vacía", prepare: func(v *Vehicle) { v.Plate = "" }, wantErr: ErrInvalidVehicle},
+		{name: "sin operador", prepare: func(v *Vehicle) { v.OperatorID = "" }, wantErr: ErrInvalidVehicle},
+		{name: "comodidad desconocida", prepare: func(v *Vehicle) { v.Amenities = []string{AmenityWifi, "jacuzzi"} }, wantErr: ErrInvalidVehicle},
+		{name: "croquis sin asientos", prepare: func(v *Vehicle) { v.Layout.Seats = nil }, wantErr: ErrInvalidVehicle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := &Vehicle{OperatorID: "op-1", Plate: "ABC-123", Amenities: []string{AmenityWifi, AmenityToilet, AmenityUSB}, Layout: testLayout()}
+			if tt.prepare != nil {
+				tt.prepare(vehicle)
+			}
+
+			err := validateVehicle(vehicle)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("validateVehicle() = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if vehicle.Plate != "ABC-123" && vehicle.Plate != "A1B-234" {
+				t.Errorf("placa = %q, se esperaba normalizada en mayúsculas y sin espacios", vehicle.Plate)
+			}
+			if vehicle.Capacity != len(vehicle.Layout.Seats) {
+				t.Errorf("capacidad = %d, se esperaba %d", vehicle.Capacity, len(vehicle.Layout.Seats))
+			}
+		})
+	}
+}
+
+func TestValidateLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(*SeatLayout)
+		wantErr error
+	}{
+		{name: "válido"},
+		{
+			name: "dos pisos",
+			prepare: func(l *SeatLayout) {
+				l.Decks = 2
+				l.Seats = append(l.Seats, Seat{Number: "3A", Deck: 2, Row: 1, Column: 1})
+			},
+		},
+		{name: "sin pisos", prepare: func(l *SeatLayout) { l.Decks = 0 }, wantErr: ErrInvalidVehicle},
+		{name: "tres pisos", prepare: func(l *SeatLayout) { l.Decks = 3 }, wantErr: ErrInvalidVehicle},
+		{name: "sin filas", prepare: func(l *SeatLayout) { l.Rows = 0 }, wantErr: ErrInvalidVehicle},
+		{name: "sin columnas", prepare: func(l *SeatLayout) { l.Columns = 0 }, wantErr: ErrInvalidVehicle},
+		{name: "sin asientos", prepare: func(l *SeatLayout) { l.Seats = nil }, wantErr: ErrInvalidVehicle},
+		{name: "asiento sin número", prepare: func(l *SeatLayout) { l.Seats[0].Number = "  " }, wantErr: ErrInvalidVehicle},
+		{name: "número repetido", prepare: func(l *SeatLayout) { l.Seats[1].Number = " 1A" }, wantErr: ErrInvalidVehicle},
+		{name: "fila fuera del croquis", prepare: func(l *SeatLayout) { l.Seats[3].Row = 3 }, wantErr: ErrInvalidVehicle},
+		{name: "columna fuera del croquis", prepare: func(l *SeatLayout) { l.Seats[3].Column = 4 }, wantErr: ErrInvalidVehicle},
+		{name: "piso fuera del croquis", prepare: func(l *SeatLayout) { l.Seats[3].Deck = 2 }, wantErr: ErrInvalidVehicle},
+		{name: "posición ocupada", prepare: func(l *SeatLayout) { l.Seats[3].Column = 1 }, wantErr: ErrInvalidVehicle},
+		{name: "tipo de asiento desconocido", prepare: func(l *SeatLayout) { l.Seats[2].Type = "premium" }, wantErr: ErrInvalidVehicle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := testLayout()
+			if tt.prepare != nil {
+				tt.prepare(&layout)
+			}
+			if err := validateLayout(&layout); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateLayout() = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLayoutDefaultsSeatType(t *testing.T) {
+	layout := testLayout()
+	layout.Seats[0].Number = " 1A "
+
+	if err := validateLayout(&layout); err != nil {
+		t.Fatal(err)
+	}
+	if layout.Seats[0].Number != "1A" || layout.Seats[2].Type != SeatStandard {
+		t.Errorf("asientos = %+v, se esperaba el número sin espacios y el tipo estándar por defecto", layout.Seats)
+	}
+}
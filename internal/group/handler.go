@@ -0,0 +1,208 @@
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"venta-de-pasajes/internal/auth"
+	"venta-de-pasajes/internal/search"
+)
+
+// GroupHandler maneja las solicitudes de reservas de grupo
+type GroupHandler struct {
+	repo    *Repository
+	manager *Manager
+}
+
+// NewGroupHandler crea una nueva instancia de GroupHandler
+func NewGroupHandler(repo *Repository, manager *Manager) *GroupHandler {
+	return &GroupHandler{
+		repo:    repo,
+		manager: manager,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *GroupHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBookingNotFound), errors.Is(err, search.ErrRouteNotFound), errors.Is(err, search.ErrReservationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBookingCancelled), errors.Is(err, ErrNamesClosed), errors.Is(err, search.ErrRouteCancelled),
+		errors.Is(err, search.ErrReservationNotConfirmed), errors.Is(err, search.ErrReservationNotActive),
+		errors.Is(err, search.ErrCancellationClosed):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidBooking), errors.Is(err, search.ErrInvalidPassengers), errors.Is(err, search.ErrInvalidSeats),
+		errors.Is(err, search.ErrInvalidSegment), errors.Is(err, search.ErrNotEnoughSeats):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CreateGroupHandler maneja las solicitudes para reservar los asientos de un grupo con tarifa negociada
+func (h *GroupHandler) CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var request CreateRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	// El organizador es siempre el usuario autenticado, y solo el personal con el permiso pacta tarifas; a los
+	// demás se les aplica el descuento de grupo
+	request.UserID = auth.UserID(r.Context())
+	if principal, ok := auth.PrincipalFrom(r.Context()); !ok || !principal.Can(auth.PermGroupFares) {
+		request.Fare = 0
+	}
+
+	booking, err := h.manager.Create(r.Context(), request)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(booking)
+}
+
+// GetGroupsHandler maneja las solicitudes para consultar una reserva de grupo (id), con su saldo y sus pasajeros,
+// o listar las reservas de grupo del organizador autenticado
+func (h *GroupHandler) GetGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID := auth.UserID(r.Context())
+
+	if bookingID := r.URL.Query().Get("id"); bookingID != "" {
+		details, err := h.manager.Get(r.Context(), bookingID)
+		if err == nil && details.UserID != userID {
+			err = ErrBookingNotFound
+		}
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(details)
+		return
+	}
+
+	bookings, err := h.repo.GetBookings(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(bookings)
+}
+
+// SetPassengersHandler maneja las solicitudes del organizador para entregar o reemplazar la lista de pasajeros
+func (h *GroupHandler) SetPassengersHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID         string             `json:"id"`
+		Passengers []search.Passenger `json:"passengers"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.manager.SetPassengers(r.Context(), requestBody.ID, auth.UserID(r.Context()), requestBody.Passengers)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// CancelPassengersHandler maneja las solicitudes del organizador para cancelar algunos pasajeros del grupo
+// (passenger_ids) y, además, una cantidad de asientos aún sin nombre (seats)
+func (h *GroupHandler) CancelPassengersHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID           string   `json:"id"`
+		PassengerIDs []string `json:"passenger_ids"`
+		Seats        int      `json:"seats"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	details, err := h.manager.CancelPassengers(r.Context(), requestBody.ID, auth.UserID(r.Context()), requestBody.PassengerIDs, requestBody.Seats)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// CancelGroupHandler maneja las solicitudes del organizador para cancelar el grupo entero
+func (h *GroupHandler) CancelGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID     string `json:"id"`
+		Reason string `json:"reason"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Reason == "" {
+		requestBody.Reason = "cancelada por el organizador"
+	}
+
+	booking, err := h.manager.Cancel(r.Context(), requestBody.ID, auth.UserID(r.Context()), requestBody.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(booking)
+}
+
+// AddPaymentHandler maneja las solicitudes para registrar un pago del adelanto o del saldo de un grupo
+func (h *GroupHandler) AddPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID        string  `json:"id"`
+		Amount    float64 `json:"amount"`
+		Reference string  `json:"reference"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := h.manager.AddPayment(r.Context(), requestBody.ID, requestBody.Amount, requestBody.Reference)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&Details{Booking: booking, Balance: booking.Balance()})
+}
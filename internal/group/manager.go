@@ -0,0 +1,459 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/search"
+)
+
+var (
+	// ErrInvalidBooking indica que los datos de la reserva de grupo no son válidos
+	ErrInvalidBooking = errors.New("reserva de grupo inválida")
+	// ErrNamesClosed indica que venció el plazo para entregar o cambiar la lista de pasajeros
+	ErrNamesClosed = errors.New("venció el plazo para la lista de pasajeros")
+)
+
+// Reservations es el acceso a las reservas de asientos sobre las que se montan los grupos
+type Reservations interface {
+	GetRouteByID(ctx context.Context, routeID string) (*search.Route, error)
+	GetReservationByID(ctx context.Context, reservationID string) (*search.Reservation, error)
+	ReserveRoute(ctx context.Context, request search.ReservationRequest) (*search.Reservation, error)
+	CancelReservation(ctx context.Context, reservationID, userID string) (*search.Reservation, error)
+	SetReservationPassengers(ctx context.Context, reservationID string, passengers []search.Passenger) (*search.Reservation, error)
+	CancelReservationPassengers(ctx context.Context, reservationID string, passengerIDs []string, unnamed int, fare float64) (*search.Reservation, error)
+}
+
+// Manager gestiona las reservas de grupo: las crea sobre una reserva de asientos con tarifa negociada, registra
+// los pagos del adelanto y el saldo, recibe la lista de pasajeros y cancela pasajeros o el grupo entero.
+// Los grupos que no pagan a tiempo se cancelan y sus asientos vuelven a la venta.
+type Manager struct {
+	repo         *Repository
+	reservations Reservations
+	operators    search.OperatorDirectory
+	boarding     search.CheckInRecords
+	vehicles     search.VehicleDirectory
+	config       config.GroupConfig
+	released     []search.SeatReleaseHook
+}
+
+// NewManager crea una nueva instancia de Manager
+func NewManager(repo *Repository, reservations Reservations, operators search.OperatorDirectory, boarding search.CheckInRecords, vehicles search.VehicleDirectory, cfg config.GroupConfig) *Manager {
+	return &Manager{
+		repo:         repo,
+		reservations: reservations,
+		operators:    operators,
+		boarding:     boarding,
+		vehicles:     vehicles,
+		config:       cfg,
+	}
+}
+
+// RegisterSeatReleaseHook registra un hook que se ejecuta al liberar asientos de un grupo
+func (m *Manager) RegisterSeatReleaseHook(hook search.SeatReleaseHook) {
+	m.released = append(m.released, hook)
+}
+
+// Create reserva los asientos del grupo con la tarifa negociada, o con el descuento de grupo si no se negoció,
+// y fija el adelanto y los plazos de pago y de la lista de pasajeros
+func (m *Manager) Create(ctx context.Context, request CreateRequest) (*Booking, error) {
+	request.GroupName = strings.TrimSpace(request.GroupName)
+	if request.UserID == "" || request.RouteID == "" || request.GroupName == "" {
+		return nil, fmt.Errorf("%w: los campos route_id, user_id y group_name son obligatorios", ErrInvalidBooking)
+	}
+	if request.Seats == 0 {
+		request.Seats = len(request.SeatNumbers)
+	}
+	if request.Seats < m.config.MinSize {
+		return nil, fmt.Errorf("%w: un grupo debe reservar al menos %d asientos", ErrInvalidBooking, m.config.MinSize)
+	}
+
+	route, err := m.reservations.GetRouteByID(ctx, request.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == search.RouteCancelled {
+		return nil, search.ErrRouteCancelled
+	}
+	now := time.Now()
+	if !route.Departure.After(now) {
+		return nil, fmt.Errorf("%w: la salida ya partió", ErrInvalidBooking)
+	}
+	// Los asientos elegidos deben existir en el croquis del vehículo y no repetirse
+	if len(request.SeatNumbers) > 0 {
+		if err := search.ValidateSeatNumbers(ctx, m.vehicles, route, request.Seats, request.SeatNumbers); err != nil {
+			return nil, err
+		}
+	}
+	segment, err := route.SegmentBetween(request.From, request.To)
+	if err != nil {
+		return nil, err
+	}
+
+	fare := request.Fare
+	switch {
+	case fare == 0:
+		fare = search.RoundAmount(segment.Price * (1 - m.config.DefaultDiscount/100))
+	case fare < 0 || fare > segment.Price:
+		return nil, fmt.Errorf("%w: la tarifa de grupo debe ser positiva y no mayor a la tarifa del tramo (%.2f)", ErrInvalidBooking, segment.Price)
+	}
+
+	booking := &Booking{
+		ID:         uuid.New().String(),
+		RouteID:    route.ID,
+		OperatorID: route.OperatorID,
+		UserID:     request.UserID,
+		GroupName:  request.GroupName,
+		Seats:      request.Seats,
+		Fare:       fare,
+		Total:      search.RoundAmount(fare * float64(request.Seats)),
+	}
+	booking.Deposit = search.RoundAmount(booking.Total * m.config.DepositPercent / 100)
+	booking.DepositDueAt, booking.BalanceDueAt, booking.NamesDueAt = m.deadlines(now, route.Departure)
+
+	reservation, err := m.reservations.ReserveRoute(ctx, search.ReservationRequest{
+		RouteID:     route.ID,
+		UserID:      request.UserID,
+		Seats:       request.Seats,
+		SeatNumbers: request.SeatNumbers,
+		From:        segment.From,
+		To:          segment.To,
+		Fare:        fare,
+		GroupID:     booking.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	booking.ReservationID = reservation.ID
+
+	if err := m.repo.CreateBooking(ctx, booking); err != nil {
+		// Devolver los asientos si el grupo no se pudo registrar
+		cancelled, cancelErr := m.reservations.CancelReservation(ctx, reservation.ID, request.UserID)
+		if cancelErr != nil {
+			log.Printf("Error al liberar la reserva %s del grupo no registrado: %v", reservation.ID, cancelErr)
+			return nil, err
+		}
+		search.RunSeatReleaseHooks(ctx, m.released, cancelled)
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// deadlines calcula los vencimientos del adelanto, del saldo y de la lista de pasajeros. Ningún plazo pasa de
+// la salida, y si la salida está próxima el saldo vence junto con el adelanto.
+func (m *Manager) deadlines(now, departure time.Time) (deposit, balance, names time.Time) {
+	deposit = now.Add(m.config.DepositWindow)
+	if deposit.After(departure) {
+		deposit = departure
+	}
+	balance = departure.Add(-m.config.BalanceLeadTime)
+	if balance.Before(deposit) {
+		balance = deposit
+	}
+	names = departure.Add(-m.config.NameListLeadTime)
+	if names.Before(now) {
+		names = departure
+	}
+	return deposit, balance, names
+}
+
+// Get obtiene una reserva de grupo con su reserva de asientos y sus pasajeros
+func (m *Manager) Get(ctx context.Context, bookingID string) (*Details, error) {
+	booking, err := m.repo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	reservation, err := m.reservations.GetReservationByID(ctx, booking.ReservationID)
+	if err != nil {
+		return nil, err
+	}
+	return &Details{Booking: booking, Balance: booking.Balance(), Reservation: reservation}, nil
+}
+
+// AddPayment registra un pago del organizador
+func (m *Manager) AddPayment(ctx context.Context, bookingID string, amount float64, reference string) (*Booking, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: el monto del pago debe ser positivo", ErrInvalidBooking)
+	}
+	return m.repo.AddPayment(ctx, bookingID, Payment{Amount: search.RoundAmount(amount), Reference: reference, PaidAt: time.Now()})
+}
+
+// SetPassengers registra o reemplaza la lista de pasajeros del grupo antes de su vencimiento. Cada pasajero
+// recibe su propio número de pasaje; los pasajeros cancelados se conservan.
+func (m *Manager) SetPassengers(ctx context.Context, bookingID, userID string, passengers []search.Passenger) (*search.Reservation, error) {
+	booking, err := m.bookingOf(ctx, bookingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(booking.NamesDueAt) {
+		return nil, ErrNamesClosed
+	}
+	if len(passengers) > booking.Seats {
+		return nil, fmt.Errorf("%w: el grupo tiene %d asientos", search.ErrInvalidPassengers, booking.Seats)
+	}
+
+	reservation, err := m.reservations.GetReservationByID(ctx, booking.ReservationID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil {
+		return nil, search.ErrReservationNotFound
+	}
+	if err := validatePassengers(passengers, reservation); err != nil {
+		return nil, err
+	}
+
+	// Conservar el número de pasaje de los pasajeros que ya estaban en la lista
+	tickets := make(map[string]string)
+	for _, passenger := range reservation.Passengers {
+		if passenger.Status == search.PassengerActive {
+			tickets[passenger.DocumentNumber] = passenger.ID
+		}
+	}
+	for i := range passengers {
+		passengers[i].ID = tickets[passengers[i].DocumentNumber]
+		if passengers[i].ID == "" {
+			passengers[i].ID = uuid.New().String()
+		}
+		passengers[i].Status = search.PassengerActive
+		passengers[i].CancelledAt = time.Time{}
+	}
+
+	return m.reservations.SetReservationPassengers(ctx, booking.ReservationID, passengers)
+}
+
+// CancelPassengers cancela los pasajes de algunos pasajeros del grupo y, además, unnamed asientos aún sin nombre.
+// El total y el adelanto del grupo se reducen en proporción; para cancelar todos los asientos se cancela el grupo.
+// Como las demás reservas, no se cancela después de la salida, dentro del plazo del operador ni después del check-in.
+func (m *Manager) CancelPassengers(ctx context.Context, bookingID, userID string, passengerIDs []string, unnamed int) (*Details, error) {
+	booking, err := m.bookingOf(ctx, bookingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkCancellation(ctx, booking); err != nil {
+		return nil, err
+	}
+
+	unique := make([]string, 0, len(passengerIDs))
+	seen := make(map[string]bool, len(passengerIDs))
+	for _, id := range passengerIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if unnamed < 0 || len(unique)+unnamed == 0 {
+		return nil, fmt.Errorf("%w: indique los pasajeros o la cantidad de asientos sin nombre a cancelar", ErrInvalidBooking)
+	}
+	seats := len(unique) + unnamed
+	if seats >= booking.Seats {
+		return nil, fmt.Errorf("%w: para cancelar todos los asientos cancele el grupo", ErrInvalidBooking)
+	}
+
+	// Descontar primero los asientos del grupo, que se pueden devolver si los pasajes no se llegan a cancelar
+	reduced, err := m.repo.ReduceSeats(ctx, booking.ID, seats)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := m.reservations.CancelReservationPassengers(ctx, booking.ReservationID, unique, unnamed, booking.Fare)
+	if err != nil {
+		if _, restoreErr := m.repo.RestoreSeats(ctx, booking.ID, seats, booking.Deposit); restoreErr != nil {
+			log.Printf("Error al devolver %d asientos a la reserva de grupo %s: %v", seats, booking.ID, restoreErr)
+		}
+		return nil, err
+	}
+	search.RunSeatReleaseHooks(ctx, m.released, reservation)
+
+	return &Details{Booking: reduced, Balance: reduced.Balance(), Reservation: reservation}, nil
+}
+
+// Cancel cancela el grupo entero y libera todos sus asientos. No se cancela después de la salida, dentro del
+// plazo del operador ni después del check-in de algún pasajero.
+func (m *Manager) Cancel(ctx context.Context, bookingID, userID, reason string) (*Booking, error) {
+	booking, err := m.bookingOf(ctx, bookingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkCancellation(ctx, booking); err != nil {
+		return nil, err
+	}
+	return m.cancel(ctx, booking, reason)
+}
+
+// checkCancellation verifica con la política de cancelación del operador que el organizador pueda cancelar
+// asientos del grupo
+func (m *Manager) checkCancellation(ctx context.Context, booking *Booking) error {
+	route, err := m.reservations.GetRouteByID(ctx, booking.RouteID)
+	if err != nil {
+		return err
+	}
+	_, err = search.ReservationCancellationTerms(ctx, m.operators, m.boarding, route, booking.ReservationID, time.Now())
+	return err
+}
+
+// cancel cancela la reserva de asientos del grupo y luego el grupo
+func (m *Manager) cancel(ctx context.Context, booking *Booking, reason string) (*Booking, error) {
+	reservation, err := m.reservations.CancelReservation(ctx, booking.ReservationID, "")
+	if err != nil && !errors.Is(err, search.ErrReservationNotActive) {
+		return nil, err
+	}
+
+	cancelled, err := m.repo.CancelBooking(ctx, booking.ID, reason)
+	if err != nil {
+		return nil, err
+	}
+	if reservation != nil {
+		search.RunSeatReleaseHooks(ctx, m.released, reservation)
+	}
+
+	return cancelled, nil
+}
+
+// bookingOf obtiene una reserva de grupo vigente del organizador; las de otros usuarios se tratan como inexistentes
+func (m *Manager) bookingOf(ctx context.Context, bookingID, userID string) (*Booking, error) {
+	booking, err := m.repo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.UserID != userID {
+		return nil, ErrBookingNotFound
+	}
+	if booking.Status == StatusCancelled {
+		return nil, ErrBookingCancelled
+	}
+	return booking, nil
+}
+
+// CancelOverdue cancela los grupos que no pagaron el adelanto o el saldo a tiempo
+func (m *Manager) CancelOverdue(ctx context.Context, now time.Time) (int, error) {
+	bookings, err := m.repo.FindOverdue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	var errs []error
+	for _, booking := range bookings {
+		reason := "saldo no pagado antes del vencimiento"
+		if booking.Status == StatusAwaitingDeposit {
+			reason = "adelanto no pagado antes del vencimiento"
+		}
+		if _, err := m.cancel(ctx, booking, reason); err != nil {
+			if !errors.Is(err, ErrBookingCancelled) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		cancelled++
+		log.Printf("Notificación al usuario %s: la reserva de grupo %s (%s) fue cancelada por %s",
+			booking.UserID, booking.ID, booking.GroupName, reason)
+	}
+
+	return cancelled, errors.Join(errs...)
+}
+
+// Run cancela los grupos con pagos vencidos cada interval hasta que se cancele el contexto
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cancelled, err := m.CancelOverdue(ctx, time.Now())
+		if err != nil {
+			log.Printf("Error al cancelar las reservas de grupo vencidas: %v", err)
+		}
+		if cancelled > 0 {
+			log.Printf("Reservas de grupo canceladas por falta de pago: %d", cancelled)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// OnRouteCancelled mantiene los grupos de una ruta cancelada: los reubicados pasan a su nueva reserva
+// y los que no se pudieron reubicar quedan cancelados
+func (m *Manager) OnRouteCancelled(ctx context.Context, route *search.Route, reservations []*search.Reservation) error {
+	return m.followReservations(ctx, reservations, search.ReservationCancelled, "salida cancelada")
+}
+
+// OnBoardingDenied mantiene los grupos retirados de una salida sobrevendida: los reubicados pasan a su nueva
+// reserva y los que quedaron con el embarque denegado se cancelan
+func (m *Manager) OnBoardingDenied(ctx context.Context, route *search.Route, reservations []*search.Reservation) error {
+	return m.followReservations(ctx, reservations, search.ReservationDeniedBoarding, "embarque denegado por sobreventa")
+}
+
+// followReservations actualiza los grupos de las reservas retiradas de una salida: los de las reservas reubicadas
+// pasan a la reserva que las reemplazó y los de las reservas con el estado closed se cancelan con el motivo indicado
+func (m *Manager) followReservations(ctx context.Context, reservations []*search.Reservation, closed, reason string) error {
+	var errs []error
+
+	for _, reservation := range reservations {
+		if reservation.GroupID == "" {
+			continue
+		}
+
+		switch reservation.Status {
+		case search.ReservationRebooked:
+			rebooked, err := m.reservations.GetReservationByID(ctx, reservation.ReplacedBy)
+			if err != nil || rebooked == nil {
+				errs = append(errs, fmt.Errorf("reserva %s reubicada sin reemplazo: %v", reservation.ID, err))
+				continue
+			}
+			if _, err := m.repo.MoveBooking(ctx, reservation.GroupID, rebooked.ID, rebooked.RouteID); err != nil {
+				errs = append(errs, err)
+			}
+		case closed:
+			if _, err := m.repo.CancelBooking(ctx, reservation.GroupID, reason); err != nil && !errors.Is(err, ErrBookingCancelled) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validatePassengers verifica que cada pasajero tenga nombre y documento sin repetir y que los asientos
+// asignados pertenezcan a la reserva y no se repitan
+func validatePassengers(passengers []search.Passenger, reservation *search.Reservation) error {
+	documents := make(map[string]bool, len(passengers))
+	seats := make(map[string]bool, len(passengers))
+	for i := range passengers {
+		passenger := &passengers[i]
+		passenger.Name = strings.TrimSpace(passenger.Name)
+		passenger.DocumentNumber = strings.TrimSpace(passenger.DocumentNumber)
+
+		if passenger.Name == "" || passenger.DocumentNumber == "" {
+			return fmt.Errorf("%w: cada pasajero requiere nombre y documento", search.ErrInvalidPassengers)
+		}
+		if documents[passenger.DocumentNumber] {
+			return fmt.Errorf("%w: documento %s repetido", search.ErrInvalidPassengers, passenger.DocumentNumber)
+		}
+		documents[passenger.DocumentNumber] = true
+
+		if passenger.SeatNumber == "" {
+			continue
+		}
+		if seats[passenger.SeatNumber] {
+			return fmt.Errorf("%w: asiento %s repetido", search.ErrInvalidPassengers, passenger.SeatNumber)
+		}
+		seats[passenger.SeatNumber] = true
+		if !slices.Contains(reservation.SeatNumbers, passenger.SeatNumber) {
+			return fmt.Errorf("%w: el asiento %s no pertenece a la reserva", search.ErrInvalidPassengers, passenger.SeatNumber)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/fleet"
+	"venta-de-pasajes/internal/search"
+)
+
+// testReservations simula las reservas de asientos con una sola salida; reservar registra la solicitud
+type testReservations struct {
+	Reservations
+	route    *search.Route
+	reserved []search.ReservationRequest
+}
+
+func (r *testReservations) GetRouteByID(ctx context.Context, routeID string) (*search.Route, error) {
+	if routeID != r.route.ID {
+		return nil, search.ErrRouteNotFound
+	}
+	return r.route, nil
+}
+
+func (r *testReservations) ReserveRoute(ctx context.Context, request search.ReservationRequest) (*search.Reservation, error) {
+	r.reserved = append(r.reserved, request)
+	return nil, errors.New("reserva no esperada en la prueba")
+}
+
+// testVehicles simula la flota con un bus de cuatro asientos
+type testVehicles struct{}
+
+func (testVehicles) GetVehicle(ctx context.Context, vehicleID string) (*fleet.Vehicle, error) {
+	return &fleet.Vehicle{ID: vehicleID, Active: true, Capacity: 4, Layout: fleet.SeatLayout{Seats: []fleet.Seat{
+		{Number: "1A"}, {Number: "1B"}, {Number: "2A"}, {Number: "2B"},
+	}}}, nil
+}
+
+func testGroupConfig() config.GroupConfig {
+	return config.GroupConfig{
+		MinSize:          2,
+		DefaultDiscount:  10,
+		DepositPercent:   30,
+		DepositWindow:    48 * time.Hour,
+		BalanceLeadTime:  72 * time.Hour,
+		NameListLeadTime: 24 * time.Hour,
+	}
+}
+
+func TestDeadlines(t *testing.T) {
+	m := NewManager(nil, nil, nil, nil, nil, testGroupConfig())
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	days := func(d int) time.Time { return now.Add(time.Duration(d) * 24 * time.Hour) }
+
+	tests := []struct {
+		name                    string
+		departure               time.Time
+		deposit, balance, names time.Time
+	}{
+		{name: "salida lejana", departure: days(30), deposit: days(2), balance: days(27), names: days(29)},
+		{name: "el saldo vence con el adelanto", departure: days(4), deposit: days(2), balance: days(2), names: days(3)},
+		{name: "salida antes del plazo del adelanto", departure: now.Add(12 * time.Hour), deposit: now.Add(12 * time.Hour), balance: now.Add(12 * time.Hour), names: now.Add(12 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deposit, balance, names := m.deadlines(now, tt.departure)
+			if !deposit.Equal(tt.deposit) || !balance.Equal(tt.balance) || !names.Equal(tt.names) {
+				t.Errorf("deadlines() = %s, %s, %s, se esperaba %s, %s, %s", deposit, balance, names, tt.deposit, tt.balance, tt.names)
+			}
+		})
+	}
+}
+
+func TestValidatePassengers(t *testing.T) {
+	reservation := &search.Reservation{Seats: 3, SeatNumbers: []string{"1A", "1B", "2A"}}
+
+	tests := []struct {
+		name       string
+		passengers []search.Passenger
+		wantErr    bool
+	}{
+		{name: "con y sin asiento", passengers: []search.Passenger{{Name: "Ana", DocumentNumber: "1", SeatNumber: "1A"}, {Name: "Luis", DocumentNumber: "2"}}},
+		{name: "espacios alrededor", passengers: []search.Passenger{{Name: " Ana ", DocumentNumber: " 1 "}}},
+		{name: "sin nombre", passengers: []search.Passenger{{Name: "  ", DocumentNumber: "1"}}, wantErr: true},
+		{name: "sin documento", passengers: []search.Passenger{{Name: "Ana"}}, wantErr: true},
+		{name: "documento repetido", passengers: []search.Passenger{{Name: "Ana", DocumentNumber: "1"}, {Name: "Luis", DocumentNumber: "1"}}, wantErr: true},
+		{name: "asiento repetido", passengers: []search.Passenger{{Name: "Ana", DocumentNumber: "1", SeatNumber: "1A"}, {Name: "Luis", DocumentNumber: "2", SeatNumber: "1A"}}, wantErr: true},
+		{name: "asiento de otra reserva", passengers: []search.Passenger{{Name: "Ana", DocumentNumber: "1", SeatNumber: "2B"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePassengers(tt.passengers, reservation)
+			if tt.wantErr && !errors.Is(err, search.ErrInvalidPassengers) {
+				t.Errorf("validatePassengers(): error = %v, se esperaba ErrInvalidPassengers", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("validatePassengers(): %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateValidatesSeatNumbers(t *testing.T) {
+	route := &search.Route{ID: "ruta-1", VehicleID: "bus-1", Capacity: 4, Seats: 4, Price: 50, Departure: time.Now().Add(72 * time.Hour)}
+	reservations := &testReservations{route: route}
+	m := NewManager(nil, reservations, nil, nil, testVehicles{}, testGroupConfig())
+
+	tests := []struct {
+		name        string
+		seatNumbers []string
+	}{
+		{name: "asiento repetido", seatNumbers: []string{"1A", "1A"}},
+		{name: "asiento que no existe", seatNumbers: []string{"1A", "9Z"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := CreateRequest{RouteID: route.ID, UserID: "u1", GroupName: "Colegio", SeatNumbers: tt.seatNumbers}
+			if _, err := m.Create(context.Background(), request); !errors.Is(err, search.ErrInvalidSeats) {
+				t.Errorf("Create(%v): error = %v, se esperaba ErrInvalidSeats", tt.seatNumbers, err)
+			}
+		})
+	}
+	if len(reservations.reserved) != 0 {
+		t.Errorf("se reservaron %d solicitudes con asientos inválidos", len(reservations.reserved))
+	}
+}
+
+// testCheckIns simula el registro de check-in con las reservas que ya tienen pasajeros registrados
+type testCheckIns map[string]bool
+
+func (c testCheckIns) HasReservationRecords(ctx context.Context, reservationID string) (bool, error) {
+	return c[reservationID], nil
+}
+
+func TestCheckCancellation(t *testing.T) {
+	tests := []struct {
+		name      string
+		departsIn time.Duration
+		booking   Booking
+		wantErr   error
+	}{
+		{name: "antes de la salida", departsIn: 72 * time.Hour, booking: Booking{RouteID: "ruta-1", ReservationID: "reserva-1"}},
+		{name: "la salida ya partió", departsIn: -time.Hour, booking: Booking{RouteID: "ruta-1", ReservationID: "reserva-1"}, wantErr: search.ErrCancellationClosed},
+		{name: "con check-in", departsIn: 72 * time.Hour, booking: Booking{RouteID: "ruta-1", ReservationID: "con-check-in"}, wantErr: search.ErrCancellationClosed},
+		{name: "salida desconocida", departsIn: 72 * time.Hour, booking: Booking{RouteID: "ruta-x", ReservationID: "reserva-1"}, wantErr: search.ErrRouteNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := &search.Route{ID: "ruta-1", Departure: time.Now().Add(tt.departsIn)}
+			m := NewManager(nil, &testReservations{route: route}, nil, testCheckIns{"con-check-in": true}, nil, testGroupConfig())
+
+			err := m.checkCancellation(context.Background(), &tt.booking)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("checkCancellation(): %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkCancellation(): error = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package group
+
+import (
+	"time"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// Estados de una reserva de grupo
+const (
+	StatusAwaitingDeposit = "pendiente_adelanto" // Asientos reservados, falta pagar el adelanto
+	StatusDepositPaid     = "adelanto_pagado"    // Falta pagar el saldo antes de su vencimiento
+	StatusPaid            = "pagado"
+	StatusCancelled       = "cancelado"
+)
+
+// Booking representa una reserva de grupo: una sola reserva de asientos con tarifa negociada, pagada por el
+// organizador en un adelanto y un saldo, con los nombres de los pasajeros entregados después
+type Booking struct {
+	ID            string    `json:"id,omitempty" bson:"_id,omitempty"`
+	ReservationID string    `json:"reservation_id" bson:"reservation_id"`
+	RouteID       string    `json:"route_id" bson:"route_id"`
+	OperatorID    string    `json:"operator_id,omitempty" bson:"operator_id,omitempty"`
+	UserID        string    `json:"user_id" bson:"user_id"`       // Organizador que paga y gestiona el grupo
+	GroupName     string    `json:"group_name" bson:"group_name"` // Colegio, agencia o institución
+	Seats         int       `json:"seats" bson:"seats"`           // Asientos vigentes
+	Fare          float64   `json:"fare" bson:"fare"`             // Tarifa negociada por asiento
+	Total         float64   `json:"total" bson:"total"`
+	Deposit       float64   `json:"deposit" bson:"deposit"` // Adelanto exigido para mantener la reserva
+	AmountPaid    float64   `json:"amount_paid" bson:"amount_paid"`
+	Payments      []Payment `json:"payments,omitempty" bson:"payments,omitempty"`
+	DepositDueAt  time.Time `json:"deposit_due_at" bson:"deposit_due_at"`
+	BalanceDueAt  time.Time `json:"balance_due_at" bson:"balance_due_at"`
+	NamesDueAt    time.Time `json:"names_due_at" bson:"names_due_at"` // Vencimiento de la lista de pasajeros
+	Status        string    `json:"status" bson:"status"`
+	CancelReason  string    `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Balance devuelve el monto pendiente de pago; negativo es un saldo a favor del organizador
+func (b *Booking) Balance() float64 {
+	return search.RoundAmount(b.Total - b.AmountPaid)
+}
+
+// Payment representa un pago del organizador del grupo
+type Payment struct {
+	Amount    float64   `json:"amount" bson:"amount"`
+	Reference string    `json:"reference,omitempty" bson:"reference,omitempty"` // Número de operación o comprobante
+	PaidAt    time.Time `json:"paid_at" bson:"paid_at"`
+}
+
+// CreateRequest representa una solicitud de reserva de grupo
+type CreateRequest struct {
+	RouteID     string   `json:"route_id"`
+	UserID      string   `json:"user_id"`
+	GroupName   string   `json:"group_name"`
+	Seats       int      `json:"seats"`
+	SeatNumbers []string `json:"seat_numbers,omitempty"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Fare        float64  `json:"fare,omitempty"` // Tarifa negociada por asiento; sin ella se aplica el descuento de grupo
+}
+
+// Details representa una reserva de grupo junto con su reserva de asientos y sus pasajeros
+type Details struct {
+	*Booking
+	Balance     float64             `json:"balance"`
+	Reservation *search.Reservation `json:"reservation,omitempty"`
+}
@@ -0,0 +1,238 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+var (
+	// ErrBookingNotFound indica que la reserva de grupo no existe
+	ErrBookingNotFound = errors.New("reserva de grupo no encontrada")
+	// ErrBookingCancelled indica que la reserva de grupo ya fue cancelada
+	ErrBookingCancelled = errors.New("la reserva de grupo fue cancelada")
+)
+
+// Repository es el repositorio de reservas de grupo en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para las reservas de grupo")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// bookings devuelve la colección de reservas de grupo
+func (r *Repository) bookings() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.GroupBookingsCollection)
+}
+
+// EnsureIndexes crea los índices de consulta por organizador y vencimiento de pagos
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.bookings().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "deposit_due_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "balance_due_at", Value: 1}}},
+	})
+	return err
+}
+
+// statusFromPayments recalcula el estado de una reserva de grupo vigente según lo pagado
+var statusFromPayments = bson.M{"$switch": bson.M{
+	"branches": bson.A{
+		bson.M{"case": bson.M{"$gte": bson.A{"$amount_paid", "$total"}}, "then": StatusPaid},
+		bson.M{"case": bson.M{"$gte": bson.A{"$amount_paid", "$deposit"}}, "then": StatusDepositPaid},
+	},
+	"default": StatusAwaitingDeposit,
+}}
+
+// CreateBooking registra una reserva de grupo pendiente del adelanto. Si no trae ID se le asigna uno.
+func (r *Repository) CreateBooking(ctx context.Context, booking *Booking) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if booking.ID == "" {
+		booking.ID = uuid.New().String()
+	}
+	booking.Status = StatusAwaitingDeposit
+	booking.CreatedAt = time.Now()
+	booking.UpdatedAt = booking.CreatedAt
+
+	_, err := r.bookings().InsertOne(ctx, booking)
+	return err
+}
+
+// GetBooking obtiene una reserva de grupo por su ID
+func (r *Repository) GetBooking(ctx context.Context, bookingID string) (*Booking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var booking Booking
+	err := r.bookings().FindOne(ctx, bson.M{"_id": bookingID}).Decode(&booking)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+
+	return &booking, nil
+}
+
+// GetBookings obtiene las reservas de grupo de un organizador, de la más reciente a la más antigua
+func (r *Repository) GetBookings(ctx context.Context, userID string) ([]*Booking, error) {
+	return r.findBookings(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+}
+
+// FindOverdue obtiene las reservas de grupo con el adelanto o el saldo vencido
+func (r *Repository) FindOverdue(ctx context.Context, now time.Time) ([]*Booking, error) {
+	return r.findBookings(ctx, bson.M{"$or": bson.A{
+		bson.M{"status": StatusAwaitingDeposit, "deposit_due_at": bson.M{"$lte": now}},
+		bson.M{"status": StatusDepositPaid, "balance_due_at": bson.M{"$lte": now}},
+	}})
+}
+
+// findBookings obtiene las reservas de grupo que cumplen el filtro
+func (r *Repository) findBookings(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*Booking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.bookings().Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	bookings := []*Booking{}
+	if err := cursor.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// AddPayment registra un pago de una reserva de grupo vigente y actualiza su estado según lo pagado
+func (r *Repository) AddPayment(ctx context.Context, bookingID string, payment Payment) (*Booking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateActive(ctx, bookingID, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"amount_paid": bson.M{"$round": bson.A{bson.M{"$add": bson.A{"$amount_paid", payment.Amount}}, 2}},
+			"payments":    bson.M{"$concatArrays": bson.A{bson.M{"$ifNull": bson.A{"$payments", bson.A{}}}, bson.M{"$literal": bson.A{payment}}}},
+			"updated_at":  time.Now(),
+		}}},
+		{{Key: "$set", Value: bson.M{"status": statusFromPayments}}},
+	})
+}
+
+// ReduceSeats descuenta asientos cancelados de una reserva de grupo vigente, con su tarifa y la parte proporcional
+// del adelanto, y actualiza su estado
+func (r *Repository) ReduceSeats(ctx context.Context, bookingID string, seats int) (*Booking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateActive(ctx, bookingID, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"seats":      bson.M{"$subtract": bson.A{"$seats", seats}},
+			"total":      bson.M{"$round": bson.A{bson.M{"$subtract": bson.A{"$total", bson.M{"$multiply": bson.A{"$fare", seats}}}}, 2}},
+			"deposit":    bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{"$deposit", bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$seats", seats}}, "$seats"}}}}, 2}},
+			"updated_at": time.Now(),
+		}}},
+		{{Key: "$set", Value: bson.M{"status": statusFromPayments}}},
+	})
+}
+
+// RestoreSeats devuelve a una reserva de grupo vigente los asientos descontados con ReduceSeats, con su tarifa y
+// el adelanto que tenía antes de descontarlos, y actualiza su estado
+func (r *Repository) RestoreSeats(ctx context.Context, bookingID string, seats int, deposit float64) (*Booking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateActive(ctx, bookingID, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"seats":      bson.M{"$add": bson.A{"$seats", seats}},
+			"total":      bson.M{"$round": bson.A{bson.M{"$add": bson.A{"$total", bson.M{"$multiply": bson.A{"$fare", seats}}}}, 2}},
+			"deposit":    deposit,
+			"updated_at": time.Now(),
+		}}},
+		{{Key: "$set", Value: bson.M{"status": statusFromPayments}}},
+	})
+}
+
+// MoveBooking asocia una reserva de grupo vigente con la reserva que reemplazó a la suya en otra salida
+func (r *Repository) MoveBooking(ctx context.Context, bookingID, reservationID, routeID string) (*Booking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateActive(ctx, bookingID, bson.M{"$set": bson.M{
+		"reservation_id": reservationID,
+		"route_id":       routeID,
+		"updated_at":     time.Now(),
+	}})
+}
+
+// CancelBooking cancela una reserva de grupo vigente indicando el motivo
+func (r *Repository) CancelBooking(ctx context.Context, bookingID, reason string) (*Booking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.updateActive(ctx, bookingID, bson.M{"$set": bson.M{
+		"status":        StatusCancelled,
+		"cancel_reason": reason,
+		"updated_at":    time.Now(),
+	}})
+}
+
+// updateActive aplica una actualización a una reserva de grupo que no esté cancelada
+func (r *Repository) updateActive(ctx context.Context, bookingID string, update interface{}) (*Booking, error) {
+	var booking Booking
+	err := r.bookings().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": bookingID, "status": bson.M{"$ne": StatusCancelled}},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&booking)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := r.GetBooking(ctx, bookingID); err != nil {
+			return nil, err
+		}
+		return nil, ErrBookingCancelled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &booking, nil
+}
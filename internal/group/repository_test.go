@@ -0,0 +1,104 @@
+package group
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/internal/testutil"
+)
+
+// newTestRepository crea un repositorio sobre una base de datos de prueba que se elimina al terminar.
+// Las pruebas se omiten si MONGO_TEST_URL no está definida.
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	repo, err := NewRepository(testutil.MongoConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.client.Disconnect(context.Background()) })
+
+	if err := repo.EnsureIndexes(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestReduceSeatsKeepsProportionalDeposit(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// Diez asientos a 50 con un adelanto del 30% ya pagado
+	booking := &Booking{RouteID: "ruta-1", UserID: "u1", GroupName: "Colegio", Seats: 10, Fare: 50, Total: 500, Deposit: 150}
+	if err := repo.CreateBooking(ctx, booking); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddPayment(ctx, booking.ID, Payment{Amount: 150, PaidAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		seats       int
+		wantSeats   int
+		wantTotal   float64
+		wantDeposit float64
+		wantStatus  string
+	}{
+		{seats: 2, wantSeats: 8, wantTotal: 400, wantDeposit: 120, wantStatus: StatusDepositPaid},
+		{seats: 3, wantSeats: 5, wantTotal: 250, wantDeposit: 75, wantStatus: StatusDepositPaid},
+		// Lo pagado cubre el nuevo total
+		{seats: 2, wantSeats: 3, wantTotal: 150, wantDeposit: 45, wantStatus: StatusPaid},
+	}
+
+	for _, tt := range tests {
+		reduced, err := repo.ReduceSeats(ctx, booking.ID, tt.seats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reduced.Seats != tt.wantSeats || reduced.Total != tt.wantTotal || reduced.Deposit != tt.wantDeposit || reduced.Status != tt.wantStatus {
+			t.Errorf("ReduceSeats(%d) = %d asientos, total %v, adelanto %v, %s; se esperaba %d, %v, %v, %s", tt.seats,
+				reduced.Seats, reduced.Total, reduced.Deposit, reduced.Status, tt.wantSeats, tt.wantTotal, tt.wantDeposit, tt.wantStatus)
+		}
+	}
+}
+
+func TestReduceSeatsRoundsDeposit(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	booking := &Booking{RouteID: "ruta-1", UserID: "u1", GroupName: "Colegio", Seats: 3, Fare: 33.33, Total: 99.99, Deposit: 30}
+	if err := repo.CreateBooking(ctx, booking); err != nil {
+		t.Fatal(err)
+	}
+
+	reduced, err := repo.ReduceSeats(ctx, booking.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reduced.Total != 66.66 || reduced.Deposit != 20 || reduced.Status != StatusAwaitingDeposit {
+		t.Errorf("ReduceSeats(1) = total %v, adelanto %v, %s; se esperaba 66.66, 20, %s", reduced.Total, reduced.Deposit, reduced.Status, StatusAwaitingDeposit)
+	}
+}
+
+func TestRestoreSeatsUndoesReduceSeats(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	booking := &Booking{RouteID: "ruta-1", UserID: "u1", GroupName: "Colegio", Seats: 3, Fare: 33.33, Total: 99.99, Deposit: 30}
+	if err := repo.CreateBooking(ctx, booking); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ReduceSeats(ctx, booking.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := repo.RestoreSeats(ctx, booking.ID, 1, booking.Deposit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Seats != 3 || restored.Total != 99.99 || restored.Deposit != 30 || restored.Status != StatusAwaitingDeposit {
+		t.Errorf("RestoreSeats(1) = %d asientos, total %v, adelanto %v, %s; se esperaba 3, 99.99, 30, %s",
+			restored.Seats, restored.Total, restored.Deposit, restored.Status, StatusAwaitingDeposit)
+	}
+}
@@ -0,0 +1,234 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLocationNotFound indica que ninguna ubicación coincide con el nombre, alias o código
+	ErrLocationNotFound = errors.New("ubicación no encontrada")
+	// ErrAmbiguousLocation indica que el texto coincide con varias ciudades
+	ErrAmbiguousLocation = errors.New("el texto coincide con varias ubicaciones, use el código")
+	// ErrInvalidLocation indica que los datos de la ubicación no son válidos
+	ErrInvalidLocation = errors.New("ubicación inválida")
+	// ErrEmptyCatalog indica que la base de datos no tiene ubicaciones
+	ErrEmptyCatalog = errors.New("el catálogo de ubicaciones está vacío, ejecute scripts/seedLocations.go")
+)
+
+// codePattern valida los códigos: tres letras para ciudades y hasta ocho caracteres para terminales
+var codePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{2,7}$`)
+
+// Catalog mantiene en memoria las ubicaciones activas para resolver nombres, alias y códigos
+type Catalog struct {
+	repo *Repository
+
+	mu         sync.RWMutex
+	byCode     map[string]*Location
+	byKey      map[string][]*Location // Nombre o alias normalizado
+	index      *suggestIndex
+	popularity map[string]float64 // Por código de ciudad
+}
+
+// NewCatalog crea un catálogo vacío; use Reload para cargar las ubicaciones
+func NewCatalog(repo *Repository) *Catalog {
+	return &Catalog{
+		repo:       repo,
+		byCode:     make(map[string]*Location),
+		byKey:      make(map[string][]*Location),
+		index:      newSuggestIndex(nil),
+		popularity: make(map[string]float64),
+	}
+}
+
+// Reload vuelve a cargar las ubicaciones desde la base de datos.
+// Si no hay ubicaciones devuelve ErrEmptyCatalog y el catálogo conserva las que tenía.
+func (c *Catalog) Reload(ctx context.Context) error {
+	locations, err := c.repo.GetLocations(ctx)
+	if err != nil {
+		return err
+	}
+	return c.load(locations)
+}
+
+// load reemplaza las ubicaciones del catálogo y sus índices
+func (c *Catalog) load(locations []*Location) error {
+	if len(locations) == 0 {
+		return ErrEmptyCatalog
+	}
+
+	byCode := make(map[string]*Location, len(locations))
+	byKey := make(map[string][]*Location)
+	for _, location := range locations {
+		byCode[location.Code] = location
+		if !location.Active {
+			continue
+		}
+		for _, key := range searchKeys(location) {
+			byKey[key] = append(byKey[key], location)
+		}
+	}
+
+	index := newSuggestIndex(locations)
+
+	c.mu.Lock()
+	c.byCode = byCode
+	c.byKey = byKey
+	c.index = index
+	c.mu.Unlock()
+
+	return nil
+}
+
+// searchKeys devuelve el nombre y los alias normalizados de una ubicación, sin repetir
+func searchKeys(location *Location) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, text := range append([]string{location.Name}, location.Aliases...) {
+		key := Normalize(text)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Get obtiene una ubicación por su código, incluidas las inactivas
+func (c *Catalog) Get(code string) (*Location, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	location, ok := c.byCode[strings.ToUpper(strings.TrimSpace(code))]
+	return location, ok
+}
+
+// Locations obtiene las ubicaciones activas ordenadas por código, opcionalmente de un tipo o de una ciudad
+func (c *Catalog) Locations(locationType, cityCode string) []*Location {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locations := []*Location{}
+	for _, location := range c.byCode {
+		if !location.Active {
+			continue
+		}
+		if locationType != "" && location.Type != locationType {
+			continue
+		}
+		if cityCode != "" && location.Code != cityCode && location.CityCode != cityCode {
+			continue
+		}
+		locations = append(locations, location)
+	}
+
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Code < locations[j].Code })
+	return locations
+}
+
+// Resolve encuentra la ubicación activa que corresponde a un código, nombre o alias.
+// La comparación no distingue mayúsculas ni tildes; si un nombre coincide con una ciudad
+// y con terminales, se prefiere la ciudad.
+func (c *Catalog) Resolve(query string) (*Location, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrLocationNotFound
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if location, ok := c.byCode[strings.ToUpper(query)]; ok && location.Active {
+		return location, nil
+	}
+
+	matches := c.byKey[Normalize(query)]
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q", ErrLocationNotFound, query)
+	case 1:
+		return matches[0], nil
+	}
+
+	var city *Location
+	for _, location := range matches {
+		if location.Type != TypeCity {
+			continue
+		}
+		if city != nil {
+			return nil, fmt.Errorf("%w: %q", ErrAmbiguousLocation, query)
+		}
+		city = location
+	}
+	if city == nil {
+		return nil, fmt.Errorf("%w: %q", ErrAmbiguousLocation, query)
+	}
+	return city, nil
+}
+
+// ResolveCity encuentra la ciudad de un código, nombre o alias; un terminal se resuelve a su ciudad.
+// Devuelve el código y el nombre de la ciudad.
+func (c *Catalog) ResolveCity(query string) (string, string, error) {
+	location, err := c.Resolve(query)
+	if err != nil {
+		return "", "", err
+	}
+
+	if location.Type == TypeTerminal {
+		city, ok := c.Get(location.CityCode)
+		if !ok {
+			return "", "", fmt.Errorf("%w: ciudad %q del terminal %s", ErrLocationNotFound, location.CityCode, location.Code)
+		}
+		location = city
+	}
+
+	return location.Code, location.Name, nil
+}
+
+// Validate verifica los datos de una ubicación nueva o modificada y completa los valores por defecto
+func (c *Catalog) Validate(location *Location) error {
+	location.Code = strings.ToUpper(strings.TrimSpace(location.Code))
+	location.Name = strings.TrimSpace(location.Name)
+
+	if !codePattern.MatchString(location.Code) {
+		return fmt.Errorf("%w: código %q, use de 3 a 8 letras mayúsculas o dígitos", ErrInvalidLocation, location.Code)
+	}
+	if location.Name == "" {
+		return fmt.Errorf("%w: el nombre es obligatorio", ErrInvalidLocation)
+	}
+
+	switch location.Type {
+	case TypeCity:
+		if len(location.Code) != 3 {
+			return fmt.Errorf("%w: el código de una ciudad debe tener 3 letras", ErrInvalidLocation)
+		}
+		location.CityCode = ""
+	case TypeTerminal:
+		city, ok := c.Get(location.CityCode)
+		if !ok || city.Type != TypeCity {
+			return fmt.Errorf("%w: el terminal debe indicar una ciudad existente en city_code", ErrInvalidLocation)
+		}
+		location.CityCode = city.Code
+	default:
+		return fmt.Errorf("%w: tipo %q, use %q o %q", ErrInvalidLocation, location.Type, TypeCity, TypeTerminal)
+	}
+
+	if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+		return fmt.Errorf("%w: coordenadas fuera de rango", ErrInvalidLocation)
+	}
+
+	if location.Timezone == "" {
+		location.Timezone = DefaultTimezone
+	}
+	if _, err := time.LoadLocation(location.Timezone); err != nil {
+		return fmt.Errorf("%w: zona horaria desconocida %q", ErrInvalidLocation, location.Timezone)
+	}
+
+	return nil
+}
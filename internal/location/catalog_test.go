@@ -0,0 +1,115 @@
+package location
+
+import (
+	"errors"
+	"testing"
+)
+
+// testLocations arma un catálogo pequeño: Lima con dos terminales, Cusco, una ciudad inactiva
+// y dos ciudades con el mismo alias
+func testLocations() []*Location {
+	return []*Location{
+		{Code: "LIM", Name: "Lima", Type: TypeCity, Aliases: []string{"Ciudad de los Reyes"}, Active: true},
+		{Code: "LIMPN", Name: "Terminal Plaza Norte", Type: TypeTerminal, CityCode: "LIM", Aliases: []string{"Lima"}, Active: true},
+		{Code: "LIMJM", Name: "Terminal Javier Prado", Type: TypeTerminal, CityCode: "LIM", Active: true},
+		{Code: "CUZ", Name: "Cusco", Type: TypeCity, Aliases: []string{"Cuzco", "Qosqo"}, Active: true},
+		{Code: "ABA", Name: "Abancay", Type: TypeCity, Region: "Apurímac", Active: false},
+		{Code: "SJL", Name: "San Juan", Type: TypeCity, Aliases: []string{"San Juan"}, Active: true},
+		{Code: "SJM", Name: "San Juan", Type: TypeCity, Active: true},
+	}
+}
+
+// newTestCatalog crea un catálogo cargado con las ubicaciones de prueba
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	catalog := NewCatalog(nil)
+	if err := catalog.load(testLocations()); err != nil {
+		t.Fatal(err)
+	}
+	return catalog
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Apurímac", want: "apurimac"},
+		{text: "  CIUDAD   de los  Reyes ", want: "ciudad de los reyes"},
+		{text: "Ñuñoa", want: "nunoa"}, // También se quita la tilde de la eñe
+		{text: "Güeppí", want: "gueppi"},
+		{text: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.text); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, se esperaba %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{name: "por código", query: "cuz", want: "CUZ"},
+		{name: "por código de terminal", query: "LIMPN", want: "LIMPN"},
+		{name: "por nombre sin tildes ni mayúsculas", query: "  cusco ", want: "CUZ"},
+		{name: "por alias", query: "Qosqo", want: "CUZ"},
+		{name: "la ciudad prevalece sobre el terminal", query: "lima", want: "LIM"},
+		{name: "alias de varias ciudades", query: "San Juan", wantErr: ErrAmbiguousLocation},
+		{name: "ubicación inactiva por código", query: "ABA", wantErr: ErrLocationNotFound},
+		{name: "ubicación inactiva por nombre", query: "Abancay", wantErr: ErrLocationNotFound},
+		{name: "texto vacío", query: " ", wantErr: ErrLocationNotFound},
+		{name: "desconocida", query: "Tumbes", wantErr: ErrLocationNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := catalog.Resolve(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Resolve(%q): error = %v, se esperaba %v", tt.query, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q): %v", tt.query, err)
+			}
+			if location.Code != tt.want {
+				t.Errorf("Resolve(%q) = %s, se esperaba %s", tt.query, location.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCity(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	code, name, err := catalog.ResolveCity("Terminal Javier Prado")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "LIM" || name != "Lima" {
+		t.Errorf("ResolveCity() = %s %s, un terminal debe resolverse a su ciudad LIM Lima", code, name)
+	}
+}
+
+func TestLoadEmptyCatalogKeepsLocations(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	if err := catalog.load(nil); !errors.Is(err, ErrEmptyCatalog) {
+		t.Errorf("load() sin ubicaciones: error = %v, se esperaba ErrEmptyCatalog", err)
+	}
+	if _, err := catalog.Resolve("Cusco"); err != nil {
+		t.Errorf("Resolve() tras una carga vacía: %v, el catálogo debe conservar las ubicaciones", err)
+	}
+	if len(catalog.Suggest("cus", 0)) == 0 {
+		t.Error("Suggest() tras una carga vacía no debe perder el índice")
+	}
+}
@@ -0,0 +1,105 @@
+package location
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// LocationHandler maneja las solicitudes del catálogo de ubicaciones
+type LocationHandler struct {
+	repo    *Repository
+	catalog *Catalog
+}
+
+// NewLocationHandler crea una nueva instancia de LocationHandler
+func NewLocationHandler(repo *Repository, catalog *Catalog) *LocationHandler {
+	return &LocationHandler{
+		repo:    repo,
+		catalog: catalog,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del catálogo
+func (h *LocationHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrLocationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAmbiguousLocation), errors.Is(err, ErrInvalidLocation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetLocationsHandler maneja las solicitudes para listar las ubicaciones activas,
+// con filtros opcionales por tipo (type) y ciudad (city)
+func (h *LocationHandler) GetLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	locations := h.catalog.Locations(r.URL.Query().Get("type"), r.URL.Query().Get("city"))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(locations)
+}
+
+// ResolveLocationHandler maneja las solicitudes para encontrar la ubicación de un nombre, alias o código (q)
+func (h *LocationHandler) ResolveLocationHandler(w http.ResponseWriter, r *http.Request) {
+	location, err := h.catalog.Resolve(r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(location)
+}
+
+// SuggestLocationsHandler maneja las solicitudes de autocompletado de ubicaciones mientras se escribe (q),
+// con una cantidad opcional de sugerencias (limit)
+func (h *LocationHandler) SuggestLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := DefaultSuggestLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > MaxSuggestLimit {
+			http.Error(w, "el parámetro limit debe estar entre 1 y "+strconv.Itoa(MaxSuggestLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	suggestions := h.catalog.Suggest(r.URL.Query().Get("q"), limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// SaveLocationHandler maneja las solicitudes para registrar o modificar una ubicación
+func (h *LocationHandler) SaveLocationHandler(w http.ResponseWriter, r *http.Request) {
+	// Las ubicaciones quedan activas salvo que la solicitud indique lo contrario
+	location := Location{Active: true}
+	err := json.NewDecoder(r.Body).Decode(&location)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.catalog.Validate(&location); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	if err := h.repo.SaveLocation(r.Context(), &location); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Actualizar el catálogo en memoria con la ubicación guardada
+	if err := h.catalog.Reload(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(location)
+}
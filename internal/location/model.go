@@ -0,0 +1,27 @@
+package location
+
+import "time"
+
+// Tipos de ubicación
+const (
+	TypeCity     = "ciudad"
+	TypeTerminal = "terminal"
+)
+
+// DefaultTimezone es la zona horaria de las ubicaciones que no indican otra
+const DefaultTimezone = "America/Lima"
+
+// Location representa una ciudad o un terminal del catálogo de ubicaciones
+type Location struct {
+	Code      string    `json:"code" bson:"_id"` // Código tipo IATA, por ejemplo "LIM"
+	Name      string    `json:"name" bson:"name"`
+	Type      string    `json:"type" bson:"type"`
+	CityCode  string    `json:"city_code,omitempty" bson:"city_code,omitempty"` // Ciudad a la que pertenece un terminal
+	Region    string    `json:"region,omitempty" bson:"region,omitempty"`
+	Aliases   []string  `json:"aliases,omitempty" bson:"aliases,omitempty"`
+	Latitude  float64   `json:"latitude" bson:"latitude"`
+	Longitude float64   `json:"longitude" bson:"longitude"`
+	Timezone  string    `json:"timezone" bson:"timezone"`
+	Active    bool      `json:"active" bson:"active"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
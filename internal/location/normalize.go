@@ -0,0 +1,21 @@
+package location
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize convierte un texto a minúsculas sin tildes ni espacios repetidos
+// para compararlo sin importar mayúsculas ni acentos ("Apurímac" y "apurimac" son iguales)
+func Normalize(text string) string {
+	folding := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(folding, text)
+	if err != nil {
+		folded = text
+	}
+	return strings.Join(strings.Fields(strings.ToLower(folded)), " ")
+}
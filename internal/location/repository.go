@@ -0,0 +1,75 @@
+package location
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+// Repository es el repositorio de ubicaciones en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para el catálogo de ubicaciones")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// GetLocations obtiene todas las ubicaciones, activas e inactivas
+func (r *Repository) GetLocations(ctx context.Context) ([]*Location, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.LocationsCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []*Location{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// SaveLocation registra o reemplaza una ubicación por su código
+func (r *Repository) SaveLocation(ctx context.Context, location *Location) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.LocationsCollection)
+
+	location.UpdatedAt = time.Now()
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": location.Code}, location, options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,225 @@
+package location
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultSuggestLimit es la cantidad de sugerencias por defecto
+	DefaultSuggestLimit = 8
+	// MaxSuggestLimit es la cantidad máxima de sugerencias por consulta
+	MaxSuggestLimit = 20
+)
+
+// Calidad de la coincidencia, de mejor a peor
+const (
+	matchExact = iota
+	matchPrefix
+	matchWordPrefix
+	matchFuzzy
+)
+
+// Suggestion representa una ubicación sugerida para el texto ingresado
+type Suggestion struct {
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	CityCode   string  `json:"city_code,omitempty"`
+	Region     string  `json:"region,omitempty"`
+	Matched    string  `json:"matched"` // Nombre, alias o código que coincidió
+	Popularity float64 `json:"popularity"`
+
+	match    int
+	distance int
+}
+
+// indexEntry representa un término buscable del índice de sugerencias
+type indexEntry struct {
+	key      string   // Término normalizado
+	words    []string // Palabras del término normalizado
+	text     string   // Término original
+	location *Location
+}
+
+// suggestIndex es el índice en memoria de sugerencias, ordenado por término
+type suggestIndex struct {
+	entries []indexEntry
+}
+
+// newSuggestIndex arma el índice con el nombre, el código y los alias de las ubicaciones activas
+func newSuggestIndex(locations []*Location) *suggestIndex {
+	index := &suggestIndex{}
+	for _, location := range locations {
+		if !location.Active {
+			continue
+		}
+		for _, text := range append([]string{location.Name, location.Code}, location.Aliases...) {
+			if key := Normalize(text); key != "" {
+				index.entries = append(index.entries, indexEntry{key: key, words: strings.Fields(key), text: text, location: location})
+			}
+		}
+	}
+
+	sort.Slice(index.entries, func(i, j int) bool { return index.entries[i].key < index.entries[j].key })
+	return index
+}
+
+// search devuelve la mejor coincidencia de cada ubicación para el texto normalizado
+func (idx *suggestIndex) search(query string) map[string]*Suggestion {
+	best := make(map[string]*Suggestion)
+	consider := func(entry indexEntry, match, distance int) {
+		current, ok := best[entry.location.Code]
+		if ok && (current.match < match || (current.match == match && current.distance < distance)) {
+			return
+		}
+		// A igual coincidencia se muestra el nombre o alias antes que el código
+		if ok && current.match == match && current.distance == distance && (current.Matched != current.Code || entry.text == entry.location.Code) {
+			return
+		}
+		best[entry.location.Code] = &Suggestion{
+			Code:     entry.location.Code,
+			Name:     entry.location.Name,
+			Type:     entry.location.Type,
+			CityCode: entry.location.CityCode,
+			Region:   entry.location.Region,
+			Matched:  entry.text,
+			match:    match,
+			distance: distance,
+		}
+	}
+
+	// Coincidencias por prefijo con búsqueda binaria sobre los términos ordenados
+	start := sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].key >= query })
+	for i := start; i < len(idx.entries) && strings.HasPrefix(idx.entries[i].key, query); i++ {
+		if idx.entries[i].key == query {
+			consider(idx.entries[i], matchExact, 0)
+		} else {
+			consider(idx.entries[i], matchPrefix, 0)
+		}
+	}
+
+	// Coincidencias por prefijo de una palabra interior y por errores de tipeo
+	maxDistance := allowedTypos(query)
+	for _, entry := range idx.entries {
+		for i, word := range entry.words {
+			if i > 0 && strings.HasPrefix(word, query) {
+				consider(entry, matchWordPrefix, 0)
+			}
+		}
+
+		if maxDistance == 0 {
+			continue
+		}
+		// Se compara con el inicio del término del mismo largo que el texto, para tolerar palabras incompletas
+		for i, candidate := range entry.words {
+			// El término completo cubre el caso de la primera palabra y los textos con espacios
+			if i == 0 {
+				candidate = entry.key
+			}
+			runes := []rune(candidate)
+			if length := len([]rune(query)); len(runes) > length {
+				runes = runes[:length]
+			}
+			if distance := editDistance(query, string(runes)); distance <= maxDistance {
+				consider(entry, matchFuzzy, distance)
+			}
+		}
+	}
+
+	return best
+}
+
+// allowedTypos devuelve la cantidad de errores de tipeo tolerados según el largo del texto
+func allowedTypos(query string) int {
+	switch length := len([]rune(query)); {
+	case length < 3:
+		return 0
+	case length < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance calcula la distancia de edición entre dos textos, contando la transposición
+// de dos letras vecinas como un solo error
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous2 := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return previous[len(rb)]
+}
+
+// Suggest devuelve las ubicaciones que coinciden con el texto ingresado, ordenadas por calidad
+// de la coincidencia y luego por popularidad. Un terminal toma la popularidad de su ciudad.
+func (c *Catalog) Suggest(query string, limit int) []*Suggestion {
+	query = Normalize(query)
+	suggestions := []*Suggestion{}
+	if query == "" {
+		return suggestions
+	}
+	if limit <= 0 || limit > MaxSuggestLimit {
+		limit = DefaultSuggestLimit
+	}
+
+	c.mu.RLock()
+	for _, suggestion := range c.index.search(query) {
+		code := suggestion.Code
+		if suggestion.CityCode != "" {
+			code = suggestion.CityCode
+		}
+		suggestion.Popularity = c.popularity[code]
+		suggestions = append(suggestions, suggestion)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.match != b.match {
+			return a.match < b.match
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.Popularity != b.Popularity {
+			return a.Popularity > b.Popularity
+		}
+		if a.Type != b.Type {
+			return a.Type == TypeCity
+		}
+		return a.Name < b.Name
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// SetPopularity reemplaza la popularidad de las ciudades usada para ordenar las sugerencias
+func (c *Catalog) SetPopularity(popularity map[string]float64) {
+	c.mu.Lock()
+	c.popularity = popularity
+	c.mu.Unlock()
+}
@@ -0,0 +1,96 @@
+package location
+
+import "testing"
+
+// suggestionCodes devuelve los códigos de las sugerencias en orden
+func suggestionCodes(suggestions []*Suggestion) []string {
+	codes := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		codes[i] = suggestion.Code
+	}
+	return codes
+}
+
+func TestSuggest(t *testing.T) {
+	catalog := newTestCatalog(t)
+	catalog.SetPopularity(map[string]float64{"SJM": 10, "LIM": 5})
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{name: "la coincidencia exacta va primero y la ciudad antes que el terminal", query: "Lima", want: []string{"LIM", "LIMPN", "LIMJM"}},
+		{name: "por prefijo de nombre o alias", query: "cus", want: []string{"CUZ"}},
+		{name: "por prefijo de una palabra interior", query: "reyes", want: []string{"LIM"}},
+		{name: "con un error de tipeo", query: "Cuscp", want: []string{"CUZ"}},
+		{name: "con una transposición", query: "Lmia", want: []string{"LIM", "LIMPN"}},
+		{name: "a igual coincidencia ordena por popularidad", query: "san", want: []string{"SJM", "SJL"}},
+		{name: "respeta el límite", query: "san", limit: 1, want: []string{"SJM"}},
+		{name: "no sugiere ubicaciones inactivas", query: "abancay", want: []string{}},
+		{name: "los textos cortos no toleran errores", query: "cx", want: []string{}},
+		{name: "texto vacío", query: "  ", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := suggestionCodes(catalog.Suggest(tt.query, tt.limit))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Suggest(%q) = %v, se esperaba %v", tt.query, got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Suggest(%q) = %v, se esperaba %v", tt.query, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestTerminalUsesCityPopularity(t *testing.T) {
+	catalog := newTestCatalog(t)
+	catalog.SetPopularity(map[string]float64{"LIM": 5})
+
+	suggestions := catalog.Suggest("terminal", 0)
+	if len(suggestions) != 2 {
+		t.Fatalf("sugerencias = %v, se esperaban los dos terminales", suggestionCodes(suggestions))
+	}
+	for _, suggestion := range suggestions {
+		if suggestion.Popularity != 5 {
+			t.Errorf("popularidad de %s = %v, se esperaba la de Lima (5)", suggestion.Code, suggestion.Popularity)
+		}
+	}
+}
+
+func TestSuggestShowsNameBeforeCode(t *testing.T) {
+	catalog := NewCatalog(nil)
+	if err := catalog.load([]*Location{{Code: "ICA", Name: "Ica", Type: TypeCity, Active: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions := catalog.Suggest("ICA", 0)
+	if len(suggestions) != 1 || suggestions[0].Matched != "Ica" {
+		t.Errorf("sugerencias = %v, a igual coincidencia se esperaba el nombre antes que el código", suggestionCodes(suggestions))
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "cusco", b: "cusco", want: 0},
+		{a: "cusco", b: "cuzco", want: 1},
+		{a: "lmia", b: "lima", want: 1}, // Transposición
+		{a: "arequipa", b: "areqipa", want: 1},
+		{a: "", b: "ica", want: 3},
+		{a: "puno", b: "piura", want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, se esperaba %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package operator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"venta-de-pasajes/internal/auth"
+)
+
+// OperatorHandler maneja las solicitudes de administración de operadores
+type OperatorHandler struct {
+	repo *Repository
+}
+
+// NewOperatorHandler crea una nueva instancia de OperatorHandler
+func NewOperatorHandler(repo *Repository) *OperatorHandler {
+	return &OperatorHandler{
+		repo: repo,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *OperatorHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrOperatorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrOperatorExists), errors.Is(err, ErrOperatorInactive):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidOperator), errors.Is(err, ErrInvalidRUC):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetOperatorsHandler maneja las solicitudes para listar los operadores activos, o uno solo con el parámetro id.
+// Con all=true se incluyen los desactivados.
+func (h *OperatorHandler) GetOperatorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if operatorID := r.URL.Query().Get("id"); operatorID != "" {
+		operator, err := h.repo.GetOperator(r.Context(), operatorID)
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(operator)
+		return
+	}
+
+	operators, err := h.repo.GetOperators(r.Context(), r.URL.Query().Get("all") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(operators)
+}
+
+// CreateOperatorHandler maneja las solicitudes para registrar un operador
+func (h *OperatorHandler) CreateOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	var operator Operator
+	err := json.NewDecoder(r.Body).Decode(&operator)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateOperator(&operator); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	if err := h.repo.CreateOperator(r.Context(), &operator); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(operator)
+}
+
+// UpdateOperatorHandler maneja las solicitudes para modificar el perfil, las políticas o las reglas de equipaje de un operador.
+// Un admin_operador solo modifica el de su operador.
+func (h *OperatorHandler) UpdateOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	var operator Operator
+	err := json.NewDecoder(r.Body).Decode(&operator)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if operator.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if err := auth.AuthorizeOperator(r.Context(), operator.ID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	if err := validateOperator(&operator); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	updated, err := h.repo.UpdateOperator(r.Context(), &operator)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// SetOperatorActiveHandler maneja las solicitudes para activar o desactivar un operador
+func (h *OperatorHandler) SetOperatorActiveHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID     string `json:"id"`
+		Active *bool  `json:"active"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" || requestBody.Active == nil {
+		http.Error(w, "los campos id y active son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	operator, err := h.repo.SetOperatorActive(r.Context(), requestBody.ID, *requestBody.Active)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(operator)
+}
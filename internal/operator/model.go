@@ -0,0 +1,62 @@
+package operator
+
+import "time"
+
+// Operator representa una empresa de transporte cuyos asientos se revenden
+type Operator struct {
+	ID           string       `json:"id,omitempty" bson:"_id,omitempty"`
+	Name         string       `json:"name" bson:"name"`             // Nombre comercial
+	LegalName    string       `json:"legal_name" bson:"legal_name"` // Razón social
+	RUC          string       `json:"ruc" bson:"ruc"`
+	LogoURL      string       `json:"logo_url,omitempty" bson:"logo_url,omitempty"`
+	ContactEmail string       `json:"contact_email,omitempty" bson:"contact_email,omitempty"`
+	ContactPhone string       `json:"contact_phone,omitempty" bson:"contact_phone,omitempty"`
+	Policies     Policies     `json:"policies" bson:"policies"`
+	BaggageRules BaggageRules `json:"baggage_rules" bson:"baggage_rules"`
+	Active       bool         `json:"active" bson:"active"`
+	CreatedAt    time.Time    `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt    time.Time    `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Policies representa las políticas comerciales del operador para sus pasajes
+type Policies struct {
+	CancellationHours int     `json:"cancellation_hours" bson:"cancellation_hours"` // Horas antes de la salida hasta las que se acepta anular
+	RefundPercentage  float64 `json:"refund_percentage" bson:"refund_percentage"`   // Porcentaje devuelto al anular dentro del plazo
+	ChangeHours       int     `json:"change_hours" bson:"change_hours"`             // Horas antes de la salida hasta las que se acepta cambiar
+	ChangeFee         float64 `json:"change_fee" bson:"change_fee"`                 // Cargo fijo por cambio de pasaje
+	Terms             string  `json:"terms,omitempty" bson:"terms,omitempty"`
+	// Sobreventa por defecto de las salidas del operador; cada salida puede definir la suya
+	Overbooking OverbookingPolicy `json:"overbooking" bson:"overbooking"`
+	// Orden para elegir a los pasajeros a reubicar cuando una salida sobrevendida no tiene asientos para todos
+	DeniedBoardingPriority string `json:"denied_boarding_priority,omitempty" bson:"denied_boarding_priority,omitempty"`
+}
+
+// Prioridades para elegir a quién se deniega el embarque; los voluntarios siempre van primero
+const (
+	PriorityLastBooked      = "ultima_reserva" // Las reservas más recientes primero
+	PriorityLowestFare      = "menor_tarifa"   // Las reservas con menor tarifa por asiento primero
+	DefaultBoardingPriority = PriorityLastBooked
+)
+
+// OverbookingPolicy representa cuántos asientos se pueden vender por encima de la capacidad de una salida,
+// como porcentaje de la capacidad o como cantidad fija. Sin valores no se permite sobreventa.
+type OverbookingPolicy struct {
+	Percent float64 `json:"percent,omitempty" bson:"percent,omitempty"`
+	Seats   int     `json:"seats,omitempty" bson:"seats,omitempty"`
+}
+
+// Limit devuelve los asientos que se pueden vender por encima de la capacidad dada
+func (p OverbookingPolicy) Limit(capacity int) int {
+	if p.Seats > 0 {
+		return p.Seats
+	}
+	return int(float64(capacity) * p.Percent / 100)
+}
+
+// BaggageRules representa las restricciones de equipaje del operador.
+// Los valores en cero no imponen límite.
+type BaggageRules struct {
+	MaxPieceWeight       float64  `json:"max_piece_weight" bson:"max_piece_weight"`                               // Peso máximo por pieza (kg)
+	MaxPieces            int      `json:"max_pieces" bson:"max_pieces"`                                           // Piezas máximas por reserva
+	DisallowedCategories []string `json:"disallowed_categories,omitempty" bson:"disallowed_categories,omitempty"` // Categorías de equipaje que no transporta
+}
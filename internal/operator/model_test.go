@@ -0,0 +1,26 @@
+package operator
+
+import "testing"
+
+func TestOverbookingPolicyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverbookingPolicy
+		capacity int
+		want     int
+	}{
+		{name: "sin política", policy: OverbookingPolicy{}, capacity: 40, want: 0},
+		{name: "por porcentaje", policy: OverbookingPolicy{Percent: 10}, capacity: 40, want: 4},
+		{name: "el porcentaje se redondea hacia abajo", policy: OverbookingPolicy{Percent: 5}, capacity: 30, want: 1},
+		{name: "cantidad fija", policy: OverbookingPolicy{Seats: 3}, capacity: 40, want: 3},
+		{name: "la cantidad fija prevalece sobre el porcentaje", policy: OverbookingPolicy{Percent: 50, Seats: 2}, capacity: 40, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Limit(tt.capacity); got != tt.want {
+				t.Errorf("Limit(%d) = %d, se esperaba %d", tt.capacity, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+var (
+	// ErrOperatorNotFound indica que el operador no existe
+	ErrOperatorNotFound = errors.New("operador no encontrado")
+	// ErrOperatorExists indica que ya existe un operador con el mismo RUC
+	ErrOperatorExists = errors.New("ya existe un operador con el mismo RUC")
+	// ErrOperatorInactive indica que el operador está desactivado
+	ErrOperatorInactive = errors.New("el operador está desactivado")
+)
+
+// Repository es el repositorio de operadores en MongoDB
+type Repository struct {
+	config *config.Config // Configuración de MongoDB
+	client *mongo.Client
+}
+
+// NewRepository inicializa y retorna una nueva instancia de Repository
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx := context.Background()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Conexión a MongoDB establecida, para los operadores")
+
+	return &Repository{
+		config: cfg,
+		client: client,
+	}, nil
+}
+
+// collection devuelve la colección de operadores
+func (r *Repository) collection() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.OperatorsCollection)
+}
+
+// EnsureIndexes crea el índice único por RUC
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "ruc", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateOperator registra un nuevo operador activo
+func (r *Repository) CreateOperator(ctx context.Context, operator *Operator) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	operator.ID = uuid.New().String()
+	operator.Active = true
+	operator.CreatedAt = time.Now()
+	operator.UpdatedAt = operator.CreatedAt
+
+	_, err := r.collection().InsertOne(ctx, operator)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrOperatorExists
+	}
+	return err
+}
+
+// UpdateOperator reemplaza el perfil del operador, conservando su estado y fecha de alta
+func (r *Repository) UpdateOperator(ctx context.Context, operator *Operator) (*Operator, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		"name":          operator.Name,
+		"legal_name":    operator.LegalName,
+		"ruc":           operator.RUC,
+		"logo_url":      operator.LogoURL,
+		"contact_email": operator.ContactEmail,
+		"contact_phone": operator.ContactPhone,
+		"policies":      operator.Policies,
+		"baggage_rules": operator.BaggageRules,
+		"updated_at":    time.Now(),
+	}
+
+	var updated Operator
+	err := r.collection().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": operator.ID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrOperatorNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrOperatorExists
+		}
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// SetOperatorActive activa o desactiva un operador
+func (r *Repository) SetOperatorActive(ctx context.Context, operatorID string, active bool) (*Operator, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var updated Operator
+	err := r.collection().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": operatorID},
+		bson.M{"$set": bson.M{"active": active, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrOperatorNotFound
+		}
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// GetOperator obtiene un operador por su ID
+func (r *Repository) GetOperator(ctx context.Context, operatorID string) (*Operator, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var operator Operator
+	err := r.collection().FindOne(ctx, bson.M{"_id": operatorID}).Decode(&operator)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrOperatorNotFound
+		}
+		return nil, err
+	}
+
+	return &operator, nil
+}
+
+// GetOperators obtiene los operadores ordenados por nombre, opcionalmente incluyendo los desactivados
+func (r *Repository) GetOperators(ctx context.Context, includeInactive bool) ([]*Operator, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"active": true}
+	if includeInactive {
+		filter = bson.M{}
+	}
+
+	cursor, err := r.collection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	operators := []*Operator{}
+	if err := cursor.All(ctx, &operators); err != nil {
+		return nil, err
+	}
+
+	return operators, nil
+}
+
+// ValidateOperator verifica que el operador exista y esté activo para asignarle rutas
+func (r *Repository) ValidateOperator(ctx context.Context, operatorID string) error {
+	operator, err := r.GetOperator(ctx, operatorID)
+	if err != nil {
+		return err
+	}
+	if !operator.Active {
+		return ErrOperatorInactive
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidOperator indica que los datos del operador no son válidos
+	ErrInvalidOperator = errors.New("operador inválido")
+	// ErrInvalidOverbooking indica que la política de sobreventa no es válida
+	ErrInvalidOverbooking = errors.New("política de sobreventa inválida")
+	// ErrInvalidRUC indica que el RUC no tiene un formato o dígito verificador válido
+	ErrInvalidRUC = errors.New("RUC inválido")
+)
+
+// rucWeights son los factores del dígito verificador del RUC
+var rucWeights = [10]int{5, 4, 3, 2, 7, 6, 5, 4, 3, 2}
+
+// ValidateRUC verifica que el RUC tenga 11 dígitos, un prefijo de contribuyente conocido
+// (10, 15, 16, 17 o 20) y un dígito verificador correcto
+func ValidateRUC(ruc string) error {
+	if len(ruc) != 11 {
+		return fmt.Errorf("%w: debe tener 11 dígitos", ErrInvalidRUC)
+	}
+	for _, r := range ruc {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("%w: solo admite dígitos", ErrInvalidRUC)
+		}
+	}
+
+	switch ruc[:2] {
+	case "10", "15", "16", "17", "20":
+	default:
+		return fmt.Errorf("%w: prefijo %s desconocido", ErrInvalidRUC, ruc[:2])
+	}
+
+	sum := 0
+	for i, weight := range rucWeights {
+		sum += int(ruc[i]-'0') * weight
+	}
+	check := 11 - sum%11
+	switch check {
+	case 10:
+		check = 0
+	case 11:
+		check = 1
+	}
+	if int(ruc[10]-'0') != check {
+		return fmt.Errorf("%w: dígito verificador incorrecto", ErrInvalidRUC)
+	}
+
+	return nil
+}
+
+// validateOperator verifica los datos del perfil del operador
+func validateOperator(operator *Operator) error {
+	operator.Name = strings.TrimSpace(operator.Name)
+	operator.LegalName = strings.TrimSpace(operator.LegalName)
+	operator.RUC = strings.TrimSpace(operator.RUC)
+
+	if operator.Name == "" || operator.LegalName == "" {
+		return fmt.Errorf("%w: el nombre comercial y la razón social son obligatorios", ErrInvalidOperator)
+	}
+	if err := ValidateRUC(operator.RUC); err != nil {
+		return err
+	}
+	if operator.LogoURL != "" && !strings.HasPrefix(operator.LogoURL, "https://") {
+		return fmt.Errorf("%w: el logo debe ser una URL https", ErrInvalidOperator)
+	}
+
+	policies := operator.Policies
+	if policies.CancellationHours < 0 || policies.ChangeHours < 0 {
+		return fmt.Errorf("%w: los plazos de las políticas no pueden ser negativos", ErrInvalidOperator)
+	}
+	if policies.RefundPercentage < 0 || policies.RefundPercentage > 100 {
+		return fmt.Errorf("%w: el porcentaje de devolución debe estar entre 0 y 100", ErrInvalidOperator)
+	}
+	if policies.ChangeFee < 0 {
+		return fmt.Errorf("%w: el cargo por cambio no puede ser negativo", ErrInvalidOperator)
+	}
+	if err := ValidateOverbooking(policies.Overbooking); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOperator, err)
+	}
+	if err := ValidateBoardingPriority(policies.DeniedBoardingPriority); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOperator, err)
+	}
+
+	rules := operator.BaggageRules
+	if rules.MaxPieceWeight < 0 || rules.MaxPieces < 0 {
+		return fmt.Errorf("%w: los límites de equipaje no pueden ser negativos", ErrInvalidOperator)
+	}
+
+	return nil
+}
+
+// maxOverbookingPercent es la sobreventa máxima permitida como porcentaje de la capacidad
+const maxOverbookingPercent = 20
+
+// ValidateOverbooking verifica que la sobreventa se defina como porcentaje o como cantidad fija, no ambas,
+// y que no supere el máximo permitido
+func ValidateOverbooking(policy OverbookingPolicy) error {
+	if policy.Percent < 0 || policy.Seats < 0 {
+		return fmt.Errorf("%w: los valores no pueden ser negativos", ErrInvalidOverbooking)
+	}
+	if policy.Percent > 0 && policy.Seats > 0 {
+		return fmt.Errorf("%w: indique un porcentaje o una cantidad de asientos, no ambos", ErrInvalidOverbooking)
+	}
+	if policy.Percent > maxOverbookingPercent {
+		return fmt.Errorf("%w: el porcentaje no puede superar %d%%", ErrInvalidOverbooking, maxOverbookingPercent)
+	}
+	return nil
+}
+
+// ValidateBoardingPriority verifica que la prioridad de embarque denegado sea conocida; vacía usa la prioridad por defecto
+func ValidateBoardingPriority(priority string) error {
+	switch priority {
+	case "", PriorityLastBooked, PriorityLowestFare:
+		return nil
+	default:
+		return fmt.Errorf("prioridad de embarque denegado desconocida %q", priority)
+	}
+}
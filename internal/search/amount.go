@@ -0,0 +1,8 @@
+package search
+
+import "math"
+
+// RoundAmount redondea un monto a céntimos
+func RoundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,21 @@
+package search
+
+import "testing"
+
+func TestRoundAmount(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   float64
+	}{
+		{amount: 92.5, want: 92.5},
+		{amount: 7.504, want: 7.5},
+		{amount: 100.005, want: 100.01},
+		{amount: -82.886, want: -82.89},
+	}
+
+	for _, tt := range tests {
+		if got := RoundAmount(tt.amount); got != tt.want {
+			t.Errorf("RoundAmount(%v) = %v, se esperaba %v", tt.amount, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCancellationClosed indica que la reserva ya no se puede cancelar según la política del operador
+var ErrCancellationClosed = errors.New("la reserva ya no se puede cancelar")
+
+// CancellationTerms verifica que una reserva de la salida se pueda cancelar y devuelve el porcentaje del total
+// que se devuelve. No se cancela después de la salida ni dentro del plazo del operador; sin operador se
+// devuelve todo.
+func CancellationTerms(ctx context.Context, operators OperatorDirectory, route *Route, now time.Time) (float64, error) {
+	if !route.Departure.After(now) {
+		return 0, fmt.Errorf("%w: la salida ya partió", ErrCancellationClosed)
+	}
+	if route.OperatorID == "" {
+		return 100, nil
+	}
+
+	op, err := operators.GetOperator(ctx, route.OperatorID)
+	if err != nil {
+		return 0, err
+	}
+	hours := op.Policies.CancellationHours
+	if route.Departure.Sub(now) < time.Duration(hours)*time.Hour {
+		return 0, fmt.Errorf("%w: las cancelaciones se aceptan hasta %d horas antes de la salida", ErrCancellationClosed, hours)
+	}
+	return op.Policies.RefundPercentage, nil
+}
+
+// CheckInRecords consulta si algún pasajero de una reserva ya hizo el check-in o embarcó
+type CheckInRecords interface {
+	HasReservationRecords(ctx context.Context, reservationID string) (bool, error)
+}
+
+// ReservationCancellationTerms verifica, como CancellationTerms, que la reserva se pueda cancelar según su
+// salida y, además, que ninguno de sus pasajeros haya hecho el check-in. Devuelve el porcentaje que se devuelve.
+func ReservationCancellationTerms(ctx context.Context, operators OperatorDirectory, records CheckInRecords, route *Route, reservationID string, now time.Time) (float64, error) {
+	percent, err := CancellationTerms(ctx, operators, route, now)
+	if err != nil {
+		return 0, err
+	}
+	checkedIn, err := records.HasReservationRecords(ctx, reservationID)
+	if err != nil {
+		return 0, err
+	}
+	if checkedIn {
+		return 0, fmt.Errorf("%w: los pasajeros ya hicieron el check-in", ErrCancellationClosed)
+	}
+	return percent, nil
+}
+
+// RefundPercent devuelve el porcentaje del total que se devuelve por una reserva ya cancelada de la salida: todo
+// si la salida la canceló el operador o no tiene operador, y si no el que fija su política de cancelación
+func RefundPercent(ctx context.Context, operators OperatorDirectory, route *Route) (float64, error) {
+	if route.Status == RouteCancelled || route.OperatorID == "" {
+		return 100, nil
+	}
+
+	op, err := operators.GetOperator(ctx, route.OperatorID)
+	if err != nil {
+		return 0, err
+	}
+	return op.Policies.RefundPercentage, nil
+}
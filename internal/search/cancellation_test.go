@@ -0,0 +1,53 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+func TestReservationCancellationTerms(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	operators := testOperators{
+		"op-1": {ID: "op-1", Policies: operator.Policies{CancellationHours: 24, RefundPercentage: 80}},
+	}
+	records := testBoardingRecords{"con-check-in": true}
+
+	tests := []struct {
+		name          string
+		operatorID    string
+		departsIn     time.Duration
+		reservationID string
+		want          float64
+		wantErr       error
+	}{
+		{name: "dentro del plazo", operatorID: "op-1", departsIn: 48 * time.Hour, reservationID: "reserva-1", want: 80},
+		{name: "sin operador se devuelve todo", departsIn: time.Hour, reservationID: "reserva-1", want: 100},
+		{name: "fuera del plazo del operador", operatorID: "op-1", departsIn: 12 * time.Hour, reservationID: "reserva-1", wantErr: ErrCancellationClosed},
+		{name: "la salida ya partió", departsIn: -time.Hour, reservationID: "reserva-1", wantErr: ErrCancellationClosed},
+		{name: "con check-in", departsIn: 48 * time.Hour, reservationID: "con-check-in", wantErr: ErrCancellationClosed},
+		{name: "operador desconocido", operatorID: "op-9", departsIn: 48 * time.Hour, reservationID: "reserva-1", wantErr: operator.ErrOperatorNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := &Route{ID: "ruta-1", OperatorID: tt.operatorID, Departure: now.Add(tt.departsIn)}
+			got, err := ReservationCancellationTerms(context.Background(), operators, records, route, tt.reservationID, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ReservationCancellationTerms(): error = %v, se esperaba %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReservationCancellationTerms(): %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReservationCancellationTerms() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"venta-de-pasajes/internal/auth"
+)
+
+// ErrChangeNotAllowed indica que la reserva no se puede cambiar según las políticas del operador
+var ErrChangeNotAllowed = errors.New("la reserva no se puede cambiar")
+
+// ChangeReservationHandler maneja las solicitudes de un usuario para cambiar su reserva a otra fecha, salida,
+// tramo o cantidad de asientos. Los campos omitidos conservan los valores de la reserva original. La respuesta
+// es la nueva reserva, enlazada con la original y con la diferencia de tarifa más el cargo por cambio.
+func (h *SearchHandler) ChangeReservationHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+		ReservationRequest
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	requestBody.UserID = auth.UserID(r.Context())
+	if requestBody.Seats < 0 {
+		http.Error(w, "la cantidad de asientos debe ser positiva", http.StatusBadRequest)
+		return
+	}
+
+	original, err := h.repo.GetReservationByID(r.Context(), requestBody.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if original == nil || original.UserID != requestBody.UserID {
+		http.Error(w, ErrReservationNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	request, changeFee, err := h.prepareChange(r.Context(), original, requestBody.ReservationRequest)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	changed, err := h.repo.ChangeReservation(r.Context(), original, request, changeFee)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	// Los asientos de la reserva original quedan libres para la lista de espera
+	RunSeatReleaseHooks(r.Context(), h.released, original)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changed)
+}
+
+// prepareChange verifica que la reserva se pueda cambiar dentro del plazo del operador y antes del check-in de sus
+// pasajeros, completa la solicitud con los datos de la reserva original y devuelve el cargo por cambio del operador
+func (h *SearchHandler) prepareChange(ctx context.Context, original *Reservation, request ReservationRequest) (ReservationRequest, float64, error) {
+	if original.Status != ReservationConfirmed {
+		return request, 0, ErrReservationNotConfirmed
+	}
+	if original.GroupID != "" {
+		return request, 0, ErrGroupReservation
+	}
+
+	current, err := h.repo.GetRouteByID(ctx, original.RouteID)
+	if err != nil {
+		return request, 0, err
+	}
+
+	var changeHours int
+	var changeFee float64
+	if original.OperatorID != "" {
+		op, err := h.operators.GetOperator(ctx, original.OperatorID)
+		if err != nil {
+			return request, 0, err
+		}
+		changeHours, changeFee = op.Policies.ChangeHours, op.Policies.ChangeFee
+	}
+	if time.Until(current.Departure) < time.Duration(changeHours)*time.Hour {
+		return request, 0, fmt.Errorf("%w: los cambios se aceptan hasta %d horas antes de la salida", ErrChangeNotAllowed, changeHours)
+	}
+	checkedIn, err := h.boarding.HasReservationRecords(ctx, original.ID)
+	if err != nil {
+		return request, 0, err
+	}
+	if checkedIn {
+		return request, 0, fmt.Errorf("%w: los pasajeros ya hicieron el check-in", ErrCancellationClosed)
+	}
+
+	// Los campos omitidos conservan la salida, el tramo y los asientos de la reserva original
+	if request.RouteID == "" {
+		request.RouteID = original.RouteID
+	}
+	if request.From == "" {
+		request.From = original.FromStop
+	}
+	if request.To == "" {
+		request.To = original.ToStop
+	}
+	if request.Seats == 0 {
+		request.Seats = len(request.SeatNumbers)
+	}
+	if request.Seats == 0 {
+		request.Seats = original.Seats
+	}
+
+	route := current
+	if request.RouteID != original.RouteID {
+		route, err = h.repo.GetRouteByID(ctx, request.RouteID)
+		if err != nil {
+			return request, 0, err
+		}
+	}
+	if route.Status == RouteCancelled {
+		return request, 0, ErrRouteCancelled
+	}
+	if !route.Departure.After(time.Now()) {
+		return request, 0, fmt.Errorf("%w: la nueva salida ya partió", ErrChangeNotAllowed)
+	}
+	if original.OperatorID != "" && route.OperatorID != original.OperatorID {
+		return request, 0, fmt.Errorf("%w: la nueva salida debe ser del mismo operador", ErrChangeNotAllowed)
+	}
+	if request.RouteID == original.RouteID && request.From == original.FromStop && request.To == original.ToStop &&
+		request.Seats == original.Seats && slices.Equal(request.SeatNumbers, original.SeatNumbers) {
+		return request, 0, fmt.Errorf("%w: la solicitud no cambia la reserva", ErrChangeNotAllowed)
+	}
+
+	if len(request.SeatNumbers) > 0 {
+		if err := ValidateSeatNumbers(ctx, h.vehicles, route, request.Seats, request.SeatNumbers); err != nil {
+			return request, 0, err
+		}
+	}
+	request.Overbooking, err = overbookingLimit(ctx, h.operators, route)
+	if err != nil {
+		return request, 0, err
+	}
+
+	return request, changeFee, nil
+}
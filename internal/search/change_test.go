@@ -0,0 +1,141 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+// testOperators simula el directorio de operadores
+type testOperators map[string]*operator.Operator
+
+func (o testOperators) ValidateOperator(ctx context.Context, operatorID string) error {
+	_, err := o.GetOperator(ctx, operatorID)
+	return err
+}
+
+func (o testOperators) GetOperator(ctx context.Context, operatorID string) (*operator.Operator, error) {
+	op, ok := o[operatorID]
+	if !ok {
+		return nil, operator.ErrOperatorNotFound
+	}
+	return op, nil
+}
+
+func TestReservationChangeSettle(t *testing.T) {
+	tests := []struct {
+		name           string
+		change         ReservationChange
+		newTotal       float64
+		wantDifference float64
+		wantDue        float64
+	}{
+		{name: "tarifa mayor", change: ReservationChange{PreviousTotal: 100, ChangeFee: 10}, newTotal: 150, wantDifference: 50, wantDue: 60},
+		{name: "misma tarifa paga solo el cargo", change: ReservationChange{PreviousTotal: 100, ChangeFee: 10}, newTotal: 100, wantDifference: 0, wantDue: 10},
+		{name: "tarifa menor deja saldo a favor", change: ReservationChange{PreviousTotal: 100, ChangeFee: 10}, newTotal: 50, wantDifference: -50, wantDue: -40},
+		{name: "sin cargo", change: ReservationChange{PreviousTotal: 80}, newTotal: 120, wantDifference: 40, wantDue: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change.Settle(tt.newTotal)
+			if tt.change.FareDifference != tt.wantDifference || tt.change.AmountDue != tt.wantDue {
+				t.Errorf("Settle(%v) = diferencia %v y monto %v, se esperaba %v y %v",
+					tt.newTotal, tt.change.FareDifference, tt.change.AmountDue, tt.wantDifference, tt.wantDue)
+			}
+		})
+	}
+}
+
+func TestPrepareChange(t *testing.T) {
+	current := soldOutRoute()
+	current.OperatorID = "op-1"
+	soon := soldOutRoute()
+	soon.ID, soon.OperatorID, soon.Departure = "ruta-pronto", "op-1", time.Now().Add(time.Hour)
+	other := soldOutRoute()
+	other.ID, other.OperatorID = "ruta-otro-operador", "op-2"
+	cancelled := soldOutRoute()
+	cancelled.ID, cancelled.OperatorID, cancelled.Status = "ruta-cancelada", "op-1", RouteCancelled
+	later := soldOutRoute()
+	later.ID, later.OperatorID, later.Departure = "ruta-2", "op-1", current.Departure.Add(24*time.Hour)
+	later.Overbooking = &operator.OverbookingPolicy{Seats: 2}
+
+	h := &SearchHandler{
+		repo: newMemoryRepository(current, soon, other, cancelled, later),
+		operators: testOperators{
+			"op-1": {ID: "op-1", Policies: operator.Policies{ChangeHours: 12, ChangeFee: 15}},
+			"op-2": {ID: "op-2"},
+		},
+		vehicles: testVehicles{},
+		boarding: testBoardingRecords{"con-check-in": true},
+	}
+	original := func(routeID string) *Reservation {
+		return &Reservation{ID: "reserva-1", RouteID: routeID, OperatorID: "op-1", UserID: "u1", Seats: 4,
+			SeatNumbers: []string{"1A", "1B", "2A", "2B"}, Status: ReservationConfirmed}
+	}
+
+	tests := []struct {
+		name            string
+		original        *Reservation
+		request         ReservationRequest
+		want            ReservationRequest
+		wantErr         error
+		wantFee         float64
+		wantOverbooking int
+	}{
+		{
+			name:     "menos asientos conservando los propios",
+			original: original("ruta-1"),
+			request:  ReservationRequest{SeatNumbers: []string{"1A", "1B"}},
+			want:     ReservationRequest{RouteID: "ruta-1", Seats: 2, SeatNumbers: []string{"1A", "1B"}},
+			wantFee:  15,
+		},
+		{
+			name:            "otra salida conserva la cantidad de asientos",
+			original:        &Reservation{ID: "reserva-1", RouteID: "ruta-1", OperatorID: "op-1", UserID: "u1", Seats: 2, Status: ReservationConfirmed},
+			request:         ReservationRequest{RouteID: "ruta-2"},
+			want:            ReservationRequest{RouteID: "ruta-2", Seats: 2},
+			wantFee:         15,
+			wantOverbooking: 2,
+		},
+		{name: "reserva no confirmada", original: &Reservation{RouteID: "ruta-1", Status: ReservationCancelled}, wantErr: ErrReservationNotConfirmed},
+		{name: "reserva de grupo", original: &Reservation{RouteID: "ruta-1", Status: ReservationConfirmed, GroupID: "grupo-1"}, wantErr: ErrGroupReservation},
+		{name: "fuera del plazo del operador", original: original("ruta-pronto"), request: ReservationRequest{RouteID: "ruta-2"}, wantErr: ErrChangeNotAllowed},
+		{
+			name:     "pasajeros con check-in",
+			original: &Reservation{ID: "con-check-in", RouteID: "ruta-1", OperatorID: "op-1", UserID: "u1", Seats: 2, Status: ReservationConfirmed},
+			request:  ReservationRequest{RouteID: "ruta-2"},
+			wantErr:  ErrCancellationClosed,
+		},
+		{name: "salida de otro operador", original: original("ruta-1"), request: ReservationRequest{RouteID: "ruta-otro-operador"}, wantErr: ErrChangeNotAllowed},
+		{name: "salida cancelada", original: original("ruta-1"), request: ReservationRequest{RouteID: "ruta-cancelada"}, wantErr: ErrRouteCancelled},
+		{name: "salida desconocida", original: original("ruta-1"), request: ReservationRequest{RouteID: "ruta-x"}, wantErr: ErrRouteNotFound},
+		{name: "sin cambios", original: original("ruta-1"), request: ReservationRequest{SeatNumbers: []string{"1A", "1B", "2A", "2B"}}, wantErr: ErrChangeNotAllowed},
+		{name: "asiento repetido", original: original("ruta-1"), request: ReservationRequest{SeatNumbers: []string{"1A", "1A"}}, wantErr: ErrInvalidSeats},
+		{name: "asiento que no existe", original: original("ruta-1"), request: ReservationRequest{SeatNumbers: []string{"9Z"}}, wantErr: ErrInvalidSeats},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, fee, err := h.prepareChange(context.Background(), tt.original, tt.request)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("prepareChange(): error = %v, se esperaba %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("prepareChange(): %v", err)
+			}
+			if request.RouteID != tt.want.RouteID || request.Seats != tt.want.Seats || len(request.SeatNumbers) != len(tt.want.SeatNumbers) {
+				t.Errorf("prepareChange() = %+v, se esperaba %+v", request, tt.want)
+			}
+			if fee != tt.wantFee || request.Overbooking != tt.wantOverbooking {
+				t.Errorf("prepareChange() = cargo %v y sobreventa %d, se esperaba %v y %d", fee, request.Overbooking, tt.wantFee, tt.wantOverbooking)
+			}
+		})
+	}
+}
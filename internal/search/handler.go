@@ -1,21 +1,103 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"time"
+
+	"venta-de-pasajes/internal/auth"
+	"venta-de-pasajes/internal/location"
+	"venta-de-pasajes/internal/operator"
 )
 
 // SearchHandler maneja las solicitudes relacionadas con la búsqueda y reserva de rutas
 type SearchHandler struct {
-	repo SearchRepository // Cambiado de *SearchRepository
+	repo       SearchRepository // Cambiado de *SearchRepository
+	locations  LocationResolver
+	operators  OperatorDirectory
+	vehicles   VehicleDirectory
+	boarding   BoardingRecords
+	denier     *BoardingDenier
+	hooks      []RouteCancellationHook
+	reschedule []RouteRescheduleHook
+	released   []SeatReleaseHook
+	enlarged   []CapacityIncreaseHook
 }
 
 // NewSearchHandler crea una nueva instancia de SearchHandler
-func NewSearchHandler(repo SearchRepository) *SearchHandler { // Cambiado de *SearchRepository
+func NewSearchHandler(repo SearchRepository, locations LocationResolver, operators OperatorDirectory, vehicles VehicleDirectory, boarding BoardingRecords, denier *BoardingDenier) *SearchHandler { // Cambiado de *SearchRepository
 	return &SearchHandler{
-		repo: repo,
+		repo:      repo,
+		locations: locations,
+		operators: operators,
+		vehicles:  vehicles,
+		boarding:  boarding,
+		denier:    denier,
+	}
+}
+
+// RegisterCancellationHook registra un hook que se ejecuta al cancelar una ruta
+func (h *SearchHandler) RegisterCancellationHook(hook RouteCancellationHook) {
+	h.hooks = append(h.hooks, hook)
+}
+
+// RegisterRescheduleHook registra un hook que se ejecuta al cambiar el horario de una ruta
+func (h *SearchHandler) RegisterRescheduleHook(hook RouteRescheduleHook) {
+	h.reschedule = append(h.reschedule, hook)
+}
+
+// RegisterSeatReleaseHook registra un hook que se ejecuta al liberar los asientos de una reserva cancelada
+func (h *SearchHandler) RegisterSeatReleaseHook(hook SeatReleaseHook) {
+	h.released = append(h.released, hook)
+}
+
+// RegisterCapacityIncreaseHook registra un hook que se ejecuta cuando una ruta gana asientos disponibles
+func (h *SearchHandler) RegisterCapacityIncreaseHook(hook CapacityIncreaseHook) {
+	h.enlarged = append(h.enlarged, hook)
+}
+
+// runCapacityIncreaseHooks ejecuta los hooks de aumento de capacidad si la ruta tiene más asientos disponibles
+// que antes del cambio. Los errores se registran sin revertir el cambio.
+func (h *SearchHandler) runCapacityIncreaseHooks(ctx context.Context, previous, route *Route) {
+	if route.Seats <= previous.Seats {
+		return
+	}
+	for _, hook := range h.enlarged {
+		if err := hook.OnCapacityIncreased(ctx, route); err != nil {
+			log.Printf("Error en hook de aumento de capacidad de la ruta %s: %v", route.ID, err)
+		}
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *SearchHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRouteNotFound), errors.Is(err, ErrReservationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRouteCancelled), errors.Is(err, ErrCapacityBelowReserved), errors.Is(err, ErrReservationNotConfirmed),
+		errors.Is(err, ErrCapacityFromVehicle), errors.Is(err, ErrVehicleDoesNotFit), errors.Is(err, ErrReservationNotActive),
+		errors.Is(err, ErrNotOversold), errors.Is(err, ErrChangeNotAllowed), errors.Is(err, ErrGroupReservation),
+		errors.Is(err, ErrCancellationClosed), errors.Is(err, ErrRescheduleRejected):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidRoute), errors.Is(err, ErrNotEnoughSeats), errors.Is(err, ErrInvalidSeats), errors.Is(err, ErrInvalidSegment):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// locationStatus determina el código HTTP de un error al resolver el origen o el destino de una búsqueda.
+// Un texto que coincide con varias ciudades es un error de la solicitud, no una ubicación inexistente.
+func locationStatus(err error) int {
+	if errors.Is(err, location.ErrAmbiguousLocation) {
+		return http.StatusBadRequest
 	}
+	return http.StatusNotFound
 }
 
 // SearchRoutesHandler maneja las solicitudes para buscar rutas disponibles entre un origen y un destino
@@ -23,25 +105,113 @@ func (h *SearchHandler) SearchRoutesHandler(w http.ResponseWriter, r *http.Reque
 	origin := r.URL.Query().Get("origin")
 	destination := r.URL.Query().Get("destination")
 
-	log.Println("origen %s\n", origin)
-	log.Println("destino %s\n", destination)
+	log.Printf("origen %s\n", origin)
+	log.Printf("destino %s\n", destination)
+
+	// El origen y el destino pueden indicarse por nombre, alias o código de ciudad o terminal
+	originCode, _, err := h.locations.ResolveCity(origin)
+	if err != nil {
+		http.Error(w, err.Error(), locationStatus(err))
+		return
+	}
+	destCode, _, err := h.locations.ResolveCity(destination)
+	if err != nil {
+		http.Error(w, err.Error(), locationStatus(err))
+		return
+	}
+
+	// Filtro opcional por operador
+	operatorID := r.URL.Query().Get("operator")
 
-	routes, err := h.repo.FindRoutes(r.Context(), origin, destination) // Pasamos el contexto
+	routes, err := h.repo.FindRoutes(r.Context(), originCode, destCode, operatorID) // Pasamos el contexto
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	// Mostrar solo las salidas con asientos en el tramo, contando la sobreventa permitida. Cada operador se
+	// consulta una sola vez por búsqueda.
+	operators := newOperatorCache(h.operators)
+	available := make([]*Route, 0, len(routes))
+	for _, route := range routes {
+		seats := route.Seats
+		if route.Segment != nil {
+			seats = route.Segment.Seats
+		}
+		limit, err := overbookingLimit(r.Context(), operators, route)
+		if err != nil {
+			log.Printf("Error al obtener la sobreventa de la ruta %s: %v", route.ID, err)
+		}
+		if seats+limit > 0 {
+			available = append(available, route)
+		}
+	}
+	routes = available
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(routes)
 }
 
-// ReserveRouteHandler maneja las solicitudes para reservar una ruta
+// ReserveRouteHandler maneja las solicitudes para reservar una ruta.
+// En las rutas con paradas se puede reservar un tramo con from y to, y en las rutas con vehículo asignado
+// se pueden elegir los asientos con seat_numbers.
 func (h *SearchHandler) ReserveRouteHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody ReservationRequest
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+	// La reserva siempre queda a nombre del usuario autenticado
+	requestBody.UserID = auth.UserID(r.Context())
+
+	if requestBody.Seats == 0 {
+		requestBody.Seats = len(requestBody.SeatNumbers)
+	}
+	if requestBody.Seats <= 0 {
+		http.Error(w, "la cantidad de asientos debe ser positiva", http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.repo.GetRouteByID(r.Context(), requestBody.RouteID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	if len(requestBody.SeatNumbers) > 0 {
+		if err := ValidateSeatNumbers(r.Context(), h.vehicles, route, requestBody.Seats, requestBody.SeatNumbers); err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+	}
+
+	// La sobreventa la define la salida o su operador, nunca la solicitud
+	requestBody.Overbooking, err = overbookingLimit(r.Context(), h.operators, route)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reservation, err := h.repo.ReserveRoute(r.Context(), requestBody) // Pasamos el contexto
+	if errors.Is(err, ErrNotEnoughSeats) {
+		http.Error(w, err.Error()+"; puede inscribirse en la lista de espera en /waitlist/join", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// CancelReservationHandler maneja las solicitudes de un usuario para cancelar su reserva.
+// Los asientos liberados se ofrecen a la lista de espera de la salida mediante los hooks registrados.
+func (h *SearchHandler) CancelReservationHandler(w http.ResponseWriter, r *http.Request) {
 	var requestBody struct {
-		RouteID string `json:"route_id"`
-		UserID  string `json:"user_id"`
-		Seats   int    `json:"seats"`
+		ID string `json:"id"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&requestBody)
@@ -49,17 +219,485 @@ func (h *SearchHandler) ReserveRouteHandler(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
 		return
 	}
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
 
-	reservation, err := h.repo.ReserveRoute(r.Context(), requestBody.RouteID, requestBody.UserID, requestBody.Seats) // Pasamos el contexto
+	// Las reservas de grupo se cancelan desde el grupo para mantener sus pagos al día
+	current, err := h.repo.GetReservationByID(r.Context(), requestBody.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if current != nil && current.GroupID != "" {
+		http.Error(w, ErrGroupReservation.Error(), h.errorStatus(ErrGroupReservation))
 		return
 	}
 
+	// Como en las agencias, rige el plazo de cancelación del operador y no se cancela después del check-in; el
+	// pago del cliente se liquida fuera del sistema
+	userID := auth.UserID(r.Context())
+	if current != nil && current.UserID == userID {
+		route, err := h.repo.GetRouteByID(r.Context(), current.RouteID)
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		if _, err := ReservationCancellationTerms(r.Context(), h.operators, h.boarding, route, current.ID, time.Now()); err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+	}
+
+	reservation, err := h.repo.CancelReservation(r.Context(), requestBody.ID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	RunSeatReleaseHooks(r.Context(), h.released, reservation)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(reservation)
 }
 
+// BoardingVolunteerHandler maneja las solicitudes de un usuario para ofrecerse, o dejar de ofrecerse,
+// a ceder su asiento si la salida está sobrevendida
+func (h *SearchHandler) BoardingVolunteerHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID        string `json:"id"`
+		Volunteer *bool  `json:"volunteer"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" || requestBody.Volunteer == nil {
+		http.Error(w, "los campos id y volunteer son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.repo.SetBoardingVolunteer(r.Context(), requestBody.ID, auth.UserID(r.Context()), *requestBody.Volunteer)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// DeniedBoardingHandler maneja las solicitudes para resolver la sobreventa de una salida al embarque.
+// El campo priority es opcional; por defecto se usa la prioridad del operador.
+func (h *SearchHandler) DeniedBoardingHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		RouteID  string `json:"route_id"`
+		Priority string `json:"priority"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.RouteID == "" {
+		http.Error(w, "el campo route_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.authorizeRoute(r.Context(), requestBody.RouteID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	result, err := h.denier.Resolve(r.Context(), requestBody.RouteID, requestBody.Priority)
+	if err != nil {
+		if result == nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		// Las reservas ya retiradas se informan aunque otras no se hayan podido procesar
+		log.Printf("Error al resolver la sobreventa de la ruta %s: %v", requestBody.RouteID, err)
+	}
+	if result == nil {
+		result = &DeniedBoardingResult{Denied: []*Reservation{}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// SeatMapHandler maneja las solicitudes del croquis de asientos de una ruta con vehículo asignado.
+// Con los parámetros from y to se muestra la disponibilidad de un tramo entre paradas.
+func (h *SearchHandler) SeatMapHandler(w http.ResponseWriter, r *http.Request) {
+	routeID := r.URL.Query().Get("route_id")
+	if routeID == "" {
+		http.Error(w, "el parámetro route_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.repo.GetRouteByID(r.Context(), routeID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	if route.VehicleID == "" {
+		http.Error(w, "la ruta no tiene vehículo asignado", http.StatusNotFound)
+		return
+	}
+	segment, err := route.SegmentBetween(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vehicle, err := h.vehicles.GetVehicle(r.Context(), route.VehicleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildSeatMap(route, segment, vehicle))
+}
+
+// GetRouteHandler maneja las solicitudes para consultar una ruta por su ID
+func (h *SearchHandler) GetRouteHandler(w http.ResponseWriter, r *http.Request) {
+	routeID := r.URL.Query().Get("id")
+	if routeID == "" {
+		http.Error(w, "el parámetro id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.authorizeRoute(r.Context(), routeID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
+
+// authorizeRoute obtiene la ruta y verifica que el usuario autenticado pueda gestionar las salidas de su operador
+func (h *SearchHandler) authorizeRoute(ctx context.Context, routeID string) (*Route, error) {
+	route, err := h.repo.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.AuthorizeOperator(ctx, route.OperatorID); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+// CreateRouteHandler maneja las solicitudes para crear una ruta
+func (h *SearchHandler) CreateRouteHandler(w http.ResponseWriter, r *http.Request) {
+	var route Route
+	err := json.NewDecoder(r.Body).Decode(&route)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	// Un admin_operador crea las salidas de su operador
+	if route.OperatorID == "" {
+		route.OperatorID = auth.OperatorScope(r.Context())
+	}
+	if err := auth.AuthorizeOperator(r.Context(), route.OperatorID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	// Con vehículo asignado los asientos y las comodidades se toman del bus
+	if route.VehicleID != "" {
+		vehicle, err := vehicleForRoute(r.Context(), h.vehicles, route.VehicleID, route.OperatorID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		route.Seats = vehicle.Capacity
+		route.Amenities = vehicle.Amenities
+	}
+	route.OccupiedSeats = nil
+
+	if err := validateRoute(&route, h.locations); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateOperator(r.Context(), h.operators, route.OperatorID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateRoute(r.Context(), &route); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(route)
+}
+
+// UpdateRouteHandler maneja las solicitudes para cambiar el precio o la capacidad de una ruta.
+// Si la ruta gana asientos disponibles se ofrecen a su lista de espera.
+func (h *SearchHandler) UpdateRouteHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+		RouteUpdate
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	// Validar los campos de la solicitud
+	if requestBody.ID == "" || (requestBody.Price == nil && requestBody.Capacity == nil && requestBody.Overbooking == nil) {
+		http.Error(w, "el campo id y al menos uno de price, capacity u overbooking son obligatorios", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Price != nil && *requestBody.Price <= 0 {
+		http.Error(w, "el precio debe ser positivo", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Capacity != nil && *requestBody.Capacity <= 0 {
+		http.Error(w, "la capacidad debe ser positiva", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Overbooking != nil {
+		if err := operator.ValidateOverbooking(*requestBody.Overbooking); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	previous, err := h.authorizeRoute(r.Context(), requestBody.ID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	route, err := h.repo.UpdateRoute(r.Context(), requestBody.ID, requestBody.RouteUpdate)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	h.runCapacityIncreaseHooks(r.Context(), previous, route)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
+
+// AssignVehicleHandler maneja las solicitudes para asignar o reasignar el vehículo de una ruta.
+// La reasignación se rechaza si las reservas existentes no caben en el nuevo bus; si el nuevo bus es más grande,
+// los asientos que gana la ruta se ofrecen a su lista de espera.
+func (h *SearchHandler) AssignVehicleHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		RouteID   string `json:"route_id"`
+		VehicleID string `json:"vehicle_id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.RouteID == "" || requestBody.VehicleID == "" {
+		http.Error(w, "los campos route_id y vehicle_id son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.authorizeRoute(r.Context(), requestBody.RouteID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	vehicle, err := vehicleForRoute(r.Context(), h.vehicles, requestBody.VehicleID, route.OperatorID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := vehicleFits(route, vehicle); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	previous := route
+	route, err = h.repo.AssignVehicle(r.Context(), route.ID, vehicle)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+	h.runCapacityIncreaseHooks(r.Context(), previous, route)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
+
+// RescheduleRouteHandler maneja las solicitudes para cambiar el horario de una ruta
+func (h *SearchHandler) RescheduleRouteHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID        string    `json:"id"`
+		Departure time.Time `json:"departure"`
+		Arrival   time.Time `json:"arrival"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if err := validateSchedule(&Route{Departure: requestBody.Departure, Arrival: requestBody.Arrival}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous, err := h.authorizeRoute(r.Context(), requestBody.ID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	stops, err := rescheduleStops(previous, requestBody.Departure, requestBody.Arrival)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Los hooks validan el nuevo horario antes de aplicarlo, por ejemplo la jornada de la tripulación
+	proposed := *previous
+	proposed.Departure, proposed.Arrival, proposed.Stops = requestBody.Departure, requestBody.Arrival, stops
+	for _, hook := range h.reschedule {
+		if err := hook.ValidateReschedule(r.Context(), previous, &proposed); err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+	}
+
+	route, err := h.repo.RescheduleRoute(r.Context(), requestBody.ID, requestBody.Departure, requestBody.Arrival, stops)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	for _, hook := range h.reschedule {
+		if err := hook.OnRouteRescheduled(r.Context(), previous, route); err != nil {
+			log.Printf("Error en hook de reprogramación de la ruta %s: %v", route.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
+
+// CancelRouteHandler maneja las solicitudes para cancelar una ruta.
+// Después de cancelarla se ejecutan los hooks de cancelación registrados con las reservas de la ruta, que se
+// cargan antes de cancelarla. Cancelar de nuevo una ruta ya cancelada vuelve a ejecutar los hooks para completar
+// una reubicación que no terminó; los hooks omiten las reservas que ya procesaron.
+func (h *SearchHandler) CancelRouteHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.authorizeRoute(r.Context(), requestBody.ID)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	reservations, err := h.repo.FindReservationsByRoute(r.Context(), route.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cancelled, err := h.repo.CancelRoute(r.Context(), route.ID)
+	switch {
+	case err == nil:
+		route = cancelled
+	case errors.Is(err, ErrRouteCancelled):
+		log.Printf("La ruta %s ya estaba cancelada, se completa la reubicación de sus reservas", route.ID)
+		route.Status = RouteCancelled
+	default:
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	h.runCancellationHooks(r.Context(), route, reservations)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CancellationResult{Route: route, Reservations: reservations})
+}
+
+// runCancellationHooks ejecuta los hooks de cancelación en orden; un hook fallido no detiene a los siguientes
+func (h *SearchHandler) runCancellationHooks(ctx context.Context, route *Route, reservations []*Reservation) {
+	for _, hook := range h.hooks {
+		if err := hook.OnRouteCancelled(ctx, route, reservations); err != nil {
+			log.Printf("Error en hook de cancelación de la ruta %s: %v", route.ID, err)
+		}
+	}
+}
+
+// OperatorReportHandler maneja las solicitudes del reporte de salidas y ventas de un operador.
+// Los parámetros from y to (AAAA-MM-DD) son opcionales; por defecto se reportan los últimos 30 días.
+// Un admin_operador solo obtiene el reporte de su operador.
+func (h *SearchHandler) OperatorReportHandler(w http.ResponseWriter, r *http.Request) {
+	operatorID := r.URL.Query().Get("operator_id")
+	if operatorID == "" {
+		operatorID = auth.OperatorScope(r.Context())
+	}
+	if operatorID == "" {
+		http.Error(w, "el parámetro operator_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if err := auth.AuthorizeOperator(r.Context(), operatorID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.ParseInLocation(dateLayout, value, scheduleLocation)
+		if err != nil {
+			http.Error(w, "el parámetro from debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.ParseInLocation(dateLayout, value, scheduleLocation)
+		if err != nil {
+			http.Error(w, "el parámetro to debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+			return
+		}
+		// Incluir el día completo
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		http.Error(w, "el parámetro to debe ser posterior a from", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.repo.GetOperatorReport(r.Context(), operatorID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 // MigrateDBHandler maneja las solicitudes para migrar la base de datos
 func (h *SearchHandler) MigrateDBHandler() error {
 	err := h.repo.MigrateDB()
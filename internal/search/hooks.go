@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// RouteCancellationHook se ejecuta cuando se cancela una ruta, con las reservas que tenía.
+// Los hooks se ejecutan en el orden en que se registran.
+type RouteCancellationHook interface {
+	OnRouteCancelled(ctx context.Context, route *Route, reservations []*Reservation) error
+}
+
+// ErrRescheduleRejected indica que un hook rechazó el nuevo horario de la ruta
+var ErrRescheduleRejected = errors.New("la ruta no se puede reprogramar")
+
+// RouteRescheduleHook se ejecuta cuando se cambia el horario de una ruta, con la ruta antes y después del cambio.
+// Antes de reprogramar, ValidateReschedule recibe la ruta con el nuevo horario y puede rechazarlo con un error
+// que envuelva ErrRescheduleRejected.
+type RouteRescheduleHook interface {
+	ValidateReschedule(ctx context.Context, previous, route *Route) error
+	OnRouteRescheduled(ctx context.Context, previous, route *Route) error
+}
+
+// SeatReleaseHook se ejecuta cuando una reserva se cancela y sus asientos vuelven a estar disponibles
+type SeatReleaseHook interface {
+	OnSeatsReleased(ctx context.Context, reservation *Reservation) error
+}
+
+// RunSeatReleaseHooks ofrece a los hooks, en orden, los asientos liberados por una reserva. Un hook fallido se
+// registra en el log y no detiene a los siguientes.
+func RunSeatReleaseHooks(ctx context.Context, hooks []SeatReleaseHook, reservation *Reservation) {
+	for _, hook := range hooks {
+		if err := hook.OnSeatsReleased(ctx, reservation); err != nil {
+			log.Printf("Error en hook de liberación de asientos de la reserva %s: %v", reservation.ID, err)
+		}
+	}
+}
+
+// CapacityIncreaseHook se ejecuta cuando una ruta gana asientos disponibles sin que se cancele una reserva,
+// al ampliar su capacidad o al asignarle un bus más grande
+type CapacityIncreaseHook interface {
+	OnCapacityIncreased(ctx context.Context, route *Route) error
+}
+
+// DeniedBoardingHook se ejecuta al resolver la sobreventa de una salida, con las reservas retiradas: reubicadas
+// en otra salida o con embarque denegado
+type DeniedBoardingHook interface {
+	OnBoardingDenied(ctx context.Context, route *Route, reservations []*Reservation) error
+}
+
+// LogNotificationHook notifica a los usuarios afectados por la cancelación de una ruta.
+// Por ahora registra la notificación en el log.
+type LogNotificationHook struct{}
+
+// OnRouteCancelled notifica a cada usuario con una reserva en la ruta cancelada
+func (LogNotificationHook) OnRouteCancelled(ctx context.Context, route *Route, reservations []*Reservation) error {
+	for _, reservation := range reservations {
+		if reservation.ReplacedBy != "" {
+			log.Printf("Notificación al usuario %s: la ruta %s-%s del %s fue cancelada, su reserva %s fue reubicada en la reserva %s",
+				reservation.UserID, route.OriginCode, route.DestCode, route.Departure.Format("2006-01-02 15:04"), reservation.ID, reservation.ReplacedBy)
+			continue
+		}
+		log.Printf("Notificación al usuario %s: la ruta %s-%s del %s fue cancelada, su reserva %s quedó %s",
+			reservation.UserID, route.OriginCode, route.DestCode, route.Departure.Format("2006-01-02 15:04"), reservation.ID, reservation.Status)
+	}
+	return nil
+}
+
+// reservationStops devuelve las paradas de subida y bajada de una reserva de la ruta. Las reservas de punta a
+// punta no guardan paradas: recorren del origen al destino de la ruta.
+func reservationStops(route *Route, reservation *Reservation) (string, string) {
+	from, to := reservation.FromStop, reservation.ToStop
+	if from == "" {
+		from = route.OriginCode
+	}
+	if to == "" {
+		to = route.DestCode
+	}
+	return from, to
+}
+
+// RebookingHook reubica las reservas confirmadas de una ruta cancelada en la siguiente salida
+// que recorra el mismo tramo. Las reservas que no se pueden reubicar quedan canceladas.
+type RebookingHook struct {
+	repo SearchRepository
+}
+
+// NewRebookingHook crea una nueva instancia de RebookingHook
+func NewRebookingHook(repo SearchRepository) *RebookingHook {
+	return &RebookingHook{
+		repo: repo,
+	}
+}
+
+// OnRouteCancelled reubica o cancela cada reserva confirmada de la ruta
+func (h *RebookingHook) OnRouteCancelled(ctx context.Context, route *Route, reservations []*Reservation) error {
+	var errs []error
+
+	for _, reservation := range reservations {
+		if reservation.Status != ReservationConfirmed {
+			continue
+		}
+
+		// Reubicar en el mismo tramo de la reserva
+		from, to := reservationStops(route, reservation)
+		next, err := h.repo.FindNextDeparture(ctx, route, from, to, reservation.Seats)
+		if err == nil {
+			rebooked, rebookErr := h.repo.RebookReservation(ctx, reservation, next.ID, from, to)
+			if rebookErr == nil {
+				reservation.Status = ReservationRebooked
+				reservation.ReplacedBy = rebooked.ID
+				continue
+			}
+			err = rebookErr
+		}
+		if !errors.Is(err, ErrRouteNotFound) && !errors.Is(err, ErrNotEnoughSeats) {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Sin salida alternativa: cancelar la reserva
+		if err := h.repo.UpdateReservationStatus(ctx, reservation.ID, ReservationConfirmed, ReservationCancelled); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reservation.Status = ReservationCancelled
+	}
+
+	return errors.Join(errs...)
+}
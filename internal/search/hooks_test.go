@@ -0,0 +1,29 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// testReleaseHook registra las reservas cuyos asientos se le ofrecen y falla con err
+type testReleaseHook struct {
+	released []string
+	err      error
+}
+
+func (h *testReleaseHook) OnSeatsReleased(ctx context.Context, reservation *Reservation) error {
+	h.released = append(h.released, reservation.ID)
+	return h.err
+}
+
+func TestRunSeatReleaseHooksContinuesAfterFailure(t *testing.T) {
+	failing := &testReleaseHook{err: errors.New("sin conexión")}
+	next := &testReleaseHook{}
+
+	RunSeatReleaseHooks(context.Background(), []SeatReleaseHook{failing, next}, &Reservation{ID: "reserva-1"})
+
+	if len(failing.released) != 1 || len(next.released) != 1 || next.released[0] != "reserva-1" {
+		t.Errorf("reservas ofrecidas = %v y %v, se esperaba reserva-1 en ambos hooks", failing.released, next.released)
+	}
+}
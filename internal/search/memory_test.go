@@ -0,0 +1,232 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// memoryRepository guarda en memoria las rutas, las reservas y la lista de espera para probar la lógica que usa el
+// repositorio sin MongoDB. Solo implementa los métodos que usan las pruebas; los demás no se deben llamar.
+type memoryRepository struct {
+	SearchRepository
+	routes       map[string]*Route
+	reservations map[string]*Reservation
+	entries      []*WaitlistEntry
+	nextID       int
+	next         *Route // Salida alternativa para reubicar; nil si no hay
+	releaseErr   error  // Error que devuelve ReleaseReservationSeats
+}
+
+// newMemoryRepository crea un repositorio en memoria con las rutas indicadas
+func newMemoryRepository(routes ...*Route) *memoryRepository {
+	repo := &memoryRepository{routes: make(map[string]*Route), reservations: make(map[string]*Reservation)}
+	for _, route := range routes {
+		repo.routes[route.ID] = route
+	}
+	return repo
+}
+
+// newID genera IDs legibles y en orden para las pruebas
+func (r *memoryRepository) newID(prefix string) string {
+	r.nextID++
+	return fmt.Sprintf("%s-%d", prefix, r.nextID)
+}
+
+func (r *memoryRepository) GetRouteByID(ctx context.Context, routeID string) (*Route, error) {
+	route, ok := r.routes[routeID]
+	if !ok {
+		return nil, ErrRouteNotFound
+	}
+	copied := *route
+	copied.OccupiedSeats = slices.Clone(route.OccupiedSeats)
+	return &copied, nil
+}
+
+// addReservation registra una reserva y ocupa sus asientos en la ruta
+func (r *memoryRepository) addReservation(reservation *Reservation) *Reservation {
+	if reservation.ID == "" {
+		reservation.ID = r.newID("reserva")
+	}
+	route := r.routes[reservation.RouteID]
+	route.Seats -= reservation.Seats
+	route.OccupiedSeats = append(route.OccupiedSeats, reservation.SeatNumbers...)
+	r.reservations[reservation.ID] = reservation
+	return reservation
+}
+
+// releaseSeats devuelve a la ruta los asientos de una reserva
+func (r *memoryRepository) releaseSeats(reservation *Reservation) {
+	route := r.routes[reservation.RouteID]
+	route.Seats += reservation.Seats
+	route.OccupiedSeats = slices.DeleteFunc(route.OccupiedSeats, func(number string) bool {
+		return slices.Contains(reservation.SeatNumbers, number)
+	})
+}
+
+func (r *memoryRepository) ReleaseReservationSeats(ctx context.Context, reservation *Reservation) error {
+	if r.releaseErr != nil {
+		return r.releaseErr
+	}
+	r.releaseSeats(reservation)
+	return nil
+}
+
+func (r *memoryRepository) GetReservationByID(ctx context.Context, reservationID string) (*Reservation, error) {
+	return r.reservations[reservationID], nil
+}
+
+func (r *memoryRepository) CancelReservation(ctx context.Context, reservationID, userID string) (*Reservation, error) {
+	reservation, ok := r.reservations[reservationID]
+	if !ok || reservation.UserID != userID {
+		return nil, ErrReservationNotFound
+	}
+	if reservation.Status != ReservationConfirmed && reservation.Status != ReservationPending {
+		return nil, ErrReservationNotActive
+	}
+	reservation.Status = ReservationCancelled
+	r.releaseSeats(reservation)
+	return reservation, nil
+}
+
+func (r *memoryRepository) FindReservationsByRoute(ctx context.Context, routeID string) ([]*Reservation, error) {
+	var reservations []*Reservation
+	for _, reservation := range r.reservations {
+		if reservation.RouteID == routeID {
+			reservations = append(reservations, reservation)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b *Reservation) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return reservations, nil
+}
+
+func (r *memoryRepository) FindNextDeparture(ctx context.Context, route *Route, from, to string, seats int) (*Route, error) {
+	if r.next == nil || r.routes[r.next.ID].Seats < seats {
+		return nil, ErrRouteNotFound
+	}
+	return r.GetRouteByID(ctx, r.next.ID)
+}
+
+func (r *memoryRepository) RebookReservation(ctx context.Context, reservation *Reservation, routeID, from, to string) (*Reservation, error) {
+	current := r.reservations[reservation.ID]
+	if current.Status != ReservationConfirmed {
+		return nil, ErrReservationNotConfirmed
+	}
+	current.Status = ReservationRebooked
+	rebooked := r.addReservation(&Reservation{
+		RouteID:               routeID,
+		UserID:                reservation.UserID,
+		Seats:                 reservation.Seats,
+		Status:                ReservationConfirmed,
+		PreviousReservationID: reservation.ID,
+	})
+	current.ReplacedBy = rebooked.ID
+	return rebooked, nil
+}
+
+func (r *memoryRepository) DenyBoarding(ctx context.Context, reservationID string) (*Reservation, error) {
+	reservation := r.reservations[reservationID]
+	if reservation.Status != ReservationConfirmed {
+		return nil, ErrReservationNotConfirmed
+	}
+	reservation.Status = ReservationDeniedBoarding
+	r.releaseSeats(reservation)
+	return reservation, nil
+}
+
+func (r *memoryRepository) HoldSeats(ctx context.Context, request ReservationRequest, expiresAt time.Time, waitlistEntryID string) (*Reservation, error) {
+	route := r.routes[request.RouteID]
+	if route.Seats < request.Seats {
+		return nil, ErrNotEnoughSeats
+	}
+	for _, number := range request.SeatNumbers {
+		if slices.Contains(route.OccupiedSeats, number) {
+			return nil, ErrInvalidSeats
+		}
+	}
+	return r.addReservation(&Reservation{
+		RouteID:         request.RouteID,
+		UserID:          request.UserID,
+		Seats:           request.Seats,
+		SeatNumbers:     request.SeatNumbers,
+		Status:          ReservationPending,
+		ExpiresAt:       expiresAt,
+		WaitlistEntryID: waitlistEntryID,
+	}), nil
+}
+
+func (r *memoryRepository) FindExpiredHolds(ctx context.Context, now time.Time) ([]*Reservation, error) {
+	var holds []*Reservation
+	for _, reservation := range r.reservations {
+		if reservation.Status == ReservationPending && !reservation.ExpiresAt.After(now) {
+			holds = append(holds, reservation)
+		}
+	}
+	return holds, nil
+}
+
+func (r *memoryRepository) ExpireHold(ctx context.Context, reservationID string, now time.Time) (*Reservation, error) {
+	reservation := r.reservations[reservationID]
+	if reservation.Status != ReservationPending || reservation.ExpiresAt.After(now) {
+		return nil, ErrReservationNotPending
+	}
+	reservation.Status = ReservationCancelled
+	r.releaseSeats(reservation)
+	return reservation, nil
+}
+
+func (r *memoryRepository) AddWaitlistEntry(ctx context.Context, entry *WaitlistEntry) error {
+	entry.ID = r.newID("inscripcion")
+	entry.Status = WaitlistWaiting
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *memoryRepository) GetWaitlistEntry(ctx context.Context, entryID string) (*WaitlistEntry, error) {
+	for _, entry := range r.entries {
+		if entry.ID == entryID {
+			return entry, nil
+		}
+	}
+	return nil, ErrWaitlistEntryNotFound
+}
+
+func (r *memoryRepository) GetWaitlist(ctx context.Context, routeID, status string) ([]*WaitlistEntry, error) {
+	var entries []*WaitlistEntry
+	for _, entry := range r.entries {
+		if entry.RouteID == routeID && (status == "" || entry.Status == status) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *memoryRepository) UpdateWaitlistEntry(ctx context.Context, entryID, from string, update WaitlistUpdate) error {
+	entry, err := r.GetWaitlistEntry(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	if entry.Status != from {
+		return ErrWaitlistEntryClosed
+	}
+	entry.Status = update.Status
+	if update.ReservationID != "" {
+		entry.ReservationID = update.ReservationID
+	}
+	if !update.OfferExpiresAt.IsZero() {
+		entry.OfferExpiresAt = update.OfferExpiresAt
+	}
+	return nil
+}
+
+func (r *memoryRepository) CancelRouteWaitlist(ctx context.Context, routeID string) (int64, error) {
+	var cancelled int64
+	for _, entry := range r.entries {
+		if entry.RouteID == routeID && entry.Status == WaitlistWaiting {
+			entry.Status = WaitlistCancelled
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
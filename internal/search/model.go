@@ -2,27 +2,300 @@ package search
 
 import (
 	"time"
+
+	"venta-de-pasajes/internal/fleet"
+	"venta-de-pasajes/internal/operator"
+)
+
+// Estados de una ruta
+const (
+	RouteScheduled = "programado"
+	RouteCancelled = "cancelado"
+)
+
+// Estados de una reserva
+const (
+	ReservationConfirmed = "confirmado"
+	ReservationPending   = "pendiente"
+	ReservationCancelled = "cancelado"
+	ReservationRebooked  = "reubicado" // Movida a otra salida; ver ReplacedBy
+	ReservationChanged   = "cambiado"  // Reemplazada a pedido del usuario; ver ReplacedBy
+	// Sin asiento en una salida sobrevendida y sin otra salida donde reubicarla
+	ReservationDeniedBoarding = "embarque_denegado"
+	// Ningún pasajero de la reserva embarcó antes de la salida
+	ReservationNoShow = "no_presentado"
 )
 
 // Route representa una ruta disponible para la reserva de pasajes
 type Route struct {
 	ID          string    `json:"id,omitempty" bson:"_id,omitempty"`
+	OperatorID  string    `json:"operator_id,omitempty" bson:"operator_id,omitempty"`
 	Origin      string    `json:"origin" bson:"origin"`
 	OriginCode  string    `json:"originCode" bson:"originCode"`
 	Destination string    `json:"destination" bson:"destination"`
 	DestCode    string    `json:"destCode" bson:"destCode"`
 	Departure   time.Time `json:"departure" bson:"departure"`
 	Arrival     time.Time `json:"arrival" bson:"arrival"`
-	Seats       int       `json:"seats" bson:"seats"`
+	Seats       int       `json:"seats" bson:"seats"`       // Asientos disponibles
+	Capacity    int       `json:"capacity" bson:"capacity"` // Asientos totales de la salida
 	Price       float64   `json:"price" bson:"price"`
+	Status      string    `json:"status,omitempty" bson:"status,omitempty"`           // Vacío equivale a programado
+	TemplateID  string    `json:"template_id,omitempty" bson:"template_id,omitempty"` // Horario que generó la salida
+	VehicleID   string    `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"`   // Bus asignado; define la capacidad y el croquis
+	Amenities   []string  `json:"amenities,omitempty" bson:"amenities,omitempty"`     // Comodidades del bus asignado
+	// Asientos del croquis ya tomados por reservas con selección de asiento, en rutas sin paradas
+	OccupiedSeats []string `json:"occupied_seats,omitempty" bson:"occupied_seats,omitempty"`
+	// Paradas en orden, desde el origen hasta el destino
+	Stops []Stop `json:"stops,omitempty" bson:"stops,omitempty"`
+	// Asientos disponibles en cada tramo entre paradas consecutivas; Seats es el mínimo de todos
+	SegmentSeats []int `json:"segment_seats,omitempty" bson:"segment_seats,omitempty"`
+	// Asientos del croquis tomados en cada tramo entre paradas consecutivas
+	SegmentOccupied [][]string `json:"-" bson:"segment_occupied,omitempty"`
+	// Sobreventa propia de la salida; sin ella rige la del operador
+	Overbooking *operator.OverbookingPolicy `json:"overbooking,omitempty" bson:"overbooking,omitempty"`
+	// Tramo buscado, cuando la búsqueda no coincide con el origen y el destino de la ruta
+	Segment   *Segment  `json:"segment,omitempty" bson:"-"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Stop representa una parada de una ruta
+type Stop struct {
+	Code      string    `json:"code" bson:"code"`
+	Name      string    `json:"name" bson:"name"`
+	Arrival   time.Time `json:"arrival" bson:"arrival"`               // En el origen coincide con la salida de la ruta
+	Departure time.Time `json:"departure" bson:"departure"`           // En el destino coincide con la llegada de la ruta
+	Fare      float64   `json:"fare,omitempty" bson:"fare,omitempty"` // Tarifa acumulada desde el origen
+}
+
+// Segment representa un tramo de una ruta entre dos de sus paradas
+type Segment struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	FromIndex int       `json:"-"`
+	ToIndex   int       `json:"-"`
+	Departure time.Time `json:"departure"`
+	Arrival   time.Time `json:"arrival"`
+	Price     float64   `json:"price"`
+	Seats     int       `json:"seats"` // Asientos disponibles en todo el tramo
 }
 
 // Reservation representa una reserva de pasajes realizada por un usuario
 type Reservation struct {
-	ID         string  `json:"id,omitempty" bson:"_id,omitempty"`
-	RouteID    string  `json:"route_id"`
-	UserID     string  `json:"user_id"`
-	Seats      int     `json:"seats"`
-	TotalPrice float64 `json:"total_price"`
-	Status     string  `json:"status"` // Puede ser "confirmado", "pendiente", "cancelado", etc.
+	ID                    string             `json:"id,omitempty" bson:"_id,omitempty"`
+	RouteID               string             `json:"route_id" bson:"route_id"`
+	OperatorID            string             `json:"operator_id,omitempty" bson:"operator_id,omitempty"`
+	UserID                string             `json:"user_id" bson:"user_id"`
+	Seats                 int                `json:"seats" bson:"seats"`
+	SeatNumbers           []string           `json:"seat_numbers,omitempty" bson:"seat_numbers,omitempty"`
+	FromStop              string             `json:"from_stop,omitempty" bson:"from_stop,omitempty"` // Parada de subida; vacío es el origen
+	ToStop                string             `json:"to_stop,omitempty" bson:"to_stop,omitempty"`     // Parada de bajada; vacío es el destino
+	TotalPrice            float64            `json:"total_price" bson:"total_price"`
+	Status                string             `json:"status" bson:"status"` // Puede ser "confirmado", "pendiente", "cancelado", etc.
+	PreviousReservationID string             `json:"previous_reservation_id,omitempty" bson:"previous_reservation_id,omitempty"`
+	ReplacedBy            string             `json:"replaced_by,omitempty" bson:"replaced_by,omitempty"`
+	ExpiresAt             time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`                 // Vencimiento de una reserva pendiente
+	WaitlistEntryID       string             `json:"waitlist_entry_id,omitempty" bson:"waitlist_entry_id,omitempty"`   // Inscripción en lista de espera que originó la reserva
+	BoardingVolunteer     bool               `json:"boarding_volunteer,omitempty" bson:"boarding_volunteer,omitempty"` // Acepta ceder su asiento si la salida está sobrevendida
+	Change                *ReservationChange `json:"change,omitempty" bson:"change,omitempty"`                         // Liquidación del cambio que originó la reserva
+	GroupID               string             `json:"group_id,omitempty" bson:"group_id,omitempty"`                     // Reserva de grupo a la que pertenece
+	AgencyID              string             `json:"agency_id,omitempty" bson:"agency_id,omitempty"`                   // Agencia que vendió la reserva
+	Passengers            []Passenger        `json:"passengers,omitempty" bson:"passengers,omitempty"`                 // Pasajeros nombrados, con un pasaje cada uno
+	CreatedAt             time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+// Estados del pasaje de un pasajero
+const (
+	PassengerActive    = "activo"
+	PassengerCancelled = "cancelado"
+)
+
+// Passenger representa a un pasajero nombrado de una reserva, con su propio pasaje
+type Passenger struct {
+	ID             string    `json:"id" bson:"id"` // Número de pasaje
+	Name           string    `json:"name" bson:"name"`
+	DocumentNumber string    `json:"document_number" bson:"document_number"`
+	SeatNumber     string    `json:"seat_number,omitempty" bson:"seat_number,omitempty"`
+	Status         string    `json:"status" bson:"status"`
+	CancelledAt    time.Time `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+}
+
+// ReservationChange representa la liquidación del cambio de una reserva por otra.
+// Un monto a pagar negativo es un saldo a favor del usuario.
+type ReservationChange struct {
+	PreviousTotal  float64   `json:"previous_total" bson:"previous_total"`   // Total de la reserva reemplazada
+	FareDifference float64   `json:"fare_difference" bson:"fare_difference"` // Total nuevo menos el anterior
+	ChangeFee      float64   `json:"change_fee" bson:"change_fee"`           // Cargo del operador por el cambio
+	AmountDue      float64   `json:"amount_due" bson:"amount_due"`
+	ChangedAt      time.Time `json:"changed_at" bson:"changed_at"`
+}
+
+// Settle calcula la diferencia de tarifa y el monto a pagar a partir del total de la nueva reserva
+func (c *ReservationChange) Settle(newTotal float64) {
+	c.FareDifference = newTotal - c.PreviousTotal
+	c.AmountDue = c.FareDifference + c.ChangeFee
+}
+
+// ReservationRequest representa una solicitud de reserva de asientos en un tramo de una ruta
+type ReservationRequest struct {
+	RouteID     string   `json:"route_id"`
+	UserID      string   `json:"user_id"`
+	Seats       int      `json:"seats"`
+	SeatNumbers []string `json:"seat_numbers,omitempty"`
+	From        string   `json:"from,omitempty"` // Código de la parada de subida; vacío es el origen
+	To          string   `json:"to,omitempty"`   // Código de la parada de bajada; vacío es el destino
+	Overbooking int      `json:"-"`              // Asientos que se pueden vender por encima de la capacidad
+	Fare        float64  `json:"-"`              // Tarifa negociada por asiento; cero usa la tarifa del tramo
+	GroupID     string   `json:"-"`              // Reserva de grupo que origina la solicitud
+	AgencyID    string   `json:"-"`              // Agencia que vende la reserva
+}
+
+// Estados de una inscripción en la lista de espera
+const (
+	WaitlistWaiting   = "en_espera"
+	WaitlistOffered   = "ofrecido"
+	WaitlistClaimed   = "confirmado"
+	WaitlistExpired   = "vencido"
+	WaitlistCancelled = "cancelado"
+)
+
+// WaitlistEntry representa la inscripción de un usuario en la lista de espera de una salida agotada.
+// Cuando se liberan asientos se ofrecen en orden de inscripción con una reserva pendiente que vence.
+type WaitlistEntry struct {
+	ID             string    `json:"id,omitempty" bson:"_id,omitempty"`
+	RouteID        string    `json:"route_id" bson:"route_id"`
+	UserID         string    `json:"user_id" bson:"user_id"`
+	Seats          int       `json:"seats" bson:"seats"`
+	FareClass      string    `json:"fare_class,omitempty" bson:"fare_class,omitempty"` // Tipo de asiento del bus; vacío acepta cualquiera
+	FromStop       string    `json:"from_stop,omitempty" bson:"from_stop,omitempty"`
+	ToStop         string    `json:"to_stop,omitempty" bson:"to_stop,omitempty"`
+	Status         string    `json:"status" bson:"status"`
+	ReservationID  string    `json:"reservation_id,omitempty" bson:"reservation_id,omitempty"` // Reserva pendiente de la oferta
+	OfferExpiresAt time.Time `json:"offer_expires_at,omitempty" bson:"offer_expires_at,omitempty"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// WaitlistUpdate representa el cambio de estado de una inscripción en la lista de espera
+type WaitlistUpdate struct {
+	Status         string
+	ReservationID  string
+	OfferExpiresAt time.Time
+}
+
+// RouteUpdate representa los cambios permitidos sobre una ruta existente
+type RouteUpdate struct {
+	Price       *float64                    `json:"price,omitempty"`
+	Capacity    *int                        `json:"capacity,omitempty"`
+	Overbooking *operator.OverbookingPolicy `json:"overbooking,omitempty"` // Sin valores vuelve a regir la del operador
+}
+
+// CancellationResult resume la cancelación de una ruta y lo ocurrido con sus reservas
+type CancellationResult struct {
+	Route        *Route         `json:"route"`
+	Reservations []*Reservation `json:"reservations"`
+}
+
+// ScheduleTemplate representa un horario recurrente a partir del cual se generan salidas concretas.
+// Las fechas y horas se interpretan en la hora de Perú.
+type ScheduleTemplate struct {
+	ID            string              `json:"id,omitempty" bson:"_id,omitempty"`
+	OperatorID    string              `json:"operator_id" bson:"operator_id"`
+	Origin        string              `json:"origin" bson:"origin"`
+	OriginCode    string              `json:"originCode" bson:"originCode"`
+	Destination   string              `json:"destination" bson:"destination"`
+	DestCode      string              `json:"destCode" bson:"destCode"`
+	DepartureTime string              `json:"departure_time" bson:"departure_time"`   // Hora de salida (HH:MM)
+	Duration      int                 `json:"duration" bson:"duration"`               // Duración del viaje en minutos
+	Stops         []ScheduleStop      `json:"stops,omitempty" bson:"stops,omitempty"` // Paradas intermedias en orden
+	Recurrence    string              `json:"recurrence" bson:"recurrence"`           // Regla tipo RRULE, por ejemplo "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR,SA"
+	Seats         int                 `json:"seats" bson:"seats"`
+	VehicleID     string              `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"` // Bus de las salidas; define los asientos
+	Amenities     []string            `json:"amenities,omitempty" bson:"amenities,omitempty"`
+	Price         float64             `json:"price" bson:"price"`
+	ValidFrom     string              `json:"valid_from" bson:"valid_from"`                       // Fecha inicial (AAAA-MM-DD)
+	ValidUntil    string              `json:"valid_until,omitempty" bson:"valid_until,omitempty"` // Fecha final opcional (AAAA-MM-DD)
+	Exceptions    []ScheduleException `json:"exceptions,omitempty" bson:"exceptions,omitempty"`
+	Holidays      *ScheduleOverride   `json:"holidays,omitempty" bson:"holidays,omitempty"` // Cambios en feriados; nulo opera normalmente
+	Active        bool                `json:"active" bson:"active"`
+	CreatedAt     time.Time           `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt     time.Time           `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// ScheduleStop representa una parada intermedia de un horario, con sus horas relativas a la salida
+type ScheduleStop struct {
+	Code            string  `json:"code" bson:"code"`
+	Name            string  `json:"name" bson:"name"`
+	ArrivalOffset   int     `json:"arrival_offset" bson:"arrival_offset"`     // Minutos desde la salida hasta la llegada a la parada
+	DepartureOffset int     `json:"departure_offset" bson:"departure_offset"` // Minutos desde la salida hasta que se parte de la parada
+	Fare            float64 `json:"fare,omitempty" bson:"fare,omitempty"`     // Tarifa acumulada desde el origen
+}
+
+// ScheduleOverride representa un cambio sobre una salida del horario
+type ScheduleOverride struct {
+	Cancelled     bool     `json:"cancelled" bson:"cancelled"`
+	DepartureTime string   `json:"departure_time,omitempty" bson:"departure_time,omitempty"` // Otra hora de salida (HH:MM)
+	Price         *float64 `json:"price,omitempty" bson:"price,omitempty"`
+}
+
+// ScheduleException representa un cambio del horario para una fecha puntual.
+// Prevalece sobre el cambio por feriado.
+type ScheduleException struct {
+	Date             string `json:"date" bson:"date"` // AAAA-MM-DD
+	ScheduleOverride `bson:",inline"`
+}
+
+// Holiday representa un feriado que afecta a los horarios
+type Holiday struct {
+	Date string `json:"date" bson:"_id"` // AAAA-MM-DD
+	Name string `json:"name" bson:"name"`
+}
+
+// GenerationResult resume la generación de salidas a partir de los horarios
+type GenerationResult struct {
+	Templates int      `json:"templates"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"` // Salidas sin ventas a las que se aplicaron los cambios del horario
+	Existing  int      `json:"existing"`
+	Cancelled int      `json:"cancelled"`
+	Conflicts []string `json:"conflicts,omitempty"` // Salidas con reservas que una excepción o la desactivación piden cancelar
+	Errors    []string `json:"errors,omitempty"`    // Horarios que no se pudieron generar, con el motivo
+}
+
+// OperatorReport resume las salidas y ventas de un operador en un periodo
+type OperatorReport struct {
+	OperatorID          string               `json:"operator_id"`
+	From                time.Time            `json:"from"`
+	To                  time.Time            `json:"to"`
+	Departures          int                  `json:"departures"`
+	CancelledDepartures int                  `json:"cancelled_departures"`
+	SeatsOffered        int                  `json:"seats_offered"`
+	SeatsSold           int                  `json:"seats_sold"`
+	Revenue             float64              `json:"revenue"` // Reservas confirmadas
+	Reservations        []ReservationSummary `json:"reservations"`
+}
+
+// ReservationSummary resume las reservas de un estado
+type ReservationSummary struct {
+	Status string  `json:"status" bson:"_id"`
+	Count  int     `json:"count" bson:"count"`
+	Seats  int     `json:"seats" bson:"seats"`
+	Amount float64 `json:"amount" bson:"amount"`
+}
+
+// SeatMap representa el croquis de asientos de una salida con su disponibilidad
+type SeatMap struct {
+	RouteID        string        `json:"route_id"`
+	VehicleID      string        `json:"vehicle_id"`
+	Decks          int           `json:"decks"`
+	Rows           int           `json:"rows"`
+	Columns        int           `json:"columns"`
+	AvailableSeats int           `json:"available_seats"`
+	Amenities      []string      `json:"amenities,omitempty"`
+	Seats          []SeatMapSeat `json:"seats"`
+}
+
+// SeatMapSeat representa un asiento del croquis de una salida
+type SeatMapSeat struct {
+	fleet.Seat
+	Available bool `json:"available"`
 }
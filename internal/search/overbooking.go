@@ -0,0 +1,294 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+// ErrNotOversold indica que la salida tiene asientos para todos sus pasajeros
+var ErrNotOversold = errors.New("la salida no está sobrevendida")
+
+// BoardingRecords consulta el check-in y el embarque de las reservas
+type BoardingRecords interface {
+	HasReservationRecords(ctx context.Context, reservationID string) (bool, error)
+	RecordedReservations(ctx context.Context, routeID string) (map[string]bool, error)
+}
+
+// OperatorDirectory obtiene los operadores, además de validarlos, para consultar sus políticas
+type OperatorDirectory interface {
+	OperatorValidator
+	GetOperator(ctx context.Context, operatorID string) (*operator.Operator, error)
+}
+
+// overbookingLimit devuelve los asientos que se pueden vender por encima de la capacidad de la salida.
+// Rige la política propia de la salida y, si no tiene, la del operador.
+func overbookingLimit(ctx context.Context, operators OperatorDirectory, route *Route) (int, error) {
+	if route.Overbooking != nil {
+		return route.Overbooking.Limit(route.Capacity), nil
+	}
+	if route.OperatorID == "" {
+		return 0, nil
+	}
+
+	op, err := operators.GetOperator(ctx, route.OperatorID)
+	if err != nil {
+		return 0, err
+	}
+	return op.Policies.Overbooking.Limit(route.Capacity), nil
+}
+
+// operatorCache obtiene cada operador una sola vez mientras dura una solicitud, para no consultarlo por cada
+// salida de una búsqueda
+type operatorCache struct {
+	OperatorDirectory
+	operators map[string]*operator.Operator
+}
+
+// newOperatorCache crea una nueva instancia de operatorCache
+func newOperatorCache(operators OperatorDirectory) *operatorCache {
+	return &operatorCache{
+		OperatorDirectory: operators,
+		operators:         make(map[string]*operator.Operator),
+	}
+}
+
+// GetOperator obtiene el operador del directorio la primera vez y luego de la caché
+func (c *operatorCache) GetOperator(ctx context.Context, operatorID string) (*operator.Operator, error) {
+	if op, ok := c.operators[operatorID]; ok {
+		return op, nil
+	}
+	op, err := c.OperatorDirectory.GetOperator(ctx, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	c.operators[operatorID] = op
+	return op, nil
+}
+
+// DeniedBoardingResult representa las reservas retiradas de una salida sobrevendida
+type DeniedBoardingResult struct {
+	Route    *Route         `json:"route"`
+	Priority string         `json:"priority"`
+	Oversold int            `json:"oversold"` // Asientos vendidos por encima de la capacidad antes de resolver
+	Denied   []*Reservation `json:"denied"`   // Reubicadas en otra salida o con embarque denegado
+}
+
+// BoardingDenier resuelve la sobreventa de una salida al momento del embarque: elige, según la prioridad
+// del operador, las reservas que no tendrán asiento y las reubica en la siguiente salida del mismo tramo.
+// Las que no se pueden reubicar quedan con embarque denegado.
+type BoardingDenier struct {
+	repo      SearchRepository
+	operators OperatorDirectory
+	boarding  BoardingRecords
+	hooks     []DeniedBoardingHook
+}
+
+// NewBoardingDenier crea una nueva instancia de BoardingDenier
+func NewBoardingDenier(repo SearchRepository, operators OperatorDirectory, boarding BoardingRecords) *BoardingDenier {
+	return &BoardingDenier{
+		repo:      repo,
+		operators: operators,
+		boarding:  boarding,
+	}
+}
+
+// RegisterHook registra un hook que se ejecuta con las reservas retiradas al resolver una sobreventa
+func (d *BoardingDenier) RegisterHook(hook DeniedBoardingHook) {
+	d.hooks = append(d.hooks, hook)
+}
+
+// Resolve retira pasajeros de la salida hasta que ningún tramo tenga más pasajeros que asientos.
+// priority vacío usa la prioridad del operador. No se retira a las reservas con pasajeros que ya hicieron el
+// check-in o embarcaron, y una salida que ya partió no se puede resolver.
+func (d *BoardingDenier) Resolve(ctx context.Context, routeID, priority string) (*DeniedBoardingResult, error) {
+	route, err := d.repo.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == RouteCancelled {
+		return nil, ErrRouteCancelled
+	}
+	if !route.Departure.After(time.Now()) {
+		return nil, fmt.Errorf("%w: la salida ya partió", ErrInvalidRoute)
+	}
+
+	if priority == "" {
+		priority, err = d.operatorPriority(ctx, route.OperatorID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := operator.ValidateBoardingPriority(priority); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRoute, err)
+	}
+
+	// Pasajeros sin asiento en cada tramo entre paradas consecutivas
+	legs := route.SegmentSeats
+	if len(legs) == 0 {
+		legs = []int{route.Seats}
+	}
+	oversold := make([]int, len(legs))
+	total := 0
+	for i, seats := range legs {
+		if seats < 0 {
+			oversold[i] = -seats
+			total = max(total, -seats)
+		}
+	}
+	if total == 0 {
+		return nil, ErrNotOversold
+	}
+
+	reservations, err := d.repo.FindReservationsByRoute(ctx, route.ID)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := d.boarding.RecordedReservations(ctx, route.ID)
+	if err != nil {
+		return nil, err
+	}
+	candidates := boardingCandidates(reservations, recorded, priority)
+
+	result := &DeniedBoardingResult{Priority: priority, Oversold: total, Denied: []*Reservation{}}
+	var errs []error
+	for _, reservation := range candidates {
+		segment, err := route.SegmentBetween(reservation.FromStop, reservation.ToStop)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		from, to := segment.FromIndex, segment.ToIndex
+		if len(route.SegmentSeats) == 0 {
+			from, to = 0, 1
+		}
+		if !anyOversold(oversold[from:to]) {
+			continue
+		}
+
+		if err := d.deny(ctx, route, reservation); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.Denied = append(result.Denied, reservation)
+		for leg := from; leg < to; leg++ {
+			oversold[leg] = max(0, oversold[leg]-reservation.Seats)
+		}
+		if !anyOversold(oversold) {
+			break
+		}
+	}
+
+	if len(result.Denied) > 0 {
+		for _, hook := range d.hooks {
+			if err := hook.OnBoardingDenied(ctx, route, result.Denied); err != nil {
+				log.Printf("Error en hook de embarque denegado de la salida %s: %v", route.ID, err)
+			}
+		}
+	}
+
+	result.Route, err = d.repo.GetRouteByID(ctx, route.ID)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return result, errors.Join(errs...)
+}
+
+// operatorPriority devuelve la prioridad de embarque denegado del operador de la salida
+func (d *BoardingDenier) operatorPriority(ctx context.Context, operatorID string) (string, error) {
+	if operatorID == "" {
+		return operator.DefaultBoardingPriority, nil
+	}
+	op, err := d.operators.GetOperator(ctx, operatorID)
+	if err != nil {
+		return "", err
+	}
+	if op.Policies.DeniedBoardingPriority == "" {
+		return operator.DefaultBoardingPriority, nil
+	}
+	return op.Policies.DeniedBoardingPriority, nil
+}
+
+// deny reubica la reserva en la siguiente salida del mismo tramo y libera sus asientos en la salida
+// sobrevendida. Sin salida alternativa, la reserva queda con embarque denegado.
+func (d *BoardingDenier) deny(ctx context.Context, route *Route, reservation *Reservation) error {
+	from, to := reservationStops(route, reservation)
+	next, err := d.repo.FindNextDeparture(ctx, route, from, to, reservation.Seats)
+	if err == nil {
+		rebooked, rebookErr := d.repo.RebookReservation(ctx, reservation, next.ID, from, to)
+		if rebookErr == nil {
+			// La reserva solo deja de ocupar la salida sobrevendida cuando se liberan sus asientos
+			if err := d.repo.ReleaseReservationSeats(ctx, reservation); err != nil {
+				return fmt.Errorf("la reserva %s se reubicó en %s pero no se liberaron sus asientos: %w", reservation.ID, rebooked.ID, err)
+			}
+			reservation.Status = ReservationRebooked
+			reservation.ReplacedBy = rebooked.ID
+			log.Printf("Notificación al usuario %s: la salida %s-%s del %s está sobrevendida, su reserva %s fue reubicada en la reserva %s",
+				reservation.UserID, route.OriginCode, route.DestCode, route.Departure.Format("2006-01-02 15:04"), reservation.ID, rebooked.ID)
+			return nil
+		}
+		err = rebookErr
+	}
+	if !errors.Is(err, ErrRouteNotFound) && !errors.Is(err, ErrNotEnoughSeats) {
+		return err
+	}
+
+	denied, err := d.repo.DenyBoarding(ctx, reservation.ID)
+	if err != nil {
+		return err
+	}
+	reservation.Status = denied.Status
+	log.Printf("Notificación al usuario %s: la salida %s-%s del %s está sobrevendida y no hay otra salida disponible, se denegó el embarque de su reserva %s",
+		reservation.UserID, route.OriginCode, route.DestCode, route.Departure.Format("2006-01-02 15:04"), reservation.ID)
+	return nil
+}
+
+// boardingCandidates ordena las reservas confirmadas según a quién se deniega el embarque primero:
+// los voluntarios, luego quienes no eligieron asiento y, entre ellos, según la prioridad. Los grupos van al final
+// para no separar ni reubicar a todo un grupo por unos pocos asientos. Las reservas de recorded, con pasajeros
+// que ya hicieron el check-in o embarcaron, no son candidatas.
+func boardingCandidates(reservations []*Reservation, recorded map[string]bool, priority string) []*Reservation {
+	candidates := make([]*Reservation, 0, len(reservations))
+	for _, reservation := range reservations {
+		if reservation.Status == ReservationConfirmed && !recorded[reservation.ID] {
+			candidates = append(candidates, reservation)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.BoardingVolunteer != b.BoardingVolunteer {
+			return a.BoardingVolunteer
+		}
+		if (a.GroupID == "") != (b.GroupID == "") {
+			return a.GroupID == ""
+		}
+		if (len(a.SeatNumbers) == 0) != (len(b.SeatNumbers) == 0) {
+			return len(a.SeatNumbers) == 0
+		}
+		if priority == operator.PriorityLowestFare {
+			fareA, fareB := a.TotalPrice/float64(a.Seats), b.TotalPrice/float64(b.Seats)
+			if fareA != fareB {
+				return fareA < fareB
+			}
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+
+	return candidates
+}
+
+// anyOversold indica si algún tramo tiene más pasajeros que asientos
+func anyOversold(legs []int) bool {
+	for _, oversold := range legs {
+		if oversold > 0 {
+			return true
+		}
+	}
+	return false
+}
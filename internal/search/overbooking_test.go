@@ -0,0 +1,168 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+// testBoardingRecords simula el registro de check-in y embarque con las reservas que ya tienen registros
+type testBoardingRecords map[string]bool
+
+func (r testBoardingRecords) HasReservationRecords(ctx context.Context, reservationID string) (bool, error) {
+	return r[reservationID], nil
+}
+
+func (r testBoardingRecords) RecordedReservations(ctx context.Context, routeID string) (map[string]bool, error) {
+	return r, nil
+}
+
+// testDeniedBoardingHook registra las reservas retiradas que recibe
+type testDeniedBoardingHook struct {
+	denied []string
+}
+
+func (h *testDeniedBoardingHook) OnBoardingDenied(ctx context.Context, route *Route, reservations []*Reservation) error {
+	h.denied = append(h.denied, reservationIDs(reservations)...)
+	return nil
+}
+
+func reservationIDs(reservations []*Reservation) []string {
+	ids := make([]string, len(reservations))
+	for i, reservation := range reservations {
+		ids[i] = reservation.ID
+	}
+	return ids
+}
+
+func TestBoardingCandidates(t *testing.T) {
+	now := time.Now()
+	reservations := []*Reservation{
+		{ID: "antigua", Seats: 1, TotalPrice: 50, Status: ReservationConfirmed, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "reciente", Seats: 1, TotalPrice: 80, Status: ReservationConfirmed, CreatedAt: now.Add(-time.Hour)},
+		{ID: "con-asiento", Seats: 1, SeatNumbers: []string{"1A"}, TotalPrice: 30, Status: ReservationConfirmed, CreatedAt: now},
+		{ID: "grupo", Seats: 2, GroupID: "grupo-1", TotalPrice: 60, Status: ReservationConfirmed, CreatedAt: now},
+		{ID: "voluntaria", Seats: 1, SeatNumbers: []string{"2A"}, TotalPrice: 90, Status: ReservationConfirmed, BoardingVolunteer: true, CreatedAt: now.Add(-4 * time.Hour)},
+		{ID: "con-check-in", Seats: 1, TotalPrice: 10, Status: ReservationConfirmed, CreatedAt: now},
+		{ID: "pendiente", Seats: 1, Status: ReservationPending, CreatedAt: now},
+	}
+	recorded := map[string]bool{"con-check-in": true}
+
+	tests := []struct {
+		priority string
+		want     []string
+	}{
+		{priority: operator.PriorityLastBooked, want: []string{"voluntaria", "reciente", "antigua", "con-asiento", "grupo"}},
+		{priority: operator.PriorityLowestFare, want: []string{"voluntaria", "antigua", "reciente", "con-asiento", "grupo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.priority, func(t *testing.T) {
+			got := reservationIDs(boardingCandidates(reservations, recorded, tt.priority))
+			if len(got) != len(tt.want) {
+				t.Fatalf("boardingCandidates() = %v, se esperaba %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("boardingCandidates() = %v, se esperaba %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// oversoldRoute arma una salida de cuatro asientos con dos vendidos por encima de su capacidad y la siguiente
+// salida del mismo tramo con un asiento libre
+func oversoldRoute(t *testing.T) (*memoryRepository, *BoardingDenier) {
+	t.Helper()
+	route := soldOutRoute()
+	route.Seats, route.OccupiedSeats = 4, nil
+	next := soldOutRoute()
+	next.ID, next.Departure, next.Seats, next.OccupiedSeats = "ruta-2", route.Departure.Add(2*time.Hour), 1, nil
+
+	repo := newMemoryRepository(route, next)
+	repo.next = next
+	now := time.Now()
+	for i, id := range []string{"r1", "r2", "r3", "r4", "r5", "r6"} {
+		repo.addReservation(&Reservation{ID: id, RouteID: route.ID, UserID: "u-" + id, Seats: 1, Status: ReservationConfirmed, CreatedAt: now.Add(time.Duration(i) * time.Minute)})
+	}
+	return repo, NewBoardingDenier(repo, nil, testBoardingRecords{"r6": true})
+}
+
+func TestResolveDeniesNewestReservations(t *testing.T) {
+	repo, denier := oversoldRoute(t)
+	hook := &testDeniedBoardingHook{}
+	denier.RegisterHook(hook)
+
+	result, err := denier.Resolve(context.Background(), "ruta-1", operator.PriorityLastBooked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// r6 ya hizo el check-in, así que se retira a las dos reservas más recientes después de ella
+	if got := reservationIDs(result.Denied); len(got) != 2 || got[0] != "r5" || got[1] != "r4" {
+		t.Fatalf("retiradas = %v, se esperaba [r5 r4]", got)
+	}
+	if result.Oversold != 2 || result.Route.Seats != 0 {
+		t.Errorf("sobrevendidos = %d y asientos libres = %d, se esperaba 2 y 0", result.Oversold, result.Route.Seats)
+	}
+	if repo.reservations["r5"].Status != ReservationRebooked || repo.reservations["r5"].ReplacedBy == "" {
+		t.Errorf("r5 = %s, se esperaba reubicada en la salida siguiente", repo.reservations["r5"].Status)
+	}
+	// La salida siguiente solo tenía un asiento libre
+	if repo.reservations["r4"].Status != ReservationDeniedBoarding {
+		t.Errorf("r4 = %s, se esperaba embarque denegado", repo.reservations["r4"].Status)
+	}
+	if repo.routes["ruta-2"].Seats != 0 {
+		t.Errorf("asientos libres en la salida siguiente = %d, se esperaba 0", repo.routes["ruta-2"].Seats)
+	}
+	if len(hook.denied) != 2 {
+		t.Errorf("el hook recibió %v, se esperaban las dos reservas retiradas", hook.denied)
+	}
+}
+
+func TestResolveRebookWithoutReleaseIsNotCounted(t *testing.T) {
+	repo, denier := oversoldRoute(t)
+	repo.releaseErr = errors.New("sin conexión")
+
+	result, err := denier.Resolve(context.Background(), "ruta-1", operator.PriorityLastBooked)
+	if !errors.Is(err, repo.releaseErr) {
+		t.Fatalf("Resolve(): error = %v, se esperaba el error al liberar los asientos", err)
+	}
+	// La reubicación de r5 no liberó asientos, así que se siguió con r4 y r3, que quedan con embarque denegado
+	for _, id := range reservationIDs(result.Denied) {
+		if id == "r5" {
+			t.Errorf("retiradas = %v, una reubicación sin liberar asientos no debe contar", reservationIDs(result.Denied))
+		}
+	}
+	if result.Route.Seats != 0 {
+		t.Errorf("asientos libres = %d, se esperaba 0", result.Route.Seats)
+	}
+}
+
+func TestResolveRejectsRoutes(t *testing.T) {
+	departed := soldOutRoute()
+	departed.ID, departed.Seats, departed.Departure = "ruta-partida", -1, time.Now().Add(-time.Hour)
+	cancelled := soldOutRoute()
+	cancelled.ID, cancelled.Seats, cancelled.Status = "ruta-cancelada", -1, RouteCancelled
+	full := soldOutRoute()
+	denier := NewBoardingDenier(newMemoryRepository(departed, cancelled, full), nil, testBoardingRecords{})
+
+	tests := []struct {
+		routeID string
+		wantErr error
+	}{
+		{routeID: "ruta-partida", wantErr: ErrInvalidRoute},
+		{routeID: "ruta-cancelada", wantErr: ErrRouteCancelled},
+		{routeID: "ruta-1", wantErr: ErrNotOversold},
+		{routeID: "ruta-desconocida", wantErr: ErrRouteNotFound},
+	}
+
+	for _, tt := range tests {
+		if _, err := denier.Resolve(context.Background(), tt.routeID, operator.PriorityLastBooked); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Resolve(%s): error = %v, se esperaba %v", tt.routeID, err, tt.wantErr)
+		}
+	}
+}
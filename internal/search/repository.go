@@ -2,12 +2,102 @@ package search
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"venta-de-pasajes/internal/fleet"
+)
+
+var (
+	// ErrRouteNotFound indica que la ruta no existe
+	ErrRouteNotFound = errors.New("ruta no encontrada")
+	// ErrNotEnoughSeats indica que la ruta no existe, está cancelada o no tiene asientos suficientes
+	ErrNotEnoughSeats = errors.New("ruta no encontrada o no hay suficientes asientos disponibles")
+	// ErrRouteCancelled indica que la ruta ya fue cancelada
+	ErrRouteCancelled = errors.New("la ruta está cancelada")
+	// ErrCapacityBelowReserved indica que la nueva capacidad es menor a los asientos ya reservados
+	ErrCapacityBelowReserved = errors.New("la capacidad no puede ser menor a los asientos reservados")
+	// ErrReservationNotFound indica que la reserva no existe
+	ErrReservationNotFound = errors.New("reserva no encontrada")
+	// ErrReservationNotConfirmed indica que la reserva no está confirmada
+	ErrReservationNotConfirmed = errors.New("la reserva no está confirmada")
+	// ErrReservationNotActive indica que la reserva ya fue cancelada o reubicada
+	ErrReservationNotActive = errors.New("la reserva ya fue cancelada o reubicada")
+	// ErrReservationNotPending indica que la reserva no está pendiente de confirmación
+	ErrReservationNotPending = errors.New("la reserva no está pendiente de confirmación")
+	// ErrHoldExpired indica que venció el plazo para confirmar la reserva pendiente
+	ErrHoldExpired = errors.New("venció el plazo para confirmar la reserva")
+	// ErrGroupReservation indica que la reserva pertenece a un grupo y se gestiona desde la reserva de grupo
+	ErrGroupReservation = errors.New("la reserva pertenece a un grupo, gestiónela desde la reserva de grupo")
+	// ErrInvalidPassengers indica que los pasajeros no corresponden a los asientos vigentes de la reserva
+	ErrInvalidPassengers = errors.New("los pasajeros no corresponden a los asientos de la reserva")
+	// ErrWaitlistEntryNotFound indica que la inscripción en la lista de espera no existe
+	ErrWaitlistEntryNotFound = errors.New("inscripción en lista de espera no encontrada")
+	// ErrWaitlistEntryClosed indica que la inscripción ya no está en el estado esperado
+	ErrWaitlistEntryClosed = errors.New("la inscripción en lista de espera ya fue atendida, venció o se canceló")
 )
 
 // SearchRepository define la interfaz para el acceso a datos del módulo de búsqueda
 type SearchRepository interface {
-	FindRoutes(ctx context.Context, origin, destination string) ([]*Route, error)
-	ReserveRoute(ctx context.Context, routeID, userID string, seats int) (*Reservation, error)
+	FindRoutes(ctx context.Context, originCode, destCode, operatorID string) ([]*Route, error)
+	ReserveRoute(ctx context.Context, request ReservationRequest) (*Reservation, error)
 	GetReservationByID(ctx context.Context, reservationID string) (*Reservation, error)
 	MigrateDB() error
+
+	// Administración de rutas
+	CreateRoute(ctx context.Context, route *Route) error
+	GetRouteByID(ctx context.Context, routeID string) (*Route, error)
+	UpdateRoute(ctx context.Context, routeID string, update RouteUpdate) (*Route, error)
+	RescheduleRoute(ctx context.Context, routeID string, departure, arrival time.Time, stops []Stop) (*Route, error)
+	CancelRoute(ctx context.Context, routeID string) (*Route, error)
+	AssignVehicle(ctx context.Context, routeID string, vehicle *fleet.Vehicle) (*Route, error)
+
+	// Reservas afectadas por cambios de rutas
+	FindReservationsByRoute(ctx context.Context, routeID string) ([]*Reservation, error)
+	FindNextDeparture(ctx context.Context, route *Route, from, to string, seats int) (*Route, error)
+	RebookReservation(ctx context.Context, reservation *Reservation, routeID, from, to string) (*Reservation, error)
+	UpdateReservationStatus(ctx context.Context, reservationID, from, to string) error
+
+	// Cancelación de reservas y reservas pendientes con vencimiento
+	CancelReservation(ctx context.Context, reservationID, userID string) (*Reservation, error)
+	HoldSeats(ctx context.Context, request ReservationRequest, expiresAt time.Time, waitlistEntryID string) (*Reservation, error)
+	ConfirmHold(ctx context.Context, reservationID, userID string, now time.Time) (*Reservation, error)
+	FindExpiredHolds(ctx context.Context, now time.Time) ([]*Reservation, error)
+	ExpireHold(ctx context.Context, reservationID string, now time.Time) (*Reservation, error)
+	ReleaseReservationSeats(ctx context.Context, reservation *Reservation) error
+
+	// Cambio de reservas
+	ChangeReservation(ctx context.Context, original *Reservation, request ReservationRequest, changeFee float64) (*Reservation, error)
+
+	// Pasajeros nombrados y cancelación parcial
+	SetReservationPassengers(ctx context.Context, reservationID string, passengers []Passenger) (*Reservation, error)
+	CancelReservationPassengers(ctx context.Context, reservationID string, passengerIDs []string, unnamed int, fare float64) (*Reservation, error)
+
+	// Sobreventa y embarque denegado
+	SetBoardingVolunteer(ctx context.Context, reservationID, userID string, volunteer bool) (*Reservation, error)
+	DenyBoarding(ctx context.Context, reservationID string) (*Reservation, error)
+
+	// Lista de espera
+	AddWaitlistEntry(ctx context.Context, entry *WaitlistEntry) error
+	GetWaitlistEntry(ctx context.Context, entryID string) (*WaitlistEntry, error)
+	GetWaitlist(ctx context.Context, routeID, status string) ([]*WaitlistEntry, error)
+	UpdateWaitlistEntry(ctx context.Context, entryID, from string, update WaitlistUpdate) error
+	CancelRouteWaitlist(ctx context.Context, routeID string) (int64, error)
+
+	// Horarios recurrentes y generación de salidas
+	CreateScheduleTemplate(ctx context.Context, template *ScheduleTemplate) error
+	GetScheduleTemplates(ctx context.Context) ([]*ScheduleTemplate, error)
+	GetScheduleTemplateByID(ctx context.Context, templateID string) (*ScheduleTemplate, error)
+	SaveScheduleException(ctx context.Context, templateID string, exception ScheduleException) (*ScheduleTemplate, error)
+	SetScheduleTemplateActive(ctx context.Context, templateID string, active bool) (*ScheduleTemplate, error)
+	SaveHoliday(ctx context.Context, holiday *Holiday) error
+	GetHolidays(ctx context.Context) ([]*Holiday, error)
+	MaterializeRoute(ctx context.Context, route *Route) (created, updated bool, err error)
+	CancelUnsoldRoute(ctx context.Context, routeID string) error
+
+	// Popularidad de las ciudades según las salidas y los asientos vendidos
+	GetCityPopularity(ctx context.Context) (map[string]float64, error)
+
+	// Reportes por operador
+	GetOperatorReport(ctx context.Context, operatorID string, from, to time.Time) (*OperatorReport, error)
 }
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// DenyBoarding deniega el embarque de una reserva confirmada de una salida sobrevendida y libera sus asientos
+func (r *MongoDBRepository) DenyBoarding(ctx context.Context, reservationID string) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	reservation, err := r.closeReservation(ctx, bson.M{"_id": reservationID, "status": search.ReservationConfirmed}, search.ReservationDeniedBoarding)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, search.ErrReservationNotConfirmed
+	}
+	return reservation, err
+}
+
+// SetBoardingVolunteer registra si el usuario acepta ceder su asiento en caso de sobreventa
+func (r *MongoDBRepository) SetBoardingVolunteer(ctx context.Context, reservationID, userID string, volunteer bool) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	var reservation search.Reservation
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": reservationID, "user_id": userID, "status": search.ReservationConfirmed},
+		bson.M{"$set": bson.M{"boarding_volunteer": volunteer}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, err := r.GetReservationByID(ctx, reservationID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || current.UserID != userID {
+			return nil, search.ErrReservationNotFound
+		}
+		return nil, search.ErrReservationNotConfirmed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
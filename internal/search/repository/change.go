@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// ChangeReservation reemplaza una reserva confirmada por otra en la salida, tramo y cantidad de asientos pedidos.
+// Primero retira la reserva original, enlazada ya con el ID de la nueva, para que nadie más la cambie o cancele.
+// Dentro de la misma salida libera los asientos originales y ocupa los nuevos con una sola actualización
+// condicional, así los asientos propios cuentan como disponibles. En otra salida reserva los nuevos asientos y
+// luego libera los originales. Si algún paso falla, el cambio se deshace y la original vuelve a quedar
+// confirmada con sus asientos.
+func (r *MongoDBRepository) ChangeReservation(ctx context.Context, original *search.Reservation, request search.ReservationRequest, changeFee float64) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	changed := &search.Reservation{
+		ID:                    uuid.New().String(),
+		Status:                search.ReservationConfirmed,
+		PreviousReservationID: original.ID,
+		Change: &search.ReservationChange{
+			PreviousTotal: original.TotalPrice,
+			ChangeFee:     changeFee,
+			ChangedAt:     time.Now(),
+		},
+	}
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": original.ID, "user_id": original.UserID, "status": search.ReservationConfirmed},
+		bson.M{"$set": bson.M{"status": search.ReservationChanged, "replaced_by": changed.ID}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, search.ErrReservationNotConfirmed
+	}
+
+	if request.RouteID == original.RouteID {
+		changed, err = r.swapReservation(ctx, original, request, changed)
+	} else {
+		changed, err = r.moveReservation(ctx, original, request, changed)
+	}
+	if err != nil {
+		r.revertChange(ctx, original)
+		return nil, err
+	}
+	return changed, nil
+}
+
+// swapReservation cambia la reserva dentro de la misma salida, liberando los asientos originales y ocupando los
+// nuevos de forma atómica antes de registrar la nueva reserva
+func (r *MongoDBRepository) swapReservation(ctx context.Context, original *search.Reservation, request search.ReservationRequest, changed *search.Reservation) (*search.Reservation, error) {
+	route, err := r.GetRouteByID(ctx, request.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := route.SegmentBetween(original.FromStop, original.ToStop)
+	if err != nil {
+		return nil, err
+	}
+	segment, err := route.SegmentBetween(request.From, request.To)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+	released := seatHold{segment: previous, seats: original.Seats, seatNumbers: original.SeatNumbers}
+	reserved := seatHold{segment: segment, seats: request.Seats, seatNumbers: request.SeatNumbers}
+	result, err := routes.UpdateOne(ctx, swapFilter(route, released, reserved, request.Overbooking), swapUpdate(route, released, reserved))
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, search.ErrNotEnoughSeats
+	}
+
+	completeReservation(route, segment, request, changed)
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+	if _, err := collection.InsertOne(ctx, changed); err != nil {
+		// Devolver sus asientos a la reserva original, sin verificar disponibilidad porque nunca dejaron de ser suyos
+		if _, restoreErr := routes.UpdateOne(ctx, bson.M{"_id": route.ID}, swapUpdate(route, reserved, released)); restoreErr != nil {
+			log.Printf("Error al devolver los asientos de la reserva %s tras fallar su cambio: %v", original.ID, restoreErr)
+		}
+		return nil, err
+	}
+	return changed, nil
+}
+
+// moveReservation cambia la reserva a otra salida: reserva los nuevos asientos y libera los originales. Si los
+// originales no se pueden liberar, retira la nueva reserva con sus asientos y devuelve el error.
+func (r *MongoDBRepository) moveReservation(ctx context.Context, original *search.Reservation, request search.ReservationRequest, changed *search.Reservation) (*search.Reservation, error) {
+	changed, err := r.createReservation(ctx, request, changed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.ReleaseReservationSeats(ctx, original); err != nil {
+		collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+		if _, deleteErr := collection.DeleteOne(ctx, bson.M{"_id": changed.ID}); deleteErr != nil {
+			log.Printf("Error al retirar la reserva %s del cambio de %s: %v", changed.ID, original.ID, deleteErr)
+			return nil, err
+		}
+		if releaseErr := r.ReleaseReservationSeats(ctx, changed); releaseErr != nil {
+			log.Printf("Error al liberar los asientos de la reserva %s del cambio de %s: %v", changed.ID, original.ID, releaseErr)
+		}
+		return nil, err
+	}
+	return changed, nil
+}
+
+// revertChange vuelve a confirmar una reserva retirada cuyo cambio falló; sus asientos siguen ocupados
+func (r *MongoDBRepository) revertChange(ctx context.Context, original *search.Reservation) {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": original.ID, "status": search.ReservationChanged},
+		bson.M{"$set": bson.M{"status": search.ReservationConfirmed}, "$unset": bson.M{"replaced_by": ""}},
+	)
+	if err != nil {
+		log.Printf("Error al restaurar la reserva %s: %v", original.ID, err)
+	}
+}
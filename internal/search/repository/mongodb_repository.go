@@ -5,11 +5,14 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"reflect"
 	"time"
 
 	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/fleet"
+	"venta-de-pasajes/internal/operator"
 	"venta-de-pasajes/internal/search"
 
 	"github.com/google/uuid"
@@ -48,7 +51,7 @@ func NewMongoDBRepository(cfg *config.Config) (*MongoDBRepository, error) {
 }
 
 // Implementación de los métodos de la interfaz SearchRepository
-func (r *MongoDBRepository) FindRoutes(ctx context.Context, origin, destination string) ([]*search.Route, error) {
+func (r *MongoDBRepository) FindRoutes(ctx context.Context, originCode, destCode, operatorID string) ([]*search.Route, error) {
 	// Contexto con timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -56,8 +59,11 @@ func (r *MongoDBRepository) FindRoutes(ctx context.Context, origin, destination
 	// Colección de rutas
 	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
 
-	// Filtro para la búsqueda de rutas
-	filter := bson.M{"origin": origin, "destination": destination, "seats": bson.M{"$gt": 0}}
+	// Filtro para la búsqueda de rutas: directas o con ambas ciudades entre sus paradas
+	filter := bson.M{"status": bson.M{"$ne": search.RouteCancelled}, "$or": stopsFilter(originCode, destCode)}
+	if operatorID != "" {
+		filter["operator_id"] = operatorID
+	}
 
 	log.Printf("filter: %s\n", filter)
 
@@ -77,6 +83,16 @@ func (r *MongoDBRepository) FindRoutes(ctx context.Context, origin, destination
 		if err := cursor.Decode(&route); err != nil {
 			return nil, err
 		}
+
+		// Descartar las rutas que pasan por las ciudades en sentido contrario. Las salidas sin asientos
+		// en el tramo se descartan en el manejador, que conoce la sobreventa permitida.
+		segment, err := route.SegmentBetween(originCode, destCode)
+		if err != nil {
+			continue
+		}
+		if !segment.IsFullRoute(&route) {
+			route.Segment = segment
+		}
 		routes = append(routes, &route)
 	}
 
@@ -88,56 +104,70 @@ func (r *MongoDBRepository) FindRoutes(ctx context.Context, origin, destination
 	return routes, nil
 }
 
-func (r *MongoDBRepository) ReserveRoute(ctx context.Context, routeID, userID string, seats int) (*search.Reservation, error) {
+func (r *MongoDBRepository) ReserveRoute(ctx context.Context, request search.ReservationRequest) (*search.Reservation, error) {
 	// Contexto con timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Colección de rutas
-	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+	return r.createReservation(ctx, request, &search.Reservation{Status: search.ReservationConfirmed})
+}
 
-	// Obtener la ruta
-	var route search.Route
-	err := collection.FindOne(ctx, bson.M{"_id": routeID, "seats": bson.M{"$gte": seats}}).Decode(&route)
+// createReservation descuenta los asientos del tramo pedido y registra la reserva. reservation trae el estado
+// y los datos propios de cada origen de la reserva; el resto se completa a partir de la solicitud y la ruta.
+func (r *MongoDBRepository) createReservation(ctx context.Context, request search.ReservationRequest, reservation *search.Reservation) (*search.Reservation, error) {
+	// Ubicar el tramo entre las paradas de subida y bajada
+	route, err := r.GetRouteByID(ctx, request.RouteID)
 	if err != nil {
-		return nil, errors.New("ruta no encontrada o no hay suficientes asientos disponibles")
+		return nil, err
 	}
-
-	// Actualizar la cantidad de asientos disponibles
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": routeID},
-		bson.M{"$inc": bson.M{"seats": -seats}},
-	)
+	segment, err := route.SegmentBetween(request.From, request.To)
 	if err != nil {
 		return nil, err
 	}
 
+	// Descontar los asientos del tramo de forma atómica
+	if _, err := r.reserveSeats(ctx, route, segment, request.Seats, request.SeatNumbers, request.Overbooking); err != nil {
+		return nil, err
+	}
+
 	// Colección de reservas
 	reservationsCollection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
 
-	// Generar un ID único UUID v4 para la reserva
-	reservationID := uuid.New().String()
-
-	// Crear la reserva
-	reservation := &search.Reservation{
-		ID:         reservationID,
-		RouteID:    routeID,
-		UserID:     userID,
-		Seats:      seats,
-		TotalPrice: float64(seats) * route.Price,
-		Status:     "confirmado", // Se puede cambiar según la lógica de negocio
-	}
-
 	// Insertar la reserva en la colección de reservas
+	completeReservation(route, segment, request, reservation)
 	_, err = reservationsCollection.InsertOne(ctx, reservation)
 	if err != nil {
+		r.releaseSeats(ctx, route, segment, request.Seats, request.SeatNumbers)
 		return nil, err
 	}
 
 	return reservation, nil
 }
 
+// completeReservation completa la reserva con los datos de la solicitud y el tramo reservado
+func completeReservation(route *search.Route, segment *search.Segment, request search.ReservationRequest, reservation *search.Reservation) {
+	// Completar la reserva con un ID único UUID v4, salvo que el origen ya lo haya fijado
+	if reservation.ID == "" {
+		reservation.ID = uuid.New().String()
+	}
+	reservation.RouteID = route.ID
+	reservation.OperatorID = route.OperatorID
+	reservation.UserID = request.UserID
+	reservation.Seats = request.Seats
+	reservation.SeatNumbers = request.SeatNumbers
+	reservation.FromStop, reservation.ToStop = segment.From, segment.To
+	reservation.TotalPrice = float64(request.Seats) * segment.Price
+	if request.Fare > 0 {
+		reservation.TotalPrice = float64(request.Seats) * request.Fare
+	}
+	reservation.GroupID = request.GroupID
+	reservation.AgencyID = request.AgencyID
+	reservation.CreatedAt = time.Now()
+	if reservation.Change != nil {
+		reservation.Change.Settle(reservation.TotalPrice)
+	}
+}
+
 func (r *MongoDBRepository) GetReservationByID(ctx context.Context, reservationID string) (*search.Reservation, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -157,6 +187,807 @@ func (r *MongoDBRepository) GetReservationByID(ctx context.Context, reservationI
 	return &reservation, nil
 }
 
+// reserveSeats descuenta asientos de un tramo de una ruta programada solo si hay suficientes disponibles en todos
+// sus tramos entre paradas, y devuelve la ruta actualizada. Si se eligen asientos numerados, además los ocupa
+// solo si ninguno estaba ocupado en el tramo. overbooking son los asientos que se pueden vender por encima de la capacidad.
+func (r *MongoDBRepository) reserveSeats(ctx context.Context, route *search.Route, segment *search.Segment, seats int, seatNumbers []string, overbooking int) (*search.Route, error) {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	// Con sobreventa los asientos disponibles pueden quedar negativos hasta el límite permitido
+	required := seats - overbooking
+
+	filter := bson.M{"_id": route.ID, "status": bson.M{"$ne": search.RouteCancelled}}
+	var update interface{}
+	if len(route.SegmentSeats) == 0 {
+		// Ruta sin paradas
+		filter["seats"] = bson.M{"$gte": required}
+		set := bson.M{"$inc": bson.M{"seats": -seats}}
+		if len(seatNumbers) > 0 {
+			filter["occupied_seats"] = bson.M{"$nin": seatNumbers}
+			set["$push"] = bson.M{"occupied_seats": bson.M{"$each": seatNumbers}}
+		}
+		update = set
+	} else {
+		for leg := segment.FromIndex; leg < segment.ToIndex; leg++ {
+			filter[fmt.Sprintf("segment_seats.%d", leg)] = bson.M{"$gte": required}
+			if len(seatNumbers) > 0 {
+				filter[fmt.Sprintf("segment_occupied.%d", leg)] = bson.M{"$nin": seatNumbers}
+			}
+		}
+		update = segmentUpdate(segment, -seats, seatNumbers)
+	}
+
+	var updated search.Route
+	err := collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, search.ErrNotEnoughSeats
+		}
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// releaseSeats devuelve asientos a un tramo de una ruta, liberando también los asientos numerados.
+// El error se registra en el log para las compensaciones que no pueden devolverlo y se devuelve a los demás.
+func (r *MongoDBRepository) releaseSeats(ctx context.Context, route *search.Route, segment *search.Segment, seats int, seatNumbers []string) error {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	var update interface{}
+	if len(route.SegmentSeats) == 0 {
+		set := bson.M{"$inc": bson.M{"seats": seats}}
+		if len(seatNumbers) > 0 {
+			set["$pullAll"] = bson.M{"occupied_seats": seatNumbers}
+		}
+		update = set
+	} else {
+		update = segmentUpdate(segment, seats, seatNumbers)
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": route.ID}, update)
+	if err != nil {
+		log.Printf("Error al liberar %d asientos de la ruta %s: %v", seats, route.ID, err)
+	}
+	return err
+}
+
+// CreateRoute registra una nueva ruta programada con todos sus asientos disponibles
+func (r *MongoDBRepository) CreateRoute(ctx context.Context, route *search.Route) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	route.ID = uuid.New().String()
+	route.InitSegments()
+	route.Status = search.RouteScheduled
+	route.UpdatedAt = time.Now()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+	_, err := collection.InsertOne(ctx, route)
+	return err
+}
+
+// GetRouteByID obtiene una ruta por su ID
+func (r *MongoDBRepository) GetRouteByID(ctx context.Context, routeID string) (*search.Route, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	var route search.Route
+	err := collection.FindOne(ctx, bson.M{"_id": routeID}).Decode(&route)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, search.ErrRouteNotFound
+		}
+		return nil, err
+	}
+
+	return &route, nil
+}
+
+// UpdateRoute actualiza el precio y la capacidad de una ruta programada.
+// Los asientos disponibles se recalculan conservando los ya reservados.
+func (r *MongoDBRepository) UpdateRoute(ctx context.Context, routeID string, update search.RouteUpdate) (*search.Route, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	// Las rutas anteriores a la capacidad tienen como capacidad sus asientos disponibles
+	capacity := bson.M{"$ifNull": bson.A{"$capacity", "$seats"}}
+	reserved := bson.M{"$subtract": bson.A{capacity, "$seats"}}
+
+	filter := bson.M{"_id": routeID, "status": bson.M{"$ne": search.RouteCancelled}}
+	set := bson.M{"updated_at": time.Now()}
+	if update.Price != nil {
+		set["price"] = *update.Price
+	}
+	if update.Capacity != nil {
+		// La capacidad de una ruta con vehículo la define su croquis
+		filter["vehicle_id"] = bson.M{"$in": bson.A{nil, ""}}
+		filter["$expr"] = bson.M{"$gte": bson.A{*update.Capacity, reserved}}
+		set["seats"] = bson.M{"$subtract": bson.A{*update.Capacity, reserved}}
+		set["segment_seats"] = shiftSegmentSeats(*update.Capacity, capacity)
+		set["capacity"] = *update.Capacity
+	}
+	if update.Overbooking != nil {
+		if *update.Overbooking == (operator.OverbookingPolicy{}) {
+			set["overbooking"] = "$$REMOVE"
+		} else {
+			set["overbooking"] = bson.M{"$literal": update.Overbooking}
+		}
+	}
+
+	var route search.Route
+	err := collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		mongo.Pipeline{{{Key: "$set", Value: set}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&route)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.routeUpdateError(ctx, routeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &route, nil
+}
+
+// RescheduleRoute cambia la salida y la llegada de una ruta programada
+func (r *MongoDBRepository) RescheduleRoute(ctx context.Context, routeID string, departure, arrival time.Time, stops []search.Stop) (*search.Route, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	var route search.Route
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": routeID, "status": bson.M{"$ne": search.RouteCancelled}},
+		bson.M{"$set": bson.M{"departure": departure, "arrival": arrival, "stops": stops, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&route)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.routeUpdateError(ctx, routeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &route, nil
+}
+
+// CancelRoute cancela una ruta programada para que no pueda buscarse ni reservarse
+func (r *MongoDBRepository) CancelRoute(ctx context.Context, routeID string) (*search.Route, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	var route search.Route
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": routeID, "status": bson.M{"$ne": search.RouteCancelled}},
+		bson.M{"$set": bson.M{"status": search.RouteCancelled, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&route)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.routeUpdateError(ctx, routeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Ruta %s cancelada", routeID)
+	return &route, nil
+}
+
+// routeUpdateError determina por qué no se pudo actualizar una ruta
+func (r *MongoDBRepository) routeUpdateError(ctx context.Context, routeID string) error {
+	route, err := r.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return err
+	}
+	if route.Status == search.RouteCancelled {
+		return search.ErrRouteCancelled
+	}
+	if route.VehicleID != "" {
+		return search.ErrCapacityFromVehicle
+	}
+	return search.ErrCapacityBelowReserved
+}
+
+// AssignVehicle asigna un vehículo a una ruta programada. La capacidad, los asientos disponibles y las comodidades
+// se toman del vehículo, solo si los asientos vendidos y los asientos numerados ocupados caben en su croquis.
+func (r *MongoDBRepository) AssignVehicle(ctx context.Context, routeID string, vehicle *fleet.Vehicle) (*search.Route, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	capacity := bson.M{"$ifNull": bson.A{"$capacity", "$seats"}}
+	reserved := bson.M{"$subtract": bson.A{capacity, "$seats"}}
+	occupied := bson.M{"$setUnion": bson.A{
+		bson.M{"$ifNull": bson.A{"$occupied_seats", bson.A{}}},
+		bson.M{"$reduce": bson.M{
+			"input":        bson.M{"$ifNull": bson.A{"$segment_occupied", bson.A{}}},
+			"initialValue": bson.A{},
+			"in":           bson.M{"$concatArrays": bson.A{"$$value", "$$this"}},
+		}},
+	}}
+
+	filter := bson.M{
+		"_id":    routeID,
+		"status": bson.M{"$ne": search.RouteCancelled},
+		"$expr": bson.M{"$and": bson.A{
+			bson.M{"$gte": bson.A{vehicle.Capacity, reserved}},
+			bson.M{"$setIsSubset": bson.A{occupied, bson.M{"$literal": vehicle.Layout.SeatNumbers()}}},
+		}},
+	}
+	set := bson.M{
+		"vehicle_id":    vehicle.ID,
+		"capacity":      vehicle.Capacity,
+		"seats":         bson.M{"$subtract": bson.A{vehicle.Capacity, reserved}},
+		"segment_seats": shiftSegmentSeats(vehicle.Capacity, capacity),
+		"amenities":     bson.M{"$literal": vehicle.Amenities},
+		"updated_at":    time.Now(),
+	}
+
+	var route search.Route
+	err := collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		mongo.Pipeline{{{Key: "$set", Value: set}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&route)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, err := r.GetRouteByID(ctx, routeID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status == search.RouteCancelled {
+			return nil, search.ErrRouteCancelled
+		}
+		return nil, search.ErrVehicleDoesNotFit
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Vehículo %s asignado a la ruta %s", vehicle.ID, routeID)
+	return &route, nil
+}
+
+// FindReservationsByRoute obtiene las reservas de una ruta
+func (r *MongoDBRepository) FindReservationsByRoute(ctx context.Context, routeID string) ([]*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{"route_id": routeID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reservations := []*search.Reservation{}
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// FindNextDeparture obtiene la siguiente salida programada entre las mismas ciudades con asientos suficientes
+func (r *MongoDBRepository) FindNextDeparture(ctx context.Context, route *search.Route, from, to string, seats int) (*search.Route, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	filter := bson.M{
+		"_id":       bson.M{"$ne": route.ID},
+		"$or":       stopsFilter(from, to),
+		"departure": bson.M{"$gte": route.Departure},
+		"status":    bson.M{"$ne": search.RouteCancelled},
+	}
+	// Reubicar con el mismo operador de la ruta cancelada
+	if route.OperatorID != "" {
+		filter["operator_id"] = route.OperatorID
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "departure", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	// La primera salida que recorre el tramo con asientos suficientes
+	for cursor.Next(ctx) {
+		var next search.Route
+		if err := cursor.Decode(&next); err != nil {
+			return nil, err
+		}
+		segment, err := next.SegmentBetween(from, to)
+		if err == nil && segment.Seats >= seats {
+			return &next, nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, search.ErrRouteNotFound
+}
+
+// RebookReservation mueve una reserva confirmada al tramo from-to de otra ruta conservando su precio. El tramo
+// se pasa resuelto porque las reservas de punta a punta no guardan sus paradas, y el origen y el destino de la
+// otra ruta pueden ser otros. La reserva original queda como reubicada y enlazada a la nueva.
+func (r *MongoDBRepository) RebookReservation(ctx context.Context, reservation *search.Reservation, routeID, from, to string) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	route, err := r.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	segment, err := route.SegmentBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los asientos numerados no se conservan porque la otra salida puede tener otro croquis
+	if _, err := r.reserveSeats(ctx, route, segment, reservation.Seats, nil, 0); err != nil {
+		return nil, err
+	}
+
+	rebooked := &search.Reservation{
+		ID:                    uuid.New().String(),
+		RouteID:               routeID,
+		OperatorID:            reservation.OperatorID,
+		UserID:                reservation.UserID,
+		Seats:                 reservation.Seats,
+		FromStop:              segment.From,
+		ToStop:                segment.To,
+		TotalPrice:            reservation.TotalPrice,
+		Status:                search.ReservationConfirmed,
+		GroupID:               reservation.GroupID,
+		AgencyID:              reservation.AgencyID,
+		PreviousReservationID: reservation.ID,
+		CreatedAt:             time.Now(),
+	}
+	// Los pasajeros de un grupo conservan su pasaje, sin el asiento de la salida original
+	for _, passenger := range reservation.Passengers {
+		passenger.SeatNumber = ""
+		rebooked.Passengers = append(rebooked.Passengers, passenger)
+	}
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+	if _, err := collection.InsertOne(ctx, rebooked); err != nil {
+		r.releaseSeats(ctx, route, segment, reservation.Seats, nil)
+		return nil, err
+	}
+
+	// Marcar la reserva original solo si sigue confirmada
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": reservation.ID, "status": search.ReservationConfirmed},
+		bson.M{"$set": bson.M{"status": search.ReservationRebooked, "replaced_by": rebooked.ID}},
+	)
+	if err == nil && result.MatchedCount == 0 {
+		err = search.ErrReservationNotConfirmed
+	}
+	if err != nil {
+		// Deshacer la nueva reserva; si no se puede retirar, sus asientos siguen ocupados por ella
+		if _, deleteErr := collection.DeleteOne(ctx, bson.M{"_id": rebooked.ID}); deleteErr != nil {
+			log.Printf("Error al retirar la reserva %s de la reubicación de %s: %v", rebooked.ID, reservation.ID, deleteErr)
+			return nil, err
+		}
+		r.releaseSeats(ctx, route, segment, reservation.Seats, nil)
+		return nil, err
+	}
+
+	return rebooked, nil
+}
+
+// UpdateReservationStatus cambia el estado de una reserva solo si tiene el estado esperado
+func (r *MongoDBRepository) UpdateReservationStatus(ctx context.Context, reservationID, from, to string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": reservationID, "status": from}, bson.M{"$set": bson.M{"status": to}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return search.ErrReservationNotConfirmed
+	}
+
+	return nil
+}
+
+// CreateScheduleTemplate registra un nuevo horario recurrente activo
+func (r *MongoDBRepository) CreateScheduleTemplate(ctx context.Context, template *search.ScheduleTemplate) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	template.ID = uuid.New().String()
+	template.Active = true
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = template.CreatedAt
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ScheduleTemplatesCollection)
+	_, err := collection.InsertOne(ctx, template)
+	return err
+}
+
+// GetScheduleTemplates obtiene todos los horarios recurrentes
+func (r *MongoDBRepository) GetScheduleTemplates(ctx context.Context) ([]*search.ScheduleTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ScheduleTemplatesCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "originCode", Value: 1}, {Key: "destCode", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []*search.ScheduleTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// GetScheduleTemplateByID obtiene un horario recurrente por su ID
+func (r *MongoDBRepository) GetScheduleTemplateByID(ctx context.Context, templateID string) (*search.ScheduleTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ScheduleTemplatesCollection)
+
+	var template search.ScheduleTemplate
+	err := collection.FindOne(ctx, bson.M{"_id": templateID}).Decode(&template)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, search.ErrScheduleNotFound
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// SaveScheduleException agrega o reemplaza la excepción del horario para la fecha indicada
+func (r *MongoDBRepository) SaveScheduleException(ctx context.Context, templateID string, exception search.ScheduleException) (*search.ScheduleTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ScheduleTemplatesCollection)
+
+	// Reemplazar la excepción de la fecha en una sola actualización
+	exceptions := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$exceptions", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.date", exception.Date}},
+	}}
+	set := bson.M{
+		"exceptions": bson.M{"$concatArrays": bson.A{exceptions, bson.A{bson.M{"$literal": exception}}}},
+		"updated_at": time.Now(),
+	}
+
+	var template search.ScheduleTemplate
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": templateID},
+		mongo.Pipeline{{{Key: "$set", Value: set}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&template)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, search.ErrScheduleNotFound
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// SetScheduleTemplateActive activa o desactiva un horario recurrente.
+// Un horario inactivo deja de generar salidas y la generación cancela sus salidas sin ventas del horizonte.
+func (r *MongoDBRepository) SetScheduleTemplateActive(ctx context.Context, templateID string, active bool) (*search.ScheduleTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ScheduleTemplatesCollection)
+
+	var template search.ScheduleTemplate
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": templateID},
+		bson.M{"$set": bson.M{"active": active, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&template)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, search.ErrScheduleNotFound
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// SaveHoliday registra o actualiza un feriado
+func (r *MongoDBRepository) SaveHoliday(ctx context.Context, holiday *search.Holiday) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.HolidaysCollection)
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": holiday.Date}, holiday, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetHolidays obtiene los feriados ordenados por fecha
+func (r *MongoDBRepository) GetHolidays(ctx context.Context) ([]*search.Holiday, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.HolidaysCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	holidays := []*search.Holiday{}
+	if err := cursor.All(ctx, &holidays); err != nil {
+		return nil, err
+	}
+
+	return holidays, nil
+}
+
+// MaterializeRoute guarda la salida generada por un horario. Si aún no existe la crea; si ya existe, sigue
+// programada, no tiene asientos vendidos y aún no parte, le aplica la hora, las paradas y el precio generados,
+// para que los cambios del horario lleguen a las salidas sin ventas. Las salidas con ventas, canceladas o de
+// otro horario no se modifican. Devuelve si la salida se creó o se actualizó.
+func (r *MongoDBRepository) MaterializeRoute(ctx context.Context, route *search.Route) (created, updated bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	route.InitSegments()
+	route.UpdatedAt = time.Now()
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": route.ID},
+		bson.M{"$setOnInsert": route},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, false, err
+	}
+	if result.UpsertedCount > 0 {
+		return true, false, nil
+	}
+
+	existing, err := r.GetRouteByID(ctx, route.ID)
+	if err != nil {
+		return false, false, err
+	}
+	if existing.TemplateID != route.TemplateID || existing.Status == search.RouteCancelled ||
+		existing.Seats != existing.Capacity || !existing.Departure.After(time.Now()) || sameScheduledDeparture(existing, route) {
+		return false, false, nil
+	}
+
+	// Los tramos se rearman con la capacidad de la salida, que puede venir del bus asignado
+	refreshed := *route
+	refreshed.Seats = existing.Capacity
+	refreshed.InitSegments()
+
+	// La condición sobre los asientos descarta la actualización si se vendió un pasaje desde la lectura
+	result, err = collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":      route.ID,
+			"status":   bson.M{"$ne": search.RouteCancelled},
+			"seats":    existing.Capacity,
+			"capacity": existing.Capacity,
+		},
+		bson.M{"$set": bson.M{
+			"departure":        refreshed.Departure,
+			"arrival":          refreshed.Arrival,
+			"stops":            refreshed.Stops,
+			"price":            refreshed.Price,
+			"segment_seats":    refreshed.SegmentSeats,
+			"segment_occupied": refreshed.SegmentOccupied,
+			"updated_at":       time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, false, err
+	}
+
+	return false, result.ModifiedCount > 0, nil
+}
+
+// sameScheduledDeparture indica si la salida guardada ya tiene la hora, las paradas y el precio generados
+func sameScheduledDeparture(existing, generated *search.Route) bool {
+	if !existing.Departure.Equal(generated.Departure) || !existing.Arrival.Equal(generated.Arrival) ||
+		existing.Price != generated.Price || len(existing.Stops) != len(generated.Stops) {
+		return false
+	}
+	for i, stop := range existing.Stops {
+		other := generated.Stops[i]
+		if stop.Code != other.Code || !stop.Arrival.Equal(other.Arrival) || !stop.Departure.Equal(other.Departure) || stop.Fare != other.Fare {
+			return false
+		}
+	}
+	return true
+}
+
+// CancelUnsoldRoute cancela una salida solo si no tiene asientos vendidos
+func (r *MongoDBRepository) CancelUnsoldRoute(ctx context.Context, routeID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":    routeID,
+			"status": bson.M{"$ne": search.RouteCancelled},
+			"$expr":  bson.M{"$eq": bson.A{"$seats", "$capacity"}},
+		},
+		bson.M{"$set": bson.M{"status": search.RouteCancelled, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount > 0 {
+		return nil
+	}
+
+	route, err := r.GetRouteByID(ctx, routeID)
+	if err != nil {
+		return err
+	}
+	if route.Status == search.RouteCancelled {
+		return search.ErrRouteCancelled
+	}
+	return search.ErrRouteHasReservations
+}
+
+// GetCityPopularity calcula la popularidad de cada ciudad como la suma de los asientos vendidos
+// y la cantidad de salidas no canceladas que parten o llegan a ella
+func (r *MongoDBRepository) GetCityPopularity(ctx context.Context) (map[string]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.RoutesCollection)
+
+	sold := bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$capacity", "$seats"}}, "$seats"}}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$ne": search.RouteCancelled}}}},
+		{{Key: "$project", Value: bson.M{"codes": bson.M{"$ifNull": bson.A{"$stops.code", bson.A{"$originCode", "$destCode"}}}, "score": bson.M{"$add": bson.A{1, sold}}}}},
+		{{Key: "$unwind", Value: "$codes"}},
+		{{Key: "$group", Value: bson.M{"_id": "$codes", "score": bson.M{"$sum": "$score"}}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Code  string  `bson:"_id"`
+		Score float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	popularity := make(map[string]float64, len(results))
+	for _, result := range results {
+		popularity[result.Code] = result.Score
+	}
+
+	return popularity, nil
+}
+
+// GetOperatorReport resume las salidas de un operador con salida en el periodo y las reservas hechas en él
+func (r *MongoDBRepository) GetOperatorReport(ctx context.Context, operatorID string, from, to time.Time) (*search.OperatorReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.client.Database(r.config.MongoDB.DatabaseName)
+	report := &search.OperatorReport{
+		OperatorID:   operatorID,
+		From:         from,
+		To:           to,
+		Reservations: []search.ReservationSummary{},
+	}
+
+	// Salidas del periodo
+	capacity := bson.M{"$ifNull": bson.A{"$capacity", "$seats"}}
+	cancelled := bson.M{"$eq": bson.A{"$status", search.RouteCancelled}}
+	routesCursor, err := db.Collection(r.config.MongoDB.RoutesCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operator_id": operatorID, "departure": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        nil,
+			"departures": bson.M{"$sum": 1},
+			"cancelled":  bson.M{"$sum": bson.M{"$cond": bson.A{cancelled, 1, 0}}},
+			"offered":    bson.M{"$sum": bson.M{"$cond": bson.A{cancelled, 0, capacity}}},
+			"sold":       bson.M{"$sum": bson.M{"$cond": bson.A{cancelled, 0, bson.M{"$subtract": bson.A{capacity, "$seats"}}}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer routesCursor.Close(ctx)
+
+	var departures []struct {
+		Departures int `bson:"departures"`
+		Cancelled  int `bson:"cancelled"`
+		Offered    int `bson:"offered"`
+		Sold       int `bson:"sold"`
+	}
+	if err := routesCursor.All(ctx, &departures); err != nil {
+		return nil, err
+	}
+	if len(departures) > 0 {
+		report.Departures = departures[0].Departures
+		report.CancelledDepartures = departures[0].Cancelled
+		report.SeatsOffered = departures[0].Offered
+		report.SeatsSold = departures[0].Sold
+	}
+
+	// Reservas del periodo agrupadas por estado
+	reservationsCursor, err := db.Collection(r.config.MongoDB.ReservationsCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operator_id": operatorID, "created_at": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$status",
+			"count":  bson.M{"$sum": 1},
+			"seats":  bson.M{"$sum": "$seats"},
+			"amount": bson.M{"$sum": "$total_price"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer reservationsCursor.Close(ctx)
+
+	if err := reservationsCursor.All(ctx, &report.Reservations); err != nil {
+		return nil, err
+	}
+	for _, summary := range report.Reservations {
+		if summary.Status == search.ReservationConfirmed {
+			report.Revenue += summary.Amount
+		}
+	}
+
+	return report, nil
+}
+
 // MigrateDB crea o migra las colecciones necesarias en la base de datos MongoDB
 func (r *MongoDBRepository) MigrateDB() error {
 	// Crear o migrar colección para el modelo Route
@@ -174,6 +1005,20 @@ func (r *MongoDBRepository) MigrateDB() error {
 	); err != nil {
 		return err
 	}
+	if err := r.createIndexIfNotExists(
+		r.config.MongoDB.ReservationsCollection,
+		bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}},
+	); err != nil {
+		return err
+	}
+
+	// Crear o migrar colección para la lista de espera
+	if err := r.createIndexIfNotExists(
+		r.config.MongoDB.WaitlistCollection,
+		bson.D{{Key: "route_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+	); err != nil {
+		return err
+	}
 
 	return nil
 }
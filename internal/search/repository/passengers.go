@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// activePassengers cuenta los pasajeros con pasaje vigente de la reserva
+var activePassengers = bson.M{"$size": bson.M{"$filter": bson.M{
+	"input": bson.M{"$ifNull": bson.A{"$passengers", bson.A{}}},
+	"cond":  bson.M{"$eq": bson.A{"$$this.status", search.PassengerActive}},
+}}}
+
+// SetReservationPassengers reemplaza los pasajeros vigentes de una reserva confirmada, conservando los cancelados.
+// Se rechaza si hay más pasajeros que asientos.
+func (r *MongoDBRepository) SetReservationPassengers(ctx context.Context, reservationID string, passengers []search.Passenger) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	cancelled := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$passengers", bson.A{}}},
+		"cond":  bson.M{"$eq": bson.A{"$$this.status", search.PassengerCancelled}},
+	}}
+
+	var reservation search.Reservation
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": reservationID, "status": search.ReservationConfirmed, "seats": bson.M{"$gte": len(passengers)}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"passengers": bson.M{"$concatArrays": bson.A{cancelled, bson.M{"$literal": passengers}}},
+		}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.passengersError(ctx, reservationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// CancelReservationPassengers cancela los pasajes de algunos pasajeros de una reserva confirmada y, además,
+// unnamed asientos aún sin nombre. Descuenta los asientos y su tarifa de la reserva y los libera en la ruta.
+// La reserva debe conservar al menos un asiento; para anularla entera se cancela la reserva.
+func (r *MongoDBRepository) CancelReservationPassengers(ctx context.Context, reservationID string, passengerIDs []string, unnamed int, fare float64) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	current, err := r.GetReservationByID(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, search.ErrReservationNotFound
+	}
+
+	// Asientos numerados que se liberan: los de los pasajeros cancelados y, por los asientos sin nombre,
+	// los últimos que no tiene asignados ningún pasajero vigente
+	cancelledIDs := make(map[string]bool, len(passengerIDs))
+	for _, id := range passengerIDs {
+		cancelledIDs[id] = true
+	}
+	assigned := make(map[string]bool)
+	var seatNumbers []string
+	for _, passenger := range current.Passengers {
+		if passenger.Status != search.PassengerActive || passenger.SeatNumber == "" {
+			continue
+		}
+		if cancelledIDs[passenger.ID] {
+			seatNumbers = append(seatNumbers, passenger.SeatNumber)
+		} else {
+			assigned[passenger.SeatNumber] = true
+		}
+	}
+	for i, freed := len(current.SeatNumbers)-1, 0; i >= 0 && freed < unnamed; i-- {
+		number := current.SeatNumbers[i]
+		if !assigned[number] && !slices.Contains(seatNumbers, number) {
+			seatNumbers = append(seatNumbers, number)
+			freed++
+		}
+	}
+
+	seats := len(passengerIDs) + unnamed
+	filter := bson.M{
+		"_id":    reservationID,
+		"status": search.ReservationConfirmed,
+		"seats":  bson.M{"$gt": seats},
+		// Los asientos sin nombre alcanzan para los que se cancelan
+		"$expr": bson.M{"$gte": bson.A{bson.M{"$subtract": bson.A{"$seats", activePassengers}}, unnamed}},
+	}
+	if len(passengerIDs) > 0 {
+		matches := make(bson.A, 0, len(passengerIDs))
+		for _, id := range passengerIDs {
+			matches = append(matches, bson.M{"$elemMatch": bson.M{"id": id, "status": search.PassengerActive}})
+		}
+		filter["passengers"] = bson.M{"$all": matches}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"seats": -seats, "total_price": -float64(seats) * fare},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if len(passengerIDs) > 0 {
+		update["$set"] = bson.M{
+			"passengers.$[cancelled].status":       search.PassengerCancelled,
+			"passengers.$[cancelled].cancelled_at": time.Now(),
+		}
+		opts.SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+			bson.M{"cancelled.id": bson.M{"$in": passengerIDs}},
+		}})
+	}
+	if len(seatNumbers) > 0 {
+		update["$pullAll"] = bson.M{"seat_numbers": seatNumbers}
+	}
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	var reservation search.Reservation
+	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.passengersError(ctx, reservationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := r.GetRouteByID(ctx, reservation.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	segment, err := route.SegmentBetween(reservation.FromStop, reservation.ToStop)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.releaseSeats(ctx, route, segment, seats, seatNumbers); err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// passengersError determina por qué no se pudieron actualizar los pasajeros de una reserva
+func (r *MongoDBRepository) passengersError(ctx context.Context, reservationID string) error {
+	current, err := r.GetReservationByID(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return search.ErrReservationNotFound
+	}
+	if current.Status != search.ReservationConfirmed {
+		return search.ErrReservationNotConfirmed
+	}
+	return search.ErrInvalidPassengers
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// stopsFilter busca las rutas directas entre dos ciudades o que tengan ambas entre sus paradas.
+// El orden de las paradas se verifica al decodificar cada ruta.
+func stopsFilter(originCode, destCode string) bson.A {
+	return bson.A{
+		bson.M{"originCode": originCode, "destCode": destCode},
+		bson.M{"stops.code": bson.M{"$all": bson.A{originCode, destCode}}},
+	}
+}
+
+// segmentUpdate suma delta asientos a cada tramo entre paradas del segmento y recalcula los asientos disponibles
+// de toda la ruta como el mínimo de los tramos. Con delta negativo ocupa los asientos numerados y con delta
+// positivo los libera.
+func segmentUpdate(segment *search.Segment, delta int, seatNumbers []string) mongo.Pipeline {
+	inSegment := legIn(segment)
+	legs := bson.M{"$range": bson.A{0, bson.M{"$size": "$segment_seats"}}}
+
+	set := bson.M{
+		"segment_seats": bson.M{"$map": bson.M{
+			"input": legs,
+			"as":    "leg",
+			"in": bson.M{"$cond": bson.A{
+				inSegment,
+				bson.M{"$add": bson.A{bson.M{"$arrayElemAt": bson.A{"$segment_seats", "$$leg"}}, delta}},
+				bson.M{"$arrayElemAt": bson.A{"$segment_seats", "$$leg"}},
+			}},
+		}},
+	}
+	if len(seatNumbers) > 0 {
+		occupied := bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$segment_occupied", "$$leg"}}, bson.A{}}}
+		changed := bson.M{"$setDifference": bson.A{occupied, bson.M{"$literal": seatNumbers}}}
+		if delta < 0 {
+			changed = bson.M{"$concatArrays": bson.A{occupied, bson.M{"$literal": seatNumbers}}}
+		}
+		set["segment_occupied"] = bson.M{"$map": bson.M{
+			"input": legs,
+			"as":    "leg",
+			"in":    bson.M{"$cond": bson.A{inSegment, changed, occupied}},
+		}}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: set}},
+		{{Key: "$set", Value: bson.M{"seats": bson.M{"$min": "$segment_seats"}}}},
+	}
+}
+
+// legIn indica, dentro de un $map sobre los tramos con la variable leg, si el tramo pertenece al segmento
+func legIn(segment *search.Segment) bson.M {
+	return bson.M{"$and": bson.A{
+		bson.M{"$gte": bson.A{"$$leg", segment.FromIndex}},
+		bson.M{"$lt": bson.A{"$$leg", segment.ToIndex}},
+	}}
+}
+
+// seatHold representa los asientos que ocupa una reserva en un segmento de una ruta
+type seatHold struct {
+	segment     *search.Segment
+	seats       int
+	seatNumbers []string
+}
+
+// swapFilter exige que, descontando los asientos liberados, cada tramo del segmento reservado tenga asientos
+// suficientes y que sus asientos numerados estén libres. Los asientos numerados que se liberan en el mismo tramo
+// se pueden volver a elegir. overbooking son los asientos que se pueden vender por encima de la capacidad.
+func swapFilter(route *search.Route, released, reserved seatHold, overbooking int) bson.M {
+	filter := bson.M{"_id": route.ID, "status": bson.M{"$ne": search.RouteCancelled}}
+	freed := make(map[string]bool, len(released.seatNumbers))
+	for _, number := range released.seatNumbers {
+		freed[number] = true
+	}
+	var taken []string
+	for _, number := range reserved.seatNumbers {
+		if !freed[number] {
+			taken = append(taken, number)
+		}
+	}
+
+	if len(route.SegmentSeats) == 0 {
+		// Ruta sin paradas
+		filter["seats"] = bson.M{"$gte": reserved.seats - released.seats - overbooking}
+		if len(taken) > 0 {
+			filter["occupied_seats"] = bson.M{"$nin": taken}
+		}
+		return filter
+	}
+
+	for leg := reserved.segment.FromIndex; leg < reserved.segment.ToIndex; leg++ {
+		required, numbers := reserved.seats-overbooking, reserved.seatNumbers
+		if leg >= released.segment.FromIndex && leg < released.segment.ToIndex {
+			required, numbers = required-released.seats, taken
+		}
+		filter[fmt.Sprintf("segment_seats.%d", leg)] = bson.M{"$gte": required}
+		if len(numbers) > 0 {
+			filter[fmt.Sprintf("segment_occupied.%d", leg)] = bson.M{"$nin": numbers}
+		}
+	}
+	return filter
+}
+
+// swapUpdate libera los asientos de una reserva y ocupa los de otra en la misma ruta con una sola actualización,
+// de modo que los asientos propios cuentan como disponibles para la nueva reserva
+func swapUpdate(route *search.Route, released, reserved seatHold) mongo.Pipeline {
+	releasedNumbers := bson.M{"$literal": append([]string{}, released.seatNumbers...)}
+	reservedNumbers := bson.M{"$literal": append([]string{}, reserved.seatNumbers...)}
+
+	if len(route.SegmentSeats) == 0 {
+		// Ruta sin paradas
+		occupied := bson.M{"$ifNull": bson.A{"$occupied_seats", bson.A{}}}
+		return mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"seats":          bson.M{"$add": bson.A{"$seats", released.seats - reserved.seats}},
+			"occupied_seats": bson.M{"$concatArrays": bson.A{bson.M{"$setDifference": bson.A{occupied, releasedNumbers}}, reservedNumbers}},
+		}}}}
+	}
+
+	inReleased, inReserved := legIn(released.segment), legIn(reserved.segment)
+	legs := bson.M{"$range": bson.A{0, bson.M{"$size": "$segment_seats"}}}
+	occupied := bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$segment_occupied", "$$leg"}}, bson.A{}}}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"segment_seats": bson.M{"$map": bson.M{
+				"input": legs,
+				"as":    "leg",
+				"in": bson.M{"$add": bson.A{
+					bson.M{"$arrayElemAt": bson.A{"$segment_seats", "$$leg"}},
+					bson.M{"$cond": bson.A{inReleased, released.seats, 0}},
+					bson.M{"$cond": bson.A{inReserved, -reserved.seats, 0}},
+				}},
+			}},
+			"segment_occupied": bson.M{"$map": bson.M{
+				"input": legs,
+				"as":    "leg",
+				"in": bson.M{"$concatArrays": bson.A{
+					bson.M{"$cond": bson.A{inReleased, bson.M{"$setDifference": bson.A{occupied, releasedNumbers}}, occupied}},
+					bson.M{"$cond": bson.A{inReserved, reservedNumbers, bson.A{}}},
+				}},
+			}},
+		}}},
+		{{Key: "$set", Value: bson.M{"seats": bson.M{"$min": "$segment_seats"}}}},
+	}
+}
+
+// shiftSegmentSeats ajusta los asientos de cada tramo entre paradas a una nueva capacidad,
+// conservando los vendidos. Las rutas sin paradas no tienen tramos.
+func shiftSegmentSeats(newCapacity int, capacity bson.M) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$isArray": "$segment_seats"},
+		bson.M{"$map": bson.M{
+			"input": "$segment_seats",
+			"as":    "seats",
+			"in":    bson.M{"$add": bson.A{"$$seats", bson.M{"$subtract": bson.A{newCapacity, capacity}}}},
+		}},
+		"$$REMOVE",
+	}}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"venta-de-pasajes/internal/search"
+)
+
+func TestSwapFilterRouteWithoutStops(t *testing.T) {
+	// Salida agotada: la reserva de cuatro asientos pasa a dos conservando los suyos
+	route := &search.Route{ID: "ruta-1", Capacity: 4, OccupiedSeats: []string{"1A", "1B", "2A", "2B"}}
+	segment := &search.Segment{FromIndex: 0, ToIndex: 1}
+	released := seatHold{segment: segment, seats: 4, seatNumbers: []string{"1A", "1B", "2A", "2B"}}
+	reserved := seatHold{segment: segment, seats: 2, seatNumbers: []string{"1A", "1B"}}
+
+	filter := swapFilter(route, released, reserved, 0)
+	if seats := filter["seats"].(bson.M)["$gte"]; seats != -2 {
+		t.Errorf("filtro de asientos = %v, se esperaba -2 al contar los cuatro asientos liberados", seats)
+	}
+	if _, ok := filter["occupied_seats"]; ok {
+		t.Errorf("filtro = %v, los asientos propios no deben exigirse libres", filter)
+	}
+
+	reserved.seatNumbers = []string{"1A", "3A"}
+	filter = swapFilter(route, released, reserved, 1)
+	if seats := filter["seats"].(bson.M)["$gte"]; seats != -3 {
+		t.Errorf("filtro de asientos = %v, se esperaba -3 con un asiento de sobreventa", seats)
+	}
+	if taken := filter["occupied_seats"].(bson.M)["$nin"].([]string); len(taken) != 1 || taken[0] != "3A" {
+		t.Errorf("asientos exigidos libres = %v, se esperaba solo 3A", taken)
+	}
+}
+
+func TestSwapFilterRouteWithStops(t *testing.T) {
+	// Tres tramos: la reserva pasa del tramo 0-2 al 1-3
+	route := &search.Route{ID: "ruta-1", Capacity: 4, SegmentSeats: []int{0, 0, 0}}
+	released := seatHold{segment: &search.Segment{FromIndex: 0, ToIndex: 2}, seats: 2, seatNumbers: []string{"1A", "1B"}}
+	reserved := seatHold{segment: &search.Segment{FromIndex: 1, ToIndex: 3}, seats: 2, seatNumbers: []string{"1A", "1B"}}
+
+	filter := swapFilter(route, released, reserved, 0)
+	if _, ok := filter["segment_seats.0"]; ok {
+		t.Error("el tramo 0 solo se libera y no debe filtrarse")
+	}
+	if seats := filter["segment_seats.1"].(bson.M)["$gte"]; seats != 0 {
+		t.Errorf("filtro del tramo 1 = %v, se esperaba 0 porque la reserva ya ocupa sus asientos", seats)
+	}
+	if _, ok := filter["segment_occupied.1"]; ok {
+		t.Error("en el tramo 1 los asientos propios no deben exigirse libres")
+	}
+	if seats := filter["segment_seats.2"].(bson.M)["$gte"]; seats != 2 {
+		t.Errorf("filtro del tramo 2 = %v, se esperaban 2 asientos libres", seats)
+	}
+	if taken := filter["segment_occupied.2"].(bson.M)["$nin"].([]string); len(taken) != 2 {
+		t.Errorf("asientos exigidos libres en el tramo 2 = %v, se esperaban 1A y 1B", taken)
+	}
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/internal/search"
+)
+
+// activeReservationStatuses son los estados de las reservas que ocupan asientos
+var activeReservationStatuses = bson.A{search.ReservationConfirmed, search.ReservationPending}
+
+// CancelReservation cancela una reserva confirmada o pendiente del usuario y devuelve sus asientos a la ruta
+func (r *MongoDBRepository) CancelReservation(ctx context.Context, reservationID, userID string) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": reservationID, "status": bson.M{"$in": activeReservationStatuses}}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+
+	reservation, err := r.closeReservation(ctx, filter, search.ReservationCancelled)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, err := r.GetReservationByID(ctx, reservationID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || (userID != "" && current.UserID != userID) {
+			return nil, search.ErrReservationNotFound
+		}
+		return nil, search.ErrReservationNotActive
+	}
+	return reservation, err
+}
+
+// ExpireHold cancela una reserva pendiente cuyo plazo venció y devuelve sus asientos a la ruta
+func (r *MongoDBRepository) ExpireHold(ctx context.Context, reservationID string, now time.Time) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	reservation, err := r.closeReservation(ctx, bson.M{
+		"_id":        reservationID,
+		"status":     search.ReservationPending,
+		"expires_at": bson.M{"$lte": now},
+	}, search.ReservationCancelled)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, search.ErrReservationNotPending
+	}
+	return reservation, err
+}
+
+// closeReservation pasa al estado indicado la reserva que cumple el filtro y libera sus asientos en el tramo reservado.
+// La ruta se obtiene antes de cerrar la reserva para que un error al leerla no deje los asientos ocupados. Si la
+// reserva se cerró pero sus asientos no se liberaron se devuelve el error, para liberarlos con ReleaseReservationSeats.
+func (r *MongoDBRepository) closeReservation(ctx context.Context, filter bson.M, status string) (*search.Reservation, error) {
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	var current search.Reservation
+	if err := collection.FindOne(ctx, filter).Decode(&current); err != nil {
+		return nil, err
+	}
+	route, err := r.GetRouteByID(ctx, current.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	segment, err := route.SegmentBetween(current.FromStop, current.ToStop)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation search.Reservation
+	err = collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"status": status}, "$unset": bson.M{"expires_at": ""}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.releaseSeats(ctx, route, segment, reservation.Seats, reservation.SeatNumbers); err != nil {
+		return nil, fmt.Errorf("la reserva %s quedó %s pero no se liberaron sus asientos: %w", reservation.ID, status, err)
+	}
+	return &reservation, nil
+}
+
+// ReleaseReservationSeats devuelve a su ruta los asientos de una reserva que ya no los ocupa
+func (r *MongoDBRepository) ReleaseReservationSeats(ctx context.Context, reservation *search.Reservation) error {
+	route, err := r.GetRouteByID(ctx, reservation.RouteID)
+	if err != nil {
+		return err
+	}
+	segment, err := route.SegmentBetween(reservation.FromStop, reservation.ToStop)
+	if err != nil {
+		return err
+	}
+	return r.releaseSeats(ctx, route, segment, reservation.Seats, reservation.SeatNumbers)
+}
+
+// HoldSeats reserva asientos de un tramo con una reserva pendiente que vence en expiresAt
+func (r *MongoDBRepository) HoldSeats(ctx context.Context, request search.ReservationRequest, expiresAt time.Time, waitlistEntryID string) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return r.createReservation(ctx, request, &search.Reservation{
+		Status:          search.ReservationPending,
+		ExpiresAt:       expiresAt,
+		WaitlistEntryID: waitlistEntryID,
+	})
+}
+
+// ConfirmHold confirma una reserva pendiente del usuario antes de su vencimiento. Las reservas pendientes de una
+// ruta cancelada ya no se pueden confirmar.
+func (r *MongoDBRepository) ConfirmHold(ctx context.Context, reservationID, userID string, now time.Time) (*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	current, err := r.GetReservationByID(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.UserID != userID {
+		return nil, search.ErrReservationNotFound
+	}
+	route, err := r.GetRouteByID(ctx, current.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status == search.RouteCancelled {
+		return nil, search.ErrRouteCancelled
+	}
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	var reservation search.Reservation
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": reservationID, "user_id": userID, "status": search.ReservationPending, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"status": search.ReservationConfirmed}, "$unset": bson.M{"expires_at": ""}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, err := r.GetReservationByID(ctx, reservationID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, search.ErrReservationNotFound
+		}
+		if current.Status == search.ReservationPending {
+			return nil, search.ErrHoldExpired
+		}
+		return nil, search.ErrReservationNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// FindExpiredHolds obtiene las reservas pendientes cuyo plazo venció
+func (r *MongoDBRepository) FindExpiredHolds(ctx context.Context, now time.Time) ([]*search.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collection := r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.ReservationsCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{"status": search.ReservationPending, "expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reservations := []*search.Reservation{}
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// waitlist devuelve la colección de la lista de espera
+func (r *MongoDBRepository) waitlist() *mongo.Collection {
+	return r.client.Database(r.config.MongoDB.DatabaseName).Collection(r.config.MongoDB.WaitlistCollection)
+}
+
+// AddWaitlistEntry inscribe a un usuario en la lista de espera de una salida
+func (r *MongoDBRepository) AddWaitlistEntry(ctx context.Context, entry *search.WaitlistEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	entry.ID = uuid.New().String()
+	entry.Status = search.WaitlistWaiting
+	entry.CreatedAt = time.Now()
+	entry.UpdatedAt = entry.CreatedAt
+
+	_, err := r.waitlist().InsertOne(ctx, entry)
+	return err
+}
+
+// GetWaitlistEntry obtiene una inscripción de la lista de espera por su ID
+func (r *MongoDBRepository) GetWaitlistEntry(ctx context.Context, entryID string) (*search.WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var entry search.WaitlistEntry
+	err := r.waitlist().FindOne(ctx, bson.M{"_id": entryID}).Decode(&entry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, search.ErrWaitlistEntryNotFound
+		}
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// GetWaitlist obtiene las inscripciones de una salida en orden de llegada, opcionalmente solo las de un estado
+func (r *MongoDBRepository) GetWaitlist(ctx context.Context, routeID, status string) ([]*search.WaitlistEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"route_id": routeID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	cursor, err := r.waitlist().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []*search.WaitlistEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// UpdateWaitlistEntry cambia el estado de una inscripción solo si tiene el estado esperado
+func (r *MongoDBRepository) UpdateWaitlistEntry(ctx context.Context, entryID, from string, update search.WaitlistUpdate) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": update.Status, "updated_at": time.Now()}
+	unset := bson.M{}
+	if update.ReservationID != "" {
+		set["reservation_id"] = update.ReservationID
+	}
+	if update.OfferExpiresAt.IsZero() {
+		unset["offer_expires_at"] = ""
+	} else {
+		set["offer_expires_at"] = update.OfferExpiresAt
+	}
+
+	changes := bson.M{"$set": set}
+	if len(unset) > 0 {
+		changes["$unset"] = unset
+	}
+
+	result, err := r.waitlist().UpdateOne(ctx, bson.M{"_id": entryID, "status": from}, changes)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.GetWaitlistEntry(ctx, entryID); err != nil {
+			return err
+		}
+		return search.ErrWaitlistEntryClosed
+	}
+	return nil
+}
+
+// CancelRouteWaitlist cancela las inscripciones en espera de una salida y devuelve cuántas se cancelaron. Las
+// inscripciones con una oferta pendiente se cancelan una a una junto con su reserva.
+func (r *MongoDBRepository) CancelRouteWaitlist(ctx context.Context, routeID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.waitlist().UpdateMany(
+		ctx,
+		bson.M{"route_id": routeID, "status": search.WaitlistWaiting},
+		bson.M{"$set": bson.M{"status": search.WaitlistCancelled, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
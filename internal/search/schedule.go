@@ -0,0 +1,500 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frecuencias soportadas en la regla de recurrencia
+const (
+	FrequencyDaily  = "DAILY"
+	FrequencyWeekly = "WEEKLY"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	timeLayout = "15:04"
+)
+
+// scheduleLocation es la hora de Perú (UTC-5, sin horario de verano)
+var scheduleLocation = time.FixedZone("PET", -5*60*60)
+
+// weekdayCodes asocia los códigos BYDAY de la regla con los días de la semana
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var (
+	// ErrInvalidSchedule indica que los datos del horario no son válidos
+	ErrInvalidSchedule = errors.New("horario inválido")
+	// ErrScheduleNotFound indica que el horario no existe
+	ErrScheduleNotFound = errors.New("horario no encontrado")
+	// ErrRouteHasReservations indica que la salida ya tiene asientos vendidos
+	ErrRouteHasReservations = errors.New("la salida tiene reservas")
+)
+
+// recurrenceRule representa una regla de recurrencia ya interpretada
+type recurrenceRule struct {
+	frequency string
+	interval  int
+	weekdays  map[time.Weekday]bool // Vacío: cualquier día (diaria) o el día de inicio (semanal)
+}
+
+// parseRecurrence interpreta una regla tipo RRULE con FREQ (DAILY o WEEKLY), INTERVAL y BYDAY
+func parseRecurrence(rule string) (*recurrenceRule, error) {
+	parsed := &recurrenceRule{interval: 1, weekdays: make(map[time.Weekday]bool)}
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: parte de la regla sin valor %q", ErrInvalidSchedule, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			parsed.frequency = strings.ToUpper(value)
+			if parsed.frequency != FrequencyDaily && parsed.frequency != FrequencyWeekly {
+				return nil, fmt.Errorf("%w: frecuencia no soportada %q, use DAILY o WEEKLY", ErrInvalidSchedule, value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("%w: intervalo inválido %q", ErrInvalidSchedule, value)
+			}
+			parsed.interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("%w: día desconocido %q", ErrInvalidSchedule, code)
+				}
+				parsed.weekdays[weekday] = true
+			}
+		default:
+			return nil, fmt.Errorf("%w: parte de la regla no soportada %q", ErrInvalidSchedule, key)
+		}
+	}
+
+	if parsed.frequency == "" {
+		return nil, fmt.Errorf("%w: la regla debe indicar FREQ", ErrInvalidSchedule)
+	}
+	return parsed, nil
+}
+
+// matches indica si la regla genera una salida en el día dado, contando los intervalos desde el día de inicio
+func (r *recurrenceRule) matches(start, day time.Time) bool {
+	days := daysBetween(start, day)
+	if days < 0 {
+		return false
+	}
+
+	switch r.frequency {
+	case FrequencyDaily:
+		if days%r.interval != 0 {
+			return false
+		}
+		return len(r.weekdays) == 0 || r.weekdays[day.Weekday()]
+	case FrequencyWeekly:
+		weeks := daysBetween(weekStart(start), weekStart(day)) / 7
+		if weeks%r.interval != 0 {
+			return false
+		}
+		if len(r.weekdays) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return r.weekdays[day.Weekday()]
+	}
+	return false
+}
+
+// daysBetween cuenta los días calendario entre dos fechas
+func daysBetween(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// weekStart devuelve el lunes de la semana de la fecha dada
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// validateScheduleTemplate verifica los datos de un horario nuevo y completa los códigos y nombres de las ciudades
+func validateScheduleTemplate(template *ScheduleTemplate, locations LocationResolver) error {
+	originCode, origin, err := locations.ResolveCity(template.OriginCode)
+	if err != nil {
+		return fmt.Errorf("%w: origen desconocido %q", ErrInvalidSchedule, template.OriginCode)
+	}
+	destCode, destination, err := locations.ResolveCity(template.DestCode)
+	if err != nil {
+		return fmt.Errorf("%w: destino desconocido %q", ErrInvalidSchedule, template.DestCode)
+	}
+	template.OriginCode, template.Origin = originCode, origin
+	template.DestCode, template.Destination = destCode, destination
+	if template.OriginCode == template.DestCode {
+		return fmt.Errorf("%w: el origen y el destino deben ser distintos", ErrInvalidSchedule)
+	}
+	if _, err := time.Parse(timeLayout, template.DepartureTime); err != nil {
+		return fmt.Errorf("%w: hora de salida inválida %q, use HH:MM", ErrInvalidSchedule, template.DepartureTime)
+	}
+	if template.Duration <= 0 {
+		return fmt.Errorf("%w: la duración debe ser positiva", ErrInvalidSchedule)
+	}
+	if err := validateScheduleStops(template, locations); err != nil {
+		return err
+	}
+	if template.Seats <= 0 {
+		return fmt.Errorf("%w: la cantidad de asientos debe ser positiva", ErrInvalidSchedule)
+	}
+	if template.Price <= 0 {
+		return fmt.Errorf("%w: el precio debe ser positivo", ErrInvalidSchedule)
+	}
+	if _, err := parseRecurrence(template.Recurrence); err != nil {
+		return err
+	}
+
+	validFrom, err := time.Parse(dateLayout, template.ValidFrom)
+	if err != nil {
+		return fmt.Errorf("%w: fecha de inicio inválida %q, use AAAA-MM-DD", ErrInvalidSchedule, template.ValidFrom)
+	}
+	if template.ValidUntil != "" {
+		validUntil, err := time.Parse(dateLayout, template.ValidUntil)
+		if err != nil {
+			return fmt.Errorf("%w: fecha final inválida %q, use AAAA-MM-DD", ErrInvalidSchedule, template.ValidUntil)
+		}
+		if validUntil.Before(validFrom) {
+			return fmt.Errorf("%w: la fecha final debe ser posterior a la de inicio", ErrInvalidSchedule)
+		}
+	}
+
+	if template.Holidays != nil {
+		if err := validateScheduleOverride(template.Holidays); err != nil {
+			return err
+		}
+	}
+	for _, exception := range template.Exceptions {
+		if err := validateScheduleException(&exception); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateScheduleStops verifica las paradas intermedias de un horario: ciudades distintas del origen, del destino
+// y entre sí, horas relativas en el orden del viaje y tarifas acumuladas crecientes en todas o en ninguna
+func validateScheduleStops(template *ScheduleTemplate, locations LocationResolver) error {
+	seen := map[string]bool{template.OriginCode: true, template.DestCode: true}
+	previous, previousFare, fares := 0, 0.0, 0
+	for i := range template.Stops {
+		stop := &template.Stops[i]
+		code, name, err := locations.ResolveCity(stop.Code)
+		if err != nil {
+			return fmt.Errorf("%w: parada desconocida %q", ErrInvalidSchedule, stop.Code)
+		}
+		if seen[code] {
+			return fmt.Errorf("%w: la parada %s está repetida", ErrInvalidSchedule, code)
+		}
+		seen[code] = true
+		stop.Code, stop.Name = code, name
+
+		if stop.ArrivalOffset <= previous || stop.DepartureOffset < stop.ArrivalOffset || stop.DepartureOffset >= template.Duration {
+			return fmt.Errorf("%w: los horarios de la parada %s no siguen el orden del viaje", ErrInvalidSchedule, code)
+		}
+		previous = stop.DepartureOffset
+
+		if stop.Fare > 0 {
+			if stop.Fare <= previousFare || stop.Fare >= template.Price {
+				return fmt.Errorf("%w: la tarifa acumulada de la parada %s debe crecer y ser menor al precio", ErrInvalidSchedule, code)
+			}
+			previousFare = stop.Fare
+			fares++
+		}
+	}
+	if fares > 0 && fares != len(template.Stops) {
+		return fmt.Errorf("%w: indique la tarifa de todas las paradas intermedias o de ninguna", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// scheduleStops arma las paradas de una salida del horario, desde el origen hasta el destino
+func scheduleStops(template *ScheduleTemplate, departure, arrival time.Time) []Stop {
+	if len(template.Stops) == 0 {
+		return nil
+	}
+
+	stops := make([]Stop, 0, len(template.Stops)+2)
+	stops = append(stops, Stop{Code: template.OriginCode, Name: template.Origin, Arrival: departure, Departure: departure})
+	for _, stop := range template.Stops {
+		stops = append(stops, Stop{
+			Code:      stop.Code,
+			Name:      stop.Name,
+			Arrival:   departure.Add(time.Duration(stop.ArrivalOffset) * time.Minute),
+			Departure: departure.Add(time.Duration(stop.DepartureOffset) * time.Minute),
+			Fare:      stop.Fare,
+		})
+	}
+	stops = append(stops, Stop{Code: template.DestCode, Name: template.Destination, Arrival: arrival, Departure: arrival})
+	return stops
+}
+
+// validateScheduleException verifica la fecha y el cambio de una excepción del horario
+func validateScheduleException(exception *ScheduleException) error {
+	if _, err := time.Parse(dateLayout, exception.Date); err != nil {
+		return fmt.Errorf("%w: fecha de excepción inválida %q, use AAAA-MM-DD", ErrInvalidSchedule, exception.Date)
+	}
+	return validateScheduleOverride(&exception.ScheduleOverride)
+}
+
+// validateScheduleOverride verifica la hora y el precio de un cambio del horario
+func validateScheduleOverride(override *ScheduleOverride) error {
+	if override.DepartureTime != "" {
+		if _, err := time.Parse(timeLayout, override.DepartureTime); err != nil {
+			return fmt.Errorf("%w: hora de salida inválida %q, use HH:MM", ErrInvalidSchedule, override.DepartureTime)
+		}
+	}
+	if override.Price != nil && *override.Price <= 0 {
+		return fmt.Errorf("%w: el precio debe ser positivo", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// scheduledDeparture representa una salida del horario para una fecha
+type scheduledDeparture struct {
+	route     *Route
+	cancelled bool
+}
+
+// scheduleRouteID arma el ID determinista de la salida de un horario en una fecha,
+// para que generar varias veces el mismo periodo no duplique salidas
+func scheduleRouteID(templateID string, day time.Time) string {
+	return templateID + "-" + day.Format("20060102")
+}
+
+// scheduleDepartures calcula las salidas del horario cuya fecha de salida cae entre from y to.
+// Aplica primero el cambio por feriado y luego la excepción de la fecha.
+func scheduleDepartures(template *ScheduleTemplate, holidays map[string]bool, from, to time.Time) ([]scheduledDeparture, error) {
+	rule, err := parseRecurrence(template.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.ParseInLocation(dateLayout, template.ValidFrom, scheduleLocation)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fecha de inicio inválida %q", ErrInvalidSchedule, template.ValidFrom)
+	}
+	end := to.In(scheduleLocation)
+	if template.ValidUntil != "" {
+		validUntil, err := time.ParseInLocation(dateLayout, template.ValidUntil, scheduleLocation)
+		if err != nil {
+			return nil, fmt.Errorf("%w: fecha final inválida %q", ErrInvalidSchedule, template.ValidUntil)
+		}
+		if validUntil.Before(end) {
+			end = validUntil.AddDate(0, 0, 1)
+		}
+	}
+
+	exceptions := make(map[string]ScheduleOverride, len(template.Exceptions))
+	for _, exception := range template.Exceptions {
+		exceptions[exception.Date] = exception.ScheduleOverride
+	}
+
+	first := from.In(scheduleLocation)
+	day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, scheduleLocation)
+	if day.Before(start) {
+		day = start
+	}
+
+	var departures []scheduledDeparture
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !rule.matches(start, day) {
+			continue
+		}
+
+		date := day.Format(dateLayout)
+		override := ScheduleOverride{DepartureTime: template.DepartureTime}
+		if holidays[date] && template.Holidays != nil {
+			override = mergeOverride(override, *template.Holidays)
+		}
+		if exception, ok := exceptions[date]; ok {
+			override = mergeOverride(override, exception)
+		}
+
+		clock, _ := time.Parse(timeLayout, override.DepartureTime)
+		departure := day.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+		if departure.Before(from) || !departure.Before(to) {
+			continue
+		}
+
+		price := template.Price
+		if override.Price != nil {
+			price = *override.Price
+		}
+		arrival := departure.Add(time.Duration(template.Duration) * time.Minute)
+
+		departures = append(departures, scheduledDeparture{
+			route: &Route{
+				ID:          scheduleRouteID(template.ID, day),
+				OperatorID:  template.OperatorID,
+				Origin:      template.Origin,
+				OriginCode:  template.OriginCode,
+				Destination: template.Destination,
+				DestCode:    template.DestCode,
+				Departure:   departure,
+				Arrival:     arrival,
+				Stops:       scheduleStops(template, departure, arrival),
+				Seats:       template.Seats,
+				Capacity:    template.Seats,
+				VehicleID:   template.VehicleID,
+				Amenities:   template.Amenities,
+				Price:       price,
+				Status:      RouteScheduled,
+				TemplateID:  template.ID,
+			},
+			cancelled: override.Cancelled,
+		})
+	}
+
+	return departures, nil
+}
+
+// mergeOverride aplica sobre base los campos indicados en el cambio
+func mergeOverride(base, change ScheduleOverride) ScheduleOverride {
+	base.Cancelled = base.Cancelled || change.Cancelled
+	if change.DepartureTime != "" {
+		base.DepartureTime = change.DepartureTime
+	}
+	if change.Price != nil {
+		base.Price = change.Price
+	}
+	return base
+}
+
+// ScheduleGenerator genera las salidas de los horarios activos para un horizonte móvil.
+// Las salidas ya generadas sin ventas siguen los cambios del horario y se cancelan si el horario se desactiva;
+// las que tienen ventas se cambian con la administración de rutas. Una salida cancelada no se vuelve a abrir.
+type ScheduleGenerator struct {
+	repo    SearchRepository
+	horizon time.Duration
+}
+
+// NewScheduleGenerator crea una nueva instancia de ScheduleGenerator
+func NewScheduleGenerator(repo SearchRepository, horizon time.Duration) *ScheduleGenerator {
+	return &ScheduleGenerator{
+		repo:    repo,
+		horizon: horizon,
+	}
+}
+
+// Generate genera las salidas de los horarios activos desde now hasta el horizonte indicado,
+// o el horizonte configurado si es cero
+func (g *ScheduleGenerator) Generate(ctx context.Context, now time.Time, horizon time.Duration) (*GenerationResult, error) {
+	if horizon <= 0 {
+		horizon = g.horizon
+	}
+
+	templates, err := g.repo.GetScheduleTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	holidayList, err := g.repo.GetHolidays(ctx)
+	if err != nil {
+		return nil, err
+	}
+	holidays := make(map[string]bool, len(holidayList))
+	for _, holiday := range holidayList {
+		holidays[holiday.Date] = true
+	}
+
+	result := &GenerationResult{}
+	for _, template := range templates {
+		if template.Active {
+			result.Templates++
+		}
+
+		// Un horario con datos inválidos o que falla al guardarse no detiene la generación de los demás
+		if err := g.generateTemplate(ctx, template, holidays, now, now.Add(horizon), result); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("horario %s: %v", template.ID, err))
+		}
+	}
+
+	return result, nil
+}
+
+// generateTemplate genera las salidas de un horario entre from y to y acumula los conteos en result.
+// Las salidas de un horario inactivo se cancelan como las de una excepción.
+func (g *ScheduleGenerator) generateTemplate(ctx context.Context, template *ScheduleTemplate, holidays map[string]bool, from, to time.Time, result *GenerationResult) error {
+	departures, err := scheduleDepartures(template, holidays, from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, departure := range departures {
+		if departure.cancelled || !template.Active {
+			err := g.repo.CancelUnsoldRoute(ctx, departure.route.ID)
+			switch {
+			case err == nil:
+				result.Cancelled++
+			case errors.Is(err, ErrRouteHasReservations):
+				result.Conflicts = append(result.Conflicts, departure.route.ID)
+			case errors.Is(err, ErrRouteNotFound), errors.Is(err, ErrRouteCancelled):
+				// La salida no se había generado o ya estaba cancelada
+			default:
+				return err
+			}
+			continue
+		}
+
+		created, updated, err := g.repo.MaterializeRoute(ctx, departure.route)
+		if err != nil {
+			return err
+		}
+		switch {
+		case created:
+			result.Created++
+		case updated:
+			result.Updated++
+		default:
+			result.Existing++
+		}
+	}
+	return nil
+}
+
+// Run genera salidas periódicamente hasta que el contexto se cancele
+func (g *ScheduleGenerator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := g.Generate(ctx, time.Now(), 0)
+		if err != nil {
+			log.Printf("Error al generar salidas de los horarios: %v", err)
+		} else {
+			log.Printf("Salidas generadas: %d nuevas, %d actualizadas, %d existentes, %d canceladas, %d con conflictos",
+				result.Created, result.Updated, result.Existing, result.Cancelled, len(result.Conflicts))
+			for _, templateErr := range result.Errors {
+				log.Printf("Error al generar salidas del %s", templateErr)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"venta-de-pasajes/internal/auth"
+)
+
+// ScheduleHandler maneja las solicitudes de administración de horarios recurrentes y feriados
+type ScheduleHandler struct {
+	repo      SearchRepository
+	generator *ScheduleGenerator
+	locations LocationResolver
+	operators OperatorValidator
+	vehicles  VehicleDirectory
+}
+
+// NewScheduleHandler crea una nueva instancia de ScheduleHandler
+func NewScheduleHandler(repo SearchRepository, generator *ScheduleGenerator, locations LocationResolver, operators OperatorValidator, vehicles VehicleDirectory) *ScheduleHandler {
+	return &ScheduleHandler{
+		repo:      repo,
+		generator: generator,
+		locations: locations,
+		operators: operators,
+		vehicles:  vehicles,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error del repositorio
+func (h *ScheduleHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidSchedule):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetSchedulesHandler maneja las solicitudes para consultar los horarios, o uno solo con el parámetro id.
+// Un admin_operador solo ve los horarios de su operador.
+func (h *ScheduleHandler) GetSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if templateID := r.URL.Query().Get("id"); templateID != "" {
+		template, err := h.authorizeTemplate(r.Context(), templateID)
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(template)
+		return
+	}
+
+	templates, err := h.repo.GetScheduleTemplates(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok && principal.Role == auth.RoleOperatorAdmin {
+		templates = slices.DeleteFunc(templates, func(template *ScheduleTemplate) bool { return template.OperatorID != principal.OperatorID })
+	}
+	json.NewEncoder(w).Encode(templates)
+}
+
+// authorizeTemplate obtiene el horario y verifica que el usuario autenticado pueda gestionar los de su operador
+func (h *ScheduleHandler) authorizeTemplate(ctx context.Context, templateID string) (*ScheduleTemplate, error) {
+	template, err := h.repo.GetScheduleTemplateByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.AuthorizeOperator(ctx, template.OperatorID); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// CreateScheduleHandler maneja las solicitudes para crear un horario recurrente
+func (h *ScheduleHandler) CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var template ScheduleTemplate
+	err := json.NewDecoder(r.Body).Decode(&template)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	template.Recurrence = strings.ToUpper(strings.TrimSpace(template.Recurrence))
+
+	// Un admin_operador crea los horarios de su operador
+	if template.OperatorID == "" {
+		template.OperatorID = auth.OperatorScope(r.Context())
+	}
+	if err := auth.AuthorizeOperator(r.Context(), template.OperatorID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	// Con vehículo asignado las salidas toman los asientos y las comodidades del bus
+	if template.VehicleID != "" {
+		vehicle, err := vehicleForRoute(r.Context(), h.vehicles, template.VehicleID, template.OperatorID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		template.Seats = vehicle.Capacity
+		template.Amenities = vehicle.Amenities
+	}
+	if err := validateScheduleTemplate(&template, h.locations); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateOperator(r.Context(), h.operators, template.OperatorID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateScheduleTemplate(r.Context(), &template); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// SaveScheduleExceptionHandler maneja las solicitudes para registrar una excepción del horario en una fecha
+func (h *ScheduleHandler) SaveScheduleExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		TemplateID string `json:"template_id"`
+		ScheduleException
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.TemplateID == "" {
+		http.Error(w, "el campo template_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+	if err := validateScheduleException(&requestBody.ScheduleException); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.authorizeTemplate(r.Context(), requestBody.TemplateID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	template, err := h.repo.SaveScheduleException(r.Context(), requestBody.TemplateID, requestBody.ScheduleException)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// SetScheduleActiveHandler maneja las solicitudes para activar o desactivar un horario
+func (h *ScheduleHandler) SetScheduleActiveHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID     string `json:"id"`
+		Active *bool  `json:"active"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" || requestBody.Active == nil {
+		http.Error(w, "los campos id y active son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.authorizeTemplate(r.Context(), requestBody.ID); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	template, err := h.repo.SetScheduleTemplateActive(r.Context(), requestBody.ID, *requestBody.Active)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// GenerateSchedulesHandler maneja las solicitudes para generar las salidas de los horarios activos.
+// El campo opcional horizon_days reemplaza el horizonte configurado. Los horarios que fallan se informan en
+// errors sin detener la generación de los demás.
+func (h *ScheduleHandler) GenerateSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		HorizonDays int `json:"horizon_days"`
+	}
+
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+			return
+		}
+	}
+	if requestBody.HorizonDays < 0 || requestBody.HorizonDays > 365 {
+		http.Error(w, "horizon_days debe estar entre 1 y 365, u omitirse para usar el horizonte configurado", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.generator.Generate(r.Context(), time.Now(), time.Duration(requestBody.HorizonDays)*24*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetHolidaysHandler maneja las solicitudes para consultar los feriados
+func (h *ScheduleHandler) GetHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	holidays, err := h.repo.GetHolidays(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holidays)
+}
+
+// SaveHolidayHandler maneja las solicitudes para registrar un feriado
+func (h *ScheduleHandler) SaveHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	var holiday Holiday
+	err := json.NewDecoder(r.Body).Decode(&holiday)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := time.Parse(dateLayout, holiday.Date); err != nil || strings.TrimSpace(holiday.Name) == "" {
+		http.Error(w, "los campos date (AAAA-MM-DD) y name son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SaveHoliday(r.Context(), &holiday); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holiday)
+}
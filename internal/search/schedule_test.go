@@ -0,0 +1,215 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{name: "diaria", rule: "FREQ=DAILY"},
+		{name: "con prefijo y minúsculas", rule: "RRULE:freq=weekly;byday=mo,fr"},
+		{name: "con intervalo", rule: "FREQ=DAILY;INTERVAL=3"},
+		{name: "sin frecuencia", rule: "BYDAY=MO", wantErr: true},
+		{name: "frecuencia no soportada", rule: "FREQ=MONTHLY", wantErr: true},
+		{name: "intervalo no positivo", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "día desconocido", rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "parte no soportada", rule: "FREQ=DAILY;COUNT=3", wantErr: true},
+		{name: "parte sin valor", rule: "FREQ=DAILY;INTERVAL", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRecurrence(tt.rule)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("parseRecurrence(%q): error = %v, se esperaba ErrInvalidSchedule", tt.rule, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("parseRecurrence(%q): %v", tt.rule, err)
+			}
+		})
+	}
+}
+
+func TestRecurrenceMatches(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, scheduleLocation) // Lunes
+	day := func(d int) time.Time { return time.Date(2026, 6, d, 0, 0, 0, 0, scheduleLocation) }
+
+	tests := []struct {
+		name string
+		rule string
+		days map[int]bool // Días de junio y si la regla genera salida
+	}{
+		{name: "diaria", rule: "FREQ=DAILY", days: map[int]bool{1: true, 2: true, 7: true}},
+		{name: "cada tres días", rule: "FREQ=DAILY;INTERVAL=3", days: map[int]bool{1: true, 2: false, 4: true, 7: true, 8: false}},
+		{name: "diaria de lunes a viernes", rule: "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", days: map[int]bool{5: true, 6: false, 7: false, 8: true}},
+		{name: "semanal sin días usa el día de inicio", rule: "FREQ=WEEKLY", days: map[int]bool{1: true, 2: false, 8: true}},
+		{name: "cada dos semanas", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", days: map[int]bool{1: true, 5: true, 8: false, 12: false, 15: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for d, want := range tt.days {
+				if got := rule.matches(start, day(d)); got != want {
+					t.Errorf("matches(%d de junio) = %v, se esperaba %v", d, got, want)
+				}
+			}
+		})
+	}
+
+	rule, _ := parseRecurrence("FREQ=DAILY")
+	if rule.matches(start, start.AddDate(0, 0, -1)) {
+		t.Error("la regla no debe generar salidas antes del día de inicio")
+	}
+}
+
+// testScheduleTemplate arma un horario diario Lima - Ica a las 08:00 del 1 al 5 de junio de 2026
+func testScheduleTemplate() *ScheduleTemplate {
+	holidayPrice, exceptionPrice := 90.0, 100.0
+	return &ScheduleTemplate{
+		ID:            "horario-1",
+		OperatorID:    "op-1",
+		OriginCode:    "LIM",
+		DestCode:      "ICA",
+		DepartureTime: "08:00",
+		Duration:      240,
+		Recurrence:    "FREQ=DAILY",
+		Seats:         40,
+		Price:         60,
+		ValidFrom:     "2026-06-01",
+		ValidUntil:    "2026-06-05",
+		Holidays:      &ScheduleOverride{DepartureTime: "10:00", Price: &holidayPrice},
+		Exceptions: []ScheduleException{
+			{Date: "2026-06-03", ScheduleOverride: ScheduleOverride{Price: &exceptionPrice}},
+			{Date: "2026-06-04", ScheduleOverride: ScheduleOverride{Cancelled: true}},
+		},
+		Active: true,
+	}
+}
+
+func TestScheduleDepartures(t *testing.T) {
+	template := testScheduleTemplate()
+	holidays := map[string]bool{"2026-06-02": true, "2026-06-03": true}
+	// La salida del 1 de junio ya partió
+	from := time.Date(2026, 6, 1, 9, 0, 0, 0, scheduleLocation)
+	to := time.Date(2026, 6, 10, 0, 0, 0, 0, scheduleLocation)
+
+	departures, err := scheduleDepartures(template, holidays, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		id        string
+		hour      int
+		price     float64
+		cancelled bool
+	}{
+		{id: "horario-1-20260602", hour: 10, price: 90},  // Feriado
+		{id: "horario-1-20260603", hour: 10, price: 100}, // La excepción prevalece sobre el feriado
+		{id: "horario-1-20260604", hour: 8, price: 60, cancelled: true},
+		{id: "horario-1-20260605", hour: 8, price: 60}, // Último día de vigencia
+	}
+	if len(departures) != len(want) {
+		t.Fatalf("salidas = %d, se esperaban %d", len(departures), len(want))
+	}
+	for i, w := range want {
+		route := departures[i].route
+		departure := route.Departure.In(scheduleLocation)
+		if route.ID != w.id || departure.Hour() != w.hour || route.Price != w.price || departures[i].cancelled != w.cancelled {
+			t.Errorf("salida %d = %s a las %d con precio %v (cancelada %v), se esperaba %s a las %d con precio %v (cancelada %v)",
+				i, route.ID, departure.Hour(), route.Price, departures[i].cancelled, w.id, w.hour, w.price, w.cancelled)
+		}
+		if !route.Arrival.Equal(route.Departure.Add(4*time.Hour)) || route.TemplateID != template.ID || route.Capacity != 40 {
+			t.Errorf("salida %s = llegada %s, horario %q y capacidad %d", route.ID, route.Arrival, route.TemplateID, route.Capacity)
+		}
+	}
+}
+
+func TestScheduleDeparturesInvalidTemplate(t *testing.T) {
+	template := testScheduleTemplate()
+	template.ValidFrom = "01/06/2026"
+
+	if _, err := scheduleDepartures(template, nil, time.Now(), time.Now().Add(time.Hour)); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("scheduleDepartures() con fecha inválida: error = %v, se esperaba ErrInvalidSchedule", err)
+	}
+}
+
+// generatorRepository simula el repositorio para la generación de salidas; los demás métodos no se usan
+type generatorRepository struct {
+	SearchRepository
+	templates []*ScheduleTemplate
+	existing  map[string]bool // Salidas ya generadas
+	sold      map[string]bool // Salidas con asientos vendidos
+	cancelled []string
+}
+
+func (r *generatorRepository) GetScheduleTemplates(ctx context.Context) ([]*ScheduleTemplate, error) {
+	return r.templates, nil
+}
+
+func (r *generatorRepository) GetHolidays(ctx context.Context) ([]*Holiday, error) {
+	return nil, nil
+}
+
+func (r *generatorRepository) MaterializeRoute(ctx context.Context, route *Route) (bool, bool, error) {
+	if !r.existing[route.ID] {
+		r.existing[route.ID] = true
+		return true, false, nil
+	}
+	return false, !r.sold[route.ID], nil
+}
+
+func (r *generatorRepository) CancelUnsoldRoute(ctx context.Context, routeID string) error {
+	if !r.existing[routeID] {
+		return ErrRouteNotFound
+	}
+	if r.sold[routeID] {
+		return ErrRouteHasReservations
+	}
+	r.cancelled = append(r.cancelled, routeID)
+	return nil
+}
+
+func TestGenerateUpdatesAndCancelsGeneratedDepartures(t *testing.T) {
+	template := testScheduleTemplate()
+	template.Exceptions, template.Holidays = nil, nil
+	repo := &generatorRepository{
+		templates: []*ScheduleTemplate{template},
+		existing:  map[string]bool{"horario-1-20260601": true, "horario-1-20260602": true},
+		sold:      map[string]bool{"horario-1-20260602": true},
+	}
+	generator := NewScheduleGenerator(repo, 10*24*time.Hour)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, scheduleLocation)
+
+	result, err := generator.Generate(context.Background(), now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Templates != 1 || result.Created != 3 || result.Updated != 1 || result.Existing != 1 {
+		t.Errorf("resultado = %+v, se esperaban 3 nuevas, 1 actualizada sin ventas y 1 existente con ventas", *result)
+	}
+
+	// Al desactivar el horario se cancelan sus salidas sin ventas y las vendidas quedan como conflicto
+	template.Active = false
+	result, err = generator.Generate(context.Background(), now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Templates != 0 || result.Cancelled != 4 || len(result.Conflicts) != 1 || result.Conflicts[0] != "horario-1-20260602" {
+		t.Errorf("resultado = %+v, se esperaban 4 canceladas y la salida vendida como conflicto", *result)
+	}
+	if result.Created != 0 || result.Updated != 0 {
+		t.Errorf("un horario inactivo no debe crear ni actualizar salidas: %+v", *result)
+	}
+}
@@ -0,0 +1,233 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInvalidSegment indica que las paradas pedidas no forman un tramo de la ruta
+var ErrInvalidSegment = errors.New("tramo inválido")
+
+// StopCodes devuelve los códigos de las paradas de la ruta en orden; una ruta sin paradas va directo del origen al destino
+func (r *Route) StopCodes() []string {
+	if len(r.Stops) == 0 {
+		return []string{r.OriginCode, r.DestCode}
+	}
+	codes := make([]string, 0, len(r.Stops))
+	for _, stop := range r.Stops {
+		codes = append(codes, stop.Code)
+	}
+	return codes
+}
+
+// InitSegments deja todos los asientos de la ruta disponibles, también en cada tramo entre paradas
+func (r *Route) InitSegments() {
+	r.Capacity = r.Seats
+	r.SegmentSeats, r.SegmentOccupied = nil, nil
+	if len(r.Stops) == 0 {
+		return
+	}
+
+	legs := len(r.Stops) - 1
+	r.SegmentSeats = make([]int, legs)
+	r.SegmentOccupied = make([][]string, legs)
+	for i := range r.SegmentSeats {
+		r.SegmentSeats[i] = r.Seats
+		r.SegmentOccupied[i] = []string{}
+	}
+}
+
+// SegmentBetween obtiene el tramo de la ruta entre dos paradas. Una parada vacía equivale al origen o al destino.
+func (r *Route) SegmentBetween(from, to string) (*Segment, error) {
+	codes := r.StopCodes()
+	fromIndex, toIndex := 0, len(codes)-1
+	if from != "" {
+		fromIndex = indexOf(codes, from)
+	}
+	if to != "" {
+		toIndex = indexOf(codes, to)
+	}
+	if fromIndex < 0 || toIndex < 0 || fromIndex >= toIndex {
+		return nil, fmt.Errorf("%w: la ruta %s no va de %s a %s", ErrInvalidSegment, r.ID, from, to)
+	}
+
+	segment := &Segment{
+		From:      codes[fromIndex],
+		To:        codes[toIndex],
+		FromIndex: fromIndex,
+		ToIndex:   toIndex,
+		Departure: r.Departure,
+		Arrival:   r.Arrival,
+		Price:     r.segmentPrice(fromIndex, toIndex),
+		Seats:     r.AvailableSeats(fromIndex, toIndex),
+	}
+	if len(r.Stops) > 0 {
+		segment.Departure = r.Stops[fromIndex].Departure
+		segment.Arrival = r.Stops[toIndex].Arrival
+	}
+	return segment, nil
+}
+
+// IsFullRoute indica si el tramo va del origen al destino de la ruta
+func (s *Segment) IsFullRoute(route *Route) bool {
+	return s.FromIndex == 0 && s.ToIndex == len(route.StopCodes())-1
+}
+
+// AvailableSeats devuelve los asientos libres en todos los tramos entre las paradas from y to (índices)
+func (r *Route) AvailableSeats(from, to int) int {
+	if len(r.SegmentSeats) == 0 {
+		return r.Seats
+	}
+	available := r.SegmentSeats[from]
+	for _, seats := range r.SegmentSeats[from:to] {
+		if seats < available {
+			available = seats
+		}
+	}
+	return available
+}
+
+// OccupiedSeatsBetween devuelve los asientos numerados tomados en algún tramo entre las paradas from y to (índices)
+func (r *Route) OccupiedSeatsBetween(from, to int) map[string]bool {
+	occupied := make(map[string]bool)
+	if len(r.SegmentOccupied) == 0 {
+		for _, number := range r.OccupiedSeats {
+			occupied[number] = true
+		}
+		return occupied
+	}
+	for _, leg := range r.SegmentOccupied[from:to] {
+		for _, number := range leg {
+			occupied[number] = true
+		}
+	}
+	return occupied
+}
+
+// segmentPrice calcula el precio por asiento del tramo con las tarifas acumuladas de las paradas.
+// Si alguna parada del tramo no tiene tarifa, el precio se prorratea según la duración del tramo.
+func (r *Route) segmentPrice(from, to int) float64 {
+	last := len(r.StopCodes()) - 1
+	if from == 0 && to == last {
+		return r.Price
+	}
+
+	fare := func(i int) float64 {
+		switch i {
+		case 0:
+			return 0
+		case last:
+			return r.Price
+		default:
+			return r.Stops[i].Fare
+		}
+	}
+	// Las tarifas pueden quedar desfasadas si luego se rebaja el precio de la ruta
+	if (from == 0 || fare(from) > 0) && (to == last || fare(to) > 0) && fare(to) > fare(from) {
+		return fare(to) - fare(from)
+	}
+
+	total := r.Arrival.Sub(r.Departure)
+	if total <= 0 {
+		return r.Price
+	}
+	duration := r.Stops[to].Arrival.Sub(r.Stops[from].Departure)
+	return math.Round(r.Price*float64(duration)/float64(total)*10) / 10
+}
+
+// validateStops verifica las paradas de una ruta nueva: el orden de los horarios, que no se repitan ciudades
+// y las tarifas acumuladas. Completa los códigos y nombres, y las horas del origen y el destino.
+func validateStops(route *Route, locations LocationResolver) error {
+	if len(route.Stops) < 2 {
+		return fmt.Errorf("%w: las paradas deben incluir el origen y el destino", ErrInvalidRoute)
+	}
+
+	seen := make(map[string]bool, len(route.Stops))
+	for i := range route.Stops {
+		stop := &route.Stops[i]
+		code, name, err := locations.ResolveCity(stop.Code)
+		if err != nil {
+			return fmt.Errorf("%w: parada desconocida %q", ErrInvalidRoute, stop.Code)
+		}
+		if seen[code] {
+			return fmt.Errorf("%w: la parada %s está repetida", ErrInvalidRoute, code)
+		}
+		seen[code] = true
+		stop.Code, stop.Name = code, name
+	}
+
+	first, last := &route.Stops[0], &route.Stops[len(route.Stops)-1]
+	if first.Code != route.OriginCode || last.Code != route.DestCode {
+		return fmt.Errorf("%w: la primera parada debe ser el origen y la última el destino", ErrInvalidRoute)
+	}
+	first.Arrival, first.Departure, first.Fare = route.Departure, route.Departure, 0
+	last.Arrival, last.Departure, last.Fare = route.Arrival, route.Arrival, 0
+
+	if err := checkStopTimes(route.Stops); err != nil {
+		return err
+	}
+
+	previousFare := 0.0
+	fares := 0
+	for i := 1; i < len(route.Stops)-1; i++ {
+		stop := &route.Stops[i]
+		if stop.Fare > 0 {
+			if stop.Fare <= previousFare || stop.Fare >= route.Price {
+				return fmt.Errorf("%w: la tarifa acumulada de la parada %s debe crecer y ser menor al precio", ErrInvalidRoute, stop.Code)
+			}
+			previousFare = stop.Fare
+			fares++
+		}
+	}
+	if fares > 0 && fares != len(route.Stops)-2 {
+		return fmt.Errorf("%w: indique la tarifa de todas las paradas intermedias o de ninguna", ErrInvalidRoute)
+	}
+
+	return nil
+}
+
+// checkStopTimes verifica que cada parada intermedia tenga llegada y partida entre las de las paradas vecinas
+func checkStopTimes(stops []Stop) error {
+	for i := 1; i < len(stops)-1; i++ {
+		stop := stops[i]
+		if !stop.Arrival.After(stops[i-1].Departure) || stop.Departure.Before(stop.Arrival) || !stops[i+1].Arrival.After(stop.Departure) {
+			return fmt.Errorf("%w: los horarios de la parada %s no siguen el orden del viaje", ErrInvalidRoute, stop.Code)
+		}
+	}
+	return nil
+}
+
+// rescheduleStops desplaza las paradas intermedias tanto como la salida y ajusta el origen y el destino al nuevo horario
+func rescheduleStops(route *Route, departure, arrival time.Time) ([]Stop, error) {
+	if len(route.Stops) == 0 {
+		return nil, nil
+	}
+
+	delta := departure.Sub(route.Departure)
+	stops := make([]Stop, len(route.Stops))
+	copy(stops, route.Stops)
+	for i := range stops {
+		stops[i].Arrival = stops[i].Arrival.Add(delta)
+		stops[i].Departure = stops[i].Departure.Add(delta)
+	}
+	first, last := &stops[0], &stops[len(stops)-1]
+	first.Arrival, first.Departure = departure, departure
+	last.Arrival, last.Departure = arrival, arrival
+
+	if err := checkStopTimes(stops); err != nil {
+		return nil, fmt.Errorf("%w: las paradas intermedias no caben en el nuevo horario", ErrInvalidRoute)
+	}
+	return stops, nil
+}
+
+// indexOf devuelve la posición del código en la lista o -1 si no está
+func indexOf(codes []string, code string) int {
+	for i, c := range codes {
+		if c == code {
+			return i
+		}
+	}
+	return -1
+}
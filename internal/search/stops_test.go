@@ -0,0 +1,105 @@
+package search
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testRoute arma una salida Lima - Ica - Nazca - Arequipa de 16 horas con las tarifas acumuladas indicadas
+func testRoute(price, icaFare, nazcaFare float64) *Route {
+	departure := time.Date(2026, 6, 1, 20, 0, 0, 0, time.UTC)
+	at := func(hours float64) time.Time { return departure.Add(time.Duration(hours * float64(time.Hour))) }
+
+	return &Route{
+		ID:         "ruta-1",
+		OriginCode: "LIM",
+		DestCode:   "AQP",
+		Departure:  departure,
+		Arrival:    at(16),
+		Price:      price,
+		Stops: []Stop{
+			{Code: "LIM", Arrival: at(0), Departure: at(0)},
+			{Code: "ICA", Arrival: at(4), Departure: at(4.25), Fare: icaFare},
+			{Code: "NAZ", Arrival: at(7), Departure: at(7.25), Fare: nazcaFare},
+			{Code: "AQP", Arrival: at(16), Departure: at(16)},
+		},
+		SegmentSeats: []int{10, 3, 8},
+	}
+}
+
+func TestSegmentBetweenPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    *Route
+		from, to string
+		want     float64
+	}{
+		{name: "ruta completa", route: testRoute(120, 40, 60), want: 120},
+		{name: "ruta completa con paradas explícitas", route: testRoute(120, 40, 60), from: "LIM", to: "AQP", want: 120},
+		{name: "desde el origen", route: testRoute(120, 40, 60), from: "LIM", to: "ICA", want: 40},
+		{name: "entre paradas intermedias", route: testRoute(120, 40, 60), from: "ICA", to: "NAZ", want: 20},
+		{name: "hasta el destino", route: testRoute(120, 40, 60), from: "NAZ", want: 60},
+		{name: "sin tarifas se prorratea por duración", route: testRoute(120, 0, 0), from: "LIM", to: "ICA", want: 30},
+		{name: "el prorrateo se redondea a décimos", route: testRoute(120, 0, 0), from: "ICA", to: "NAZ", want: 20.6},
+		{name: "tarifa desfasada por debajo del precio", route: testRoute(50, 40, 60), from: "ICA", want: 10},
+		{name: "tarifa desfasada por encima del precio se prorratea", route: testRoute(50, 40, 60), from: "NAZ", want: 27.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segment, err := tt.route.SegmentBetween(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("SegmentBetween(%q, %q): %v", tt.from, tt.to, err)
+			}
+			if segment.Price != tt.want {
+				t.Errorf("precio del tramo %s-%s = %v, se esperaba %v", segment.From, segment.To, segment.Price, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentBetweenDirectRoute(t *testing.T) {
+	route := &Route{ID: "ruta-2", OriginCode: "LIM", DestCode: "TRU", Price: 70, Seats: 12}
+
+	segment, err := route.SegmentBetween("LIM", "TRU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segment.Price != 70 || segment.Seats != 12 {
+		t.Errorf("tramo = precio %v y %d asientos, se esperaba 70 y 12", segment.Price, segment.Seats)
+	}
+}
+
+func TestSegmentBetweenSeatsAndTimes(t *testing.T) {
+	route := testRoute(120, 40, 60)
+
+	segment, err := route.SegmentBetween("ICA", "AQP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segment.Seats != 3 {
+		t.Errorf("asientos = %d, se esperaba el mínimo de los tramos recorridos, 3", segment.Seats)
+	}
+	if !segment.Departure.Equal(route.Stops[1].Departure) || !segment.Arrival.Equal(route.Arrival) {
+		t.Errorf("horario del tramo = %s - %s, se esperaba la salida de Ica y la llegada a Arequipa", segment.Departure, segment.Arrival)
+	}
+
+	segment, err = route.SegmentBetween("NAZ", "AQP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segment.Seats != 8 {
+		t.Errorf("asientos = %d, se esperaba 8", segment.Seats)
+	}
+}
+
+func TestSegmentBetweenInvalid(t *testing.T) {
+	route := testRoute(120, 40, 60)
+
+	for _, stops := range [][2]string{{"AQP", "LIM"}, {"ICA", "ICA"}, {"LIM", "CUS"}, {"TAC", ""}} {
+		if _, err := route.SegmentBetween(stops[0], stops[1]); !errors.Is(err, ErrInvalidSegment) {
+			t.Errorf("SegmentBetween(%q, %q) = %v, se esperaba ErrInvalidSegment", stops[0], stops[1], err)
+		}
+	}
+}
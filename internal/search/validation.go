@@ -0,0 +1,108 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"venta-de-pasajes/internal/operator"
+)
+
+// PeruCities contiene las ciudades iniciales y sus códigos, usadas por los scripts de datos de ejemplo
+var PeruCities = map[string]string{
+	"Lima":     "LIM",
+	"Cusco":    "CUZ",
+	"Arequipa": "AQP",
+	"Trujillo": "TRU",
+	"Iquitos":  "IQT",
+	"Piura":    "PIU",
+	"Tacna":    "TCQ",
+	"Pucallpa": "PCL",
+}
+
+// ErrInvalidRoute indica que los datos de la ruta no son válidos
+var ErrInvalidRoute = errors.New("ruta inválida")
+
+// LocationResolver resuelve un nombre, alias o código de ubicación a la ciudad correspondiente,
+// sin distinguir mayúsculas ni tildes. Devuelve el código y el nombre de la ciudad.
+type LocationResolver interface {
+	ResolveCity(query string) (code, name string, err error)
+}
+
+// OperatorValidator verifica que un operador exista y esté activo para asignarle rutas
+type OperatorValidator interface {
+	ValidateOperator(ctx context.Context, operatorID string) error
+}
+
+// validateOperator verifica el operador de una ruta u horario nuevo
+func validateOperator(ctx context.Context, operators OperatorValidator, operatorID string) error {
+	if operatorID == "" {
+		return fmt.Errorf("%w: el campo operator_id es obligatorio", ErrInvalidRoute)
+	}
+	if err := operators.ValidateOperator(ctx, operatorID); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRoute, err)
+	}
+	return nil
+}
+
+// validateRoute verifica los datos de una ruta nueva y completa los códigos y nombres de las ciudades.
+// Si la ruta tiene paradas, el origen y el destino pueden omitirse y se toman de la primera y la última.
+func validateRoute(route *Route, locations LocationResolver) error {
+	if len(route.Stops) > 0 {
+		if route.OriginCode == "" {
+			route.OriginCode = route.Stops[0].Code
+		}
+		if route.DestCode == "" {
+			route.DestCode = route.Stops[len(route.Stops)-1].Code
+		}
+	}
+
+	originCode, origin, err := locations.ResolveCity(route.OriginCode)
+	if err != nil {
+		return fmt.Errorf("%w: origen desconocido %q", ErrInvalidRoute, route.OriginCode)
+	}
+	destCode, destination, err := locations.ResolveCity(route.DestCode)
+	if err != nil {
+		return fmt.Errorf("%w: destino desconocido %q", ErrInvalidRoute, route.DestCode)
+	}
+	if originCode == destCode {
+		return fmt.Errorf("%w: el origen y el destino deben ser distintos", ErrInvalidRoute)
+	}
+	if err := validateSchedule(route); err != nil {
+		return err
+	}
+	if route.Seats <= 0 {
+		return fmt.Errorf("%w: la cantidad de asientos debe ser positiva", ErrInvalidRoute)
+	}
+	if route.Price <= 0 {
+		return fmt.Errorf("%w: el precio debe ser positivo", ErrInvalidRoute)
+	}
+
+	if route.Overbooking != nil {
+		if err := operator.ValidateOverbooking(*route.Overbooking); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRoute, err)
+		}
+		if *route.Overbooking == (operator.OverbookingPolicy{}) {
+			route.Overbooking = nil
+		}
+	}
+
+	route.OriginCode, route.Origin = originCode, origin
+	route.DestCode, route.Destination = destCode, destination
+
+	if len(route.Stops) > 0 {
+		return validateStops(route, locations)
+	}
+	return nil
+}
+
+// validateSchedule verifica que la ruta tenga horarios y que la llegada sea posterior a la salida
+func validateSchedule(route *Route) error {
+	if route.Departure.IsZero() || route.Arrival.IsZero() {
+		return fmt.Errorf("%w: la salida y la llegada son obligatorias", ErrInvalidRoute)
+	}
+	if !route.Arrival.After(route.Departure) {
+		return fmt.Errorf("%w: la llegada debe ser posterior a la salida", ErrInvalidRoute)
+	}
+	return nil
+}
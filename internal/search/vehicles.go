@@ -0,0 +1,122 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"venta-de-pasajes/internal/fleet"
+)
+
+var (
+	// ErrVehicleDoesNotFit indica que las reservas de la salida no caben en el vehículo
+	ErrVehicleDoesNotFit = errors.New("las reservas existentes no caben en el vehículo")
+	// ErrCapacityFromVehicle indica que la capacidad de la salida la define su vehículo asignado
+	ErrCapacityFromVehicle = errors.New("la capacidad de la ruta la define el vehículo asignado")
+	// ErrInvalidSeats indica que la selección de asientos no es válida
+	ErrInvalidSeats = errors.New("selección de asientos inválida")
+)
+
+// VehicleDirectory obtiene los vehículos de la flota
+type VehicleDirectory interface {
+	GetVehicle(ctx context.Context, vehicleID string) (*fleet.Vehicle, error)
+}
+
+// vehicleForRoute obtiene el vehículo a asignar a una ruta u horario del operador,
+// verificando que esté en servicio y pertenezca al mismo operador
+func vehicleForRoute(ctx context.Context, vehicles VehicleDirectory, vehicleID, operatorID string) (*fleet.Vehicle, error) {
+	vehicle, err := vehicles.GetVehicle(ctx, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRoute, err)
+	}
+	if !vehicle.Active {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRoute, fleet.ErrVehicleInactive)
+	}
+	if operatorID != "" && vehicle.OperatorID != operatorID {
+		return nil, fmt.Errorf("%w: el vehículo pertenece a otro operador", ErrInvalidRoute)
+	}
+	return vehicle, nil
+}
+
+// vehicleFits verifica que los asientos vendidos de la ruta y los asientos numerados ocupados en cualquiera de sus
+// tramos quepan en el croquis del vehículo. Es la misma condición con la que el repositorio asigna el vehículo.
+func vehicleFits(route *Route, vehicle *fleet.Vehicle) error {
+	// Las rutas anteriores a la capacidad no tienen el campo: toda su capacidad está en los asientos disponibles
+	reserved := 0
+	if route.Capacity > 0 {
+		reserved = route.Capacity - route.Seats
+	}
+	if reserved > vehicle.Capacity {
+		return fmt.Errorf("%w: la ruta tiene %d asientos vendidos y el bus %d asientos", ErrVehicleDoesNotFit, reserved, vehicle.Capacity)
+	}
+
+	occupied := slices.Clone(route.OccupiedSeats)
+	for _, numbers := range route.SegmentOccupied {
+		occupied = append(occupied, numbers...)
+	}
+	for _, number := range occupied {
+		if !vehicle.Layout.HasSeat(number) {
+			return fmt.Errorf("%w: el asiento %s no existe en el bus", ErrVehicleDoesNotFit, number)
+		}
+	}
+	return nil
+}
+
+// ValidateSeatNumbers verifica los asientos elegidos contra el croquis del vehículo asignado a la ruta
+func ValidateSeatNumbers(ctx context.Context, vehicles VehicleDirectory, route *Route, seats int, seatNumbers []string) error {
+	if route.VehicleID == "" {
+		return fmt.Errorf("%w: la ruta no tiene vehículo asignado", ErrInvalidSeats)
+	}
+
+	vehicle, err := vehicles.GetVehicle(ctx, route.VehicleID)
+	if err != nil {
+		return err
+	}
+	return ValidateSeatSelection(vehicle, seats, seatNumbers)
+}
+
+// ValidateSeatSelection verifica que los asientos elegidos sean tantos como los reservados,
+// no se repitan y existan en el croquis del vehículo de la salida
+func ValidateSeatSelection(vehicle *fleet.Vehicle, seats int, seatNumbers []string) error {
+	if len(seatNumbers) != seats {
+		return fmt.Errorf("%w: se deben elegir %d asientos", ErrInvalidSeats, seats)
+	}
+
+	chosen := make(map[string]bool, len(seatNumbers))
+	for _, number := range seatNumbers {
+		if chosen[number] {
+			return fmt.Errorf("%w: asiento %s repetido", ErrInvalidSeats, number)
+		}
+		chosen[number] = true
+
+		if !vehicle.Layout.HasSeat(number) {
+			return fmt.Errorf("%w: el asiento %s no existe en el bus", ErrInvalidSeats, number)
+		}
+	}
+	return nil
+}
+
+// buildSeatMap arma el croquis de un tramo de la salida marcando los asientos ocupados en él
+func buildSeatMap(route *Route, segment *Segment, vehicle *fleet.Vehicle) *SeatMap {
+	occupied := route.OccupiedSeatsBetween(segment.FromIndex, segment.ToIndex)
+
+	seatMap := &SeatMap{
+		RouteID:        route.ID,
+		VehicleID:      vehicle.ID,
+		Decks:          vehicle.Layout.Decks,
+		Rows:           vehicle.Layout.Rows,
+		Columns:        vehicle.Layout.Columns,
+		AvailableSeats: segment.Seats,
+		Amenities:      route.Amenities,
+		Seats:          make([]SeatMapSeat, 0, len(vehicle.Layout.Seats)),
+	}
+	for _, seat := range vehicle.Layout.Seats {
+		seatMap.Seats = append(seatMap.Seats, SeatMapSeat{
+			Seat:      seat,
+			Available: !occupied[seat.Number] && segment.Seats > 0 && route.Status != RouteCancelled,
+		})
+	}
+
+	return seatMap
+}
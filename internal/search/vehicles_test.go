@@ -0,0 +1,37 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestVehicleFits(t *testing.T) {
+	vehicle, _ := testVehicles{}.GetVehicle(context.Background(), "bus-1")
+
+	tests := []struct {
+		name    string
+		route   *Route
+		wantErr error
+	}{
+		{name: "sin reservas", route: &Route{Capacity: 40, Seats: 40}},
+		{name: "los asientos vendidos caben", route: &Route{Capacity: 40, Seats: 36, OccupiedSeats: []string{"1A", "2B"}}},
+		{name: "ruta anterior a la capacidad", route: &Route{Seats: 40}},
+		{name: "más asientos vendidos que los del bus", route: &Route{Capacity: 40, Seats: 35}, wantErr: ErrVehicleDoesNotFit},
+		{name: "sobreventa que no cabe", route: &Route{Capacity: 4, Seats: -1}, wantErr: ErrVehicleDoesNotFit},
+		{name: "asiento ocupado que no existe en el bus", route: &Route{Capacity: 40, Seats: 39, OccupiedSeats: []string{"10C"}}, wantErr: ErrVehicleDoesNotFit},
+		{
+			name:    "asiento ocupado en un tramo que no existe en el bus",
+			route:   &Route{Capacity: 40, Seats: 39, SegmentOccupied: [][]string{{"1A"}, {"10C"}}},
+			wantErr: ErrVehicleDoesNotFit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := vehicleFits(tt.route, vehicle); !errors.Is(err, tt.wantErr) {
+				t.Errorf("vehicleFits() = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"venta-de-pasajes/internal/fleet"
+)
+
+var (
+	// ErrInvalidWaitlistEntry indica que los datos de la inscripción en la lista de espera no son válidos
+	ErrInvalidWaitlistEntry = errors.New("inscripción en lista de espera inválida")
+	// ErrSeatsAvailable indica que la salida todavía tiene asientos y se puede reservar directamente
+	ErrSeatsAvailable = errors.New("la salida tiene asientos disponibles, reserve directamente")
+)
+
+// Waitlist administra las listas de espera de las salidas agotadas. Cuando se liberan asientos,
+// por una cancelación o por una oferta vencida, se ofrecen en estricto orden de inscripción con
+// una reserva pendiente que el usuario debe confirmar dentro del plazo.
+type Waitlist struct {
+	repo        SearchRepository
+	vehicles    VehicleDirectory
+	claimWindow time.Duration
+}
+
+// NewWaitlist crea una nueva instancia de Waitlist
+func NewWaitlist(repo SearchRepository, vehicles VehicleDirectory, claimWindow time.Duration) *Waitlist {
+	return &Waitlist{
+		repo:        repo,
+		vehicles:    vehicles,
+		claimWindow: claimWindow,
+	}
+}
+
+// Join inscribe a un usuario en la lista de espera de un tramo de la salida, opcionalmente para
+// un tipo de asiento del bus. Solo se aceptan inscripciones cuando no hay asientos para atenderlas.
+func (w *Waitlist) Join(ctx context.Context, entry *WaitlistEntry) error {
+	if entry.RouteID == "" || entry.UserID == "" {
+		return fmt.Errorf("%w: los campos route_id y user_id son obligatorios", ErrInvalidWaitlistEntry)
+	}
+	if entry.Seats <= 0 {
+		return fmt.Errorf("%w: la cantidad de asientos debe ser positiva", ErrInvalidWaitlistEntry)
+	}
+
+	route, err := w.repo.GetRouteByID(ctx, entry.RouteID)
+	if err != nil {
+		return err
+	}
+	if route.Status == RouteCancelled {
+		return ErrRouteCancelled
+	}
+	if !route.Departure.After(time.Now()) {
+		return fmt.Errorf("%w: la salida ya partió", ErrInvalidWaitlistEntry)
+	}
+
+	segment, err := route.SegmentBetween(entry.FromStop, entry.ToStop)
+	if err != nil {
+		return err
+	}
+	entry.FromStop, entry.ToStop = segment.From, segment.To
+
+	available := segment.Seats
+	if entry.FareClass != "" {
+		if route.VehicleID == "" {
+			return fmt.Errorf("%w: la salida no tiene bus asignado para elegir el tipo de asiento", ErrInvalidWaitlistEntry)
+		}
+		vehicle, err := w.vehicles.GetVehicle(ctx, route.VehicleID)
+		if err != nil {
+			return err
+		}
+		if !hasSeatType(vehicle, entry.FareClass) {
+			return fmt.Errorf("%w: el bus no tiene asientos de tipo %q", ErrInvalidWaitlistEntry, entry.FareClass)
+		}
+		available = min(available, len(freeSeatsOfType(route, segment, vehicle, entry.FareClass)))
+	}
+	if available >= entry.Seats {
+		return ErrSeatsAvailable
+	}
+
+	return w.repo.AddWaitlistEntry(ctx, entry)
+}
+
+// Leave retira a un usuario de la lista de espera. Si tenía una oferta pendiente, la reserva
+// se cancela y sus asientos se ofrecen al siguiente de la lista.
+func (w *Waitlist) Leave(ctx context.Context, entryID, userID string) (*WaitlistEntry, error) {
+	entry, err := w.entryOf(ctx, entryID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch entry.Status {
+	case WaitlistWaiting:
+		if err := w.repo.UpdateWaitlistEntry(ctx, entry.ID, WaitlistWaiting, WaitlistUpdate{Status: WaitlistCancelled}); err != nil {
+			return nil, err
+		}
+	case WaitlistOffered:
+		reservation, err := w.repo.CancelReservation(ctx, entry.ReservationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.OnSeatsReleased(ctx, reservation); err != nil {
+			log.Printf("Error al ofrecer los asientos liberados de la ruta %s: %v", entry.RouteID, err)
+		}
+	default:
+		return nil, ErrWaitlistEntryClosed
+	}
+
+	return w.repo.GetWaitlistEntry(ctx, entry.ID)
+}
+
+// Claim confirma la reserva ofrecida a una inscripción antes de que venza el plazo
+func (w *Waitlist) Claim(ctx context.Context, entryID, userID string) (*Reservation, error) {
+	entry, err := w.entryOf(ctx, entryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != WaitlistOffered {
+		return nil, ErrWaitlistEntryClosed
+	}
+
+	reservation, err := w.repo.ConfirmHold(ctx, entry.ReservationID, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	update := WaitlistUpdate{Status: WaitlistClaimed, ReservationID: reservation.ID}
+	if err := w.repo.UpdateWaitlistEntry(ctx, entry.ID, WaitlistOffered, update); err != nil {
+		// La reserva ya está confirmada; la inscripción solo queda desactualizada
+		log.Printf("Error al cerrar la inscripción %s de la lista de espera: %v", entry.ID, err)
+	}
+
+	return reservation, nil
+}
+
+// entryOf obtiene una inscripción del usuario; las de otros usuarios se tratan como inexistentes
+func (w *Waitlist) entryOf(ctx context.Context, entryID, userID string) (*WaitlistEntry, error) {
+	entry, err := w.repo.GetWaitlistEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.UserID != userID {
+		return nil, ErrWaitlistEntryNotFound
+	}
+	return entry, nil
+}
+
+// OnSeatsReleased ofrece a la lista de espera los asientos de una reserva cancelada. Si la reserva
+// era una oferta de la lista, la inscripción que la originó queda cancelada.
+func (w *Waitlist) OnSeatsReleased(ctx context.Context, reservation *Reservation) error {
+	if reservation.WaitlistEntryID != "" {
+		err := w.repo.UpdateWaitlistEntry(ctx, reservation.WaitlistEntryID, WaitlistOffered, WaitlistUpdate{Status: WaitlistCancelled})
+		if err != nil && !errors.Is(err, ErrWaitlistEntryClosed) {
+			log.Printf("Error al cancelar la inscripción %s de la lista de espera: %v", reservation.WaitlistEntryID, err)
+		}
+	}
+	return w.OfferReleasedSeats(ctx, reservation.RouteID)
+}
+
+// OnCapacityIncreased ofrece a la lista de espera los asientos que gana la ruta
+func (w *Waitlist) OnCapacityIncreased(ctx context.Context, route *Route) error {
+	return w.OfferReleasedSeats(ctx, route.ID)
+}
+
+// OnRouteCancelled cancela las inscripciones de la ruta cancelada. Las que tenían una oferta pendiente se
+// cancelan con su reserva pendiente, que ya no se podrá confirmar.
+func (w *Waitlist) OnRouteCancelled(ctx context.Context, route *Route, reservations []*Reservation) error {
+	offered, err := w.repo.GetWaitlist(ctx, route.ID, WaitlistOffered)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range offered {
+		if err := w.withdrawOffer(ctx, entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cancelled, err := w.repo.CancelRouteWaitlist(ctx, route.ID)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if total := int(cancelled) + len(offered); total > 0 {
+		log.Printf("Lista de espera de la ruta %s cancelada: %d inscripciones", route.ID, total)
+	}
+	return errors.Join(errs...)
+}
+
+// withdrawOffer cancela una inscripción con una oferta pendiente y su reserva pendiente. Si el usuario ya la
+// confirmó, la reserva confirmada se deja como las demás reservas de la ruta.
+func (w *Waitlist) withdrawOffer(ctx context.Context, entry *WaitlistEntry) error {
+	err := w.repo.UpdateWaitlistEntry(ctx, entry.ID, WaitlistOffered, WaitlistUpdate{Status: WaitlistCancelled})
+	if errors.Is(err, ErrWaitlistEntryClosed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if entry.ReservationID == "" {
+		return nil
+	}
+
+	hold, err := w.repo.GetReservationByID(ctx, entry.ReservationID)
+	if err != nil {
+		return err
+	}
+	if hold == nil || hold.Status != ReservationPending {
+		return nil
+	}
+	if _, err := w.repo.CancelReservation(ctx, hold.ID, entry.UserID); err != nil && !errors.Is(err, ErrReservationNotActive) {
+		return err
+	}
+	log.Printf("Notificación al usuario %s: la ruta %s fue cancelada, su oferta de la lista de espera (reserva %s) quedó cancelada",
+		entry.UserID, entry.RouteID, hold.ID)
+	return nil
+}
+
+// OfferReleasedSeats recorre la lista de espera de la salida en orden de inscripción y ofrece
+// los asientos disponibles. La primera inscripción que no quepa detiene el recorrido, para que
+// un grupo más chico inscrito después no le gane los asientos que se sigan liberando.
+func (w *Waitlist) OfferReleasedSeats(ctx context.Context, routeID string) error {
+	entries, err := w.repo.GetWaitlist(ctx, routeID, WaitlistWaiting)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		err := w.offer(ctx, entry)
+		if errors.Is(err, ErrNotEnoughSeats) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// offer retiene los asientos para una inscripción con una reserva pendiente que vence al terminar
+// el plazo para confirmarla. Si no hay asientos suficientes la inscripción sigue en espera y se
+// devuelve ErrNotEnoughSeats.
+func (w *Waitlist) offer(ctx context.Context, entry *WaitlistEntry) error {
+	route, err := w.repo.GetRouteByID(ctx, entry.RouteID)
+	if err != nil {
+		return err
+	}
+	if route.Status == RouteCancelled {
+		return nil
+	}
+	segment, err := route.SegmentBetween(entry.FromStop, entry.ToStop)
+	if err != nil {
+		return err
+	}
+	if segment.Seats < entry.Seats {
+		return ErrNotEnoughSeats
+	}
+
+	var seatNumbers []string
+	if entry.FareClass != "" {
+		vehicle, err := w.vehicles.GetVehicle(ctx, route.VehicleID)
+		if err != nil {
+			return err
+		}
+		free := freeSeatsOfType(route, segment, vehicle, entry.FareClass)
+		if len(free) < entry.Seats {
+			return ErrNotEnoughSeats
+		}
+		seatNumbers = free[:entry.Seats]
+	}
+
+	// Marcar la inscripción antes de retener los asientos para no ofrecérselos dos veces
+	expiresAt := time.Now().Add(w.claimWindow)
+	err = w.repo.UpdateWaitlistEntry(ctx, entry.ID, WaitlistWaiting, WaitlistUpdate{Status: WaitlistOffered, OfferExpiresAt: expiresAt})
+	if errors.Is(err, ErrWaitlistEntryClosed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hold, err := w.repo.HoldSeats(ctx, ReservationRequest{
+		RouteID:     entry.RouteID,
+		UserID:      entry.UserID,
+		Seats:       entry.Seats,
+		SeatNumbers: seatNumbers,
+		From:        entry.FromStop,
+		To:          entry.ToStop,
+	}, expiresAt, entry.ID)
+	if err != nil {
+		// Otra venta ganó los asientos: la inscripción vuelve a esperar en su lugar
+		if revertErr := w.repo.UpdateWaitlistEntry(ctx, entry.ID, WaitlistOffered, WaitlistUpdate{Status: WaitlistWaiting}); revertErr != nil {
+			return errors.Join(err, revertErr)
+		}
+		if errors.Is(err, ErrInvalidSeats) {
+			return ErrNotEnoughSeats
+		}
+		return err
+	}
+
+	update := WaitlistUpdate{Status: WaitlistOffered, ReservationID: hold.ID, OfferExpiresAt: expiresAt}
+	if err := w.repo.UpdateWaitlistEntry(ctx, entry.ID, WaitlistOffered, update); err != nil {
+		return err
+	}
+
+	log.Printf("Notificación al usuario %s: se liberaron %d asientos en la ruta %s-%s del %s, confirme la reserva %s antes del %s",
+		entry.UserID, entry.Seats, entry.FromStop, entry.ToStop, route.Departure.Format("2006-01-02 15:04"), hold.ID, expiresAt.Format("2006-01-02 15:04"))
+	return nil
+}
+
+// ExpireHolds vence las ofertas no confirmadas dentro del plazo y ofrece sus asientos al siguiente de la lista
+func (w *Waitlist) ExpireHolds(ctx context.Context, now time.Time) (int, error) {
+	holds, err := w.repo.FindExpiredHolds(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	var errs []error
+	for _, hold := range holds {
+		reservation, err := w.repo.ExpireHold(ctx, hold.ID, now)
+		if errors.Is(err, ErrReservationNotPending) {
+			// El usuario la confirmó o canceló mientras tanto
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		expired++
+
+		if reservation.WaitlistEntryID != "" {
+			err := w.repo.UpdateWaitlistEntry(ctx, reservation.WaitlistEntryID, WaitlistOffered, WaitlistUpdate{Status: WaitlistExpired})
+			if err != nil && !errors.Is(err, ErrWaitlistEntryClosed) {
+				errs = append(errs, err)
+			}
+		}
+		if err := w.OfferReleasedSeats(ctx, reservation.RouteID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return expired, errors.Join(errs...)
+}
+
+// Run vence las ofertas de la lista de espera cada interval hasta que se cancele el contexto
+func (w *Waitlist) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := w.ExpireHolds(ctx, time.Now())
+		if err != nil {
+			log.Printf("Error al vencer las ofertas de la lista de espera: %v", err)
+		}
+		if expired > 0 {
+			log.Printf("Ofertas de la lista de espera vencidas: %d", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hasSeatType indica si el bus tiene asientos del tipo dado
+func hasSeatType(vehicle *fleet.Vehicle, seatType string) bool {
+	for _, seat := range vehicle.Layout.Seats {
+		if seat.Type == seatType {
+			return true
+		}
+	}
+	return false
+}
+
+// freeSeatsOfType devuelve, en el orden del croquis, los asientos del tipo dado libres en el tramo
+func freeSeatsOfType(route *Route, segment *Segment, vehicle *fleet.Vehicle, seatType string) []string {
+	occupied := route.OccupiedSeatsBetween(segment.FromIndex, segment.ToIndex)
+
+	var free []string
+	for _, seat := range vehicle.Layout.Seats {
+		if seat.Type == seatType && !occupied[seat.Number] {
+			free = append(free, seat.Number)
+		}
+	}
+	return free
+}
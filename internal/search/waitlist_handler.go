@@ -0,0 +1,152 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"venta-de-pasajes/internal/auth"
+)
+
+// WaitlistHandler maneja las solicitudes de la lista de espera de las salidas agotadas
+type WaitlistHandler struct {
+	repo     SearchRepository
+	waitlist *Waitlist
+}
+
+// NewWaitlistHandler crea una nueva instancia de WaitlistHandler
+func NewWaitlistHandler(repo SearchRepository, waitlist *Waitlist) *WaitlistHandler {
+	return &WaitlistHandler{
+		repo:     repo,
+		waitlist: waitlist,
+	}
+}
+
+// errorStatus determina el código HTTP correspondiente a un error de la lista de espera
+func (h *WaitlistHandler) errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRouteNotFound), errors.Is(err, ErrReservationNotFound), errors.Is(err, ErrWaitlistEntryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRouteCancelled), errors.Is(err, ErrSeatsAvailable), errors.Is(err, ErrWaitlistEntryClosed),
+		errors.Is(err, ErrHoldExpired), errors.Is(err, ErrReservationNotPending), errors.Is(err, ErrReservationNotActive):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidWaitlistEntry), errors.Is(err, ErrInvalidSegment):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// JoinWaitlistHandler maneja las solicitudes para inscribirse en la lista de espera de una salida agotada
+func (h *WaitlistHandler) JoinWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		RouteID   string `json:"route_id"`
+		Seats     int    `json:"seats"`
+		FareClass string `json:"fare_class"`
+		From      string `json:"from"`
+		To        string `json:"to"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	entry := &WaitlistEntry{
+		RouteID:   requestBody.RouteID,
+		UserID:    auth.UserID(r.Context()),
+		Seats:     requestBody.Seats,
+		FareClass: requestBody.FareClass,
+		FromStop:  requestBody.From,
+		ToStop:    requestBody.To,
+	}
+	if err := h.waitlist.Join(r.Context(), entry); err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetWaitlistHandler maneja las solicitudes para consultar una inscripción con el parámetro id,
+// o las inscripciones del usuario en la lista de espera de una salida con route_id y opcionalmente status
+func (h *WaitlistHandler) GetWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID := auth.UserID(r.Context())
+
+	if entryID := r.URL.Query().Get("id"); entryID != "" {
+		entry, err := h.repo.GetWaitlistEntry(r.Context(), entryID)
+		if err == nil && entry.UserID != userID {
+			err = ErrWaitlistEntryNotFound
+		}
+		if err != nil {
+			http.Error(w, err.Error(), h.errorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(entry)
+		return
+	}
+
+	routeID := r.URL.Query().Get("route_id")
+	if routeID == "" {
+		http.Error(w, "el parámetro id o route_id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.repo.GetWaitlist(r.Context(), routeID, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Cada usuario solo ve sus propias inscripciones
+	entries = slices.DeleteFunc(entries, func(entry *WaitlistEntry) bool { return entry.UserID != userID })
+	json.NewEncoder(w).Encode(entries)
+}
+
+// ClaimWaitlistHandler maneja las solicitudes para confirmar la reserva ofrecida a una inscripción
+func (h *WaitlistHandler) ClaimWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.waitlist.Claim(r.Context(), requestBody.ID, auth.UserID(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// LeaveWaitlistHandler maneja las solicitudes para salir de la lista de espera o rechazar una oferta
+func (h *WaitlistHandler) LeaveWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		ID string `json:"id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil || requestBody.ID == "" {
+		http.Error(w, "el campo id es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.waitlist.Leave(r.Context(), requestBody.ID, auth.UserID(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), h.errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
@@ -0,0 +1,217 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"venta-de-pasajes/internal/fleet"
+)
+
+// testVehicles simula la flota con un bus de cuatro asientos, dos de ellos cama
+type testVehicles struct{}
+
+func (testVehicles) GetVehicle(ctx context.Context, vehicleID string) (*fleet.Vehicle, error) {
+	return &fleet.Vehicle{ID: vehicleID, Active: true, Capacity: 4, Layout: fleet.SeatLayout{Seats: []fleet.Seat{
+		{Number: "1A", Type: fleet.SeatSleep},
+		{Number: "1B", Type: fleet.SeatSleep},
+		{Number: "2A", Type: fleet.SeatStandard},
+		{Number: "2B", Type: fleet.SeatStandard},
+	}}}, nil
+}
+
+// soldOutRoute arma una salida de cuatro asientos agotada que parte mañana
+func soldOutRoute() *Route {
+	return &Route{
+		ID:            "ruta-1",
+		OriginCode:    "LIM",
+		DestCode:      "ICA",
+		Departure:     time.Now().Add(24 * time.Hour),
+		Capacity:      4,
+		VehicleID:     "bus-1",
+		OccupiedSeats: []string{"1A", "1B", "2A", "2B"},
+		Status:        RouteScheduled,
+	}
+}
+
+func TestWaitlistJoin(t *testing.T) {
+	departed := soldOutRoute()
+	departed.ID, departed.Departure = "ruta-partida", time.Now().Add(-time.Hour)
+	cancelled := soldOutRoute()
+	cancelled.ID, cancelled.Status = "ruta-cancelada", RouteCancelled
+	available := soldOutRoute()
+	available.ID, available.Seats, available.OccupiedSeats = "ruta-libre", 2, []string{"2A", "2B"}
+
+	tests := []struct {
+		name    string
+		entry   WaitlistEntry
+		wantErr error
+	}{
+		{name: "salida agotada", entry: WaitlistEntry{RouteID: "ruta-1", UserID: "u1", Seats: 2}},
+		{name: "salida agotada para un tipo de asiento", entry: WaitlistEntry{RouteID: "ruta-1", UserID: "u1", Seats: 1, FareClass: fleet.SeatSleep}},
+		{name: "sin usuario", entry: WaitlistEntry{RouteID: "ruta-1", Seats: 1}, wantErr: ErrInvalidWaitlistEntry},
+		{name: "sin asientos", entry: WaitlistEntry{RouteID: "ruta-1", UserID: "u1"}, wantErr: ErrInvalidWaitlistEntry},
+		{name: "salida que ya partió", entry: WaitlistEntry{RouteID: "ruta-partida", UserID: "u1", Seats: 1}, wantErr: ErrInvalidWaitlistEntry},
+		{name: "salida cancelada", entry: WaitlistEntry{RouteID: "ruta-cancelada", UserID: "u1", Seats: 1}, wantErr: ErrRouteCancelled},
+		{name: "con asientos disponibles", entry: WaitlistEntry{RouteID: "ruta-libre", UserID: "u1", Seats: 2}, wantErr: ErrSeatsAvailable},
+		{name: "sin asientos libres del tipo", entry: WaitlistEntry{RouteID: "ruta-libre", UserID: "u1", Seats: 1, FareClass: fleet.SeatStandard}},
+		{name: "tipo de asiento que el bus no tiene", entry: WaitlistEntry{RouteID: "ruta-libre", UserID: "u1", Seats: 1, FareClass: fleet.SeatSemiSleep}, wantErr: ErrInvalidWaitlistEntry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepository(soldOutRoute(), departed, cancelled, available)
+			waitlist := NewWaitlist(repo, testVehicles{}, 30*time.Minute)
+
+			err := waitlist.Join(context.Background(), &tt.entry)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Join(): error = %v, se esperaba %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Join(): %v", err)
+			}
+			if len(repo.entries) != 1 || repo.entries[0].Status != WaitlistWaiting {
+				t.Errorf("inscripciones = %d, se esperaba una en espera", len(repo.entries))
+			}
+		})
+	}
+}
+
+// joinWaitlist inscribe en orden a los usuarios en la salida agotada, con la cantidad de asientos indicada
+func joinWaitlist(t *testing.T, waitlist *Waitlist, seats ...int) {
+	t.Helper()
+	for i, count := range seats {
+		entry := &WaitlistEntry{RouteID: "ruta-1", UserID: string(rune('a' + i)), Seats: count}
+		if err := waitlist.Join(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOfferReleasedSeatsKeepsOrder(t *testing.T) {
+	repo := newMemoryRepository(soldOutRoute())
+	waitlist := NewWaitlist(repo, testVehicles{}, 30*time.Minute)
+	joinWaitlist(t, waitlist, 2, 1)
+
+	// Se libera un asiento: no alcanza para el primero y el segundo no puede adelantarse
+	repo.routes["ruta-1"].Seats, repo.routes["ruta-1"].OccupiedSeats = 1, []string{"1A", "1B", "2A"}
+	if err := waitlist.OfferReleasedSeats(context.Background(), "ruta-1"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.entries[0].Status != WaitlistWaiting || repo.entries[1].Status != WaitlistWaiting {
+		t.Fatalf("estados = %s y %s, ambas inscripciones debían seguir en espera", repo.entries[0].Status, repo.entries[1].Status)
+	}
+
+	// Con dos asientos se ofrecen al primero con una reserva pendiente
+	repo.routes["ruta-1"].Seats, repo.routes["ruta-1"].OccupiedSeats = 2, []string{"1A", "1B"}
+	if err := waitlist.OfferReleasedSeats(context.Background(), "ruta-1"); err != nil {
+		t.Fatal(err)
+	}
+	first := repo.entries[0]
+	if first.Status != WaitlistOffered || first.ReservationID == "" {
+		t.Fatalf("primera inscripción = %s con la reserva %q, se esperaba ofrecida", first.Status, first.ReservationID)
+	}
+	hold := repo.reservations[first.ReservationID]
+	if hold.Status != ReservationPending || hold.Seats != 2 || hold.WaitlistEntryID != first.ID {
+		t.Errorf("reserva ofrecida = %+v, se esperaba pendiente de 2 asientos", *hold)
+	}
+	if repo.entries[1].Status != WaitlistWaiting || repo.routes["ruta-1"].Seats != 0 {
+		t.Errorf("la segunda inscripción debía seguir en espera sin asientos libres")
+	}
+}
+
+func TestOnCapacityIncreasedOffersSeats(t *testing.T) {
+	repo := newMemoryRepository(soldOutRoute())
+	waitlist := NewWaitlist(repo, testVehicles{}, 30*time.Minute)
+	joinWaitlist(t, waitlist, 1)
+
+	// La ruta gana un asiento sin que se cancele ninguna reserva
+	route := repo.routes["ruta-1"]
+	route.Capacity, route.Seats = 5, 1
+	if err := waitlist.OnCapacityIncreased(context.Background(), route); err != nil {
+		t.Fatal(err)
+	}
+	if repo.entries[0].Status != WaitlistOffered {
+		t.Errorf("estado = %s, se esperaba que se ofreciera el asiento nuevo", repo.entries[0].Status)
+	}
+}
+
+func TestExpireHoldsOffersToNext(t *testing.T) {
+	repo := newMemoryRepository(soldOutRoute())
+	waitlist := NewWaitlist(repo, testVehicles{}, 30*time.Minute)
+	joinWaitlist(t, waitlist, 1, 1)
+
+	repo.routes["ruta-1"].Seats, repo.routes["ruta-1"].OccupiedSeats = 1, []string{"1A", "1B", "2A"}
+	if err := waitlist.OfferReleasedSeats(context.Background(), "ruta-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Antes del plazo no vence nada
+	if expired, err := waitlist.ExpireHolds(context.Background(), time.Now()); err != nil || expired != 0 {
+		t.Fatalf("ExpireHolds() antes del plazo = %d, %v; no debía vencer ofertas", expired, err)
+	}
+
+	expired, err := waitlist.ExpireHolds(context.Background(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired != 1 || repo.entries[0].Status != WaitlistExpired {
+		t.Fatalf("ofertas vencidas = %d y primera inscripción %s, se esperaba 1 vencida", expired, repo.entries[0].Status)
+	}
+	if repo.entries[1].Status != WaitlistOffered {
+		t.Errorf("segunda inscripción = %s, se esperaba que recibiera el asiento liberado", repo.entries[1].Status)
+	}
+}
+
+func TestOnRouteCancelledWithdrawsOffers(t *testing.T) {
+	repo := newMemoryRepository(soldOutRoute())
+	waitlist := NewWaitlist(repo, testVehicles{}, 30*time.Minute)
+	joinWaitlist(t, waitlist, 1, 1)
+
+	repo.routes["ruta-1"].Seats, repo.routes["ruta-1"].OccupiedSeats = 1, []string{"1A", "1B", "2A"}
+	if err := waitlist.OfferReleasedSeats(context.Background(), "ruta-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	route := repo.routes["ruta-1"]
+	route.Status = RouteCancelled
+	if err := waitlist.OnRouteCancelled(context.Background(), route, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range repo.entries {
+		if entry.Status != WaitlistCancelled {
+			t.Errorf("inscripción %s = %s, se esperaba cancelada", entry.ID, entry.Status)
+		}
+	}
+	if hold := repo.reservations[repo.entries[0].ReservationID]; hold.Status != ReservationCancelled {
+		t.Errorf("reserva ofrecida = %s, se esperaba cancelada", hold.Status)
+	}
+}
+
+// capacityHook registra las rutas que ganaron asientos
+type capacityHook struct {
+	routes []string
+}
+
+func (h *capacityHook) OnCapacityIncreased(ctx context.Context, route *Route) error {
+	h.routes = append(h.routes, route.ID)
+	return nil
+}
+
+func TestRunCapacityIncreaseHooks(t *testing.T) {
+	hook := &capacityHook{}
+	handler := &SearchHandler{}
+	handler.RegisterCapacityIncreaseHook(hook)
+
+	handler.runCapacityIncreaseHooks(context.Background(), &Route{ID: "igual", Seats: 2}, &Route{ID: "igual", Seats: 2})
+	handler.runCapacityIncreaseHooks(context.Background(), &Route{ID: "menor", Seats: 2}, &Route{ID: "menor", Seats: 1})
+	handler.runCapacityIncreaseHooks(context.Background(), &Route{ID: "mayor", Seats: 0}, &Route{ID: "mayor", Seats: 6})
+
+	if len(hook.routes) != 1 || hook.routes[0] != "mayor" {
+		t.Errorf("rutas notificadas = %v, solo se esperaba la que ganó asientos", hook.routes)
+	}
+}
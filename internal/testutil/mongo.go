@@ -0,0 +1,44 @@
+// Package testutil reúne las utilidades compartidas por las pruebas de los repositorios
+package testutil
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+// MongoConfig devuelve la configuración de una base de datos de prueba con nombre aleatorio en MONGO_TEST_URL,
+// que se elimina al terminar la prueba. La prueba se omite si MONGO_TEST_URL no está definida.
+func MongoConfig(t testing.TB) *config.Config {
+	t.Helper()
+
+	url := os.Getenv("MONGO_TEST_URL")
+	if url == "" {
+		t.Skip("MONGO_TEST_URL no está definida; se omiten las pruebas con MongoDB")
+	}
+
+	cfg := config.NewConfig()
+	cfg.MongoDB.MongoURL = url
+	cfg.MongoDB.DatabaseName = "venta_de_pasajes_test_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+		if err != nil {
+			t.Logf("Error al conectar para eliminar la base de datos de prueba %s: %v", cfg.MongoDB.DatabaseName, err)
+			return
+		}
+		defer client.Disconnect(ctx)
+		if err := client.Database(cfg.MongoDB.DatabaseName).Drop(ctx); err != nil {
+			t.Logf("Error al eliminar la base de datos de prueba %s: %v", cfg.MongoDB.DatabaseName, err)
+		}
+	})
+	return cfg
+}
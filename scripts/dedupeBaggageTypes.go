@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/baggage"
+)
+
+// Depura los tipos de equipaje con nombres duplicados, registrados antes del índice único por nombre. De cada
+// nombre se conserva el tipo activo registrado primero; los demás se desactivan y se renombran con su ID para
+// que baggage-service pueda crear el índice. Las líneas ya vendidas conservan su tipo y su precio.
+//
+//	go run scripts/dedupeBaggageTypes.go
+func main() {
+	// Obtener la configuración desde el paquete config
+	cfg := config.NewConfig()
+
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err = client.Connect(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	// Seleccionar la base de datos y la colección
+	db := client.Database(cfg.MongoDB.DatabaseName)
+	typesCollection := db.Collection(cfg.MongoDB.BaggageTypesCollection)
+
+	renamed, err := baggage.DeduplicateBaggageTypes(ctx, typesCollection)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Se depuraron %d tipos de equipaje duplicados", renamed)
+}
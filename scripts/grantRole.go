@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/auth"
+)
+
+// Asigna el rol de una cuenta ya registrada. Sirve para crear el primer superadmin, que luego asigna los demás
+// roles en /admin/users/role.
+//
+//	go run scripts/grantRole.go correo@ejemplo.com superadmin
+//	go run scripts/grantRole.go correo@ejemplo.com admin_operador <operator_id>
+func main() {
+	if len(os.Args) < 3 {
+		log.Fatal("uso: go run scripts/grantRole.go <correo> <rol> [operator_id]")
+	}
+	email, role := strings.ToLower(strings.TrimSpace(os.Args[1])), os.Args[2]
+	operatorID := ""
+	if len(os.Args) > 3 {
+		operatorID = os.Args[3]
+	}
+	if !auth.ValidRole(role) {
+		log.Fatalf("rol desconocido: %s", role)
+	}
+	if (role == auth.RoleOperatorAdmin) != (operatorID != "") {
+		log.Fatalf("el operator_id se indica solo, y siempre, para el rol %s", auth.RoleOperatorAdmin)
+	}
+
+	// Obtener la configuración desde el paquete config
+	cfg := config.NewConfig()
+
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = client.Connect(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	// Seleccionar la base de datos y la colección
+	db := client.Database(cfg.MongoDB.DatabaseName)
+	usersCollection := db.Collection(cfg.MongoDB.UsersCollection)
+
+	update := bson.M{"$set": bson.M{"role": role}, "$unset": bson.M{"operator_id": ""}}
+	if operatorID != "" {
+		update = bson.M{"$set": bson.M{"role": role, "operator_id": operatorID}}
+	}
+	result, err := usersCollection.UpdateOne(ctx, bson.M{"email": email}, update)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if result.MatchedCount == 0 {
+		log.Fatalf("no existe una cuenta con el correo %s; regístrela primero en /auth/register", email)
+	}
+
+	log.Printf("Rol %s asignado a %s; rige desde su siguiente solicitud", role, email)
+}
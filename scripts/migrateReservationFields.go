@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+)
+
+// Renombra los campos de las reservas guardadas antes de que el modelo fijara sus nombres en bson: routeid,
+// userid y totalprice pasan a route_id, user_id y total_price. Sin esta migración las reservas anteriores no
+// aparecen en las consultas por ruta ni por usuario. Se puede ejecutar más de una vez.
+//
+//	go run scripts/migrateReservationFields.go
+func main() {
+	// Obtener la configuración desde el paquete config
+	cfg := config.NewConfig()
+
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err = client.Connect(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	// Seleccionar la base de datos y la colección
+	db := client.Database(cfg.MongoDB.DatabaseName)
+	reservationsCollection := db.Collection(cfg.MongoDB.ReservationsCollection)
+
+	renames := []struct{ from, to string }{
+		{"routeid", "route_id"},
+		{"userid", "user_id"},
+		{"totalprice", "total_price"},
+	}
+	for _, rename := range renames {
+		result, err := reservationsCollection.UpdateMany(
+			ctx,
+			bson.M{rename.from: bson.M{"$exists": true}},
+			bson.M{"$rename": bson.M{rename.from: rename.to}},
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Campo %s renombrado a %s en %d reservas", rename.from, rename.to, result.ModifiedCount)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
@@ -35,16 +36,59 @@ func main() {
 	db := client.Database(cfg.MongoDB.DatabaseName)
 	baggageTypesCollection := db.Collection(cfg.MongoDB.BaggageTypesCollection)
 
+	// Depurar los duplicados dejados por ejecuciones anteriores: se desactivan y renombran, no se eliminan,
+	// porque las líneas ya vendidas siguen apuntando a ellos
+	if _, err := baggage.DeduplicateBaggageTypes(ctx, baggageTypesCollection); err != nil {
+		log.Fatal(err)
+	}
+
+	// Categorías de equipaje especial
+	categories := []*baggage.BaggageCategory{
+		{Code: baggage.CategorySports, Name: "Equipo deportivo", MaxPerDeparture: 4},
+		{Code: baggage.CategoryPet, Name: "Mascotas", RequiresApproval: true, MaxPerDeparture: 2, RequiredDocuments: []string{"certificado_sanitario", "carnet_vacunacion"}},
+		{Code: baggage.CategoryFragile, Name: "Frágil", MaxPerDeparture: 6, RequiresDeclaration: true},
+		{Code: baggage.CategoryRestricted, Name: "Mercancía restringida", RequiresApproval: true, RequiresDeclaration: true},
+	}
+
+	// Registrar las categorías, actualizando sus reglas si ya existen
+	categoriesCollection := db.Collection(cfg.MongoDB.BaggageCategoriesCollection)
+	for _, category := range categories {
+		_, err := categoriesCollection.ReplaceOne(ctx, bson.M{"_id": category.Code}, category, options.Replace().SetUpsert(true))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Tipos de equipaje
+	now := time.Now()
 	baggageTypes := []*baggage.BaggageType{
-		{ID: uuid.New().String(), Name: "Maleta pequeña", Price: 10.0},
-		{ID: uuid.New().String(), Name: "Maleta mediana", Price: 20.0},
-		{ID: uuid.New().String(), Name: "Maleta grande", Price: 30.0},
+		{Name: "Maleta pequeña", Price: 10.0, Weight: 10, Volume: 0.04},
+		{Name: "Maleta mediana", Price: 20.0, Weight: 18, Volume: 0.07},
+		{Name: "Maleta grande", Price: 30.0, Weight: 23, Volume: 0.11},
+		{Name: "Bicicleta", Price: 40.0, Category: baggage.CategorySports, Weight: 15, Volume: 0.35},
+		{Name: "Tabla de surf", Price: 45.0, Category: baggage.CategorySports, Weight: 8, Volume: 0.3},
+		{Name: "Mascota en jaula", Price: 50.0, Category: baggage.CategoryPet, Weight: 12, Volume: 0.2},
+		{Name: "Artículo frágil", Price: 25.0, Category: baggage.CategoryFragile, Weight: 8, Volume: 0.06},
 	}
 
-	// Insertar los tipos de equipaje en la base de datos
+	// Insertar los tipos de equipaje que no existan, sin modificar los ya registrados
 	for _, bt := range baggageTypes {
-		_, err := baggageTypesCollection.InsertOne(ctx, bt)
+		_, err := baggageTypesCollection.UpdateOne(
+			ctx,
+			bson.M{"name": bt.Name},
+			bson.M{"$setOnInsert": bson.M{
+				"_id":           uuid.New().String(),
+				"price":         bt.Price,
+				"category":      bt.Category,
+				"weight":        bt.Weight,
+				"volume":        bt.Volume,
+				"active":        true,
+				"price_history": []baggage.PriceChange{{Price: bt.Price, EffectiveFrom: now}},
+				"created_at":    now,
+				"updated_at":    now,
+			}},
+			options.Update().SetUpsert(true),
+		)
 		if err != nil {
 			log.Fatal(err)
 		}
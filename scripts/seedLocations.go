@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/location"
+)
+
+func main() {
+	// Obtener la configuración desde el paquete config
+	cfg := config.NewConfig()
+
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = client.Connect(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	// Seleccionar la base de datos y la colección
+	db := client.Database(cfg.MongoDB.DatabaseName)
+	locationsCollection := db.Collection(cfg.MongoDB.LocationsCollection)
+
+	// Ciudades atendidas con sus alias más comunes
+	locations := []location.Location{
+		{Code: "LIM", Name: "Lima", Type: location.TypeCity, Region: "Lima", Aliases: []string{"Lima Metropolitana", "Ciudad de los Reyes"}, Latitude: -12.0464, Longitude: -77.0428},
+		{Code: "CUZ", Name: "Cusco", Type: location.TypeCity, Region: "Cusco", Aliases: []string{"Cuzco", "Qosqo"}, Latitude: -13.5320, Longitude: -71.9675},
+		{Code: "AQP", Name: "Arequipa", Type: location.TypeCity, Region: "Arequipa", Aliases: []string{"Ciudad Blanca"}, Latitude: -16.4090, Longitude: -71.5375},
+		{Code: "TRU", Name: "Trujillo", Type: location.TypeCity, Region: "La Libertad", Latitude: -8.1116, Longitude: -79.0288},
+		{Code: "IQT", Name: "Iquitos", Type: location.TypeCity, Region: "Loreto", Latitude: -3.7437, Longitude: -73.2516},
+		{Code: "PIU", Name: "Piura", Type: location.TypeCity, Region: "Piura", Latitude: -5.1945, Longitude: -80.6328},
+		{Code: "TCQ", Name: "Tacna", Type: location.TypeCity, Region: "Tacna", Latitude: -18.0066, Longitude: -70.2463},
+		{Code: "PCL", Name: "Pucallpa", Type: location.TypeCity, Region: "Ucayali", Latitude: -8.3791, Longitude: -74.5539},
+
+		// Terminales terrestres
+		{Code: "LIMPN", Name: "Terminal Plaza Norte", Type: location.TypeTerminal, CityCode: "LIM", Region: "Lima", Aliases: []string{"Plaza Norte"}, Latitude: -12.0067, Longitude: -77.0580},
+		{Code: "LIMJM", Name: "Terminal Javier Prado", Type: location.TypeTerminal, CityCode: "LIM", Region: "Lima", Aliases: []string{"Javier Prado"}, Latitude: -12.0879, Longitude: -77.0141},
+		{Code: "CUZTT", Name: "Terminal Terrestre de Cusco", Type: location.TypeTerminal, CityCode: "CUZ", Region: "Cusco", Latitude: -13.5350, Longitude: -71.9650},
+		{Code: "AQPTT", Name: "Terminal Terrestre de Arequipa", Type: location.TypeTerminal, CityCode: "AQP", Region: "Arequipa", Aliases: []string{"Terrapuerto Arequipa"}, Latitude: -16.4250, Longitude: -71.5430},
+	}
+
+	// Insertar o actualizar las ubicaciones por su código
+	for _, loc := range locations {
+		loc.Timezone = location.DefaultTimezone
+		loc.Active = true
+		loc.UpdatedAt = time.Now()
+
+		_, err := locationsCollection.ReplaceOne(ctx, bson.M{"_id": loc.Code}, loc, options.Replace().SetUpsert(true))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Println("Catálogo de ubicaciones insertado correctamente.")
+}
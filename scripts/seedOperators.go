@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"venta-de-pasajes/config"
+	"venta-de-pasajes/internal/operator"
+)
+
+func main() {
+	// Obtener la configuración desde el paquete config
+	cfg := config.NewConfig()
+
+	// Configurar cliente de MongoDB
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MongoDB.MongoURL))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Conectar al servidor de MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = client.Connect(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	// Seleccionar la base de datos y la colección
+	db := client.Database(cfg.MongoDB.DatabaseName)
+	operatorsCollection := db.Collection(cfg.MongoDB.OperatorsCollection)
+
+	// Operadores de ejemplo
+	operators := []operator.Operator{
+		{
+			Name:      "Transportes Andinos",
+			LegalName: "Transportes Andinos S.A.C.",
+			RUC:       "20601234565",
+			Policies:  operator.Policies{CancellationHours: 24, RefundPercentage: 80, ChangeHours: 6, ChangeFee: 10},
+			BaggageRules: operator.BaggageRules{
+				MaxPieceWeight: 30,
+				MaxPieces:      4,
+			},
+		},
+		{
+			Name:      "Costa Norte Express",
+			LegalName: "Costa Norte Express E.I.R.L.",
+			RUC:       "20555555556",
+			Policies:  operator.Policies{CancellationHours: 12, RefundPercentage: 50, ChangeHours: 3, ChangeFee: 15},
+			BaggageRules: operator.BaggageRules{
+				MaxPieceWeight:       25,
+				DisallowedCategories: []string{"mascota"},
+			},
+		},
+	}
+
+	// Insertar los operadores que no existan, identificados por su RUC
+	for _, op := range operators {
+		if err := operator.ValidateRUC(op.RUC); err != nil {
+			log.Fatal(err)
+		}
+
+		op.ID = uuid.New().String()
+		op.Active = true
+		op.CreatedAt = time.Now()
+		op.UpdatedAt = op.CreatedAt
+
+		_, err := operatorsCollection.UpdateOne(ctx, bson.M{"ruc": op.RUC}, bson.M{"$setOnInsert": op}, options.Update().SetUpsert(true))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Println("Operadores de ejemplo insertados correctamente.")
+}
@@ -37,16 +37,7 @@ func main() {
 	routesCollection := db.Collection(cfg.MongoDB.RoutesCollection)
 
 	// Lista de ciudades y sus códigos en el Perú
-	peruCities := map[string]string{
-		"Lima":     "LIM",
-		"Cusco":    "CUZ",
-		"Arequipa": "AQP",
-		"Trujillo": "TRU",
-		"Iquitos":  "IQT",
-		"Piura":    "PIU",
-		"Tacna":    "TCQ",
-		"Pucallpa": "PCL",
-	}
+	peruCities := search.PeruCities
 
 	// Generar todas las combinaciones de pares de ciudades
 	var routes []search.Route
@@ -73,7 +64,9 @@ func main() {
 						Departure:   time.Now().Add(24 * time.Hour),
 						Arrival:     time.Now().Add(26 * time.Hour),
 						Seats:       100,
+						Capacity:    100,
 						Price:       50.0,
+						Status:      search.RouteScheduled,
 					}
 					routes = append(routes, route)
 